@@ -0,0 +1,210 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// audioDurationSlack is how much the durations of two encodings of the same song may differ, as encoders pad and
+	// trim silence differently.
+	audioDurationSlack = 5 * time.Second
+	// audioMaxShift is the furthest two fingerprints are slid against each other to line them up, in fingerprint
+	// items (each covering about 0.12s of audio).
+	audioMaxShift = 20
+)
+
+// audioExtensions are the formats fingerprinted for --match audio.
+var audioExtensions = map[string]bool{
+	".mp3": true, ".flac": true, ".wav": true, ".m4a": true, ".aac": true, ".ogg": true, ".opus": true, ".wma": true,
+	".aif": true, ".aiff": true, ".ape": true, ".wv": true,
+}
+
+// losslessExtensions are audio formats which are always preferred over lossy ones when choosing which copy to keep.
+var losslessExtensions = map[string]bool{
+	".flac": true, ".wav": true, ".aif": true, ".aiff": true, ".ape": true, ".wv": true,
+}
+
+// audioPrint is an acoustic fingerprint, as computed by Chromaprint.
+type audioPrint struct {
+	Duration    time.Duration
+	Fingerprint []uint32
+}
+
+// audioFile is a song found by the scan, along with what's needed to group it and choose which copy to keep.
+type audioFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+	print   audioPrint
+}
+
+// compareLossless orders files in lossless formats before lossy ones.
+func compareLossless(a, b string) int {
+	aLossless, bLossless := losslessExtensions[strings.ToLower(filepath.Ext(a))], losslessExtensions[strings.ToLower(filepath.Ext(b))]
+	switch {
+	case aLossless && !bLossless:
+		return -1
+	case !aLossless && bLossless:
+		return 1
+	}
+	return 0
+}
+
+// bitrate is the file's average bitrate in bits per second, including any tags and artwork.
+func (a audioFile) bitrate() float64 {
+	if a.print.Duration <= 0 {
+		return 0
+	}
+	return float64(a.size*8) / a.print.Duration.Seconds()
+}
+
+// scanAudio groups songs which sound alike, even in different formats or at different bitrates, by comparing
+// acoustic fingerprints. The highest quality copy in each group is treated as the original: lossless before lossy,
+// then the highest bitrate, then the oldest.
+func (c *CLI) scanAudio(ctx context.Context) (map[string][]string, error) {
+	fingerprint := c.fingerprint
+	if fingerprint == nil {
+		if _, err := exec.LookPath(c.fpcalcPath()); err != nil {
+			return nil, fmt.Errorf("--match audio needs Chromaprint's fpcalc (see --fpcalc): %w", err)
+		}
+		fingerprint = c.fpcalc
+	}
+
+	var songs []audioFile
+	err := c.walk(ctx, func(path string, info os.FileInfo) {
+		if audioExtensions[strings.ToLower(filepath.Ext(path))] {
+			songs = append(songs, audioFile{path: path, size: info.Size(), modTime: info.ModTime()})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	printed := songs[:0]
+	for _, song := range songs {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("%w while fingerprinting audio; no files were changed", context.Cause(ctx))
+		}
+		if err := c.throttle.op(ctx); err != nil {
+			return nil, fmt.Errorf("%w while fingerprinting audio; no files were changed", context.Cause(ctx))
+		}
+		fp, err := fingerprint(ctx, song.path)
+		if err == nil && len(fp.Fingerprint) == 0 {
+			err = errors.New("no audio could be fingerprinted")
+		}
+		if err != nil {
+			if !c.SkipErrors {
+				return nil, fmt.Errorf("unable to fingerprint %s: %w", song.path, err)
+			}
+			c.skip(song.path, err)
+			continue
+		}
+		song.print = fp
+		printed = append(printed, song)
+	}
+
+	files := make(map[string][]string)
+	for _, cluster := range groupSimilar(len(printed), func(i, j int) bool {
+		return sameRecording(printed[i].print, printed[j].print, c.AudioThreshold)
+	}) {
+		members := make([]audioFile, len(cluster))
+		for i, idx := range cluster {
+			members[i] = printed[idx]
+		}
+		slices.SortFunc(members, func(a, b audioFile) int {
+			return cmp.Or(
+				compareLossless(a.path, b.path),
+				cmp.Compare(b.bitrate(), a.bitrate()),
+				a.modTime.Compare(b.modTime),
+				cmp.Compare(a.path, b.path),
+			)
+		})
+		for _, m := range members[1:] {
+			files[members[0].path] = append(files[members[0].path], m.path)
+		}
+	}
+	return files, nil
+}
+
+func (c *CLI) fpcalcPath() string {
+	if c.Fpcalc == "" {
+		return "fpcalc"
+	}
+	return c.Fpcalc
+}
+
+// fpcalc fingerprints the audio at path by running Chromaprint's fpcalc.
+func (c *CLI) fpcalc(ctx context.Context, path string) (audioPrint, error) {
+	var stderr strings.Builder
+	cmd := exec.CommandContext(ctx, c.fpcalcPath(), "-raw", "-json", path)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return audioPrint{}, fmt.Errorf("%w: %s", err, msg)
+		}
+		return audioPrint{}, err
+	}
+	return parseFpcalc(out)
+}
+
+// parseFpcalc reads the output of fpcalc -raw -json.
+func parseFpcalc(out []byte) (audioPrint, error) {
+	var result struct {
+		Duration float64 `json:"duration"`
+		// older versions of fpcalc print the fingerprint as signed integers
+		Fingerprint []int64 `json:"fingerprint"`
+	}
+	if err := json.Unmarshal(out, &result); err != nil {
+		return audioPrint{}, fmt.Errorf("unexpected output from fpcalc: %w", err)
+	}
+	fp := audioPrint{
+		Duration:    time.Duration(result.Duration * float64(time.Second)),
+		Fingerprint: make([]uint32, len(result.Fingerprint)),
+	}
+	for i, v := range result.Fingerprint {
+		fp.Fingerprint[i] = uint32(v)
+	}
+	return fp, nil
+}
+
+// sameRecording reports whether a and b are fingerprints of the same audio: their durations are close, and once
+// lined up, at most threshold of the bits in their overlapping fingerprint items differ.
+func sameRecording(a, b audioPrint, threshold float64) bool {
+	if (a.Duration - b.Duration).Abs() > audioDurationSlack {
+		return false
+	}
+	return fingerprintDistance(a.Fingerprint, b.Fingerprint) <= threshold
+}
+
+// fingerprintDistance is the smallest fraction of differing bits between a and b when shifted against each other by
+// up to audioMaxShift items, considering only shifts which overlap at least half of the shorter fingerprint.
+func fingerprintDistance(a, b []uint32) float64 {
+	best := math.Inf(1)
+	minOverlap := max((min(len(a), len(b))+1)/2, 1)
+	for shift := -audioMaxShift; shift <= audioMaxShift; shift++ {
+		// compare a[i] with b[i+shift]
+		start, end := max(0, -shift), min(len(a), len(b)-shift)
+		if end-start < minOverlap {
+			continue
+		}
+		differing := 0
+		for i := start; i < end; i++ {
+			differing += bits.OnesCount32(a[i] ^ b[i+shift])
+		}
+		best = min(best, float64(differing)/float64(32*(end-start)))
+	}
+	return best
+}
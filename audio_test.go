@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testFingerprint returns a pseudo-random fingerprint which differs with seed.
+func testFingerprint(seed uint64, n int) []uint32 {
+	r := rand.New(rand.NewPCG(seed, seed))
+	fp := make([]uint32, n)
+	for i := range fp {
+		fp[i] = r.Uint32()
+	}
+	return fp
+}
+
+// flipBits returns a copy of fp with every nth bit flipped, as a lossy encoding would.
+func flipBits(fp []uint32, nth int) []uint32 {
+	out := make([]uint32, len(fp))
+	for i, v := range fp {
+		for b := 0; b < 32; b++ {
+			if (i*32+b)%nth == 0 {
+				v ^= 1 << b
+			}
+		}
+		out[i] = v
+	}
+	return out
+}
+
+func TestParseFpcalc(t *testing.T) {
+	fp, err := parseFpcalc([]byte(`{"duration": 187.35, "fingerprint": [1, -1, 4294967295]}`))
+	if err != nil {
+		t.Fatalf("parseFpcalc() error = %v", err)
+	}
+	if fp.Duration != 187350*time.Millisecond {
+		t.Errorf("Duration = %v, want 3m7.35s", fp.Duration)
+	}
+	if want := []uint32{1, 0xffffffff, 0xffffffff}; !reflect.DeepEqual(fp.Fingerprint, want) {
+		t.Errorf("Fingerprint = %v, want %v", fp.Fingerprint, want)
+	}
+
+	if _, err := parseFpcalc([]byte("ERROR: unable to open file")); err == nil {
+		t.Error("parseFpcalc() of invalid output should fail")
+	}
+}
+
+func TestFingerprintDistance(t *testing.T) {
+	song := testFingerprint(1, 200)
+	tests := []struct {
+		name    string
+		other   []uint32
+		atLeast float64
+		atMost  float64
+	}{
+		{"identical", song, 0, 0},
+		{"re-encoded", flipBits(song, 10), 0.09, 0.11},
+		{"leading silence", append(testFingerprint(9, 8), song...), 0, 0},
+		{"trimmed", song[5:190], 0, 0},
+		{"different song", testFingerprint(2, 200), 0.4, 0.6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := fingerprintDistance(song, tt.other)
+			if d < tt.atLeast || d > tt.atMost {
+				t.Errorf("fingerprintDistance() = %.3f, want between %.2f and %.2f", d, tt.atLeast, tt.atMost)
+			}
+			if r := fingerprintDistance(tt.other, song); r != d {
+				t.Errorf("fingerprintDistance() isn't symmetric: %.3f and %.3f", d, r)
+			}
+		})
+	}
+}
+
+func TestSameRecording_Duration(t *testing.T) {
+	fp := testFingerprint(1, 200)
+	a := audioPrint{Duration: 180 * time.Second, Fingerprint: fp}
+	if !sameRecording(a, audioPrint{Duration: 182 * time.Second, Fingerprint: fp}, 0.15) {
+		t.Error("songs whose durations differ by 2s should match")
+	}
+	// e.g. a radio edit, whose opening is the same as the album version
+	if sameRecording(a, audioPrint{Duration: 240 * time.Second, Fingerprint: fp}, 0.15) {
+		t.Error("songs whose durations differ by a minute shouldn't match")
+	}
+}
+
+func TestCLI_ScanAudio(t *testing.T) {
+	dir := t.TempDir()
+	song, other := testFingerprint(1, 200), testFingerprint(2, 200)
+	prints := map[string]audioPrint{
+		"song.mp3":         {Duration: 200 * time.Second, Fingerprint: flipBits(song, 12)},
+		"Track 01.flac":    {Duration: 201 * time.Second, Fingerprint: song},
+		"song-128k.mp3":    {Duration: 200 * time.Second, Fingerprint: flipBits(song, 9)},
+		"other.mp3":        {Duration: 200 * time.Second, Fingerprint: other},
+		"other-copy.ogg":   {Duration: 199 * time.Second, Fingerprint: flipBits(other, 15)},
+		"unrelated.mp3":    {Duration: 95 * time.Second, Fingerprint: testFingerprint(3, 90)},
+		"broken.mp3":       {},
+		"cover-art.jpg":    {},
+		"song (1).mp3.txt": {},
+	}
+	sizes := map[string]int{
+		"song.mp3":       320,
+		"Track 01.flac":  100, // lossless beats a higher bitrate
+		"song-128k.mp3":  128,
+		"other.mp3":      192,
+		"other-copy.ogg": 256,
+	}
+	for name := range prints {
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, sizes[name]), 0644); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+
+	var fingerprinted []string
+	cli := &CLI{Path: []string{dir}, Match: "audio", AudioThreshold: 0.15, SkipErrors: true, Out: filepath.Join(dir, "results.txt")}
+	cli.fingerprint = func(_ context.Context, path string) (audioPrint, error) {
+		fingerprinted = append(fingerprinted, filepath.Base(path))
+		if filepath.Base(path) == "broken.mp3" {
+			return audioPrint{}, errors.New("invalid frame header")
+		}
+		return prints[filepath.Base(path)], nil
+	}
+	files, err := cli.scanAudio(context.Background())
+	if err != nil {
+		t.Fatalf("scanAudio() error = %v", err)
+	}
+	if len(fingerprinted) != 7 {
+		t.Errorf("fingerprinted %v, want only the 7 audio files", fingerprinted)
+	}
+	if cli.skipped != 1 {
+		t.Errorf("skipped = %d, want 1 for broken.mp3", cli.skipped)
+	}
+	want := map[string][]string{
+		filepath.Join(dir, "Track 01.flac"):  {filepath.Join(dir, "song.mp3"), filepath.Join(dir, "song-128k.mp3")},
+		filepath.Join(dir, "other-copy.ogg"): {filepath.Join(dir, "other.mp3")},
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("scanAudio() = %v, want %v", files, want)
+	}
+}
+
+func TestCLI_ScanAudio_MissingFpcalc(t *testing.T) {
+	cli := &CLI{Path: []string{t.TempDir()}, Match: "audio", Fpcalc: filepath.Join(t.TempDir(), "fpcalc")}
+	if _, err := cli.scanAudio(context.Background()); err == nil {
+		t.Error("scanAudio() without fpcalc should fail")
+	}
+}
+
+func TestCLI_Run_KeepBestAudio(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	createTestFile(t, filepath.Join(dir, "song.mp3"), "128k")
+	createTestFile(t, filepath.Join(dir, "song (1).mp3"), "320k")
+	createTestFile(t, filepath.Join(dir, "track.mp3"), "256k")
+	createTestFile(t, filepath.Join(dir, "track (1).mp3"), "192k")
+	bitrates := map[string]int64{"song.mp3": 128000, "song (1).mp3": 320000, "track.mp3": 256000, "track (1).mp3": 192000}
+
+	cli := &CLI{
+		Path:           []string{dir},
+		Delete:         true,
+		AllowDifferent: true,
+		KeepBestAudio:  true,
+		Out:            filepath.Join(t.TempDir(), "results.txt"),
+		Regex:          []string{defaultRegex},
+		probe: func(_ context.Context, path string) (videoInfo, error) {
+			return videoInfo{BitRate: bitrates[filepath.Base(path)]}, nil
+		},
+	}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	for name, want := range map[string]string{"song.mp3": "320k", "track.mp3": "256k"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v, want %q", name, data, err, want)
+		}
+	}
+	if fileExists(filepath.Join(dir, "song (1).mp3")) || fileExists(filepath.Join(dir, "track (1).mp3")) {
+		t.Error("copies should be deleted or renamed")
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// auditLockWait bounds how long a record waits for another run to finish writing to a shared audit log.
+const auditLockWait = 30 * time.Second
+
+// auditRecord is a line of the --audit-log, describing a single delete or rename.
+type auditRecord struct {
+	// Seq numbers the records of a log from 1, and Prev is the SHA-256 of the line before, so records can't be
+	// removed or edited without breaking the chain, which ohman audit verify checks.
+	Seq  int64     `json:"seq"`
+	Prev string    `json:"prev,omitempty"`
+	Time time.Time `json:"time"`
+	// RunID identifies the run which made the change.
+	RunID string `json:"run_id,omitempty"`
+	// Op is "delete" or "rename".
+	Op     string `json:"op"`
+	Path   string `json:"path"`
+	Target string `json:"target,omitempty"`
+	// Size and SHA256 describe a file as it was before it was deleted or renamed. Neither is given for directories.
+	Size   *int64 `json:"size,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+	// Outcome is "ok" or "failed", in which case Error says why, and the errorInfo fields classify it.
+	Outcome string `json:"outcome"`
+	Error   string `json:"error,omitempty"`
+	errorInfo
+}
+
+// auditLog appends a record of every delete and rename to a JSON Lines file, whatever else a run writes. Once a
+// record can't be written, nothing more is deleted or renamed, so nothing is changed without being recorded. A nil
+// *auditLog records nothing.
+type auditLog struct {
+	mu  sync.Mutex
+	f   *os.File
+	err error
+}
+
+// openAuditLog opens the audit log at path for appending, creating it if need be, or returns nil when path is "".
+func openAuditLog(path string) (*auditLog, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the audit log: %w", err)
+	}
+	return &auditLog{f: f}, nil
+}
+
+// check returns the error which stopped records being written, if any.
+func (l *auditLog) check() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return fmt.Errorf("the audit log can't be written, so nothing more is changed: %w", l.err)
+	}
+	return nil
+}
+
+func (l *auditLog) write(r auditRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err == nil {
+		l.err = l.append(r)
+	}
+}
+
+// append chains r to the last record in the log and writes it. The log is locked meanwhile, so runs sharing a log
+// each chain their records to the others'.
+func (l *auditLog) append(r auditRecord) error {
+	deadline := time.Now().Add(auditLockWait)
+	for {
+		err := tryLock(l.f, true)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, errLocked) || time.Now().After(deadline) {
+			return fmt.Errorf("unable to lock the audit log: %w", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	defer func() { _ = unlock(l.f) }()
+
+	last, err := lastLine(l.f)
+	if err != nil {
+		return err
+	}
+	r.Seq = 1
+	if last != nil {
+		var prev auditRecord
+		if err := json.Unmarshal(last, &prev); err != nil {
+			return fmt.Errorf("the audit log's last record is invalid: %w", err)
+		}
+		sum := sha256.Sum256(last)
+		r.Seq, r.Prev = prev.Seq+1, hex.EncodeToString(sum[:])
+	}
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = l.f.Write(append(line, '\n'))
+	return err
+}
+
+// lastLine returns the last complete line of f, without its newline, or nil if f is empty.
+func lastLine(f *os.File) ([]byte, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	end := info.Size()
+	if end == 0 {
+		return nil, nil
+	}
+	var tail []byte
+	for offset := end; offset > 0; {
+		n := min(offset, 4096)
+		offset -= n
+		chunk := make([]byte, n)
+		if _, err := f.ReadAt(chunk, offset); err != nil {
+			return nil, err
+		}
+		tail = append(chunk, tail...)
+		if tail[len(tail)-1] != '\n' {
+			return nil, fmt.Errorf("the audit log ends within a record")
+		}
+		if i := bytes.LastIndexByte(tail[:len(tail)-1], '\n'); i >= 0 {
+			return tail[i+1 : len(tail)-1], nil
+		}
+	}
+	return tail[:len(tail)-1], nil
+}
+
+// close flushes the log to disk and closes it, returning the first error met writing it.
+func (l *auditLog) close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	err := l.f.Sync()
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	if l.err != nil {
+		return l.err
+	}
+	return err
+}
+
+// audited performs op, a delete or rename of path, recording it in the audit log along with path's size and hash as
+// they were beforehand. Nothing is performed when the log can't be written.
+func (c *CLI) audited(ctx context.Context, op, path, target string, do func() error) error {
+	if c.audit == nil {
+		return do()
+	}
+	if err := c.audit.check(); err != nil {
+		return err
+	}
+	r := auditRecord{RunID: c.runID, Op: op, Path: auditPath(path), Outcome: "ok"}
+	if target != "" {
+		r.Target = auditPath(target)
+	}
+	if info, err := c.stat(path); err == nil && info.Mode().IsRegular() {
+		size := info.Size()
+		r.Size = &size
+		r.SHA256, _ = c.sha256(ctx, path)
+	}
+	err := do()
+	r.Time = time.Now().UTC()
+	if err != nil {
+		r.Outcome, r.Error, r.errorInfo = "failed", err.Error(), describeError(err)
+	}
+	c.audit.write(r)
+	return err
+}
+
+// auditPath returns the absolute form of a local path, leaving URLs like s3://bucket/key as they are.
+func auditPath(path string) string {
+	if hasScheme(path) {
+		return path
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// AuditCmd works with --audit-log files.
+type AuditCmd struct {
+	Verify auditVerifyCmd `cmd:"" help:"Check that an audit log's records are all present and unedited."`
+}
+
+type auditVerifyCmd struct {
+	Log  string `arg:"" name:"log" help:"Audit log to check." type:"path"`
+	Head string `name:"head" help:"Hash of a record printed by an earlier verify, which must still be in the log, to detect records removed from its end."`
+}
+
+func (a *auditVerifyCmd) Run() error {
+	f, err := os.Open(a.Log)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	count, head, err := verifyAuditLog(f, a.Head)
+	if err != nil {
+		return fmt.Errorf("%s: %w", a.Log, err)
+	}
+	fmt.Printf("%s: %d records, head %s\n", a.Log, count, head)
+	return nil
+}
+
+// verifyAuditLog checks that each record of the log read from r is chained to the one before, and that a record
+// hashing to want, if given, is among them. It returns the number of records and the hash of the last, by which a
+// later check can tell whether records were since removed from the end.
+func verifyAuditLog(r io.Reader, want string) (count int64, head string, err error) {
+	br := bufio.NewReader(r)
+	found := want == ""
+	for n := 1; ; n++ {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			break
+		}
+		if err == io.EOF {
+			return count, head, fmt.Errorf("line %d: the log ends within a record, so it was truncated", n)
+		}
+		if err != nil {
+			return count, head, err
+		}
+		line = line[:len(line)-1]
+		var rec auditRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return count, head, fmt.Errorf("line %d: invalid record: %w", n, err)
+		}
+		if rec.Prev != head {
+			if head == "" {
+				return count, head, fmt.Errorf("line %d: the first record follows one which is missing", n)
+			}
+			return count, head, fmt.Errorf("line %d: the record before was removed or edited", n)
+		}
+		if rec.Seq != count+1 {
+			return count, head, fmt.Errorf("line %d: record %d follows record %d", n, rec.Seq, count)
+		}
+		sum := sha256.Sum256(line)
+		count, head = rec.Seq, hex.EncodeToString(sum[:])
+		found = found || head == want
+	}
+	if !found {
+		return count, head, fmt.Errorf("no record hashes to %s, so records were removed from the end", want)
+	}
+	return count, head, nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// readAuditLog returns the records of the audit log at path.
+func readAuditLog(t *testing.T, path string) []auditRecord {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open the audit log: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	var records []auditRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r auditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("invalid audit record %q: %v", scanner.Text(), err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestCLI_Run_AuditLog(t *testing.T) {
+	t.Parallel()
+	log := filepath.Join(t.TempDir(), "audit.jsonl")
+	for range 2 {
+		cli := &CLI{
+			Path:     []string{"/media"},
+			Delete:   true,
+			Out:      filepath.Join(t.TempDir(), "results.txt"),
+			Regex:    []string{defaultRegex},
+			AuditLog: log,
+			memory: fstest.MapFS{
+				"media/book.pdf":     {Data: []byte("content")},
+				"media/book (1).pdf": {Data: []byte("content")},
+			},
+		}
+		if err := cli.Run(nil); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	}
+
+	records := readAuditLog(t, log)
+	if len(records) != 2 {
+		t.Fatalf("expected a record for each run, got %+v", records)
+	}
+	sum := sha256.Sum256([]byte("content"))
+	want, _ := filepath.Abs("/media/book (1).pdf")
+	for i, r := range records {
+		if r.Seq != int64(i+1) || (i == 0) != (r.Prev == "") {
+			t.Errorf("record %d isn't chained: %+v", i, r)
+		}
+		if r.Op != opDelete || r.Path != want || r.Size == nil || *r.Size != int64(len("content")) || r.SHA256 != hex.EncodeToString(sum[:]) || r.Outcome != "ok" || r.Time.IsZero() {
+			t.Errorf("unexpected record %+v", r)
+		}
+	}
+}
+
+func TestCLI_Audited_StopsWhenUnwritable(t *testing.T) {
+	t.Parallel()
+	l, err := openAuditLog(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every write fails from now on
+	_ = l.f.Close()
+	c := &CLI{audit: l, memory: fstest.MapFS{"media/a.pdf": {Data: []byte("a")}}}
+
+	calls := 0
+	do := func() error {
+		calls++
+		return nil
+	}
+	if err := c.audited(context.Background(), opDelete, "/media/a.pdf", "", do); err != nil {
+		t.Fatalf("the first delete, whose record failed, should have succeeded: %v", err)
+	}
+	if err := c.audited(context.Background(), opDelete, "/media/a.pdf", "", do); err == nil {
+		t.Error("expected an error once the audit log can't be written")
+	}
+	if calls != 1 {
+		t.Errorf("deleted %d times, want only the first", calls)
+	}
+	if err := l.close(); err == nil {
+		t.Error("expected close to report the failed write")
+	}
+}
+
+func TestVerifyAuditLog(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := openAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"/media/a.pdf", "/media/b.pdf", "/media/c.pdf"} {
+		l.write(auditRecord{Op: opDelete, Path: p, Outcome: "ok"})
+	}
+	if err := l.close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := string(data)
+	lines := strings.SplitAfter(log, "\n")
+
+	count, head, err := verifyAuditLog(strings.NewReader(log), "")
+	if err != nil || count != 3 {
+		t.Fatalf("verifyAuditLog() = %d, %v, want 3 records", count, err)
+	}
+	_, twoHead, err := verifyAuditLog(strings.NewReader(lines[0]+lines[1]), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := verifyAuditLog(strings.NewReader(log), twoHead); err != nil {
+		t.Errorf("an earlier head should still be found: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name, log, head, want string
+	}{
+		{name: "edited", log: strings.Replace(log, "b.pdf", "x.pdf", 1), want: "line 3: the record before was removed or edited"},
+		{name: "removed", log: lines[0] + lines[2], want: "line 2: the record before was removed or edited"},
+		{name: "first removed", log: lines[1] + lines[2], want: "line 1: the first record follows one which is missing"},
+		{name: "partly truncated", log: log[:len(log)-5], want: "line 3: the log ends within a record"},
+		{name: "end removed", log: lines[0] + lines[1], head: head, want: "no record hashes to " + head},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, _, err := verifyAuditLog(strings.NewReader(tt.log), tt.head)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("verifyAuditLog() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
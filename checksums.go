@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// keptFiles returns the files which remain in groups after a run, or which would remain after a dry run: originals,
+// renamed and adopted copies, copies left alone because they differ, and any which couldn't be deleted or renamed.
+func keptFiles(groups []group) []string {
+	var kept []string
+	for _, g := range groups {
+		if g.Actions == nil {
+			if g.Orphan {
+				kept = append(kept, g.Duplicates[0])
+			} else {
+				kept = append(kept, g.Original)
+			}
+			kept = append(kept, g.Mismatched...)
+			continue
+		}
+		for _, a := range g.Actions {
+			switch {
+			case a.Op == opRename && a.Err == nil:
+				kept = append(kept, a.Target)
+			case a.Op == opKeep || a.Err != nil:
+				kept = append(kept, a.Path)
+			}
+		}
+	}
+	slices.Sort(kept)
+	return slices.Compact(kept)
+}
+
+// writeChecksums writes the SHA-256 of each of paths to filename in the format of sha256sum, so the files can be
+// verified with sha256sum -c. Files which can't be read are left out, and reported in the returned error.
+func (c *CLI) writeChecksums(ctx context.Context, filename string, paths []string) error {
+	var sb strings.Builder
+	var failed []string
+	for _, p := range paths {
+		sum, err := c.sha256(ctx, p)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", p, err))
+			continue
+		}
+		sb.WriteString(checksumLine(sum, p))
+	}
+	if err := os.WriteFile(filename, []byte(sb.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write checksums to %s: %w", filename, err)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("unable to checksum %d file(s), which were left out of %s:\n  - %s", len(failed), filename, strings.Join(failed, "\n  - "))
+	}
+	return nil
+}
+
+func (c *CLI) sha256(ctx context.Context, path string) (sum string, err error) {
+	ctx, span := startSpan(ctx, "hash", "path", path)
+	defer func() { span.finish(err) }()
+	f, err := c.files().Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	if _, err := io.Copy(h, c.throttle.reader(ctx, f)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// checksumLine formats a line of sha256sum output. As in coreutils, names containing a backslash or newline are
+// escaped, and the line marked as such by a leading backslash.
+func checksumLine(sum, path string) string {
+	if !strings.ContainsAny(path, "\\\n\r") {
+		return sum + "  " + path + "\n"
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`).Replace(path)
+	return `\` + sum + "  " + escaped + "\n"
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestKeptFiles(t *testing.T) {
+	t.Parallel()
+	groups := []group{
+		{Original: "/a.pdf", Duplicates: []string{"/a (1).pdf", "/a (2).pdf"}, Mismatched: []string{"/a (2).pdf"}},
+		{Original: "/b.pdf", Duplicates: []string{"/b (1).pdf"}, Orphan: true},
+		{Original: "/c.pdf", Actions: []action{
+			{Op: opKeep, Path: "/c.pdf", implicit: true},
+			{Op: opDelete, Path: "/c (1).pdf"},
+			{Op: opDelete, Path: "/c (2).pdf", Err: errors.New("denied")},
+		}},
+		{Original: "/d.pdf", Actions: []action{
+			{Op: opDelete, Path: "/d.pdf"},
+			{Op: opRename, Path: "/d (1).pdf", Target: "/d.pdf"},
+		}},
+	}
+	want := []string{"/a (2).pdf", "/a.pdf", "/b (1).pdf", "/c (2).pdf", "/c.pdf", "/d.pdf"}
+	if got := keptFiles(groups); !slices.Equal(got, want) {
+		t.Errorf("keptFiles() = %q, want %q", got, want)
+	}
+}
+
+func TestCLI_Run_WriteChecksums(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "hello\n")
+	createTestFile(t, filepath.Join(dir, "book (1).pdf"), "hello\n")
+	sums := filepath.Join(t.TempDir(), "SHA256SUMS")
+
+	cli := &CLI{
+		Path:           []string{dir},
+		Delete:         true,
+		Out:            filepath.Join(t.TempDir(), "results.txt"),
+		Regex:          []string{defaultRegex},
+		WriteChecksums: sums,
+	}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	data, err := os.ReadFile(sums)
+	if err != nil {
+		t.Fatalf("failed to read checksums: %v", err)
+	}
+	// as printed by printf 'hello\n' | sha256sum
+	want := "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03  " + filepath.Join(dir, "book.pdf") + "\n"
+	if string(data) != want {
+		t.Errorf("checksums = %q, want %q", data, want)
+	}
+}
+
+func TestChecksumLine(t *testing.T) {
+	t.Parallel()
+	if got, want := checksumLine("abc", `/a\b`+"\nc"), `\abc  /a\\b\nc`+"\n"; got != want {
+		t.Errorf("checksumLine() = %q, want %q", got, want)
+	}
+}
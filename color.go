@@ -0,0 +1,52 @@
+package main
+
+import "os"
+
+// palette colors text output for a terminal. Its zero value leaves text plain.
+type palette struct {
+	color bool
+}
+
+// newPalette returns a palette coloring text printed to f, when f is a terminal able to show colors and neither
+// --no-color nor $NO_COLOR (see https://no-color.org) asks for plain text.
+func newPalette(f *os.File, noColor bool) palette {
+	if noColor || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return palette{}
+	}
+	info, err := f.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return palette{}
+	}
+	return palette{color: enableVirtualTerminal(f)}
+}
+
+func (p palette) paint(code, s string) string {
+	if !p.color {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
+
+// kept colors the files which are kept, such as originals.
+func (p palette) kept(s string) string { return p.paint("32", s) }
+
+// deleted colors the files which were deleted.
+func (p palette) deleted(s string) string { return p.paint("31", s) }
+
+// failed colors the operations which failed, so they stand out from the rest.
+func (p palette) failed(s string) string { return p.paint("1;31", s) }
+
+// pending colors the files which a dry run would act on.
+func (p palette) pending(s string) string { return p.paint("33", s) }
+
+// action describes a as action.String does, colored by its outcome.
+func (p palette) action(a action) string {
+	switch {
+	case a.Err != nil:
+		return p.failed(a.String())
+	case a.Op == opDelete:
+		return p.deleted(a.String())
+	default:
+		return p.kept(a.String())
+	}
+}
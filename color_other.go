@@ -0,0 +1,10 @@
+//go:build !windows
+
+package main
+
+import "os"
+
+// enableVirtualTerminal reports true, as terminals elsewhere interpret escape sequences already.
+func enableVirtualTerminal(*os.File) bool {
+	return true
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderColored(t *testing.T) {
+	t.Parallel()
+	colors := palette{color: true}
+
+	dryRun := []group{{Original: "/a/book.pdf", Duplicates: []string{"/a/book (1).pdf", "/a/book (2).pdf"}, Mismatched: []string{"/a/book (2).pdf"}}}
+	want := "\x1b[32mOriginal: /a/book.pdf\x1b[0m\n\x1b[33m  - Duplicate: /a/book (1).pdf\x1b[0m\n  - Duplicate: /a/book (2).pdf (content differs)"
+	if got := renderColored("text", dryRun, colors); got != want {
+		t.Errorf("dry run: expected %q, got %q", want, got)
+	}
+
+	deleted := []group{{Original: "/a/book.pdf", Duplicates: []string{"/a/book (1).pdf", "/a/book (2).pdf"}, Actions: []action{
+		{Op: opDelete, Path: "/a/book (1).pdf"},
+		{Op: opDelete, Path: "/a/book (2).pdf", Err: errors.New("permission denied")},
+		{Op: opRename, Path: "/a/book (3).pdf", Target: "/a/book.pdf"},
+	}}}
+	want = "\x1b[31mDeleted /a/book (1).pdf\x1b[0m\n\x1b[1;31mFailed to delete /a/book (2).pdf: permission denied\x1b[0m\n\x1b[32mRenamed /a/book (3).pdf to /a/book.pdf\x1b[0m"
+	if got := renderColored("text", deleted, colors); got != want {
+		t.Errorf("delete: expected %q, got %q", want, got)
+	}
+
+	if got, want := renderColored("fdupes", dryRun, colors), render("fdupes", dryRun); got != want {
+		t.Errorf("fdupes should never be colored: expected %q, got %q", want, got)
+	}
+}
+
+func TestNewPalette(t *testing.T) {
+	t.Parallel()
+	f, err := os.Create(filepath.Join(t.TempDir(), "results.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	if newPalette(f, false).color {
+		t.Error("expected output to a file to be plain")
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// compareAll sets the copies whose content differs from the original's on each of groups, comparing up to --jobs
+// groups at once. Each disk gets its own workers, at most --jobs-per-disk of them, or just one on a spinning disk,
+// where reading several files at once would only make its head seek back and forth between them.
+func (c *CLI) compareAll(ctx context.Context, groups []group) {
+	jobs := c.jobs()
+
+	// groups are compared on the disk holding their original
+	var disks []uint64
+	queues := map[uint64][]int{}
+	for i, g := range groups {
+		if !c.comparesContent(g) {
+			continue
+		}
+		var disk uint64
+		if info, err := c.stat(g.Original); err == nil {
+			disk = deviceOf(info)
+		}
+		if _, ok := queues[disk]; !ok {
+			disks = append(disks, disk)
+		}
+		queues[disk] = append(queues[disk], i)
+	}
+
+	slots := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for _, disk := range disks {
+		queue := make(chan int, len(queues[disk]))
+		for _, i := range queues[disk] {
+			queue <- i
+		}
+		close(queue)
+		for range min(c.jobsPerDisk(disk, jobs), len(queues[disk])) {
+			wg.Go(func() {
+				for i := range queue {
+					if ctx.Err() != nil {
+						// the rest won't be acted on, so needn't be read
+						return
+					}
+					slots <- struct{}{}
+					groups[i].Mismatched = c.differing(ctx, groups[i])
+					<-slots
+				}
+			})
+		}
+	}
+	wg.Wait()
+}
+
+// comparesContent reports whether g's copies have their content compared with the original before being removed, or,
+// for an orphan, with the copy adopted in its place. Files matched by similarity or by a plugin are expected to
+// differ, and an orphan with a single copy has nothing to be compared with.
+func (c *CLI) comparesContent(g group) bool {
+	switch {
+	case c.matcher != nil:
+		return false
+	case g.Orphan && (len(g.Duplicates) < 2 || c.Match == "dirs"):
+		return false
+	case c.Match == "dirs":
+		return !c.MergeDirs
+	case c.Match == "" || c.Match == "name":
+		return !c.AllowDifferent
+	}
+	return false
+}
+
+// differing returns the copies in g whose content differs from the original's.
+func (c *CLI) differing(ctx context.Context, g group) (differ []string) {
+	ctx, span := startSpan(ctx, "compare.group", "original", g.Original, "duplicates", len(g.Duplicates))
+	defer func() {
+		span.set("differing", len(differ))
+		span.finish(nil)
+	}()
+	if c.Match == "dirs" {
+		return c.mismatchedDirs(ctx, g)
+	}
+	return c.mismatched(ctx, g)
+}
+
+// jobsPerDisk returns how many groups on disk may be compared at once, out of jobs.
+func (c *CLI) jobsPerDisk(disk uint64, jobs int) int {
+	switch {
+	case c.JobsPerDisk > 0:
+		return min(c.JobsPerDisk, jobs)
+	case disk != 0 && isRotational(disk):
+		return 1
+	}
+	return jobs
+}
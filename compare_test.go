@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCLI_CompareAll(t *testing.T) {
+	t.Parallel()
+	memory := fstest.MapFS{}
+	var groups []group
+	for i := range 20 {
+		original, copied := fmt.Sprintf("media/book%d.pdf", i), fmt.Sprintf("media/book%d (1).pdf", i)
+		memory[original] = &fstest.MapFile{Data: []byte("content")}
+		memory[copied] = &fstest.MapFile{Data: []byte("content")}
+		if i%3 == 0 {
+			memory[copied] = &fstest.MapFile{Data: []byte("another edition")}
+		}
+		groups = append(groups, group{Original: "/" + original, Duplicates: []string{"/" + copied}})
+	}
+	groups = append(groups, group{Original: "/media/gone.pdf", Duplicates: []string{"/media/book1 (1).pdf"}, Orphan: true})
+
+	cli := &CLI{Jobs: 4, memory: memory}
+	cli.compareAll(context.Background(), groups)
+	for i, g := range groups {
+		want := i%3 == 0 && !g.Orphan
+		if got := len(g.Mismatched) > 0; got != want {
+			t.Errorf("%s: mismatched = %q, want differing %v", g.Original, g.Mismatched, want)
+		}
+		if got := slices.Equal(g.Mismatched, g.Duplicates); want && !got {
+			t.Errorf("%s: mismatched = %q, want %q", g.Original, g.Mismatched, g.Duplicates)
+		}
+	}
+}
+
+func TestCLI_JobsPerDisk(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		perDisk, jobs, want int
+	}{
+		{perDisk: 0, jobs: 8, want: 8},
+		{perDisk: 2, jobs: 8, want: 2},
+		{perDisk: 16, jobs: 8, want: 8},
+	}
+	for _, tt := range tests {
+		cli := &CLI{JobsPerDisk: tt.perDisk}
+		// 0 is an unknown disk, which is never treated as spinning
+		if got := cli.jobsPerDisk(0, tt.jobs); got != tt.want {
+			t.Errorf("jobsPerDisk with --jobs-per-disk %d and --jobs %d = %d, want %d", tt.perDisk, tt.jobs, got, tt.want)
+		}
+	}
+}
+
+func TestCLI_Jobs_Nice(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		jobs  int
+		nice  bool
+		want  int
+		lists int
+	}{
+		{jobs: 0, nice: true, want: 1, lists: 1},
+		{jobs: 4, nice: true, want: 4, lists: 1},
+		{jobs: 4, nice: false, want: 4, lists: listAhead},
+	}
+	for _, tt := range tests {
+		cli := &CLI{Jobs: tt.jobs, Nice: tt.nice}
+		if got := cli.jobs(); got != tt.want {
+			t.Errorf("jobs with --jobs %d and --nice=%v = %d, want %d", tt.jobs, tt.nice, got, tt.want)
+		}
+		if got := cli.lists(); got != tt.lists {
+			t.Errorf("lists with --nice=%v = %d, want %d", tt.nice, got, tt.lists)
+		}
+	}
+}
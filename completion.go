@@ -0,0 +1,431 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/kong"
+)
+
+// CompletionCmd prints a shell completion script. Scripts are generated from the command line model, so they
+// complete every command and flag (including enum values and file arguments) without being maintained by hand.
+type CompletionCmd struct {
+	Shell string `arg:"" name:"shell" help:"Shell to generate completions for: ${enum}." enum:"bash,zsh,fish,powershell"`
+}
+
+func (c *CompletionCmd) Run(kctx *Context) error {
+	return writeCompletion(os.Stdout, c.Shell, kctx.Model)
+}
+
+// completionCommand is a command and the flags it accepts, as needed by completion scripts.
+type completionCommand struct {
+	Name string
+	Help string
+	// Default is set for the command run when none is given.
+	Default bool
+	Flags   []completionFlag
+	// Files is set when the command's positional arguments are paths.
+	Files bool
+	// Values lists the command's subcommands, or the allowed values of its first positional argument if it is an enum.
+	Values []string
+}
+
+type completionFlag struct {
+	Name  string
+	Short string
+	Help  string
+	// Bool flags take no value.
+	Bool bool
+	// Files is set when the flag's value is a path.
+	Files  bool
+	Values []string
+}
+
+// completionCommands flattens the model into its commands, each with the root's flags as well as its own.
+func completionCommands(app *kong.Application) []completionCommand {
+	global := completionFlags(app.Flags)
+	var commands []completionCommand
+	for _, node := range app.Children {
+		if node.Type != kong.CommandNode || node.Hidden {
+			continue
+		}
+		cmd := completionCommand{
+			Name:    node.Name,
+			Help:    completionHelp(node.Help),
+			Default: node == app.DefaultCmd,
+			Flags:   append(append([]completionFlag(nil), global...), completionFlags(node.Flags)...),
+		}
+		for _, sub := range node.Children {
+			// subcommands complete like the values of a positional argument, along with their flags
+			if sub.Type == kong.CommandNode && !sub.Hidden {
+				cmd.Values = append(cmd.Values, sub.Name)
+				for _, f := range completionFlags(sub.Flags) {
+					if !slices.ContainsFunc(cmd.Flags, func(existing completionFlag) bool { return existing.Name == f.Name }) {
+						cmd.Flags = append(cmd.Flags, f)
+					}
+				}
+			}
+		}
+		for _, p := range node.Positional {
+			if isPathType(p.Tag.Type) {
+				cmd.Files = true
+			}
+			if cmd.Values == nil && p.Enum != "" {
+				cmd.Values = p.EnumSlice()
+			}
+		}
+		commands = append(commands, cmd)
+	}
+	return commands
+}
+
+func completionFlags(flags []*kong.Flag) []completionFlag {
+	var out []completionFlag
+	for _, f := range flags {
+		if f.Hidden {
+			continue
+		}
+		cf := completionFlag{
+			Name:  f.Name,
+			Help:  completionHelp(f.Help),
+			Bool:  f.IsBool(),
+			Files: isPathType(f.Tag.Type),
+		}
+		if f.Enum != "" {
+			cf.Values = f.EnumSlice()
+		}
+		if f.Short != 0 {
+			cf.Short = string(f.Short)
+		}
+		out = append(out, cf)
+		switch negation := f.Tag.Negatable; {
+		case negation == "_":
+			out = append(out, completionFlag{Name: "no-" + f.Name, Help: cf.Help, Bool: true})
+		case negation != "":
+			out = append(out, completionFlag{Name: negation, Help: cf.Help, Bool: true})
+		}
+	}
+	return out
+}
+
+func isPathType(typ string) bool {
+	switch typ {
+	case "path", "existingfile", "existingdir", "location":
+		return true
+	}
+	return false
+}
+
+// completionHelp shortens help to its first sentence, dropping warning decorations which don't belong in a menu.
+func completionHelp(help string) string {
+	help = strings.TrimLeft(help, "⚠️ ")
+	for i := 0; ; i++ {
+		n := strings.Index(help[i:], ". ")
+		if n < 0 {
+			break
+		}
+		i += n
+		if !strings.HasSuffix(help[:i], "e.g") && !strings.HasSuffix(help[:i], "i.e") {
+			help = help[:i]
+			break
+		}
+	}
+	return strings.TrimSuffix(strings.TrimSpace(help), ".")
+}
+
+func writeCompletion(w io.Writer, shell string, app *kong.Application) error {
+	commands := completionCommands(app)
+	var script string
+	switch shell {
+	case "bash":
+		script = bashCompletion(app.Name, commands)
+	case "zsh":
+		script = zshCompletion(app.Name, commands)
+	case "fish":
+		script = fishCompletion(app.Name, commands)
+	case "powershell":
+		script = powershellCompletion(app.Name, commands)
+	default:
+		return fmt.Errorf("unsupported shell %q", shell)
+	}
+	_, err := io.WriteString(w, script)
+	return err
+}
+
+func commandNames(commands []completionCommand) []string {
+	names := make([]string, len(commands))
+	for i, c := range commands {
+		names[i] = c.Name
+	}
+	return names
+}
+
+func bashCompletion(name string, commands []completionCommand) string {
+	var sb strings.Builder
+	fn := "_" + strings.ReplaceAll(name, "-", "_")
+	names := strings.Join(commandNames(commands), " ")
+	fmt.Fprintf(&sb, "# bash completion for %s; generated by `%s completion bash`\n\n", name, name)
+	fmt.Fprintf(&sb, "%s() {\n", fn)
+	sb.WriteString("    local cur prev cmd word\n")
+	sb.WriteString("    cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	sb.WriteString("    prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	for _, c := range commands {
+		if c.Default {
+			fmt.Fprintf(&sb, "    cmd=%s\n", c.Name)
+		}
+	}
+	sb.WriteString("    for word in \"${COMP_WORDS[@]:1:COMP_CWORD-1}\"; do\n")
+	sb.WriteString("        case \"$word\" in\n")
+	fmt.Fprintf(&sb, "            %s) cmd=\"$word\"; break ;;\n", strings.ReplaceAll(names, " ", "|"))
+	sb.WriteString("            -*) ;;\n")
+	sb.WriteString("            *) break ;;\n")
+	sb.WriteString("        esac\n")
+	sb.WriteString("    done\n\n")
+
+	// flag values; file values are left to the default completion
+	sb.WriteString("    case \"$cmd:$prev\" in\n")
+	for _, c := range commands {
+		if len(c.Values) > 0 {
+			fmt.Fprintf(&sb, "        %s:%s) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")); return ;;\n", c.Name, c.Name, strings.Join(c.Values, " "))
+		}
+		for _, f := range c.Flags {
+			if f.Bool {
+				continue
+			}
+			pattern := c.Name + ":--" + f.Name
+			if f.Short != "" {
+				pattern += "|" + c.Name + ":-" + f.Short
+			}
+			if len(f.Values) > 0 {
+				fmt.Fprintf(&sb, "        %s) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")); return ;;\n", pattern, strings.Join(f.Values, " "))
+			} else {
+				fmt.Fprintf(&sb, "        %s) return ;;\n", pattern)
+			}
+		}
+	}
+	sb.WriteString("    esac\n\n")
+
+	sb.WriteString("    if [[ \"$cur\" == -* ]]; then\n")
+	sb.WriteString("        case \"$cmd\" in\n")
+	for _, c := range commands {
+		var flags []string
+		for _, f := range c.Flags {
+			flags = append(flags, "--"+f.Name)
+			if f.Short != "" {
+				flags = append(flags, "-"+f.Short)
+			}
+		}
+		fmt.Fprintf(&sb, "            %s) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")) ;;\n", c.Name, strings.Join(flags, " "))
+	}
+	sb.WriteString("        esac\n")
+	sb.WriteString("        return\n")
+	sb.WriteString("    fi\n")
+	sb.WriteString("    if [[ $COMP_CWORD -eq 1 ]]; then\n")
+	fmt.Fprintf(&sb, "        COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n", names)
+	sb.WriteString("    fi\n")
+	sb.WriteString("}\n\n")
+	fmt.Fprintf(&sb, "complete -o default -F %s %s\n", fn, name)
+	return sb.String()
+}
+
+// zshQuote escapes s for use within a single-quoted _arguments spec or description.
+func zshQuote(s string) string {
+	return strings.NewReplacer("'", `'\''`, "[", `\[`, "]", `\]`, ":", `\:`).Replace(s)
+}
+
+func zshFlagSpec(f completionFlag) string {
+	var action string
+	switch {
+	case f.Bool:
+	case len(f.Values) > 0:
+		action = fmt.Sprintf(":%s:(%s)", f.Name, strings.Join(f.Values, " "))
+	case f.Files:
+		action = fmt.Sprintf(":%s:_files", f.Name)
+	default:
+		action = fmt.Sprintf(":%s: ", f.Name)
+	}
+	long := "--" + f.Name
+	if !f.Bool {
+		long += "="
+	}
+	desc := "[" + zshQuote(f.Help) + "]"
+	if f.Short != "" {
+		return fmt.Sprintf("'(-%s --%s)'{-%s,%s}'%s%s'", f.Short, f.Name, f.Short, long, desc, action)
+	}
+	return fmt.Sprintf("'%s%s%s'", long, desc, action)
+}
+
+func zshCompletion(name string, commands []completionCommand) string {
+	var sb strings.Builder
+	fn := "_" + strings.ReplaceAll(name, "-", "_")
+	fmt.Fprintf(&sb, "#compdef %s\n# zsh completion for %s; generated by `%s completion zsh`\n\n", name, name, name)
+	fmt.Fprintf(&sb, "%s() {\n", fn)
+	sb.WriteString("    local cmd\n")
+	sb.WriteString("    local -a commands\n")
+	sb.WriteString("    commands=(\n")
+	for _, c := range commands {
+		fmt.Fprintf(&sb, "        '%s:%s'\n", c.Name, zshQuote(c.Help))
+	}
+	sb.WriteString("    )\n\n")
+	sb.WriteString("    case $words[2] in\n")
+	fmt.Fprintf(&sb, "        %s)\n", strings.Join(commandNames(commands), "|"))
+	sb.WriteString("            cmd=$words[2]\n")
+	sb.WriteString("            shift words\n")
+	sb.WriteString("            (( CURRENT-- ))\n")
+	sb.WriteString("            ;;\n")
+	sb.WriteString("        *)\n")
+	for _, c := range commands {
+		if c.Default {
+			fmt.Fprintf(&sb, "            cmd=%s\n", c.Name)
+		}
+	}
+	sb.WriteString("            if (( CURRENT == 2 )) && [[ $PREFIX != -* ]]; then\n")
+	sb.WriteString("                _alternative 'commands:command:_describe -t commands command commands' 'files:path:_files'\n")
+	sb.WriteString("                return\n")
+	sb.WriteString("            fi\n")
+	sb.WriteString("            ;;\n")
+	sb.WriteString("    esac\n\n")
+	sb.WriteString("    case $cmd in\n")
+	for _, c := range commands {
+		fmt.Fprintf(&sb, "        %s)\n", c.Name)
+		sb.WriteString("            _arguments -s")
+		for _, f := range c.Flags {
+			sb.WriteString(" \\\n                " + zshFlagSpec(f))
+		}
+		switch {
+		case len(c.Values) > 0:
+			fmt.Fprintf(&sb, " \\\n                '1:%s:(%s)'", c.Name, strings.Join(c.Values, " "))
+		case c.Files:
+			sb.WriteString(" \\\n                '*:path:_files'")
+		}
+		sb.WriteString("\n            ;;\n")
+	}
+	sb.WriteString("    esac\n")
+	sb.WriteString("}\n\n")
+	fmt.Fprintf(&sb, "compdef %s %s\n", fn, name)
+	return sb.String()
+}
+
+// fishQuote single-quotes s for fish.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+func fishCompletion(name string, commands []completionCommand) string {
+	var sb strings.Builder
+	names := commandNames(commands)
+	fmt.Fprintf(&sb, "# fish completion for %s; generated by `%s completion fish`\n\n", name, name)
+	for _, c := range commands {
+		fmt.Fprintf(&sb, "complete -c %s -n 'not __fish_seen_subcommand_from %s' -a %s -d %s\n",
+			name, strings.Join(names, " "), c.Name, fishQuote(c.Help))
+	}
+	for _, c := range commands {
+		condition := "__fish_seen_subcommand_from " + c.Name
+		if c.Default {
+			// the default command's flags apply until another command is given
+			var others []string
+			for _, n := range names {
+				if n != c.Name {
+					others = append(others, n)
+				}
+			}
+			condition = "not __fish_seen_subcommand_from " + strings.Join(others, " ")
+		}
+		sb.WriteString("\n")
+		switch {
+		case len(c.Values) > 0:
+			fmt.Fprintf(&sb, "complete -c %s -n '%s' -f -a %s\n", name, condition, fishQuote(strings.Join(c.Values, " ")))
+		case !c.Files:
+			fmt.Fprintf(&sb, "complete -c %s -n '%s' -f\n", name, condition)
+		}
+		for _, f := range c.Flags {
+			fmt.Fprintf(&sb, "complete -c %s -n '%s' -l %s", name, condition, f.Name)
+			if f.Short != "" {
+				fmt.Fprintf(&sb, " -s %s", f.Short)
+			}
+			switch {
+			case f.Bool:
+			case len(f.Values) > 0:
+				fmt.Fprintf(&sb, " -x -a %s", fishQuote(strings.Join(f.Values, " ")))
+			case f.Files:
+				sb.WriteString(" -r -F")
+			default:
+				sb.WriteString(" -x")
+			}
+			fmt.Fprintf(&sb, " -d %s\n", fishQuote(f.Help))
+		}
+	}
+	return sb.String()
+}
+
+// powershellList formats values as a PowerShell array literal.
+func powershellList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	}
+	return "@(" + strings.Join(quoted, ", ") + ")"
+}
+
+func powershellCompletion(name string, commands []completionCommand) string {
+	var sb strings.Builder
+	defaultCmd := ""
+	fmt.Fprintf(&sb, "# PowerShell completion for %s; generated by `%s completion powershell`\n\n", name, name)
+	fmt.Fprintf(&sb, "Register-ArgumentCompleter -Native -CommandName %s -ScriptBlock {\n", name)
+	sb.WriteString("    param($wordToComplete, $commandAst, $cursorPosition)\n\n")
+	sb.WriteString("    $flags = @{\n")
+	for _, c := range commands {
+		if c.Default {
+			defaultCmd = c.Name
+		}
+		var flags []string
+		for _, f := range c.Flags {
+			flags = append(flags, "--"+f.Name)
+			if f.Short != "" {
+				flags = append(flags, "-"+f.Short)
+			}
+		}
+		fmt.Fprintf(&sb, "        '%s' = %s\n", c.Name, powershellList(flags))
+	}
+	sb.WriteString("    }\n")
+	sb.WriteString("    # values for the word after a command or flag; commands without one fall back to paths\n")
+	sb.WriteString("    $values = @{\n")
+	for _, c := range commands {
+		if len(c.Values) > 0 {
+			fmt.Fprintf(&sb, "        '%s %s' = %s\n", c.Name, c.Name, powershellList(c.Values))
+		}
+		for _, f := range c.Flags {
+			if len(f.Values) == 0 {
+				continue
+			}
+			fmt.Fprintf(&sb, "        '%s --%s' = %s\n", c.Name, f.Name, powershellList(f.Values))
+			if f.Short != "" {
+				fmt.Fprintf(&sb, "        '%s -%s' = %s\n", c.Name, f.Short, powershellList(f.Values))
+			}
+		}
+	}
+	sb.WriteString("    }\n\n")
+	sb.WriteString("    $words = @($commandAst.CommandElements | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })\n")
+	sb.WriteString("    if ($wordToComplete -ne '') {\n")
+	sb.WriteString("        $words = if ($words.Count -gt 1) { $words[0..($words.Count - 2)] } else { @() }\n")
+	sb.WriteString("    }\n")
+	fmt.Fprintf(&sb, "    $cmd = '%s'\n", defaultCmd)
+	sb.WriteString("    if ($words.Count -gt 0 -and $flags.ContainsKey($words[0])) { $cmd = $words[0] }\n")
+	sb.WriteString("    $prev = if ($words.Count -gt 0) { $words[-1] } else { '' }\n\n")
+	sb.WriteString("    $candidates = $values[\"$cmd $prev\"]\n")
+	sb.WriteString("    if (-not $candidates) {\n")
+	sb.WriteString("        if ($wordToComplete -like '-*') {\n")
+	sb.WriteString("            $candidates = $flags[$cmd]\n")
+	sb.WriteString("        } elseif ($words.Count -eq 0) {\n")
+	sb.WriteString("            $candidates = $flags.Keys | Sort-Object\n")
+	sb.WriteString("        }\n")
+	sb.WriteString("    }\n")
+	sb.WriteString("    $candidates | Where-Object { $_ -like \"$wordToComplete*\" } | ForEach-Object {\n")
+	sb.WriteString("        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)\n")
+	sb.WriteString("    }\n")
+	sb.WriteString("}\n")
+	return sb.String()
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+func newTestModel(t *testing.T) *kong.Application {
+	t.Helper()
+	parser, err := kong.New(&App{}, kong.Name("ohman"), kong.Vars{"version": version, "default_pattern": defaultPattern, "delete_help": deleteHelp})
+	if err != nil {
+		t.Fatalf("failed to build the command line model: %v", err)
+	}
+	return parser.Model
+}
+
+func TestWriteCompletion(t *testing.T) {
+	t.Parallel()
+	model := newTestModel(t)
+
+	tests := []struct {
+		shell string
+		want  []string
+	}{
+		{"bash", []string{"complete -o default -F _ohman ohman", "scan|watch|daemon", "--dry-run", "--no-skip-errors", `compgen -W "text fdupes markdown json"`}},
+		{"zsh", []string{"#compdef ohman", "'watch:Watch directories", "'(-o --out)'{-o,--out=}'[Output file for results]:out:_files'", "'1:completion:(bash zsh fish powershell)'"}},
+		{"fish", []string{"-a daemon -d", "-l format -x -a 'text fdupes markdown json'", "-l out -s o -r -F", "-l no-skip-errors"}},
+		{"powershell", []string{"Register-ArgumentCompleter -Native -CommandName ohman", "'serve' = @(", "'scan --lock' = @('root', 'global', 'none')"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeCompletion(&buf, tt.shell, model); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("expected %s completion to contain %q, got:\n%s", tt.shell, want, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestWriteCompletion_BashSyntax(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash isn't installed")
+	}
+	var buf bytes.Buffer
+	if err := writeCompletion(&buf, "bash", newTestModel(t)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	script := filepath.Join(t.TempDir(), "ohman.bash")
+	if err := os.WriteFile(script, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+	// complete the value of --format for the default command
+	cmd := exec.Command(bash, "-c", `source "$0"; COMP_WORDS=(ohman --format m); COMP_CWORD=2; _ohman; echo "${COMPREPLY[@]}"`, script)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("script failed: %v\n%s", err, out)
+	}
+	if got := strings.TrimSpace(string(out)); got != "markdown" {
+		t.Errorf("expected markdown, got %q", got)
+	}
+}
+
+func TestCompletionHelp(t *testing.T) {
+	t.Parallel()
+	tests := map[string]string{
+		"⚠️  WARNING: Permanently delete duplicate files. USE AT YOUR OWN RISK.": "WARNING: Permanently delete duplicate files",
+		"Stop after this long (e.g. 30m). Disabled by default.":                  "Stop after this long (e.g. 30m)",
+		"Show version information.":                                              "Show version information",
+	}
+	for help, want := range tests {
+		if got := completionHelp(help); got != want {
+			t.Errorf("completionHelp(%q) = %q, want %q", help, got, want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// sameContent reports whether a and b are byte-identical, comparing their sizes before reading either.
+func (c *CLI) sameContent(ctx context.Context, a, b string) (bool, error) {
+	files := c.files()
+	if cc, ok := files.(contentComparer); ok {
+		return cc.sameContent(ctx, a, b)
+	}
+	infoA, err := files.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	infoB, err := files.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	if infoA.Size() != infoB.Size() {
+		return false, nil
+	}
+
+	fa, err := files.Open(a)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = fa.Close() }()
+	fb, err := files.Open(b)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = fb.Close() }()
+
+	ra, rb := c.throttle.reader(ctx, fa), c.throttle.reader(ctx, fb)
+	bufA, bufB := make([]byte, 64*1024), make([]byte, 64*1024)
+	for {
+		na, errA := io.ReadFull(ra, bufA)
+		nb, errB := io.ReadFull(rb, bufB)
+		if na != nb || !bytes.Equal(bufA[:na], bufB[:nb]) {
+			return false, nil
+		}
+		if errA == io.EOF || errA == io.ErrUnexpectedEOF {
+			return errB == io.EOF || errB == io.ErrUnexpectedEOF, nil
+		}
+		if errA != nil {
+			return false, errA
+		}
+		if errB != nil {
+			return false, errB
+		}
+	}
+}
+
+// mismatched returns the duplicates in g whose content differs from the original, or, for an orphan, from the copy
+// adopted in its place. Duplicates which can't be compared are treated as differing, so they're never deleted on the
+// assumption that they're redundant.
+func (c *CLI) mismatched(ctx context.Context, g group) []string {
+	kept := g.Original
+	if g.Orphan {
+		kept = g.Duplicates[0]
+	}
+	var differ []string
+	for _, d := range g.Duplicates {
+		if d == kept || c.isPartialOf(kept, d) {
+			continue
+		}
+		same, err := c.sameContent(ctx, kept, d)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, tr("Warning: unable to compare %s with %s: %v", d, kept, err))
+		}
+		if !same {
+			differ = append(differ, d)
+		}
+	}
+	return differ
+}
+
+// oversized returns the duplicates in g whose size differs from the file which would be kept by more than
+// --max-size-diff percent of the larger of the two, warning about each. Such a copy may well be another edition or a
+// better download, rather than something redundant. Copies which can't be described are returned too.
+func (c *CLI) oversized(g group) []string {
+	kept := g.Original
+	if g.Orphan {
+		kept = g.Duplicates[0]
+	}
+	keptInfo, err := c.stat(kept)
+	if err != nil {
+		return nil
+	}
+	var differ []string
+	for _, d := range g.Duplicates {
+		if d == kept {
+			continue
+		}
+		info, err := c.stat(d)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, tr("Warning: unable to compare the size of %s with %s: %v", d, kept, err))
+			differ = append(differ, d)
+			continue
+		}
+		if percent := sizeDiff(info.Size(), keptInfo.Size()); percent > c.MaxSizeDiff {
+			fmt.Fprintln(os.Stderr, tr("Warning: %s (%s) differs in size from %s (%s) by %.0f%%, more than --max-size-diff; its group won't be changed",
+				d, byteSize(info.Size()), kept, byteSize(keptInfo.Size()), percent))
+			differ = append(differ, d)
+		}
+	}
+	return differ
+}
+
+// sizeDiff returns how much a and b differ, as a percentage of the larger.
+func sizeDiff(a, b int64) float64 {
+	larger, diff := max(a, b), a-b
+	if larger == 0 {
+		return 0
+	}
+	if diff < 0 {
+		diff = -diff
+	}
+	return float64(diff) / float64(larger) * 100
+}
+
+// countMismatched counts the groups with duplicates which differ from their original.
+func countMismatched(groups []group) int {
+	n := 0
+	for _, g := range groups {
+		if len(g.Mismatched) > 0 {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCLI_SameContent(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	large := strings.Repeat("x", 200*1024)
+	files := map[string]string{
+		"a":        "same content",
+		"b":        "same content",
+		"c":        "same length!!",
+		"d":        "different size",
+		"large":    large,
+		"large-eq": large,
+		"large-ne": large[:len(large)-1] + "y",
+	}
+	for name, content := range files {
+		createTestFile(t, filepath.Join(dir, name), content)
+	}
+
+	tests := []struct {
+		a, b    string
+		want    bool
+		wantErr bool
+	}{
+		{a: "a", b: "b", want: true},
+		{a: "a", b: "c", want: false},
+		{a: "a", b: "d", want: false},
+		{a: "large", b: "large-eq", want: true},
+		{a: "large", b: "large-ne", want: false},
+		{a: "a", b: "missing", wantErr: true},
+	}
+	cli := &CLI{}
+	for _, tt := range tests {
+		got, err := cli.sameContent(context.Background(), filepath.Join(dir, tt.a), filepath.Join(dir, tt.b))
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("sameContent(%s, %s) = %v, %v; want %v", tt.a, tt.b, got, err, tt.want)
+		}
+	}
+}
+
+func TestCLI_Run_ContentDiffers(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "original content")
+	createTestFile(t, filepath.Join(dir, "book (1).pdf"), "original content")
+	createTestFile(t, filepath.Join(dir, "book (2).pdf"), "another edition")
+	createTestFile(t, filepath.Join(dir, "song.mp3"), "original content")
+	createTestFile(t, filepath.Join(dir, "song (1).mp3"), "original content")
+
+	cli := &CLI{Path: []string{dir}, Delete: true, Out: filepath.Join(dir, "results.txt"), Regex: []string{defaultRegex}}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !fileExists(filepath.Join(dir, "book (1).pdf")) || !fileExists(filepath.Join(dir, "book (2).pdf")) {
+		t.Error("a group with differing content must not be changed")
+	}
+	if fileExists(filepath.Join(dir, "song (1).mp3")) {
+		t.Error("identical duplicates should still be deleted")
+	}
+	if cli.status != exitDuplicatesFound {
+		t.Errorf("expected the skipped group to be reported as found, got status %d", cli.status)
+	}
+
+	cli = &CLI{Path: []string{dir}, Delete: true, AllowDifferent: true, Out: filepath.Join(dir, "results.txt"), Regex: []string{defaultRegex}}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fileExists(filepath.Join(dir, "book (2).pdf")) {
+		t.Error("differing duplicates should be deleted with --allow-different")
+	}
+}
+
+func TestCLI_Mismatched(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	g := group{
+		Original:   filepath.Join(dir, "book.pdf"),
+		Duplicates: []string{filepath.Join(dir, "book (1).pdf"), filepath.Join(dir, "book (2).pdf")},
+	}
+	createTestFile(t, g.Original, "original content")
+	createTestFile(t, g.Duplicates[0], "original content")
+	createTestFile(t, g.Duplicates[1], "another edition")
+
+	got := (&CLI{}).mismatched(context.Background(), g)
+	if want := g.Duplicates[1:]; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	g.Mismatched = got
+	if text := renderText([]group{g}, palette{}); !strings.Contains(text, "book (2).pdf (content differs)") {
+		t.Errorf("expected the differing duplicate to be marked, got:\n%s", text)
+	}
+}
+
+func TestCLI_Run_MaxSizeDiff(t *testing.T) {
+	t.Parallel()
+	memory := fstest.MapFS{
+		"media/book.pdf":     {Data: []byte("0123456789")},
+		"media/book (1).pdf": {Data: []byte("012345678")},
+		"media/song.mp3":     {Data: []byte("0123456789")},
+		"media/song (1).mp3": {Data: []byte("0123456789012345678901234567890123456789")},
+	}
+	cli := &CLI{
+		Path:           []string{"/media"},
+		Delete:         true,
+		AllowDifferent: true,
+		MaxSizeDiff:    20,
+		Out:            filepath.Join(t.TempDir(), "results.txt"),
+		Regex:          []string{defaultRegex},
+		memory:         memory,
+	}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if _, ok := memory["media/book (1).pdf"]; ok {
+		t.Error("book (1).pdf is within 20% of book.pdf's size, so should be deleted")
+	}
+	if _, ok := memory["media/song (1).mp3"]; !ok {
+		t.Error("song (1).mp3 is four times song.mp3's size, so should be kept")
+	}
+}
+
+func TestSizeDiff(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		a, b int64
+		want float64
+	}{{100, 100, 0}, {100, 50, 50}, {50, 100, 50}, {0, 0, 0}, {0, 10, 100}} {
+		if got := sizeDiff(tt.a, tt.b); got != tt.want {
+			t.Errorf("sizeDiff(%d, %d) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// DaemonCmd keeps running and performs a scan on a cron-like schedule.
+type DaemonCmd struct {
+	CLI `embed:""`
+
+	Schedule      string `name:"schedule" required:"" help:"When to run, as a standard 5-field cron expression (e.g. \"0 3 * * *\") or a descriptor like @daily or @every 6h."`
+	MetricsListen string `name:"metrics-listen" help:"Serve Prometheus metrics at /metrics on this address (e.g. 127.0.0.1:9100)." placeholder:"ADDR"`
+
+	// logger receives a record of each run; defaults to stderr.
+	logger *slog.Logger
+}
+
+func (d *DaemonCmd) Run(kctx *Context) error {
+	if err := d.resolvePaths(os.Stdin); err != nil {
+		return err
+	}
+	if len(d.Path) == 0 {
+		return fmt.Errorf("at least one path must be specified")
+	}
+	if err := d.checkRoots(); err != nil {
+		return err
+	}
+	schedule, err := cron.ParseStandard(d.Schedule)
+	if err != nil {
+		return fmt.Errorf("invalid schedule %q: %w", d.Schedule, err)
+	}
+	if d.logger == nil {
+		d.logger = newDaemonLogger()
+	}
+
+	ctx := kctx.context()
+	if d.MetricsListen != "" {
+		d.metrics = newMetrics()
+		if err := serveMetrics(ctx, d.MetricsListen, d.metrics); err != nil {
+			return err
+		}
+	}
+	if interval := watchdogInterval(); interval > 0 {
+		go runWatchdog(ctx, interval)
+	}
+	d.logger.Info("daemon started", "schedule", d.Schedule, "paths", d.Path)
+	_ = sdNotify("READY=1")
+
+	for run := 1; ; run++ {
+		next := schedule.Next(time.Now())
+		d.logger.Info("next run scheduled", "at", next.Format(time.RFC3339))
+		_ = sdNotify("STATUS=Next run at " + next.Format(time.RFC3339))
+		if err := sleep(ctx, time.Until(next)); err != nil {
+			return d.stopped(ctx)
+		}
+
+		if err := d.runOnce(ctx, run); errors.Is(err, errInterrupted) {
+			return d.stopped(ctx)
+		}
+	}
+}
+
+// runOnce performs a single scheduled scan, logging its outcome. A failed run doesn't stop the daemon.
+func (d *DaemonCmd) runOnce(ctx context.Context, run int) error {
+	started := time.Now()
+	d.logger.Info("run started", "run", run)
+	_ = sdNotify(fmt.Sprintf("STATUS=Run %d in progress", run))
+
+	c := d.CLI
+	c.progress = &progress{}
+	err := c.Run(&Context{Ctx: ctx})
+	attrs := []any{"run", run, "duration", time.Since(started).Round(time.Millisecond), "status", c.status}
+	if err != nil {
+		d.logger.Error("run failed", append(attrs, "error", err)...)
+		return err
+	}
+	d.logger.Info("run finished", attrs...)
+	return nil
+}
+
+// serveMetrics serves m at /metrics on addr until ctx is done.
+func serveMetrics(ctx context.Context, addr string, m *metrics) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("unable to listen on %s: %w", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", m)
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		_ = srv.Close()
+	}()
+	go func() { _ = srv.Serve(listener) }()
+	fmt.Fprintf(os.Stderr, "Serving metrics on http://%s/metrics\n", listener.Addr())
+	return nil
+}
+
+// stopped reports how the daemon was stopped, treating a signal as a clean shutdown.
+func (d *DaemonCmd) stopped(ctx context.Context) error {
+	cause := context.Cause(ctx)
+	_ = sdNotify("STOPPING=1")
+	d.logger.Info("daemon stopped", "reason", cause)
+	if errors.Is(cause, errInterrupted) {
+		return nil
+	}
+	return cause
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer guards a bytes.Buffer, as the daemon logs from its own goroutine.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestDaemonCmd_Run_InvalidSchedule(t *testing.T) {
+	t.Parallel()
+	d := &DaemonCmd{CLI: CLI{Path: []string{setupTestDir(t)}, Regex: []string{defaultRegex}}, Schedule: "not a schedule"}
+	if err := d.Run(nil); err == nil || !strings.Contains(err.Error(), "invalid schedule") {
+		t.Fatalf("expected invalid schedule error, got: %v", err)
+	}
+}
+
+func TestDaemonCmd_Run_RunsOnSchedule(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "original content")
+	duplicate := filepath.Join(dir, "book (1).pdf")
+	createTestFile(t, duplicate, "original content")
+
+	var logs syncBuffer
+	ctx, cancel := context.WithCancelCause(context.Background())
+	d := &DaemonCmd{
+		CLI: CLI{
+			Path:   []string{dir},
+			Delete: true,
+			Out:    filepath.Join(dir, "results.txt"),
+			Regex:  []string{defaultRegex},
+		},
+		Schedule: "@every 1s",
+		logger:   slog.New(slog.NewTextHandler(&logs, nil)),
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- d.Run(&Context{Ctx: ctx}) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for fileExists(duplicate) && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	cancel(errInterrupted)
+
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fileExists(duplicate) {
+		t.Error("duplicate should be deleted by the scheduled run")
+	}
+	for _, want := range []string{"daemon started", "run started", "daemon stopped"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("expected logs to contain %q, got:\n%s", want, logs.String())
+		}
+	}
+}
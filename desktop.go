@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// desktopTimeout bounds how long showing a desktop notification may take.
+const desktopTimeout = 10 * time.Second
+
+// notifyDesktop pops up a desktop notification saying how the run went, when --desktop-notify is set and the run took
+// at least --desktop-notify-after, warning when it can't.
+func (c *CLI) notifyDesktop(ctx context.Context, took time.Duration, groups []group, runErr error) {
+	if !c.DesktopNotify || took < c.DesktopAfter {
+		return
+	}
+	n := countGroups(groups)
+	if c.Stream {
+		n = c.streamed
+	}
+	title, body := tr("ohman finished"), c.summary(n)
+	if runErr != nil {
+		title, body = tr("ohman failed"), runErr.Error()
+	}
+	show := c.desktopNotifier
+	if show == nil {
+		show = showNotification
+	}
+	ctx, cancel := context.WithTimeout(ctx, desktopTimeout)
+	defer cancel()
+	if err := show(ctx, title, body); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: unable to show a desktop notification: %v\n", err)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// showNotification pops up a notification in Notification Center with osascript. The title and body are passed as
+// arguments, so they needn't be quoted for AppleScript.
+func showNotification(ctx context.Context, title, body string) error {
+	out, err := exec.CommandContext(ctx, "osascript",
+		"-e", "on run argv",
+		"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+		"-e", "end run",
+		title, body).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("osascript: %w: %s", err, msg)
+		}
+		return fmt.Errorf("osascript: %w", err)
+	}
+	return nil
+}
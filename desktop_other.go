@@ -0,0 +1,22 @@
+//go:build !windows && !darwin
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// showNotification pops up a notification with notify-send, which most Linux and BSD desktops provide.
+func showNotification(ctx context.Context, title, body string) error {
+	out, err := exec.CommandContext(ctx, "notify-send", "--app-name=ohman", "--", title, body).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("notify-send: %w: %s", err, msg)
+		}
+		return fmt.Errorf("notify-send: %w", err)
+	}
+	return nil
+}
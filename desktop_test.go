@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestCLI_Run_DesktopNotify(t *testing.T) {
+	t.Parallel()
+	var titles, bodies []string
+	cli := &CLI{
+		Path:          []string{"/media"},
+		DryRun:        true,
+		Out:           filepath.Join(t.TempDir(), "results.txt"),
+		Regex:         []string{defaultRegex},
+		DesktopNotify: true,
+		memory: fstest.MapFS{
+			"media/book.pdf":     {Data: []byte("content")},
+			"media/book (1).pdf": {Data: []byte("content")},
+		},
+		desktopNotifier: func(_ context.Context, title, body string) error {
+			titles, bodies = append(titles, title), append(bodies, body)
+			return nil
+		},
+	}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(titles) != 1 || titles[0] != "ohman finished" || bodies[0] != "Found 1 duplicate(s) of 1 file(s); nothing was changed. Run "+cli.runID+"." {
+		t.Errorf("notifications = %q, %q", titles, bodies)
+	}
+
+	// quick runs aren't worth interrupting anyone for
+	cli.DesktopAfter = time.Hour
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(titles) != 1 {
+		t.Errorf("expected no notification for a run shorter than --desktop-notify-after, got %q", titles[1:])
+	}
+}
+
+func TestCLI_NotifyDesktop_Failed(t *testing.T) {
+	t.Parallel()
+	var title, body string
+	cli := &CLI{DesktopNotify: true, desktopNotifier: func(_ context.Context, t, b string) error {
+		title, body = t, b
+		return nil
+	}}
+	cli.notifyDesktop(context.Background(), time.Minute, nil, errors.New("timed out after 1h"))
+	if title != "ohman failed" || body != "timed out after 1h" {
+		t.Errorf("notification = %q, %q", title, body)
+	}
+}
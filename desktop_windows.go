@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// toastScript shows a toast with the title and body in OHMAN_TOAST_TITLE and OHMAN_TOAST_BODY, which needn't be
+// quoted for PowerShell. Toasts must come from a registered app, so it borrows PowerShell's app ID.
+const toastScript = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $template.GetElementsByTagName('text')
+$text.Item(0).AppendChild($template.CreateTextNode($env:OHMAN_TOAST_TITLE)) > $null
+$text.Item(1).AppendChild($template.CreateTextNode($env:OHMAN_TOAST_BODY)) > $null
+$app = '{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe'
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($app).Show([Windows.UI.Notifications.ToastNotification]::new($template))
+`
+
+// showNotification pops up a toast notification with PowerShell.
+func showNotification(ctx context.Context, title, body string) error {
+	cmd := exec.CommandContext(ctx, "powershell.exe", "-NoProfile", "-NonInteractive", "-Command", toastScript)
+	cmd.Env = append(os.Environ(), "OHMAN_TOAST_TITLE="+title, "OHMAN_TOAST_BODY="+body)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("powershell: %w: %s", err, msg)
+		}
+		return fmt.Errorf("powershell: %w", err)
+	}
+	return nil
+}
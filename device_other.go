@@ -0,0 +1,20 @@
+//go:build !linux && !darwin
+
+package main
+
+import "io/fs"
+
+// deviceOf returns 0, as the device holding a file can't be told on this platform.
+func deviceOf(fs.FileInfo) uint64 {
+	return 0
+}
+
+// fileID reports false, as files can't be identified by device and inode on this platform.
+func fileID(fs.FileInfo) (id [2]uint64, ok bool) {
+	return id, false
+}
+
+// linkCount reports false, as hard links can't be counted on this platform.
+func linkCount(fs.FileInfo) (n uint64, ok bool) {
+	return 0, false
+}
@@ -0,0 +1,35 @@
+//go:build linux || darwin
+
+package main
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// deviceOf returns the ID of the device holding the file described by info, or 0 if it isn't known.
+func deviceOf(info fs.FileInfo) uint64 {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0
+	}
+	return uint64(st.Dev)
+}
+
+// fileID returns the device and inode of the file described by info, which identify it however it's reached.
+func fileID(info fs.FileInfo) (id [2]uint64, ok bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return id, false
+	}
+	return [2]uint64{uint64(st.Dev), uint64(st.Ino)}, true
+}
+
+// linkCount returns how many hard links the file described by info has.
+func linkCount(info fs.FileInfo) (n uint64, ok bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(st.Nlink), true
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// maxDiffBytes is the largest text file which is diffed line by line.
+	maxDiffBytes = 1 << 20
+	// maxDiffLines bounds the lines compared, as the diff takes time and memory proportional to their product.
+	maxDiffLines = 2000
+	// maxDiffOutput is the number of unified diff lines shown for each pair of files.
+	maxDiffOutput = 20
+	// diffContext is the number of unchanged lines shown around each change.
+	diffContext = 3
+)
+
+// renderDiffs describes how each differing duplicate compares to its original, so a reader can decide which version
+// to keep. The unified diffs of text files are fenced for markdown.
+func renderDiffs(files fs.StatFS, groups []group, markdown bool) string {
+	var sb strings.Builder
+	for _, g := range groups {
+		original := g.Original
+		if g.Orphan {
+			// the adopted copy takes the missing original's place
+			original = g.Duplicates[0]
+		}
+		for _, d := range g.Mismatched {
+			if sb.Len() > 0 {
+				sb.WriteString("\n")
+			}
+			describeDifference(&sb, files, original, d, markdown)
+		}
+	}
+	return sb.String()
+}
+
+func describeDifference(w io.Writer, files fs.StatFS, original, duplicate string, markdown bool) {
+	if markdown {
+		fmt.Fprintf(w, "#### %s differs from %s\n\n", markdownCode(duplicate), markdownCode(original))
+	} else {
+		fmt.Fprintf(w, "%s differs from %s\n", duplicate, original)
+	}
+	bullet := "  "
+	if markdown {
+		bullet = "- "
+	}
+
+	origInfo, err := files.Stat(original)
+	if err != nil {
+		fmt.Fprintf(w, "%sunable to read the original: %v\n", bullet, err)
+		return
+	}
+	dupInfo, err := files.Stat(duplicate)
+	if err != nil {
+		fmt.Fprintf(w, "%sunable to read the duplicate: %v\n", bullet, err)
+		return
+	}
+
+	sizeDelta := dupInfo.Size() - origInfo.Size()
+	fmt.Fprintf(w, "%ssize: %s vs %s (%s)\n", bullet, byteSize(dupInfo.Size()), byteSize(origInfo.Size()), signedSize(sizeDelta))
+	fmt.Fprintf(w, "%smodified: %s vs %s (%s)\n", bullet,
+		dupInfo.ModTime().Format(time.RFC3339), origInfo.ModTime().Format(time.RFC3339),
+		relativeTime(dupInfo.ModTime().Sub(origInfo.ModTime())))
+
+	a, aText := readText(files, original)
+	b, bText := readText(files, duplicate)
+	if !aText || !bText {
+		return
+	}
+	lines := diffLines(a, b)
+	if lines == nil {
+		fmt.Fprintf(w, "%slines: too many to compare\n", bullet)
+		return
+	}
+	added, removed := 0, 0
+	for _, l := range lines {
+		switch l.op {
+		case '+':
+			added++
+		case '-':
+			removed++
+		}
+	}
+	fmt.Fprintf(w, "%slines: +%d -%d\n", bullet, added, removed)
+
+	hunks := unifiedDiff(lines, diffContext)
+	if len(hunks) == 0 {
+		return
+	}
+	shown := hunks[:min(len(hunks), maxDiffOutput)]
+	if markdown {
+		fmt.Fprintf(w, "\n```diff\n%s\n```\n", strings.Join(shown, "\n"))
+	} else {
+		for _, l := range shown {
+			fmt.Fprintf(w, "    %s\n", l)
+		}
+	}
+	if more := len(hunks) - len(shown); more > 0 {
+		fmt.Fprintf(w, "%s... %d more diff lines\n", bullet, more)
+	}
+}
+
+func signedSize(delta int64) string {
+	if delta < 0 {
+		return "-" + byteSize(-delta).String()
+	}
+	return "+" + byteSize(delta).String()
+}
+
+func relativeTime(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d > 0:
+		return d.String() + " newer"
+	case d < 0:
+		return (-d).String() + " older"
+	}
+	return "same time"
+}
+
+// readText returns path's lines when it is a reasonably small, valid UTF-8 file without NUL bytes.
+func readText(files fs.FS, path string) ([]string, bool) {
+	f, err := files.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	defer func() { _ = f.Close() }()
+	content, err := io.ReadAll(io.LimitReader(f, maxDiffBytes+1))
+	if err != nil || len(content) > maxDiffBytes || !utf8.Valid(content) || bytes.IndexByte(content, 0) >= 0 {
+		return nil, false
+	}
+	text := strings.TrimSuffix(string(content), "\n")
+	if text == "" {
+		return nil, true
+	}
+	return strings.Split(text, "\n"), true
+}
+
+// diffLine is a line of a diff: unchanged (' '), removed from the original ('-'), or added in the duplicate ('+').
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// diffLines compares a and b by their longest common subsequence, returning nil when either is too long.
+func diffLines(a, b []string) []diffLine {
+	if len(a) > maxDiffLines || len(b) > maxDiffLines {
+		return nil
+	}
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]diffLine, 0, max(len(a), len(b)))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, diffLine{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, diffLine{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, diffLine{'+', b[j]})
+	}
+	return lines
+}
+
+// unifiedDiff formats the changes in lines as unified diff hunks with the given number of context lines.
+func unifiedDiff(lines []diffLine, context int) []string {
+	var out []string
+	for start := 0; start < len(lines); {
+		// find the next change
+		for start < len(lines) && lines[start].op == ' ' {
+			start++
+		}
+		if start == len(lines) {
+			break
+		}
+		// extend the hunk until a run of unchanged lines long enough to separate hunks
+		end := start
+		for unchanged := 0; end < len(lines) && unchanged <= 2*context; end++ {
+			if lines[end].op == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+		for end > start && lines[end-1].op == ' ' {
+			end--
+		}
+		from, to := max(start-context, 0), min(end+context, len(lines))
+
+		origLine, dupLine := 1, 1
+		for _, l := range lines[:from] {
+			if l.op != '+' {
+				origLine++
+			}
+			if l.op != '-' {
+				dupLine++
+			}
+		}
+		origCount, dupCount := 0, 0
+		for _, l := range lines[from:to] {
+			if l.op != '+' {
+				origCount++
+			}
+			if l.op != '-' {
+				dupCount++
+			}
+		}
+		out = append(out, fmt.Sprintf("@@ -%d,%d +%d,%d @@", origLine, origCount, dupLine, dupCount))
+		for _, l := range lines[from:to] {
+			out = append(out, string(l.op)+l.text)
+		}
+		start = to
+	}
+	return out
+}
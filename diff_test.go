@@ -0,0 +1,71 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	t.Parallel()
+	a := strings.Split("a b c d e f g h i j k", " ")
+	b := strings.Split("a b C d e f g h i j k l", " ")
+
+	got := strings.Join(unifiedDiff(diffLines(a, b), 3), "\n")
+	want := strings.Join([]string{
+		"@@ -1,6 +1,6 @@",
+		" a", " b", "-c", "+C", " d", " e", " f",
+		"@@ -9,3 +9,4 @@",
+		" i", " j", " k", "+l",
+	}, "\n")
+	if got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestDiffLines_Limits(t *testing.T) {
+	t.Parallel()
+	if lines := diffLines(make([]string, maxDiffLines+1), nil); lines != nil {
+		t.Error("expected files with too many lines not to be compared")
+	}
+	if lines := diffLines([]string{"same"}, []string{"same"}); len(unifiedDiff(lines, 3)) != 0 {
+		t.Error("expected identical files to produce no hunks")
+	}
+}
+
+func TestRenderDiffs(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	now := time.Now().Truncate(time.Second)
+	original := filepath.Join(dir, "notes.txt")
+	duplicate := filepath.Join(dir, "notes (1).txt")
+	binary := filepath.Join(dir, "notes (2).txt")
+	createTestFileWithModTime(t, original, "one\ntwo\nthree\n", now)
+	createTestFileWithModTime(t, duplicate, "one\n2\nthree\nfour\n", now.Add(2*time.Hour))
+	createTestFileWithModTime(t, binary, "one\x00two", now.Add(-time.Minute))
+
+	report := renderDiffs(localFS{}, []group{{Original: original, Duplicates: []string{duplicate, binary}, Mismatched: []string{duplicate, binary}}}, false)
+	for _, want := range []string{
+		duplicate + " differs from " + original,
+		"size: 17 B vs 14 B (+3 B)",
+		"(2h0m0s newer)",
+		"lines: +2 -1",
+		"    -two",
+		"    +2",
+		"    +four",
+		"(1m0s older)",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, report)
+		}
+	}
+	if strings.Count(report, "lines:") != 1 {
+		t.Errorf("expected binary files not to be diffed, got:\n%s", report)
+	}
+
+	markdown := renderDiffs(localFS{}, []group{{Original: original, Duplicates: []string{duplicate}, Mismatched: []string{duplicate}}}, true)
+	if !strings.Contains(markdown, "```diff\n@@ -1,3 +1,4 @@") {
+		t.Errorf("expected a fenced diff, got:\n%s", markdown)
+	}
+}
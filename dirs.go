@@ -0,0 +1,102 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// dirTotal is how many duplicates a directory holds, and how many bytes removing them would reclaim.
+type dirTotal struct {
+	Dir        string
+	Duplicates int
+	Bytes      int64
+}
+
+// totalsByDir aggregates the duplicates which would be removed by the directory they're in, largest first. Copies
+// whose content differs and orphans' adopted copies are kept, so aren't counted. Sizes are read from files, and files
+// which can no longer be found count towards a directory's duplicates but not its bytes. A file with several hard
+// links counts towards the bytes of the first directory holding one, and only when all of them would be removed.
+func totalsByDir(files fs.StatFS, groups []group) []dirTotal {
+	totals := map[string]*dirTotal{}
+	type removal struct {
+		dir  string
+		info fs.FileInfo
+	}
+	var removals []removal
+	// links counts the removals of each file with an id
+	links := map[[2]uint64]uint64{}
+	for _, g := range groups {
+		for i, d := range g.Duplicates {
+			if (g.Orphan && i == 0) || slices.Contains(g.Mismatched, d) {
+				continue
+			}
+			dir := parentDir(d)
+			t, ok := totals[dir]
+			if !ok {
+				t = &dirTotal{Dir: dir}
+				totals[dir] = t
+			}
+			t.Duplicates++
+			if info, err := files.Stat(d); err == nil {
+				removals = append(removals, removal{dir, info})
+				if id, ok := fileID(info); ok {
+					links[id]++
+				}
+			}
+		}
+	}
+	for _, r := range removals {
+		id, ok := fileID(r.info)
+		if !ok {
+			totals[r.dir].Bytes += r.info.Size()
+			continue
+		}
+		if n, ok := linkCount(r.info); links[id] > 0 && (!ok || links[id] >= n) {
+			totals[r.dir].Bytes += r.info.Size()
+		}
+		// counted once
+		links[id] = 0
+	}
+
+	sorted := make([]dirTotal, 0, len(totals))
+	for _, t := range totals {
+		sorted = append(sorted, *t)
+	}
+	slices.SortFunc(sorted, func(a, b dirTotal) int {
+		return cmp.Or(cmp.Compare(b.Bytes, a.Bytes), cmp.Compare(b.Duplicates, a.Duplicates), cmp.Compare(a.Dir, b.Dir))
+	})
+	return sorted
+}
+
+// parentDir returns the directory holding path, which may be a URL such as s3://bucket/prefix/book.pdf.
+func parentDir(path string) string {
+	if hasScheme(path) {
+		return path[:strings.LastIndex(path, "/")]
+	}
+	return filepath.Dir(path)
+}
+
+// renderDirs emits a table of the directories holding duplicates, with the worst offenders first.
+func renderDirs(files fs.StatFS, groups []group) string {
+	totals := totalsByDir(files, groups)
+	if len(totals) == 0 {
+		return "No duplicates found."
+	}
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "DUPLICATES\tRECLAIMABLE\t  DIRECTORY")
+	var all dirTotal
+	for _, t := range totals {
+		fmt.Fprintf(tw, "%d\t%s\t  %s\n", t.Duplicates, byteSize(t.Bytes), t.Dir)
+		all.Duplicates += t.Duplicates
+		all.Bytes += t.Bytes
+	}
+	fmt.Fprintf(tw, "%d\t%s\t  %s\n", all.Duplicates, byteSize(all.Bytes), "total")
+	_ = tw.Flush()
+	return strings.TrimSuffix(sb.String(), "\n")
+}
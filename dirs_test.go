@@ -0,0 +1,51 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestTotalsByDir(t *testing.T) {
+	t.Parallel()
+	files := &memFS{files: fstest.MapFS{
+		"books/a (1).pdf":  {Data: make([]byte, 100)},
+		"books/b (1).pdf":  {Data: make([]byte, 50)},
+		"books/c (1).pdf":  {Data: make([]byte, 10)},
+		"music/s (1).mp3":  {Data: make([]byte, 500)},
+		"music/s (2).mp3":  {Data: make([]byte, 500)},
+		"photos/p (1).jpg": {Data: make([]byte, 1)},
+	}}
+	groups := []group{
+		{Original: "/books/a.pdf", Duplicates: []string{"/books/a (1).pdf"}},
+		{Original: "/books/b.pdf", Duplicates: []string{"/books/b (1).pdf", "/books/b (2).pdf"}},
+		// differing copies are kept
+		{Original: "/books/c.pdf", Duplicates: []string{"/books/c (1).pdf"}, Mismatched: []string{"/books/c (1).pdf"}},
+		// the adopted copy is kept
+		{Original: "/music/s.mp3", Duplicates: []string{"/music/s (1).mp3", "/music/s (2).mp3"}, Orphan: true},
+		{Original: "/photos/p.jpg", Duplicates: []string{"/photos/p (1).jpg"}},
+	}
+
+	want := []dirTotal{
+		{Dir: "/music", Duplicates: 1, Bytes: 500},
+		{Dir: "/books", Duplicates: 3, Bytes: 150},
+		{Dir: "/photos", Duplicates: 1, Bytes: 1},
+	}
+	if got := totalsByDir(files, groups); !slices.Equal(got, want) {
+		t.Errorf("totalsByDir() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRenderDirs(t *testing.T) {
+	t.Parallel()
+	files := &memFS{files: fstest.MapFS{"books/a (1).pdf": {Data: make([]byte, 2048)}}}
+	got := renderDirs(files, []group{{Original: "/books/a.pdf", Duplicates: []string{"/books/a (1).pdf"}}})
+	lines := strings.Split(got, "\n")
+	if len(lines) != 3 || !strings.HasSuffix(lines[1], "2.0 KiB  /books") || !strings.HasSuffix(lines[2], "total") {
+		t.Errorf("renderDirs() = %q", got)
+	}
+	if got := renderDirs(files, nil); got != "No duplicates found." {
+		t.Errorf("renderDirs() of nothing = %q", got)
+	}
+}
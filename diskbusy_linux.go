@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// diskStats are a block device's cumulative counters from /proc/diskstats.
+type diskStats struct {
+	// ticks are the milliseconds spent doing I/O
+	ticks uint64
+	ops   uint64
+	bytes uint64
+}
+
+// newDiskBusy returns a sampler reporting the utilization (0-1) of the busiest block device backing paths since the
+// previous sample, based on the io_ticks column of /proc/diskstats. That counts every process's I/O, so the share of
+// each device's operations and bytes which were ohman's own, given to the sampler, is taken out. When no path is on a
+// device listed there, e.g. on btrfs, whose devices are virtual, every device is sampled.
+func newDiskBusy(paths []string) func(own ioCount) (float64, bool) {
+	devices := backingDevices(paths)
+	last := readDiskStats(devices)
+	if len(last) == 0 {
+		devices, last = nil, readDiskStats(nil)
+	}
+	lastTime := time.Now()
+	return func(own ioCount) (float64, bool) {
+		current, now := readDiskStats(devices), time.Now()
+		elapsed := now.Sub(lastTime).Milliseconds()
+		if current == nil || elapsed <= 0 {
+			return 0, false
+		}
+		var busiest float64
+		for dev, stats := range current {
+			if prev, ok := last[dev]; ok {
+				d := diskStats{ticks: stats.ticks - prev.ticks, ops: stats.ops - prev.ops, bytes: stats.bytes - prev.bytes}
+				busiest = max(busiest, float64(d.ticks)*d.othersShare(own)/float64(elapsed))
+			}
+		}
+		last, lastTime = current, now
+		return min(busiest, 1), true
+	}
+}
+
+// othersShare returns the share of the I/O counted in d which wasn't ohman's own, the larger of its shares of the
+// operations and of the bytes, as many stats may barely touch the disk while another process streams a film. ohman's
+// own I/O can't be told apart by device, and some of it is served from the page cache, so all of it is taken out of
+// each device, down to nothing.
+func (d diskStats) othersShare(own ioCount) float64 {
+	if d.ops == 0 {
+		// the device was busy with I/O which hasn't completed yet, so can't be attributed
+		return 1
+	}
+	share := float64(d.ops-min(own.ops, d.ops)) / float64(d.ops)
+	if d.bytes > 0 {
+		share = max(share, float64(d.bytes-min(own.bytes, d.bytes))/float64(d.bytes))
+	}
+	return share
+}
+
+// backingDevices returns the major:minor numbers of the devices holding paths, as /proc/diskstats lists them. Paths
+// which can't be statted are skipped.
+func backingDevices(paths []string) map[string]bool {
+	devices := make(map[string]bool)
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			if dev := deviceOf(info); dev != 0 {
+				devices[fmt.Sprintf("%d:%d", unix.Major(dev), unix.Minor(dev))] = true
+			}
+		}
+	}
+	return devices
+}
+
+// readDiskStats returns the counters of each of devices, by major:minor, or of every device when devices is nil. It
+// returns nil if they're unavailable.
+func readDiskStats(devices map[string]bool) map[string]diskStats {
+	f, err := os.Open("/proc/diskstats")
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = f.Close() }()
+
+	stats := make(map[string]diskStats)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// major minor name reads ... io_ticks is the 10th statistic, i.e. the 13th field
+		if len(fields) < 13 || strings.HasPrefix(fields[2], "loop") || strings.HasPrefix(fields[2], "ram") {
+			continue
+		}
+		dev := fields[0] + ":" + fields[1]
+		if devices != nil && !devices[dev] {
+			continue
+		}
+		// reads and writes completed are the 1st and 5th statistics, and sectors of 512 bytes read and written the
+		// 3rd and 7th
+		var v [13]uint64
+		parsed := true
+		for _, i := range []int{3, 5, 7, 9, 12} {
+			var err error
+			if v[i], err = strconv.ParseUint(fields[i], 10, 64); err != nil {
+				parsed = false
+			}
+		}
+		if parsed {
+			stats[dev] = diskStats{ticks: v[12], ops: v[3] + v[7], bytes: (v[5] + v[9]) * 512}
+		}
+	}
+	return stats
+}
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestDiskStats_OthersShare(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		stats diskStats
+		own   ioCount
+		want  float64
+	}{
+		{"all others'", diskStats{ops: 10, bytes: 1000}, ioCount{}, 1},
+		{"all ohman's", diskStats{ops: 10, bytes: 1000}, ioCount{ops: 10, bytes: 1000}, 0},
+		{"more than the device saw", diskStats{ops: 10, bytes: 1000}, ioCount{ops: 50, bytes: 5000}, 0},
+		{"others' bytes", diskStats{ops: 10, bytes: 1000}, ioCount{ops: 9, bytes: 500}, 0.5},
+		{"others' operations", diskStats{ops: 10, bytes: 1000}, ioCount{ops: 5, bytes: 1000}, 0.5},
+		{"nothing completed", diskStats{ticks: 100}, ioCount{ops: 5}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.stats.othersShare(tt.own); got != tt.want {
+				t.Errorf("othersShare() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
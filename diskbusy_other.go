@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// newDiskBusy returns a sampler which never reports activity, as disk utilization isn't available on this platform.
+func newDiskBusy([]string) func(ioCount) (float64, bool) {
+	return func(ioCount) (float64, bool) { return 0, false }
+}
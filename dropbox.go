@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// dropboxScheme prefixes paths which name folders and files in Dropbox, e.g. dropbox://Photos.
+const dropboxScheme = "dropbox://"
+
+// conflictedCopy matches the names Dropbox gives files edited in two places at once, e.g.
+// "report (Jane's conflicted copy 2024-01-05).pdf" or "report (conflicted copy 2024-01-05 (1)).pdf".
+var conflictedCopy = regexp.MustCompile(`^(.+) \([^()]*conflicted copy[^()]*(?:\(\d+\))?\)(\.[^.]*)?$`)
+
+// isDropbox reports whether path names Dropbox files rather than local ones.
+func isDropbox(path string) bool {
+	return strings.HasPrefix(path, dropboxScheme)
+}
+
+// dropboxFile is a file found by listing Dropbox.
+type dropboxFile struct {
+	Tag            string    `json:".tag"`
+	Name           string    `json:"name"`
+	PathDisplay    string    `json:"path_display"`
+	Size           int64     `json:"size"`
+	ContentHash    string    `json:"content_hash"`
+	ServerModified time.Time `json:"server_modified"`
+}
+
+// dropboxInfo describes a Dropbox file as a file, so listings can be visited like a walk of the filesystem.
+type dropboxInfo struct{ dropboxFile }
+
+func (i dropboxInfo) Name() string       { return i.dropboxFile.Name }
+func (i dropboxInfo) Size() int64        { return i.dropboxFile.Size }
+func (i dropboxInfo) Mode() fs.FileMode  { return 0o644 }
+func (i dropboxInfo) ModTime() time.Time { return i.ServerModified }
+func (i dropboxInfo) IsDir() bool        { return false }
+func (i dropboxInfo) Sys() any           { return i.dropboxFile }
+
+// dropboxClient lists, moves, and deletes files through the Dropbox API.
+type dropboxClient struct {
+	// base is the API's URL, overridden in tests.
+	base   string
+	token  string
+	client *http.Client
+}
+
+func newDropboxClient(token string) (*dropboxClient, error) {
+	if token == "" {
+		return nil, errors.New("a Dropbox access token is needed for dropbox:// paths; set --dropbox-token or $OHMAN_DROPBOX_TOKEN")
+	}
+	return &dropboxClient{base: "https://api.dropboxapi.com/2", token: token, client: &http.Client{Timeout: time.Minute}}, nil
+}
+
+// list lists every file beneath the folder at path, which is empty for the root, calling page for each page of
+// results.
+func (d *dropboxClient) list(ctx context.Context, path string, page func([]dropboxFile) error) error {
+	var result struct {
+		Entries []dropboxFile `json:"entries"`
+		Cursor  string        `json:"cursor"`
+		HasMore bool          `json:"has_more"`
+	}
+	if err := d.do(ctx, "/files/list_folder", map[string]any{"path": path, "recursive": true, "limit": 2000}, &result); err != nil {
+		return err
+	}
+	for {
+		if err := page(result.Entries); err != nil {
+			return err
+		}
+		if !result.HasMore {
+			return nil
+		}
+		cursor := result.Cursor
+		result.Entries = nil
+		if err := d.do(ctx, "/files/list_folder/continue", map[string]any{"cursor": cursor}, &result); err != nil {
+			return err
+		}
+	}
+}
+
+// remove deletes the file at path. Dropbox keeps deleted files, which can be restored for at least 30 days.
+func (d *dropboxClient) remove(ctx context.Context, path string) error {
+	return d.do(ctx, "/files/delete_v2", map[string]any{"path": path}, nil)
+}
+
+// move moves the file at from to to.
+func (d *dropboxClient) move(ctx context.Context, from, to string) error {
+	return d.do(ctx, "/files/move_v2", map[string]any{"from_path": from, "to_path": to}, nil)
+}
+
+func (d *dropboxClient) do(ctx context.Context, endpoint string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.base+endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+d.token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		// endpoint errors are JSON; others, such as a bad token, are plain text
+		var apiErr struct {
+			ErrorSummary string `json:"error_summary"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.ErrorSummary != "" {
+			return fmt.Errorf("Dropbox: %s", apiErr.ErrorSummary)
+		}
+		if msg := strings.TrimSpace(string(data)); msg != "" {
+			return fmt.Errorf("Dropbox: %s: %s", resp.Status, msg)
+		}
+		return fmt.Errorf("Dropbox: unexpected status %s", resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// dropboxPath returns the Dropbox API's path for a dropbox:// path.
+func dropboxPath(path string) string {
+	p := strings.TrimSuffix(strings.TrimPrefix(path, dropboxScheme), "/")
+	if p == "" {
+		return ""
+	}
+	return "/" + strings.TrimPrefix(p, "/")
+}
+
+// setupDropbox prepares to scan Dropbox paths, if there are any. Only names can be matched in Dropbox, as matching
+// by content would mean downloading every file.
+func (c *CLI) setupDropbox() error {
+	if !slices.ContainsFunc(c.Path, isDropbox) {
+		return nil
+	}
+	if slices.ContainsFunc(c.Path, func(p string) bool { return !isDropbox(p) }) {
+		return errors.New("dropbox:// paths can't be scanned along with other paths")
+	}
+	if c.Match != "" && c.Match != "name" {
+		return fmt.Errorf("--match %s needs local files; only name can be matched in Dropbox", c.Match)
+	}
+	if c.Diff {
+		return errors.New("--diff needs local files and can't be used with Dropbox")
+	}
+	if c.dropbox != nil {
+		return nil
+	}
+	var err error
+	c.dropbox, err = newDropboxClient(c.DropboxToken)
+	return err
+}
+
+// dropboxFS is the files in Dropbox, listed through the Dropbox API.
+type dropboxFS struct {
+	client   *dropboxClient
+	throttle *throttle
+	// files holds what the last walk listed, by path.
+	files map[string]dropboxFile
+}
+
+func (d *dropboxFS) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: errUnreadable}
+}
+
+func (d *dropboxFS) Stat(name string) (fs.FileInfo, error) {
+	if f, ok := d.files[name]; ok {
+		return dropboxInfo{f}, nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// walk lists the files beneath root.
+func (d *dropboxFS) walk(ctx context.Context, root string, visit filepath.WalkFunc) error {
+	if err := d.throttle.op(ctx); err != nil {
+		return err
+	}
+	err := d.client.list(ctx, dropboxPath(root), func(entries []dropboxFile) error {
+		for _, f := range entries {
+			if f.Tag != "file" {
+				continue
+			}
+			path := dropboxScheme + strings.TrimPrefix(f.PathDisplay, "/")
+			d.files[path] = f
+			if err := visit(path, dropboxInfo{f}, nil); err != nil {
+				return err
+			}
+		}
+		return d.throttle.op(ctx)
+	})
+	if err != nil {
+		return fmt.Errorf("unable to list %s: %w", root, err)
+	}
+	return nil
+}
+
+// addCopies adds Dropbox's conflicted copies to files, as duplicates of the file each conflicts with. The usual
+// rules apply: identical copies are deleted, and copies which differ are kept unless --allow-different is given.
+func (d *dropboxFS) addCopies(patterns copyPatterns, files map[string][]string) {
+	for path := range d.files {
+		dir, name := path[:strings.LastIndex(path, "/")+1], path[strings.LastIndex(path, "/")+1:]
+		matches := conflictedCopy.FindStringSubmatch(name)
+		if matches == nil {
+			continue
+		}
+		original := dir + matches[1] + matches[2]
+		if o, ok := originalFor(patterns, original); ok {
+			original = o
+		}
+		files[original] = append(files[original], path)
+	}
+	for _, duplicates := range files {
+		slices.Sort(duplicates)
+	}
+}
+
+func (d *dropboxFS) remove(ctx context.Context, path string) error {
+	if err := d.client.remove(ctx, dropboxPath(path)); err != nil {
+		return err
+	}
+	delete(d.files, path)
+	return nil
+}
+
+func (d *dropboxFS) rename(ctx context.Context, from, to string) error {
+	f, ok := d.files[from]
+	if !ok {
+		return &fs.PathError{Op: "rename", Path: from, Err: fs.ErrNotExist}
+	}
+	if err := d.client.move(ctx, dropboxPath(from), dropboxPath(to)); err != nil {
+		return err
+	}
+	delete(d.files, from)
+	f.PathDisplay = dropboxPath(to)
+	f.Name = to[strings.LastIndex(to, "/")+1:]
+	d.files[to] = f
+	return nil
+}
+
+func (d *dropboxFS) sameContent(_ context.Context, x, y string) (bool, error) {
+	a, ok := d.files[x]
+	if !ok {
+		return false, &fs.PathError{Op: "stat", Path: x, Err: fs.ErrNotExist}
+	}
+	b, ok := d.files[y]
+	if !ok {
+		return false, &fs.PathError{Op: "stat", Path: y, Err: fs.ErrNotExist}
+	}
+	return sameDropboxFile(a, b)
+}
+
+// sameDropboxFile reports whether two Dropbox files have the same content, judging by their content hashes.
+func sameDropboxFile(a, b dropboxFile) (bool, error) {
+	if a.ContentHash == "" || b.ContentHash == "" {
+		return false, fmt.Errorf("Dropbox has no content hash for %q or %q", a.Name, b.Name)
+	}
+	return a.Size == b.Size && a.ContentHash == b.ContentHash, nil
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDropbox serves the parts of the Dropbox API ohman uses, listing at most two entries per page.
+type fakeDropbox struct {
+	t     *testing.T
+	mu    sync.Mutex
+	files map[string]dropboxFile
+}
+
+func (f *fakeDropbox) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("Error in call to API function: invalid access token"))
+		return
+	}
+	var body struct {
+		Path     string `json:"path"`
+		Cursor   string `json:"cursor"`
+		FromPath string `json:"from_path"`
+		ToPath   string `json:"to_path"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		f.t.Errorf("invalid request: %v", err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	notFound := func() {
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(`{"error_summary":"path/not_found/.."}`))
+	}
+
+	switch r.URL.Path {
+	case "/files/list_folder", "/files/list_folder/continue":
+		prefix, start := body.Path, 0
+		if body.Cursor != "" {
+			prefix, body.Cursor, _ = strings.Cut(body.Cursor, "|")
+			start, _ = strconv.Atoi(body.Cursor)
+		}
+		var paths []string
+		for p := range f.files {
+			if strings.HasPrefix(strings.ToLower(p), strings.ToLower(prefix)+"/") {
+				paths = append(paths, p)
+			}
+		}
+		if len(paths) == 0 {
+			notFound()
+			return
+		}
+		slices.Sort(paths)
+		end := min(start+2, len(paths))
+		var entries []dropboxFile
+		for _, p := range paths[start:end] {
+			entries = append(entries, f.files[p])
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"entries": entries, "cursor": prefix + "|" + strconv.Itoa(end), "has_more": end < len(paths)})
+	case "/files/delete_v2":
+		if _, ok := f.files[body.Path]; !ok {
+			notFound()
+			return
+		}
+		delete(f.files, body.Path)
+		_, _ = w.Write([]byte(`{}`))
+	case "/files/move_v2":
+		file, ok := f.files[body.FromPath]
+		if !ok {
+			notFound()
+			return
+		}
+		delete(f.files, body.FromPath)
+		file.PathDisplay, file.Name = body.ToPath, body.ToPath[strings.LastIndex(body.ToPath, "/")+1:]
+		f.files[body.ToPath] = file
+		_, _ = w.Write([]byte(`{}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestCLI_Run_Dropbox(t *testing.T) {
+	files := map[string]dropboxFile{}
+	for path, hash := range map[string]string{
+		"/Docs/report.pdf":     "1",
+		"/Docs/report (1).pdf": "1",
+		"/Docs/report (Jane's conflicted copy 2024-01-05).pdf": "1",
+		"/Docs/notes.txt": "2",
+		"/Docs/notes (conflicted copy 2024-01-05 (1)).txt":        "3",
+		"/Docs/old/lost (1).epub":                                 "4",
+		"/Docs/old/lost (2).epub":                                 "4",
+		"/Other/keep (1).pdf":                                     "5",
+		"/Docs/plain (Jane's MacBook conflicted copy 2024-01-05)": "6",
+		"/Docs/plain":                                             "6",
+	} {
+		files[path] = dropboxFile{Tag: "file", Name: path[strings.LastIndex(path, "/")+1:], PathDisplay: path, Size: 7, ContentHash: hash}
+	}
+	files["/Docs/old"] = dropboxFile{Tag: "folder", Name: "old", PathDisplay: "/Docs/old"}
+	server := &fakeDropbox{t: t, files: files}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	cli := &CLI{
+		Path:         []string{"dropbox://docs"},
+		Delete:       true,
+		Regex:        []string{defaultRegex},
+		AdoptOrphans: "lowest",
+		Out:          filepath.Join(t.TempDir(), "results.txt"),
+		dropbox:      &dropboxClient{base: ts.URL, token: "token", client: ts.Client()},
+	}
+	if err := cli.Run(&Context{}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var got []string
+	for p := range server.files {
+		got = append(got, p)
+	}
+	slices.Sort(got)
+	// the conflicted copy of notes differs, so it's kept
+	want := []string{"/Docs/notes (conflicted copy 2024-01-05 (1)).txt", "/Docs/notes.txt", "/Docs/old", "/Docs/old/lost.epub", "/Docs/plain", "/Docs/report.pdf", "/Other/keep (1).pdf"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected files %q, got %q", want, got)
+	}
+}
+
+func TestConflictedCopy(t *testing.T) {
+	t.Parallel()
+	for name, want := range map[string]string{
+		"report (Jane's conflicted copy 2024-01-05).pdf":           "report.pdf",
+		"report (conflicted copy 2024-01-05 (1)).pdf":              "report.pdf",
+		"archive.tar (Jane's conflicted copy 2024-01-05).gz":       "archive.tar.gz",
+		"Makefile (Jane's MacBook Pro conflicted copy 2024-01-05)": "Makefile",
+		"report (1).pdf":  "",
+		"conflicted copy": "",
+	} {
+		matches := conflictedCopy.FindStringSubmatch(name)
+		got := ""
+		if matches != nil {
+			got = matches[1] + matches[2]
+		}
+		if got != want {
+			t.Errorf("%q: expected %q, got %q", name, want, got)
+		}
+	}
+}
+
+func TestCLI_SetupDropbox(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		cli  CLI
+		want string
+	}{
+		{"mixed", CLI{Path: []string{"dropbox://Docs", "/media"}}, "along with other paths"},
+		{"match", CLI{Path: []string{"dropbox://Photos"}, Match: "image"}, "--match image needs local files"},
+		{"diff", CLI{Path: []string{"dropbox://Photos"}, Diff: true}, "--diff"},
+		{"token", CLI{Path: []string{"dropbox://Photos"}}, "--dropbox-token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cli.setupDropbox(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestCLI_WalkDropbox_Errors(t *testing.T) {
+	t.Parallel()
+	server := &fakeDropbox{t: t, files: map[string]dropboxFile{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	for token, want := range map[string]string{
+		"token":   "path/not_found",
+		"expired": "invalid access token",
+	} {
+		cli := &CLI{Path: []string{"dropbox://Missing"}, dropbox: &dropboxClient{base: ts.URL, token: token, client: ts.Client()}}
+		if err := cli.walk(t.Context(), func(string, os.FileInfo) {}); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected an error containing %q, got %v", want, err)
+		}
+	}
+	if got := dropboxPath("dropbox://"); got != "" {
+		t.Errorf("expected the root to be listed as \"\", got %q", got)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// dirCopyRegex matches the names of copied directories, like "Photos 2020 (1)" or "Photos 2020 (1) (2)", capturing
+// the original's name.
+var dirCopyRegex = regexp.MustCompile(`^(.+?)(?:\s\(\d+\))+$`)
+
+// dirMaker is implemented by backends in which a file can only be moved into a directory which exists.
+type dirMaker interface {
+	// mkdirAll creates the directory at path, along with any parents it needs.
+	mkdirAll(path string) error
+}
+
+// scanDirs maps directories to the copies found of them, for --match dirs. Copies within other copies are left to
+// be dealt with along with the copy holding them, and copies of directories which don't exist are ignored.
+func (c *CLI) scanDirs(ctx context.Context) (map[string][]string, error) {
+	seen := map[string]bool{}
+	err := c.walk(ctx, func(path string, _ os.FileInfo) {
+		for dir := filepath.Dir(path); !seen[dir] && c.withinPaths(dir); dir = filepath.Dir(dir) {
+			seen[dir] = true
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var copies []string
+	for _, dir := range slices.Sorted(maps.Keys(seen)) {
+		if !dirCopyRegex.MatchString(filepath.Base(dir)) {
+			continue
+		}
+		// sorted, so any copy holding dir has already been seen
+		if slices.ContainsFunc(copies, func(copied string) bool { return isWithin(dir, copied) }) {
+			continue
+		}
+		copies = append(copies, dir)
+	}
+
+	files := make(map[string][]string)
+	for _, dir := range copies {
+		original := filepath.Join(filepath.Dir(dir), dirCopyRegex.FindStringSubmatch(filepath.Base(dir))[1])
+		if info, err := c.stat(original); err != nil || !info.IsDir() {
+			continue
+		}
+		files[original] = append(files[original], dir)
+	}
+	return files, nil
+}
+
+// withinPaths reports whether dir is beneath one of the scanned paths, rather than one of them or above them.
+func (c *CLI) withinPaths(dir string) bool {
+	return slices.ContainsFunc(c.Path, func(root string) bool { return isWithin(dir, root) })
+}
+
+// isWithin reports whether path is beneath dir.
+func isWithin(path, dir string) bool {
+	return strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// tree lists the files beneath dir by their paths relative to it, along with every directory beneath it.
+func (c *CLI) tree(ctx context.Context, dir string) (files map[string]string, dirs []string, err error) {
+	files = map[string]string{}
+	err = c.files().walk(ctx, dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			dirs = append(dirs, path)
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[rel] = path
+		return nil
+	})
+	return files, dirs, err
+}
+
+// sameTree reports whether the directories a and b hold the same files, by their paths and content.
+func (c *CLI) sameTree(ctx context.Context, a, b string) (bool, error) {
+	filesA, _, err := c.tree(ctx, a)
+	if err != nil {
+		return false, err
+	}
+	filesB, _, err := c.tree(ctx, b)
+	if err != nil {
+		return false, err
+	}
+	if len(filesA) != len(filesB) {
+		return false, nil
+	}
+	for _, rel := range slices.Sorted(maps.Keys(filesA)) {
+		pathB, ok := filesB[rel]
+		if !ok {
+			return false, nil
+		}
+		if same, err := c.sameContent(ctx, filesA[rel], pathB); err != nil || !same {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// mismatchedDirs returns the copies in g which don't hold the same files as the original. Copies which can't be
+// compared are treated as differing.
+func (c *CLI) mismatchedDirs(ctx context.Context, g group) []string {
+	var differ []string
+	for _, d := range g.Duplicates {
+		same, err := c.sameTree(ctx, g.Original, d)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, tr("Warning: unable to compare %s with %s: %v", d, g.Original, err))
+		}
+		if !same {
+			differ = append(differ, d)
+		}
+	}
+	return differ
+}
+
+// removeDirCopy removes d, a copied directory in g. Each file in it is deleted when the original holds the same file,
+// and moved into the original when it has none there, which only happens with --merge-dirs. Files which differ from
+// the original's are left in place, along with the directories holding them, and reported as failures.
+func (c *CLI) removeDirCopy(ctx context.Context, g *group, d string) error {
+	files, dirs, err := c.tree(ctx, d)
+	if err != nil {
+		return c.act(g, action{Op: opDelete, Path: d, Err: err})
+	}
+
+	for _, rel := range slices.Sorted(maps.Keys(files)) {
+		path, target := files[rel], filepath.Join(g.Original, rel)
+		if err := c.act(g, c.mergeFile(ctx, path, target)); err != nil {
+			return err
+		}
+	}
+
+	// deepest first, so each is empty by the time it's removed unless something was left in it
+	slices.SortFunc(dirs, func(a, b string) int { return len(b) - len(a) })
+	for _, dir := range dirs {
+		if _, err := c.stat(dir); errors.Is(err, fs.ErrNotExist) {
+			// backends without directories drop them along with their last file
+			continue
+		}
+		if entries, err := fs.ReadDir(c.files(), dir); err == nil && len(entries) > 0 {
+			continue
+		}
+		if err := c.act(g, action{Op: opDelete, Path: dir, Err: c.discard(ctx, dir)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeFile deletes path when target has the same content, or moves it to target when there's nothing there.
+func (c *CLI) mergeFile(ctx context.Context, path, target string) action {
+	if _, err := c.stat(target); errors.Is(err, fs.ErrNotExist) {
+		if m, ok := c.files().(dirMaker); ok {
+			if err := m.mkdirAll(filepath.Dir(target)); err != nil {
+				return action{Op: opRename, Path: path, Target: target, Err: err}
+			}
+		}
+		return action{Op: opRename, Path: path, Target: target, Err: c.rename(ctx, path, target)}
+	}
+	same, err := c.sameContent(ctx, path, target)
+	if err == nil && !same {
+		err = fmt.Errorf("it differs from %s, so was left in place", target)
+	}
+	if err != nil {
+		return action{Op: opDelete, Path: path, Err: err}
+	}
+	return c.deleteFile(ctx, path)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCLI_Run_MatchDirs(t *testing.T) {
+	t.Parallel()
+	for _, merge := range []bool{false, true} {
+		memory := fstest.MapFS{
+			"media/Photos/a.jpg":                 {Data: []byte("a")},
+			"media/Photos/2020/b.jpg":            {Data: []byte("b")},
+			"media/Photos (1)/a.jpg":             {Data: []byte("a")},
+			"media/Photos (1)/2020/b.jpg":        {Data: []byte("b")},
+			"media/Photos (1)/2020 (1)/b.jpg":    {Data: []byte("b")},
+			"media/Music/song.mp3":               {Data: []byte("song")},
+			"media/Music (2)/song.mp3":           {Data: []byte("song")},
+			"media/Music (2)/new.mp3":            {Data: []byte("new")},
+			"media/Music (2)/Live/song.mp3":      {Data: []byte("live")},
+			"media/Music (2)/Live (1)/other.mp3": {Data: []byte("other")},
+			"media/Books (1)/book.pdf":           {Data: []byte("book")},
+			"media/Docs/x.txt":                   {Data: []byte("x")},
+			"media/Docs/sub/y.txt":               {Data: []byte("y")},
+			"media/Docs (1)/x.txt":               {Data: []byte("x")},
+			"media/Docs (1)/sub/y.txt":           {Data: []byte("y")},
+		}
+		cli := &CLI{
+			Path:      []string{"/media"},
+			Delete:    true,
+			Match:     "dirs",
+			MergeDirs: merge,
+			Out:       t.TempDir() + "/results.txt",
+			memory:    memory,
+		}
+		_ = cli.Run(nil)
+
+		var names []string
+		for name, f := range memory {
+			if !f.Mode.IsDir() {
+				names = append(names, name)
+			}
+		}
+		slices.Sort(names)
+		want := []string{
+			"media/Books (1)/book.pdf",
+			"media/Docs/sub/y.txt",
+			"media/Docs/x.txt",
+			"media/Music (2)/Live (1)/other.mp3",
+			"media/Music (2)/Live/song.mp3",
+			"media/Music (2)/new.mp3",
+			"media/Music (2)/song.mp3",
+			"media/Music/song.mp3",
+			"media/Photos (1)/2020 (1)/b.jpg",
+			"media/Photos (1)/2020/b.jpg",
+			"media/Photos (1)/a.jpg",
+			"media/Photos/2020/b.jpg",
+			"media/Photos/a.jpg",
+		}
+		if merge {
+			want = []string{
+				"media/Books (1)/book.pdf",
+				"media/Docs/sub/y.txt",
+				"media/Docs/x.txt",
+				"media/Music/Live (1)/other.mp3",
+				"media/Music/Live/song.mp3",
+				"media/Music/new.mp3",
+				"media/Music/song.mp3",
+				"media/Photos/2020 (1)/b.jpg",
+				"media/Photos/2020/b.jpg",
+				"media/Photos/a.jpg",
+			}
+		}
+		if !slices.Equal(names, want) {
+			t.Errorf("with merge %v, files = %q, want %q", merge, names, want)
+		}
+	}
+}
+
+func TestIsWithin(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		path, dir string
+		want      bool
+	}{
+		{"/media/Photos (1)/2020", "/media/Photos (1)", true},
+		{"/media/Photos (1)", "/media/Photos (1)", false},
+		{"/media/Photos (10)", "/media/Photos (1)", false},
+		{"/media/a", "/media/", true},
+	}
+	for _, tt := range tests {
+		if got := isWithin(filepath.FromSlash(tt.path), filepath.FromSlash(tt.dir)); got != tt.want {
+			t.Errorf("isWithin(%q, %q) = %v, want %v", tt.path, tt.dir, got, tt.want)
+		}
+	}
+}
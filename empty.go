@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// emptyMatched reports whether zero-byte files are scanned like any other, so --empty leaves them alone.
+func (c *CLI) emptyMatched() bool {
+	return c.Empty == "" || c.Empty == "match"
+}
+
+// removeEmpty deletes the zero-byte files found by the last scan, when --empty delete is given and files are being
+// deleted. Files which have been written to since the scan are left alone.
+func (c *CLI) removeEmpty(ctx context.Context) []action {
+	if c.Empty != "delete" || !c.Delete || c.DryRun || ctx.Err() != nil {
+		return nil
+	}
+	var emptied []action
+	for _, path := range slices.Sorted(slices.Values(c.empty)) {
+		if info, err := c.stat(path); err != nil || info.Size() != 0 {
+			continue
+		}
+		a := action{Op: opDelete, Path: path, Err: c.discard(ctx, path)}
+		c.progress.acted(a)
+		emptied = append(emptied, a)
+	}
+	return emptied
+}
+
+// renderEmpty lists the zero-byte files set aside by --empty list or delete, or what was done with them.
+func renderEmpty(paths []string, emptied []action, markdown bool) string {
+	return renderSetAside("Empty files", paths, emptied, markdown)
+}
+
+// renderSetAside lists the files set aside from the scan under heading, or what was done with them when acted isn't
+// nil.
+func renderSetAside(heading string, paths []string, acted []action, markdown bool) string {
+	var sb strings.Builder
+	if markdown {
+		fmt.Fprintf(&sb, "### %s\n\n", heading)
+	} else {
+		fmt.Fprintf(&sb, "%s:\n", heading)
+	}
+	if acted == nil {
+		for _, p := range slices.Sorted(slices.Values(paths)) {
+			if markdown {
+				fmt.Fprintf(&sb, "- %s\n", markdownCode(p))
+			} else {
+				fmt.Fprintf(&sb, "  - %s\n", p)
+			}
+		}
+		return sb.String()
+	}
+	for _, a := range acted {
+		switch {
+		case !markdown:
+			fmt.Fprintf(&sb, "  %s\n", a)
+		case a.Err != nil:
+			fmt.Fprintf(&sb, "- %s (**failed**: %s)\n", markdownCode(a.Path), markdownCell(a.Err.Error()))
+		default:
+			fmt.Fprintf(&sb, "- %s (deleted)\n", markdownCode(a.Path))
+		}
+	}
+	return sb.String()
+}
+
+// emptyFailures adds the zero-byte files which couldn't be deleted to err, the failures of the run's groups.
+func emptyFailures(err error, emptied []action) error {
+	if failed := collectFailures([]group{{Actions: emptied}}); failed != nil {
+		return errors.Join(err, failed)
+	}
+	return err
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCLI_Run_Empty(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		policy string
+		delete bool
+		// remaining lists the files expected to be left
+		remaining []string
+		section   bool
+	}{
+		{policy: "match", delete: true, remaining: []string{"media/book.pdf", "media/empty.pdf", "media/notes.txt"}},
+		{policy: "skip", delete: true, remaining: []string{"media/book.pdf", "media/empty (1).pdf", "media/empty.pdf", "media/notes.txt"}},
+		{policy: "list", delete: true, remaining: []string{"media/book.pdf", "media/empty (1).pdf", "media/empty.pdf", "media/notes.txt"}, section: true},
+		{policy: "delete", delete: false, remaining: []string{"media/book (1).pdf", "media/book.pdf", "media/empty (1).pdf", "media/empty.pdf", "media/notes.txt"}, section: true},
+		{policy: "delete", delete: true, remaining: []string{"media/book.pdf"}, section: true},
+	}
+	for _, tt := range tests {
+		memory := fstest.MapFS{
+			"media/book.pdf":      {Data: []byte("content")},
+			"media/book (1).pdf":  {Data: []byte("content")},
+			"media/empty.pdf":     {},
+			"media/empty (1).pdf": {},
+			"media/notes.txt":     {},
+		}
+		out := filepath.Join(t.TempDir(), "results.txt")
+		cli := &CLI{
+			Path:   []string{"/media"},
+			Delete: tt.delete,
+			DryRun: !tt.delete,
+			Empty:  tt.policy,
+			Out:    out,
+			Regex:  []string{defaultRegex},
+			memory: memory,
+		}
+		if err := cli.Run(nil); err != nil {
+			t.Fatalf("%s: Run() error = %v", tt.policy, err)
+		}
+
+		var names []string
+		for name := range memory {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		if !slices.Equal(names, tt.remaining) {
+			t.Errorf("%s: files = %q, want %q", tt.policy, names, tt.remaining)
+		}
+		data, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatalf("failed to read results: %v", err)
+		}
+		if got := strings.Contains(string(data), "Empty files:"); got != tt.section {
+			t.Errorf("%s: results = %q, want the section of empty files %v", tt.policy, data, tt.section)
+		}
+	}
+}
+
+func TestRenderEmpty(t *testing.T) {
+	t.Parallel()
+	if got, want := renderEmpty([]string{"/b", "/a"}, nil, false), "Empty files:\n  - /a\n  - /b\n"; got != want {
+		t.Errorf("renderEmpty() = %q, want %q", got, want)
+	}
+	emptied := []action{{Op: opDelete, Path: "/a"}, {Op: opDelete, Path: "/b", Err: errors.New("denied")}}
+	if got, want := renderEmpty([]string{"/a", "/b"}, emptied, true), "### Empty files\n\n- `/a` (deleted)\n- `/b` (**failed**: denied)\n"; got != want {
+		t.Errorf("renderEmpty(markdown) = %q, want %q", got, want)
+	}
+	if err := emptyFailures(nil, emptied); err == nil || !strings.Contains(err.Error(), "/b") {
+		t.Errorf("emptyFailures() = %v", err)
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// A run has three stages, each of which can be used and tested on its own: a Scanner finds the groups of copies, a
+// Planner decides what's to be done with each by the keep policy, and an Executor carries the plans out.
+
+// Scanner finds the groups of copies beneath a run's paths, matching files as --match says, without changing
+// anything.
+type Scanner struct {
+	cli *CLI
+	// patterns match the names of copies, when they're matched by name
+	patterns copyPatterns
+}
+
+// Scan walks the paths, returning each original with its duplicates and those whose content differs from it.
+func (s *Scanner) Scan(ctx context.Context) ([]group, error) {
+	scanCtx, span := startSpan(ctx, "scan", "match", cmp.Or(s.cli.Match, "name"))
+	files, err := s.find(scanCtx)
+	span.set("files", s.cli.progress.filesScanned())
+	span.set("originals", len(files))
+	span.finish(err)
+	if err != nil {
+		return nil, err
+	}
+	return s.cli.groupFiles(ctx, files), nil
+}
+
+// find maps each original found to its duplicates.
+func (s *Scanner) find(ctx context.Context) (map[string][]string, error) {
+	c := s.cli
+	switch c.Match {
+	case "image":
+		return c.scanImages(ctx)
+	case "audio":
+		return c.scanAudio(ctx)
+	case "tags":
+		return c.scanTags(ctx)
+	case "video":
+		return c.scanVideos(ctx)
+	case "exif":
+		return c.scanEXIF(ctx)
+	case "dirs":
+		return c.scanDirs(ctx)
+	}
+	if c.matcher != nil {
+		return c.scanPlugin(ctx)
+	}
+	return c.scan(ctx, s.patterns)
+}
+
+// Planner decides which file in a group is kept and what becomes of the rest, by --script, --plugin-keep,
+// --inverse, --keep-best-audio, or else by keeping the original. It doesn't change anything.
+type Planner struct {
+	cli *CLI
+}
+
+// Plan returns the actions to take on g, in the order they're to be taken, explaining on g why with --explain. When
+// the keep policy fails, or leaves the group alone, the plan only keeps the original, saying why.
+func (p *Planner) Plan(ctx context.Context, g *group) []action {
+	c := p.cli
+	original, duplicates := g.Original, g.Duplicates
+
+	if g.Orphan {
+		// The first duplicate is the one to adopt as the original
+		adopted := duplicates[0]
+		planned := []action{{Op: opRename, Path: adopted, Target: original}}
+		for _, d := range duplicates[1:] {
+			c.explain(g, d, "deleted, as %s was adopted in its place", adopted)
+			planned = append(planned, action{Op: opDelete, Path: d})
+		}
+		return planned
+	}
+
+	if c.Match == "dirs" {
+		// each copied directory is merged into the original as it's removed
+		return p.deleteDuplicates(g)
+	}
+
+	if c.script != nil {
+		decision, err := c.script.decide(*g, c.stat)
+		switch {
+		case err != nil:
+			return []action{{Op: opKeep, Path: original, Err: fmt.Errorf("--script failed, so none were deleted: %w", err)}}
+		case decision.leave:
+			return []action{{Op: opKeep, Path: original, Reason: "left alone by --script"}}
+		case decision.keep == original:
+			return p.deleteDuplicates(g)
+		case decision.keep != "":
+			return p.keepCopy(g, decision.keep, false, "chosen by --script")
+		}
+	}
+
+	if c.keepPolicy != nil {
+		keep, err := c.keepPolicy.keep(ctx, *g, c.describe(append([]string{original}, duplicates...)))
+		if err == nil && keep != "" && keep != original && !slices.Contains(duplicates, keep) {
+			err = fmt.Errorf("it chose to keep %s, which isn't in the group", keep)
+		}
+		switch {
+		case err != nil:
+			return []action{{Op: opKeep, Path: original, Err: fmt.Errorf("the keep plugin failed, so none were deleted: %w", err)}}
+		case keep == original:
+			return p.deleteDuplicates(g)
+		case keep != "":
+			return p.keepCopy(g, keep, false, "chosen by the keep plugin")
+		}
+	}
+
+	if c.Inverse || c.InverseAndRename {
+		// Keep the newest file
+		rule, err := newestFirst(duplicates, c.stat, c.MtimeTolerance)
+		if err != nil {
+			return []action{{Op: opKeep, Path: original, Err: fmt.Errorf("unable to tell which copy is newest, so none were deleted: %w", err)}}
+		}
+		c.explain(g, duplicates[0], "kept by --inverse, as the newest copy")
+		return p.keepCopy(g, duplicates[0], c.InverseAndRename, rule)
+	}
+
+	if c.KeepBestAudio && audioExtensions[strings.ToLower(filepath.Ext(original))] {
+		best, err := c.bestAudio(ctx, append([]string{original}, duplicates...))
+		if err != nil {
+			return []action{{Op: opKeep, Path: original, Err: fmt.Errorf("unable to tell which copy sounds best, so none were deleted: %w", err)}}
+		}
+		if best != original {
+			// a copy in another format keeps its own name, so its extension still matches its content
+			rename := strings.EqualFold(filepath.Ext(best), filepath.Ext(original))
+			return p.keepCopy(g, best, rename, "the highest quality copy")
+		}
+	}
+
+	return p.deleteDuplicates(g)
+}
+
+// deleteDuplicates plans keeping g's original, deleting every duplicate.
+func (p *Planner) deleteDuplicates(g *group) []action {
+	p.cli.explain(g, g.Original, "kept, as the original")
+	planned := []action{{Op: opKeep, Path: g.Original, implicit: true}}
+	for _, d := range g.Duplicates {
+		p.cli.explain(g, d, "deleted, as the original is kept")
+		planned = append(planned, action{Op: opDelete, Path: d})
+	}
+	return planned
+}
+
+// keepCopy plans keeping the duplicate keep in place of g's original, deleting the original and every other
+// duplicate. When rename is set, keep then takes the original's name. reason explains why keep was chosen, if that
+// isn't obvious.
+func (p *Planner) keepCopy(g *group, keep string, rename bool, reason string) []action {
+	if reason != "" {
+		p.cli.explain(g, keep, "kept, as %s", reason)
+	}
+	var planned []action
+	for _, d := range g.Duplicates {
+		if d != keep {
+			planned = append(planned, action{Op: opDelete, Path: d})
+		}
+	}
+	planned = append(planned, action{Op: opDelete, Path: g.Original})
+	for _, a := range planned {
+		p.cli.explain(g, a.Path, "deleted, as %s is kept in its place", keep)
+	}
+	if rename {
+		// The original has been deleted by then, so the copy can take its name
+		return append(planned, action{Op: opRename, Path: keep, Target: g.Original, Reason: reason})
+	}
+	return append(planned, action{Op: opKeep, Path: keep, Reason: reason})
+}
+
+// Executor carries out the plan for each group in turn, recording what was done on the group.
+type Executor struct {
+	cli     *CLI
+	planner *Planner
+	// dryRun lists each group as it was found, without planning or changing anything
+	dryRun bool
+}
+
+// executor returns the Executor for the run's options.
+func (c *CLI) executor() *Executor {
+	return &Executor{cli: c, planner: &Planner{cli: c}, dryRun: c.DryRun}
+}
+
+// Execute plans and performs the actions for each group found, returning the groups with what was done to each.
+// stopped reports whether ctx was done before every group could be processed. Groups whose copies differ are only
+// listed, as they're usually other editions.
+func (e *Executor) Execute(ctx context.Context, found []group) (groups []group, stopped bool) {
+	c := e.cli
+	ctx, span := startSpan(ctx, "act", "groups", len(found), "dry_run", e.dryRun || !c.Delete)
+	defer span.finish(nil)
+	for _, g := range found {
+		// Groups are never interrupted part way through, only between one another.
+		if ctx.Err() != nil {
+			return groups, true
+		}
+		c.progress.groupFound(g)
+
+		if e.dryRun {
+			groups = append(groups, g)
+			continue
+		}
+		if !c.Delete {
+			continue
+		}
+		if len(g.Mismatched) > 0 {
+			groups = append(groups, g)
+			continue
+		}
+		err := e.executeGroup(ctx, &g)
+		groups = append(groups, g)
+		if err != nil {
+			// only returned when --fail-fast is set
+			break
+		}
+	}
+	return groups, false
+}
+
+// executeGroup plans and performs the actions for g. Once started, a group is always finished so it isn't left half
+// processed.
+func (e *Executor) executeGroup(ctx context.Context, g *group) error {
+	ctx = context.WithoutCancel(ctx)
+	return e.perform(ctx, g, e.planner.Plan(ctx, g))
+}
+
+// perform takes the planned actions in order, recording each on g with its outcome. Actions planned with an error
+// are only recorded. When a copy can't be renamed, the remaining actions aren't taken, so the files it was to
+// replace are kept. Failures are only returned when --fail-fast is set.
+func (e *Executor) perform(ctx context.Context, g *group, planned []action) error {
+	c := e.cli
+
+	// The permissions, owner, and attributes of a file to be replaced are read before it's deleted, to be given to
+	// the copy taking its name
+	metas := map[string]fileMeta{}
+	carrier, carries := c.files().(metadataCarrier)
+	for _, a := range planned {
+		replaced := slices.ContainsFunc(planned, func(b action) bool { return b.Op == opDelete && b.Path == a.Target })
+		if !carries || a.Op != opRename || !replaced {
+			continue
+		}
+		meta, err := carrier.metadata(a.Target)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, tr("Warning: %v; it won't be carried over to %s", err, a.Path))
+		}
+		metas[a.Target] = meta
+	}
+
+	for _, a := range planned {
+		if a.Err != nil {
+			return c.act(g, a)
+		}
+		switch {
+		case a.Op == opDelete && c.Match == "dirs":
+			if err := c.removeDirCopy(ctx, g, a.Path); err != nil {
+				return err
+			}
+			continue
+		case a.Op == opDelete:
+			deleted := c.deleteFile(ctx, a.Path)
+			a.Size, a.Err = deleted.Size, deleted.Err
+		case a.Op == opRename:
+			a.Err = c.rename(ctx, a.Path, a.Target)
+			if meta, ok := metas[a.Target]; ok && a.Err == nil {
+				if err := carrier.setMetadata(a.Target, meta); err != nil {
+					fmt.Fprintln(os.Stderr, tr("Warning: the metadata of %s wasn't all kept: %v", a.Target, err))
+				}
+			}
+		}
+		if err := c.act(g, a); err != nil {
+			return err
+		}
+		if a.Op == opRename && a.Err != nil {
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestScanner_Scan(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "original content")
+	createTestFile(t, filepath.Join(dir, "book (1).pdf"), "original content")
+	createTestFile(t, filepath.Join(dir, "song.mp3"), "original content")
+	createTestFile(t, filepath.Join(dir, "song (1).mp3"), "another recording")
+
+	s := &Scanner{cli: &CLI{Path: []string{dir}, Delete: true}, patterns: copyPatterns{{re: regexp.MustCompile(defaultRegex)}}}
+	groups, err := s.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %+v", groups)
+	}
+	if groups[0].Original != filepath.Join(dir, "book.pdf") || len(groups[0].Mismatched) > 0 {
+		t.Errorf("unexpected group: %+v", groups[0])
+	}
+	if want := filepath.Join(dir, "song (1).mp3"); !slices.Equal(groups[1].Mismatched, []string{want}) {
+		t.Errorf("expected %s to differ, got %+v", want, groups[1])
+	}
+	for _, name := range []string{"book (1).pdf", "song (1).mp3"} {
+		if !fileExists(filepath.Join(dir, name)) {
+			t.Errorf("scanning must not delete %s", name)
+		}
+	}
+}
+
+func TestPlanner_Plan(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	original, older, newer := filepath.Join(dir, "book.pdf"), filepath.Join(dir, "book (1).pdf"), filepath.Join(dir, "book (2).pdf")
+	now := time.Now()
+	createTestFileWithModTime(t, original, "content", now.Add(-2*time.Hour))
+	createTestFileWithModTime(t, older, "content", now.Add(-time.Hour))
+	createTestFileWithModTime(t, newer, "content", now)
+
+	tests := []struct {
+		name string
+		cli  *CLI
+		want []action
+	}{
+		{"original", &CLI{}, []action{
+			{Op: opKeep, Path: original, implicit: true},
+			{Op: opDelete, Path: older},
+			{Op: opDelete, Path: newer},
+		}},
+		{"inverse and rename", &CLI{InverseAndRename: true}, []action{
+			{Op: opDelete, Path: older},
+			{Op: opDelete, Path: original},
+			{Op: opRename, Path: newer, Target: original},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			g := group{Original: original, Duplicates: []string{older, newer}}
+			got := (&Planner{cli: tt.cli}).Plan(context.Background(), &g)
+			if !slices.EqualFunc(got, tt.want, func(a, b action) bool {
+				return a.Op == b.Op && a.Path == b.Path && a.Target == b.Target && a.Reason == b.Reason && a.Err == nil
+			}) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+	for _, path := range []string{original, older, newer} {
+		if !fileExists(path) {
+			t.Errorf("planning must not delete %s", path)
+		}
+	}
+}
+
+func TestExecutor_Execute(t *testing.T) {
+	t.Parallel()
+	for _, dryRun := range []bool{false, true} {
+		dir := setupTestDir(t)
+		original, duplicate := filepath.Join(dir, "book.pdf"), filepath.Join(dir, "book (1).pdf")
+		createTestFile(t, original, "content")
+		createTestFile(t, duplicate, "content")
+
+		c := &CLI{Delete: true, DryRun: dryRun}
+		groups, stopped := c.executor().Execute(context.Background(), []group{{Original: original, Duplicates: []string{duplicate}}})
+		if stopped || len(groups) != 1 {
+			t.Fatalf("dry run %v: unexpected groups %+v", dryRun, groups)
+		}
+		if dryRun {
+			if len(groups[0].Actions) > 0 || !fileExists(duplicate) {
+				t.Errorf("a dry run must not act, got %+v", groups[0].Actions)
+			}
+			continue
+		}
+		if fileExists(duplicate) || !fileExists(original) {
+			t.Error("the duplicate should be deleted and the original kept")
+		}
+		if n := len(groups[0].Actions); n != 2 {
+			t.Errorf("expected the original kept and the duplicate deleted, got %+v", groups[0].Actions)
+		}
+	}
+}
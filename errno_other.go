@@ -0,0 +1,8 @@
+//go:build !unix && !windows
+
+package main
+
+// errnoInfo reports nothing, as this platform's errors aren't known.
+func errnoInfo(_ error) (name, kind string, ok bool) {
+	return "", "", false
+}
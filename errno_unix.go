@@ -0,0 +1,44 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"strconv"
+	"syscall"
+)
+
+// errnos names the errors a delete or rename commonly fails with, and classifies them.
+var errnos = map[syscall.Errno]struct{ name, kind string }{
+	syscall.EACCES:       {"EACCES", "permission"},
+	syscall.EPERM:        {"EPERM", "permission"},
+	syscall.ENOENT:       {"ENOENT", "not_found"},
+	syscall.EEXIST:       {"EEXIST", "exists"},
+	syscall.ENOTEMPTY:    {"ENOTEMPTY", "not_empty"},
+	syscall.EISDIR:       {"EISDIR", "is_directory"},
+	syscall.ENOTDIR:      {"ENOTDIR", "not_directory"},
+	syscall.EBUSY:        {"EBUSY", "busy"},
+	syscall.ETXTBSY:      {"ETXTBSY", "busy"},
+	syscall.EAGAIN:       {"EAGAIN", "busy"},
+	syscall.ESTALE:       {"ESTALE", "stale"},
+	syscall.EINTR:        {"EINTR", "interrupted"},
+	syscall.ETIMEDOUT:    {"ETIMEDOUT", "timeout"},
+	syscall.EROFS:        {"EROFS", "read_only"},
+	syscall.ENOSPC:       {"ENOSPC", "no_space"},
+	syscall.EDQUOT:       {"EDQUOT", "no_space"},
+	syscall.EXDEV:        {"EXDEV", "cross_device"},
+	syscall.ENAMETOOLONG: {"ENAMETOOLONG", "name_too_long"},
+	syscall.EIO:          {"EIO", "io"},
+}
+
+// errnoInfo returns the name and kind of the errno err wraps, if any. Errnos without a name are given by number.
+func errnoInfo(err error) (name, kind string, ok bool) {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return "", "", false
+	}
+	if e, ok := errnos[errno]; ok {
+		return e.name, e.kind, true
+	}
+	return strconv.Itoa(int(errno)), "other", true
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// errnos names the errors a delete or rename commonly fails with, and classifies them.
+var errnos = map[syscall.Errno]struct{ name, kind string }{
+	windows.ERROR_ACCESS_DENIED:     {"ERROR_ACCESS_DENIED", "permission"},
+	windows.ERROR_FILE_NOT_FOUND:    {"ERROR_FILE_NOT_FOUND", "not_found"},
+	windows.ERROR_PATH_NOT_FOUND:    {"ERROR_PATH_NOT_FOUND", "not_found"},
+	windows.ERROR_ALREADY_EXISTS:    {"ERROR_ALREADY_EXISTS", "exists"},
+	windows.ERROR_FILE_EXISTS:       {"ERROR_FILE_EXISTS", "exists"},
+	windows.ERROR_DIR_NOT_EMPTY:     {"ERROR_DIR_NOT_EMPTY", "not_empty"},
+	windows.ERROR_SHARING_VIOLATION: {"ERROR_SHARING_VIOLATION", "busy"},
+	windows.ERROR_LOCK_VIOLATION:    {"ERROR_LOCK_VIOLATION", "busy"},
+	windows.ERROR_NETWORK_BUSY:      {"ERROR_NETWORK_BUSY", "busy"},
+	windows.ERROR_NETNAME_DELETED:   {"ERROR_NETNAME_DELETED", "network"},
+	windows.ERROR_UNEXP_NET_ERR:     {"ERROR_UNEXP_NET_ERR", "network"},
+	windows.ERROR_SEM_TIMEOUT:       {"ERROR_SEM_TIMEOUT", "timeout"},
+	windows.ERROR_WRITE_PROTECT:     {"ERROR_WRITE_PROTECT", "read_only"},
+	windows.ERROR_DISK_FULL:         {"ERROR_DISK_FULL", "no_space"},
+	windows.ERROR_HANDLE_DISK_FULL:  {"ERROR_HANDLE_DISK_FULL", "no_space"},
+	windows.ERROR_NOT_SAME_DEVICE:   {"ERROR_NOT_SAME_DEVICE", "cross_device"},
+}
+
+// errnoInfo returns the name and kind of the Windows error err wraps, if any. Errors without a name are given by
+// number.
+func errnoInfo(err error) (name, kind string, ok bool) {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return "", "", false
+	}
+	if e, ok := errnos[errno]; ok {
+		return e.name, e.kind, true
+	}
+	return strconv.Itoa(int(errno)), "other", true
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// operationError records a failed operation against a single file.
+type operationError struct {
+	Op   string
+	Path string
+	Err  error
+}
+
+func (e *operationError) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.Op, e.Path, e.Err)
+}
+
+func (e *operationError) Unwrap() error {
+	return e.Err
+}
+
+// operationErrors aggregates every failed operation from a run.
+type operationErrors []*operationError
+
+func (e operationErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	lines := make([]string, 0, len(e)+1)
+	lines = append(lines, fmt.Sprintf("%d operations failed:", len(e)))
+	for _, err := range e {
+		lines = append(lines, "  - "+err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e operationErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+func (e operationErrors) ExitCode() int {
+	return exitPartialFailure
+}
+
+// collectFailures gathers the failed actions across groups, returning nil if every action succeeded.
+func collectFailures(groups []group) error {
+	var failures operationErrors
+	for _, g := range groups {
+		for _, a := range g.Actions {
+			if a.Err != nil {
+				failures = append(failures, &operationError{Op: a.Op, Path: a.Path, Err: a.Err})
+			}
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	return failures
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCLI_Process_CollectsFailures(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+
+	original := filepath.Join(dir, "book.pdf")
+	createTestFile(t, original, "original content")
+	g := group{
+		Original:   original,
+		Duplicates: []string{filepath.Join(dir, "book (1).pdf"), filepath.Join(dir, "book (2).pdf")},
+	}
+
+	cli := &CLI{Delete: true}
+	if err := cli.process(context.Background(), &g); err != nil {
+		t.Fatalf("expected failures to be deferred without --fail-fast, got: %v", err)
+	}
+
+	err := collectFailures([]group{g})
+	var failures operationErrors
+	if !errors.As(err, &failures) {
+		t.Fatalf("expected operationErrors, got %T: %v", err, err)
+	}
+	if len(failures) != 2 {
+		t.Errorf("expected 2 failures, got %d", len(failures))
+	}
+	if failures.ExitCode() != exitPartialFailure {
+		t.Errorf("expected exit code %d, got %d", exitPartialFailure, failures.ExitCode())
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected aggregated error to wrap os.ErrNotExist: %v", err)
+	}
+	if !strings.Contains(err.Error(), "2 operations failed") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+func TestCLI_Process_FailFast(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+
+	original := filepath.Join(dir, "book.pdf")
+	existing := filepath.Join(dir, "book (2).pdf")
+	createTestFile(t, original, "original content")
+	createTestFile(t, existing, "duplicate 2")
+	g := group{
+		Original:   original,
+		Duplicates: []string{filepath.Join(dir, "book (1).pdf"), existing},
+	}
+
+	cli := &CLI{Delete: true, FailFast: true}
+	if err := cli.process(context.Background(), &g); err == nil {
+		t.Fatal("expected the first failure to be returned with --fail-fast")
+	}
+
+	if !fileExists(existing) {
+		t.Error("processing should have stopped before deleting the remaining duplicate")
+	}
+	if err := collectFailures([]group{g}); err == nil || strings.Contains(err.Error(), "operations failed") {
+		t.Errorf("expected exactly one failure, got: %v", err)
+	}
+}
+
+func TestCollectFailures_None(t *testing.T) {
+	t.Parallel()
+	groups := []group{{Original: "a", Actions: []action{{Op: opDelete, Path: "a (1)"}}}}
+	if err := collectFailures(groups); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// defaultExcludes are the system and trash directories no scan should wander into, unless --no-default-excludes is
+// given: names matched anywhere, ignoring case, and absolute paths of Unix virtual filesystems.
+var defaultExcludes = []string{
+	// Windows
+	"$RECYCLE.BIN",
+	"System Volume Information",
+	// macOS
+	".Trashes",
+	".Spotlight-V100",
+	".fseventsd",
+	".DocumentRevisions-V100",
+	// Linux and NAS
+	"lost+found",
+	".Trash",
+	"@eaDir",
+	"#recycle",
+	"/proc",
+	"/sys",
+	"/dev",
+	"/run",
+}
+
+// leftOut reports whether path, beneath a scanned path, is left out of the scan as hidden, as a system directory, or as
+// a snapshot. info describes path, when it's known.
+func (c *CLI) leftOut(path string, info os.FileInfo) bool {
+	return c.hidden(path) || c.systemPath(path) || c.snapshot(path, info)
+}
+
+// systemPath reports whether path is one of the defaultExcludes, which are left out unless --no-default-excludes is
+// given.
+func (c *CLI) systemPath(path string) bool {
+	if !c.DefaultExcludes {
+		return false
+	}
+	name := filepath.Base(path)
+	local := c.Remote == "" && !hasScheme(path)
+	for _, ex := range defaultExcludes {
+		if !strings.HasPrefix(ex, "/") {
+			if strings.EqualFold(name, ex) {
+				return true
+			}
+			continue
+		}
+		if local && runtime.GOOS != "windows" {
+			if abs, err := filepath.Abs(path); err == nil && abs == ex {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCLI_SystemPath(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		path string
+		want bool
+	}{
+		{path: "/media/$RECYCLE.BIN", want: true},
+		{path: "/media/$Recycle.Bin", want: true},
+		{path: "/media/System Volume Information", want: true},
+		{path: "/media/lost+found", want: true},
+		{path: "s3://bucket/photos/@eaDir", want: true},
+		{path: "/proc", want: runtime.GOOS != "windows"},
+		{path: "/media/proc", want: false},
+		{path: "/media/book.pdf", want: false},
+	} {
+		if got := (&CLI{DefaultExcludes: true}).systemPath(tt.path); got != tt.want {
+			t.Errorf("systemPath(%q) = %t, want %t", tt.path, got, tt.want)
+		}
+		if (&CLI{}).systemPath(tt.path) {
+			t.Errorf("systemPath(%q) with --no-default-excludes = true", tt.path)
+		}
+	}
+}
+
+func TestCLI_Run_DefaultExcludes(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	if err := os.Mkdir(filepath.Join(dir, "lost+found"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"book.pdf", "book (1).pdf", "lost+found/notes.pdf", "lost+found/notes (1).pdf"} {
+		createTestFile(t, filepath.Join(dir, name), "content")
+	}
+
+	cli := &CLI{Path: []string{dir}, Delete: true, DefaultExcludes: true, Out: filepath.Join(t.TempDir(), "results.txt"), Regex: []string{defaultRegex}}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fileExists(filepath.Join(dir, "book (1).pdf")) {
+		t.Error("book (1).pdf should be deleted")
+	}
+	if !fileExists(filepath.Join(dir, "lost+found", "notes (1).pdf")) {
+		t.Error("nothing in lost+found should be touched")
+	}
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// maxEXIFEntries bounds the entries read from each EXIF directory, guarding against corrupt files.
+const maxEXIFEntries = 1000
+
+// exifExtensions are the formats whose EXIF metadata can be read for --match exif.
+var exifExtensions = map[string]bool{".jpg": true, ".jpeg": true, ".tif": true, ".tiff": true}
+
+// errNoEXIF is returned by readPhotoMeta when a photo has no EXIF metadata.
+var errNoEXIF = errors.New("no EXIF metadata")
+
+// EXIF tags which are read.
+const (
+	tagImageWidth       = 0x0100
+	tagImageLength      = 0x0101
+	tagMake             = 0x010f
+	tagModel            = 0x0110
+	tagExifIFD          = 0x8769
+	tagDateTimeOriginal = 0x9003
+	tagSubSecTimeOrig   = 0x9291
+	tagPixelXDimension  = 0xa002
+	tagPixelYDimension  = 0xa003
+)
+
+// EXIF value types which are read, and the size of each directory entry.
+const (
+	exifTypeASCII = 2
+	exifTypeShort = 3
+	exifTypeLong  = 4
+	exifEntrySize = 12
+)
+
+// photoMeta is the metadata used to recognise the same photo.
+type photoMeta struct {
+	// Taken is the DateTimeOriginal, e.g. "2023:07:14 18:02:11", and SubSec its fraction of a second, if recorded.
+	Taken, SubSec string
+	Make, Model   string
+	Width, Height int
+}
+
+// key identifies the shot, or is empty when the photo doesn't record when it was taken. Dimensions are compared
+// regardless of orientation, as importers sometimes rotate the pixels rather than keeping the orientation tag.
+func (m photoMeta) key() string {
+	if m.Taken == "" {
+		return ""
+	}
+	long, short := max(m.Width, m.Height), min(m.Width, m.Height)
+	return fmt.Sprintf("%s.%s\x00%s\x00%dx%d", m.Taken, m.SubSec, normalizeTag(m.Make+" "+m.Model), long, short)
+}
+
+// scanEXIF groups photos taken at the same moment by the same camera at the same size, whatever they're named.
+// Photos sharing that metadata are also compared by how they look, so bursts taken within the same second aren't
+// mistaken for copies. The largest file in each group is treated as the original, then the oldest.
+func (c *CLI) scanEXIF(ctx context.Context) (map[string][]string, error) {
+	var photos []imageFile
+	err := c.walk(ctx, func(path string, info os.FileInfo) {
+		if exifExtensions[strings.ToLower(filepath.Ext(path))] {
+			photos = append(photos, imageFile{path: path, size: info.Size(), modTime: info.ModTime()})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// warn and skip, or abort, on a file which can't be read
+	skip := func(path, doing string, err error) error {
+		if !c.SkipErrors {
+			return fmt.Errorf("unable to %s %s: %w", doing, path, err)
+		}
+		c.skip(path, err)
+		return nil
+	}
+
+	byKey := make(map[string][]imageFile)
+	for _, photo := range photos {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("%w while reading EXIF metadata; no files were changed", context.Cause(ctx))
+		}
+		if err := c.throttle.op(ctx); err != nil {
+			return nil, fmt.Errorf("%w while reading EXIF metadata; no files were changed", context.Cause(ctx))
+		}
+		meta, err := readPhotoMetaFile(c.files(), photo.path)
+		if errors.Is(err, errNoEXIF) {
+			continue
+		}
+		if err != nil {
+			if err := skip(photo.path, "read EXIF metadata of", err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if key := meta.key(); key != "" {
+			byKey[key] = append(byKey[key], photo)
+		}
+	}
+
+	files := make(map[string][]string)
+	for _, candidates := range byKey {
+		if len(candidates) < 2 {
+			continue
+		}
+		hashed := candidates[:0]
+		for _, photo := range candidates {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("%w while hashing images; no files were changed", context.Cause(ctx))
+			}
+			hash, pixels, err := c.hashImage(ctx, photo.path)
+			if err != nil {
+				if err := skip(photo.path, "hash", err); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			photo.hash, photo.pixels = hash, pixels
+			hashed = append(hashed, photo)
+		}
+
+		for _, cluster := range groupSimilar(len(hashed), func(i, j int) bool {
+			return bits.OnesCount64(hashed[i].hash^hashed[j].hash) <= c.ImageThreshold
+		}) {
+			members := make([]imageFile, len(cluster))
+			for i, idx := range cluster {
+				members[i] = hashed[idx]
+			}
+			slices.SortFunc(members, func(a, b imageFile) int {
+				return cmp.Or(
+					cmp.Compare(b.size, a.size),
+					a.modTime.Compare(b.modTime),
+					cmp.Compare(a.path, b.path),
+				)
+			})
+			for _, m := range members[1:] {
+				files[members[0].path] = append(files[members[0].path], m.path)
+			}
+		}
+	}
+	return files, nil
+}
+
+func readPhotoMetaFile(files fs.FS, path string) (photoMeta, error) {
+	f, err := openSeekable(files, path)
+	if err != nil {
+		return photoMeta{}, err
+	}
+	defer func() { _ = f.Close() }()
+	return readPhotoMeta(f)
+}
+
+// readPhotoMeta reads the EXIF metadata and dimensions of a JPEG or TIFF.
+func readPhotoMeta(r io.ReadSeeker) (photoMeta, error) {
+	var magic [4]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		return photoMeta{}, errNoEXIF
+	}
+	switch {
+	case magic[0] == 0xff && magic[1] == 0xd8:
+		if _, err := r.Seek(2, io.SeekStart); err != nil {
+			return photoMeta{}, err
+		}
+		return readJPEGMeta(r)
+	case string(magic[:]) == "II*\x00" || string(magic[:]) == "MM\x00*":
+		ra, ok := r.(io.ReaderAt)
+		if !ok {
+			return photoMeta{}, errors.New("TIFF metadata needs random access")
+		}
+		return parseTIFF(ra)
+	}
+	return photoMeta{}, errNoEXIF
+}
+
+// readJPEGMeta reads the EXIF segment and frame dimensions of a JPEG, with r positioned just after its SOI marker.
+func readJPEGMeta(r io.ReadSeeker) (photoMeta, error) {
+	var meta photoMeta
+	var found bool
+	var header [4]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return photoMeta{}, fmt.Errorf("corrupt JPEG: %w", err)
+		}
+		if header[0] != 0xff {
+			return photoMeta{}, errors.New("corrupt JPEG: expected a marker")
+		}
+		marker, length := header[1], int64(binary.BigEndian.Uint16(header[2:]))-2
+		if length < 0 {
+			return photoMeta{}, errors.New("corrupt JPEG: invalid segment length")
+		}
+		switch {
+		case marker == 0xe1 && !found:
+			segment := make([]byte, length)
+			if _, err := io.ReadFull(r, segment); err != nil {
+				return photoMeta{}, fmt.Errorf("corrupt JPEG: %w", err)
+			}
+			if !bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+				// e.g. XMP, which is also stored in APP1
+				continue
+			}
+			exif, err := parseTIFF(bytes.NewReader(segment[6:]))
+			if err != nil {
+				return photoMeta{}, err
+			}
+			meta, found = exif, true
+		case marker >= 0xc0 && marker <= 0xcf && marker != 0xc4 && marker != 0xc8 && marker != 0xcc:
+			// start of frame, which holds the dimensions of the image
+			var frame [5]byte
+			if _, err := io.ReadFull(r, frame[:]); err != nil {
+				return photoMeta{}, fmt.Errorf("corrupt JPEG: %w", err)
+			}
+			meta.Height, meta.Width = int(binary.BigEndian.Uint16(frame[1:3])), int(binary.BigEndian.Uint16(frame[3:5]))
+			if !found {
+				// the EXIF segment always comes first
+				return photoMeta{}, errNoEXIF
+			}
+			return meta, nil
+		case marker == 0xda || marker == 0xd9:
+			// start of scan or end of image, without a frame header
+			if !found {
+				return photoMeta{}, errNoEXIF
+			}
+			return meta, nil
+		default:
+			if _, err := r.Seek(length, io.SeekCurrent); err != nil {
+				return photoMeta{}, err
+			}
+		}
+	}
+}
+
+// exifEntry is an entry in a TIFF image file directory.
+type exifEntry struct {
+	typ   uint16
+	count uint32
+	value [4]byte
+}
+
+// tiff reads image file directories from TIFF data, such as a TIFF file or a JPEG's EXIF segment.
+type tiff struct {
+	r     io.ReaderAt
+	order binary.ByteOrder
+}
+
+// parseTIFF reads the metadata in the first image file directory of TIFF data, and its EXIF directory.
+func parseTIFF(r io.ReaderAt) (photoMeta, error) {
+	var header [8]byte
+	if _, err := r.ReadAt(header[:], 0); err != nil {
+		return photoMeta{}, fmt.Errorf("corrupt EXIF: %w", err)
+	}
+	t := tiff{r: r}
+	switch string(header[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return photoMeta{}, errors.New("corrupt EXIF: invalid byte order")
+	}
+
+	ifd0, err := t.directory(int64(t.order.Uint32(header[4:])))
+	if err != nil {
+		return photoMeta{}, err
+	}
+	meta := photoMeta{
+		Make:   t.ascii(ifd0[tagMake]),
+		Model:  t.ascii(ifd0[tagModel]),
+		Width:  t.int(ifd0[tagImageWidth]),
+		Height: t.int(ifd0[tagImageLength]),
+	}
+	if pointer, ok := ifd0[tagExifIFD]; ok {
+		exif, err := t.directory(int64(t.int(pointer)))
+		if err != nil {
+			return photoMeta{}, err
+		}
+		meta.Taken = t.ascii(exif[tagDateTimeOriginal])
+		meta.SubSec = t.ascii(exif[tagSubSecTimeOrig])
+		if meta.Width == 0 {
+			meta.Width, meta.Height = t.int(exif[tagPixelXDimension]), t.int(exif[tagPixelYDimension])
+		}
+	}
+	if meta.Taken == "" && meta.Make == "" && meta.Model == "" {
+		return photoMeta{}, errNoEXIF
+	}
+	return meta, nil
+}
+
+// directory reads the entries of the image file directory at offset.
+func (t tiff) directory(offset int64) (map[uint16]exifEntry, error) {
+	var count [2]byte
+	if _, err := t.r.ReadAt(count[:], offset); err != nil {
+		return nil, fmt.Errorf("corrupt EXIF: %w", err)
+	}
+	n := min(int(t.order.Uint16(count[:])), maxEXIFEntries)
+	data := make([]byte, n*exifEntrySize)
+	if _, err := t.r.ReadAt(data, offset+2); err != nil {
+		return nil, fmt.Errorf("corrupt EXIF: %w", err)
+	}
+	entries := make(map[uint16]exifEntry, n)
+	for i := 0; i < n; i++ {
+		e := data[i*exifEntrySize:]
+		entry := exifEntry{typ: t.order.Uint16(e[2:]), count: t.order.Uint32(e[4:])}
+		copy(entry.value[:], e[8:12])
+		entries[t.order.Uint16(e)] = entry
+	}
+	return entries, nil
+}
+
+// ascii reads a string entry, which is stored inline when it fits in 4 bytes.
+func (t tiff) ascii(e exifEntry) string {
+	if e.typ != exifTypeASCII || e.count == 0 || e.count > maxTagFrame {
+		return ""
+	}
+	data := e.value[:min(e.count, 4)]
+	if e.count > 4 {
+		data = make([]byte, e.count)
+		if _, err := t.r.ReadAt(data, int64(t.order.Uint32(e.value[:]))); err != nil {
+			return ""
+		}
+	}
+	data, _, _ = bytes.Cut(data, []byte{0})
+	return strings.TrimSpace(string(data))
+}
+
+// int reads a SHORT or LONG entry.
+func (t tiff) int(e exifEntry) int {
+	switch e.typ {
+	case exifTypeShort:
+		return int(t.order.Uint16(e.value[:]))
+	case exifTypeLong:
+		return int(t.order.Uint32(e.value[:]))
+	}
+	return 0
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// byteOrder is binary.LittleEndian or binary.BigEndian.
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+// exifTIFF builds TIFF data with an IFD0 holding camera and model, pointing to an EXIF IFD holding taken.
+func exifTIFF(order byteOrder, camera, model, taken string) []byte {
+	type entry struct {
+		tag, typ uint16
+		count    uint32
+		data     []byte
+	}
+	ascii := func(tag uint16, s string) entry {
+		return entry{tag, exifTypeASCII, uint32(len(s) + 1), append([]byte(s), 0)}
+	}
+	// the directories are written one after another, each followed by the values too big to store inline
+	write := func(out []byte, entries []entry, next func(out []byte) []byte) []byte {
+		start := len(out)
+		extra := start + 2 + len(entries)*exifEntrySize + 4
+		out = order.AppendUint16(out, uint16(len(entries)))
+		var values []byte
+		for _, e := range entries {
+			out = order.AppendUint16(out, e.tag)
+			out = order.AppendUint16(out, e.typ)
+			out = order.AppendUint32(out, e.count)
+			var value [4]byte
+			switch {
+			case e.data == nil:
+				// a pointer to the next directory
+				order.PutUint32(value[:], uint32(extra+len(values)))
+			case len(e.data) <= 4:
+				copy(value[:], e.data)
+			default:
+				order.PutUint32(value[:], uint32(extra+len(values)))
+				values = append(values, e.data...)
+			}
+			out = append(out, value[:]...)
+		}
+		out = order.AppendUint32(out, 0)
+		out = append(out, values...)
+		if next != nil {
+			out = next(out)
+		}
+		return out
+	}
+
+	out := []byte("II*\x00")
+	if order == binary.BigEndian {
+		out = []byte("MM\x00*")
+	}
+	out = order.AppendUint32(out, 8)
+	ifd0 := []entry{ascii(tagMake, camera), ascii(tagModel, model)}
+	if taken == "" {
+		return write(out, ifd0, nil)
+	}
+	ifd0 = append(ifd0, entry{tagExifIFD, exifTypeLong, 1, nil})
+	return write(out, ifd0, func(out []byte) []byte {
+		return write(out, []entry{ascii(tagDateTimeOriginal, taken), ascii(tagSubSecTimeOrig, "12")}, nil)
+	})
+}
+
+// jpegWithEXIF encodes img as a JPEG, with tiffData as its EXIF segment.
+func jpegWithEXIF(t *testing.T, img image.Image, quality int, tiffData []byte) []byte {
+	t.Helper()
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, img, &jpeg.Options{Quality: quality}); err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	segment := append([]byte("Exif\x00\x00"), tiffData...)
+	out := []byte{0xff, 0xd8}
+	// an XMP segment, which also uses APP1
+	xmp := []byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta/>")
+	out = append(out, 0xff, 0xe1)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(xmp)+2))
+	out = append(out, xmp...)
+	out = append(out, 0xff, 0xe1)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(segment)+2))
+	out = append(out, segment...)
+	return append(out, encoded.Bytes()[2:]...)
+}
+
+func TestReadPhotoMeta(t *testing.T) {
+	want := photoMeta{Taken: "2023:07:14 18:02:11", SubSec: "12", Make: "Canon", Model: "Canon EOS R6", Width: 64, Height: 64}
+	for name, order := range map[string]byteOrder{"little endian": binary.LittleEndian, "big endian": binary.BigEndian} {
+		t.Run(name, func(t *testing.T) {
+			data := jpegWithEXIF(t, testImage(64, 1), 90, exifTIFF(order, "Canon", "Canon EOS R6", "2023:07:14 18:02:11"))
+			got, err := readPhotoMeta(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("readPhotoMeta() error = %v", err)
+			}
+			if got != want {
+				t.Errorf("readPhotoMeta() = %+v, want %+v", got, want)
+			}
+		})
+	}
+
+	t.Run("TIFF", func(t *testing.T) {
+		got, err := readPhotoMeta(bytes.NewReader(exifTIFF(binary.LittleEndian, "NIKON", "D750", "2020:01:02 03:04:05")))
+		if err != nil {
+			t.Fatalf("readPhotoMeta() error = %v", err)
+		}
+		if got.Taken != "2020:01:02 03:04:05" || got.Model != "D750" {
+			t.Errorf("readPhotoMeta() = %+v", got)
+		}
+	})
+}
+
+func TestReadPhotoMeta_None(t *testing.T) {
+	var plain bytes.Buffer
+	if err := jpeg.Encode(&plain, testImage(16, 1), nil); err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	for name, data := range map[string][]byte{
+		"empty":      nil,
+		"PNG":        []byte("\x89PNG\r\n\x1a\n"),
+		"plain JPEG": plain.Bytes(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if meta, err := readPhotoMeta(bytes.NewReader(data)); err != errNoEXIF {
+				t.Errorf("readPhotoMeta() = %+v, %v, want errNoEXIF", meta, err)
+			}
+		})
+	}
+
+	// without DateTimeOriginal, photos can't be matched
+	meta, err := readPhotoMeta(bytes.NewReader(jpegWithEXIF(t, testImage(16, 1), 90, exifTIFF(binary.LittleEndian, "Apple", "iPhone 12", ""))))
+	if err != nil || meta.key() != "" {
+		t.Errorf("readPhotoMeta() = %+v, %v, want no key", meta, err)
+	}
+}
+
+func TestReadPhotoMeta_Corrupt(t *testing.T) {
+	data := jpegWithEXIF(t, testImage(16, 1), 90, exifTIFF(binary.LittleEndian, "Canon", "EOS", "2023:07:14 18:02:11"))
+	if _, err := readPhotoMeta(bytes.NewReader(data[:60])); err == nil {
+		t.Error("readPhotoMeta() of a truncated JPEG should fail")
+	}
+}
+
+func TestPhotoMeta_Key(t *testing.T) {
+	landscape := photoMeta{Taken: "2023:07:14 18:02:11", Make: "Canon", Model: "EOS", Width: 6000, Height: 4000}
+	portrait := landscape
+	portrait.Width, portrait.Height = 4000, 6000
+	if landscape.key() != portrait.key() {
+		t.Error("rotated copies should have the same key")
+	}
+	resized := landscape
+	resized.Width, resized.Height = 3000, 2000
+	if landscape.key() == resized.key() {
+		t.Error("resized copies should have different keys")
+	}
+}
+
+func TestCLI_ScanEXIF(t *testing.T) {
+	dir := t.TempDir()
+	taken := exifTIFF(binary.LittleEndian, "Canon", "Canon EOS R6", "2023:07:14 18:02:11")
+	files := map[string][]byte{
+		"IMG_0123.jpg":            jpegWithEXIF(t, testImage(64, 1), 95, taken),
+		"import/IMG_0123 (1).jpg": jpegWithEXIF(t, testImage(64, 1), 70, taken),
+		"import/2023-07-14.jpg":   jpegWithEXIF(t, testImage(64, 1), 80, taken),
+		// a burst: the same second, but a different shot
+		"IMG_0124.jpg": jpegWithEXIF(t, testImage(64, 5), 95, taken),
+		// another camera at the same moment
+		"phone.jpg": jpegWithEXIF(t, testImage(64, 1), 95, exifTIFF(binary.LittleEndian, "Apple", "iPhone 12", "2023:07:14 18:02:11")),
+		"notes.txt": []byte("IMG_0123"),
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+
+	cli := &CLI{Path: []string{dir}, Match: "exif", ImageThreshold: 10, SkipErrors: true}
+	got, err := cli.scanEXIF(context.Background())
+	if err != nil {
+		t.Fatalf("scanEXIF() error = %v", err)
+	}
+	for _, duplicates := range got {
+		slices.Sort(duplicates)
+	}
+	want := map[string][]string{
+		filepath.Join(dir, "IMG_0123.jpg"): {filepath.Join(dir, "import", "2023-07-14.jpg"), filepath.Join(dir, "import", "IMG_0123 (1).jpg")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("scanEXIF() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+// Exit codes reported by ohman, so that automation can react to the outcome of a run.
+const (
+	// exitOK indicates no duplicates were found, or every requested operation succeeded.
+	exitOK = 0
+	// exitDuplicatesFound indicates duplicates were found but not acted upon (e.g. --dry-run).
+	exitDuplicatesFound = 1
+	// exitPartialFailure indicates at least one delete or rename failed.
+	exitPartialFailure = 2
+	// exitFatal indicates the run could not complete (invalid arguments, unreadable paths, etc.).
+	exitFatal = 3
+)
+
+// exitError associates an exit code with an error. It satisfies kong.ExitCoder.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+func (e *exitError) ExitCode() int {
+	return e.code
+}
+
+// fatal gives err, if any, the exit code exitFatal, in place of any it has, such as kong's for invalid arguments.
+func fatal(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: exitFatal, err: err}
+}
+
+// exitStatus determines the exit code for a completed run.
+func exitStatus(groups []group) int {
+	status := exitOK
+	for _, g := range groups {
+		if g.Actions == nil {
+			status = exitDuplicatesFound
+			continue
+		}
+		for _, a := range g.Actions {
+			if a.Err != nil {
+				return exitPartialFailure
+			}
+		}
+	}
+	return status
+}
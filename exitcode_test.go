@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExitStatus(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		groups []group
+		want   int
+	}{
+		{name: "no duplicates", groups: nil, want: exitOK},
+		{name: "dry-run with duplicates", groups: []group{{Original: "a", Duplicates: []string{"a (1)"}}}, want: exitDuplicatesFound},
+		{name: "successful delete", groups: []group{{Original: "a", Actions: []action{{Op: opDelete, Path: "a (1)"}}}}, want: exitOK},
+		{name: "failed delete", groups: []group{{Original: "a", Actions: []action{
+			{Op: opDelete, Path: "a (1)"},
+			{Op: opDelete, Path: "a (2)", Err: errors.New("denied")},
+		}}}, want: exitPartialFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := exitStatus(tt.groups); got != tt.want {
+				t.Errorf("expected exit status %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCLI_Run_DryRun_SetsStatus(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "original content")
+	createTestFile(t, filepath.Join(dir, "book (1).pdf"), "duplicate 1")
+
+	cli := &CLI{
+		Path:   []string{dir},
+		DryRun: true,
+		Out:    filepath.Join(dir, "results.txt"),
+		Regex:  []string{defaultRegex},
+	}
+
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cli.status != exitDuplicatesFound {
+		t.Errorf("expected exit status %d, got %d", exitDuplicatesFound, cli.status)
+	}
+}
+
+// TestMain_ExitCodes re-executes the test binary as the ohman CLI to observe real process exit codes.
+func TestMain_ExitCodes(t *testing.T) {
+	if os.Getenv("OHMAN_TEST_MAIN") == "1" {
+		os.Args = append([]string{"ohman"}, filepath.SplitList(os.Getenv("OHMAN_TEST_ARGS"))...)
+		main()
+		return
+	}
+	t.Parallel()
+	dir := setupTestDir(t)
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "original content")
+	createTestFile(t, filepath.Join(dir, "book (1).pdf"), "duplicate 1")
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "duplicates found", args: []string{"--dry-run", "--no-history", dir}, want: exitDuplicatesFound},
+		{name: "fatal error", args: []string{"--dry-run", "--no-history", "--regex", "[invalid", dir}, want: exitFatal},
+		{name: "unknown flag", args: []string{"--bogus", dir}, want: exitFatal},
+		{name: "unwritable profile", args: []string{"--dry-run", "--no-history", "--cpuprofile", filepath.Join(dir, "missing", "cpu.out"), dir}, want: exitFatal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command(os.Args[0], "-test.run=^TestMain_ExitCodes$")
+			cmd.Env = append(os.Environ(), "OHMAN_TEST_MAIN=1", "OHMAN_TEST_ARGS="+strings.Join(tt.args, string(os.PathListSeparator)))
+			err := cmd.Run()
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				t.Fatalf("expected process to exit with %d, got %v", tt.want, err)
+			}
+			if exitErr.ExitCode() != tt.want {
+				t.Errorf("expected exit code %d, got %d", tt.want, exitErr.ExitCode())
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// explain records why path was classified as it was in g, when --explain is set. Each reason is added to those
+// already given for path.
+func (c *CLI) explain(g *group, path, format string, a ...any) {
+	if !c.Explain {
+		return
+	}
+	if g.Reasons == nil {
+		g.Reasons = make(map[string]string)
+	}
+	reason := fmt.Sprintf(format, a...)
+	if prior := g.Reasons[path]; prior != "" {
+		reason = prior + "; " + reason
+	}
+	g.Reasons[path] = reason
+}
+
+// explainFound records why g's files were found to be an original and its duplicates.
+func (c *CLI) explainFound(g *group) {
+	if !c.Explain {
+		return
+	}
+	if c.Match != "" && c.Match != "name" || c.matcher != nil {
+		how := "--match " + c.Match
+		if c.matcher != nil {
+			how = "the --plugin-matcher"
+		}
+		c.explain(g, g.Original, "the original, as grouped by %s", how)
+		for _, d := range g.Duplicates {
+			c.explain(g, d, "a duplicate of the original, as grouped by %s", how)
+		}
+		return
+	}
+	if g.Orphan {
+		c.explain(g, g.Original, "the original's name, taken from its copies, but no file has it")
+	} else {
+		c.explain(g, g.Original, "the original, named like its copies without a copy suffix")
+	}
+	for _, d := range g.Duplicates {
+		if c.isPartialOf(g.Original, d) {
+			c.explain(g, d, "a partial download of the original, left by an unfinished download")
+		} else if p, _, _, ok := c.copies.match(filepath.Base(d)); ok {
+			c.explain(g, d, "a duplicate, named as a copy by the pattern %s", p)
+		} else if rank := c.preferRank(g.Original); rank < len(c.PreferPath) && c.rootOf(d) != c.rootOf(g.Original) {
+			c.explain(g, d, "a duplicate, at the original's path within another scan path, and the original is kept as it's within --prefer-path %s", c.PreferPath[rank])
+		} else {
+			c.explain(g, d, "a duplicate")
+		}
+	}
+}
+
+// explainAdoption records which duplicate of an orphaned group is adopted in place of its missing original, and why.
+func (c *CLI) explainAdoption(g *group) {
+	rule := "it has the lowest copy number, then the first name in order"
+	if c.AdoptOrphans == "newest" {
+		rule = "it was modified most recently, then the first name in order"
+	}
+	c.explain(g, g.Duplicates[0], "adopted as the original by --adopt-orphans %s, as %s", c.AdoptOrphans, rule)
+}
+
+// explainSwap records that kept, the file now taking the original's place in g, was preferred over replaced, as
+// rule says.
+func (c *CLI) explainSwap(g *group, kept, replaced, rule string) {
+	if kept != replaced {
+		c.explain(g, kept, "kept in place of %s, as %s", replaced, rule)
+		c.explain(g, replaced, "not kept, as %s", rule)
+	}
+}
+
+// reasons returns the lines explaining g's files, the original's first, or none when there's no explanation.
+func (g group) reasons() []string {
+	if len(g.Reasons) == 0 {
+		return nil
+	}
+	var lines []string
+	for _, path := range append([]string{g.Original}, g.Duplicates...) {
+		if reason, ok := g.Reasons[path]; ok {
+			lines = append(lines, path+": "+reason)
+		}
+	}
+	return lines
+}
+
+// String describes p by its regex, after its label when it has one.
+func (p copyPattern) String() string {
+	if p.label != "" {
+		return "[" + p.label + "] " + p.re.String()
+	}
+	return p.re.String()
+}
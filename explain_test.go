@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCLI_Run_Explain(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name    string
+		cli     CLI
+		reasons map[string]string
+	}{
+		{
+			name: "dry run",
+			cli:  CLI{DryRun: true},
+			reasons: map[string]string{
+				"/media/book.pdf":     "the original, named like its copies without a copy suffix",
+				"/media/book (1).pdf": "a duplicate, named as a copy by the pattern " + defaultRegex,
+			},
+		},
+		{
+			name: "delete",
+			cli:  CLI{Delete: true},
+			reasons: map[string]string{
+				"/media/book.pdf":     "the original, named like its copies without a copy suffix; kept, as the original",
+				"/media/book (1).pdf": "a duplicate, named as a copy by the pattern " + defaultRegex + "; deleted, as the original is kept",
+			},
+		},
+		{
+			name: "inverse",
+			cli:  CLI{Delete: true, Inverse: true},
+			reasons: map[string]string{
+				"/media/book.pdf":     "the original, named like its copies without a copy suffix; deleted, as /media/book (1).pdf is kept in its place",
+				"/media/book (1).pdf": "a duplicate, named as a copy by the pattern " + defaultRegex + "; kept by --inverse, as the newest copy",
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			out := filepath.Join(t.TempDir(), "results.json")
+			cli := tt.cli
+			cli.Path, cli.Explain, cli.Format, cli.Out, cli.Regex = []string{"/media"}, true, "json", out, []string{defaultRegex}
+			cli.memory = fstest.MapFS{
+				"media/book.pdf":     {Data: []byte("content")},
+				"media/book (1).pdf": {Data: []byte("content")},
+			}
+			_ = cli.Run(nil)
+			data, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatalf("failed to read results: %v", err)
+			}
+			groups, err := parseResults(data)
+			if err != nil {
+				t.Fatalf("parseResults() error = %v", err)
+			}
+			if len(groups) != 1 {
+				t.Fatalf("expected one group, got %+v", groups)
+			}
+			for path, want := range tt.reasons {
+				if got := groups[0].Reasons[path]; got != want {
+					t.Errorf("reason for %s = %q, want %q", path, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderText_Reasons(t *testing.T) {
+	t.Parallel()
+	g := group{
+		Original:   "/a/book.pdf",
+		Duplicates: []string{"/a/book (1).pdf"},
+		Reasons:    map[string]string{"/a/book.pdf": "the original", "/a/book (1).pdf": "a duplicate"},
+	}
+	want := strings.Join([]string{
+		"Original: /a/book.pdf",
+		"  - Duplicate: /a/book (1).pdf",
+		"  Why:",
+		"    /a/book.pdf: the original",
+		"    /a/book (1).pdf: a duplicate",
+	}, "\n")
+	if got := render("text", []group{g}); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
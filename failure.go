@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// errorInfo describes why an action failed, for tooling to retry or alert on selectively.
+type errorInfo struct {
+	// Failure is "transient" for errors which persisted through retries, or "permanent" for those retrying can't fix.
+	Failure string `json:"failure,omitempty"`
+	// Kind classifies the error, e.g. "not_found", "permission", "busy", "stale", "protected", "special", or "other".
+	Kind string `json:"kind,omitempty"`
+	// Errno names the system error, e.g. "EACCES" or "ERROR_SHARING_VIOLATION", or gives its number.
+	Errno string `json:"errno,omitempty"`
+	// Syscall is the operation which failed, e.g. "remove" or "rename".
+	Syscall string `json:"syscall,omitempty"`
+}
+
+// savedError is an error read back from saved results, with what was known about it when it was saved.
+type savedError struct {
+	msg  string
+	info errorInfo
+}
+
+func (e *savedError) Error() string {
+	return e.msg
+}
+
+// describeError classifies err, the error of a failed action.
+func describeError(err error) errorInfo {
+	var saved *savedError
+	if errors.As(err, &saved) {
+		return saved.info
+	}
+	info := errorInfo{Failure: "permanent", Kind: errorKind(err)}
+	var retried *retriedError
+	if errors.As(err, &retried) {
+		info.Failure = "transient"
+	}
+	info.Errno, _, _ = errnoInfo(err)
+	var pathErr *fs.PathError
+	var linkErr *os.LinkError
+	var syscallErr *os.SyscallError
+	switch {
+	case errors.As(err, &pathErr):
+		info.Syscall = pathErr.Op
+	case errors.As(err, &linkErr):
+		info.Syscall = linkErr.Op
+	case errors.As(err, &syscallErr):
+		info.Syscall = syscallErr.Syscall
+	}
+	return info
+}
+
+// errorKind classifies err by what went wrong, as errorInfo.Kind does.
+func errorKind(err error) string {
+	if errors.Is(err, errProtected) {
+		return "protected"
+	}
+	if errors.Is(err, errSpecial) {
+		return "special"
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return "canceled"
+	}
+	if _, kind, ok := errnoInfo(err); ok {
+		return kind
+	}
+	var timeout interface{ Timeout() bool }
+	switch {
+	case errors.As(err, &timeout) && timeout.Timeout():
+		return "timeout"
+	case errors.Is(err, fs.ErrNotExist):
+		return "not_found"
+	case errors.Is(err, fs.ErrPermission):
+		return "permission"
+	case errors.Is(err, fs.ErrExist):
+		return "exists"
+	}
+	return "other"
+}
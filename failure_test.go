@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDescribeError(t *testing.T) {
+	t.Parallel()
+	err := os.Remove(filepath.Join(t.TempDir(), "missing.pdf"))
+	info := describeError(err)
+	if info.Failure != "permanent" || info.Kind != "not_found" || info.Syscall != "remove" || info.Errno == "" {
+		t.Errorf("describeError(%v) = %+v", err, info)
+	}
+
+	protected := fmt.Errorf("%w: /media/a.pdf is protected by /media", errProtected)
+	if info := describeError(protected); info.Kind != "protected" || info.Errno != "" {
+		t.Errorf("describeError(%v) = %+v", protected, info)
+	}
+}
+
+func TestAction_JSON_ErrorInfo(t *testing.T) {
+	t.Parallel()
+	a := action{Op: opDelete, Path: "/media/a (1).pdf", Err: os.Remove(filepath.Join(t.TempDir(), "missing.pdf"))}
+	data, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back action
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := describeError(back.Err), describeError(a.Err); got != want || back.Err.Error() != a.Err.Error() {
+		t.Errorf("read back %v, %+v, want %v, %+v", back.Err, got, a.Err, want)
+	}
+}
@@ -0,0 +1,447 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io/fs"
+	"maps"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// filterKind is the type of a --filter attribute, which decides how the literals compared with it are read.
+type filterKind int
+
+const (
+	kindBool filterKind = iota
+	kindString
+	kindCount
+	kindSize
+	kindAge
+)
+
+// filterFacts describes a duplicate to a --filter expression.
+type filterFacts struct {
+	path string
+	// info is nil when the duplicate couldn't be statted.
+	info fs.FileInfo
+	g    *group
+	now  time.Time
+}
+
+// filterAttr is an attribute of a duplicate, or of its group, which a --filter expression may use.
+type filterAttr struct {
+	kind filterKind
+	// stats marks attributes read from the file's info.
+	stats bool
+	get   func(f filterFacts) any
+}
+
+var filterAttrs = map[string]filterAttr{
+	"size": {kind: kindSize, stats: true, get: func(f filterFacts) any { return float64(f.info.Size()) }},
+	"age":  {kind: kindAge, stats: true, get: func(f filterFacts) any { return f.now.Sub(f.info.ModTime()).Seconds() }},
+	"path": {kind: kindString, get: func(f filterFacts) any { return f.path }},
+	"name": {kind: kindString, get: func(f filterFacts) any { return filepath.Base(f.path) }},
+	"dir":  {kind: kindString, get: func(f filterFacts) any { return parentDir(f.path) }},
+	"ext": {kind: kindString, get: func(f filterFacts) any {
+		return strings.ToLower(strings.TrimPrefix(filepath.Ext(f.path), "."))
+	}},
+	"original": {kind: kindString, get: func(f filterFacts) any { return f.g.Original }},
+	"dupes":    {kind: kindCount, get: func(f filterFacts) any { return float64(len(f.g.Duplicates)) }},
+	"orphan":   {kind: kindBool, get: func(f filterFacts) any { return f.g.Orphan }},
+}
+
+// ageUnits are the units an age may be given in, in seconds.
+var ageUnits = map[string]float64{
+	"s":   1,
+	"m":   60,
+	"min": 60,
+	"h":   60 * 60,
+	"d":   24 * 60 * 60,
+	"w":   7 * 24 * 60 * 60,
+	"y":   365 * 24 * 60 * 60,
+}
+
+// filter is a compiled --filter expression, such as `size > 1MB && age > 30d && dir !~ "Work"`, deciding which
+// duplicates may be acted on.
+type filter struct {
+	root filterNode
+	// stats reports whether the expression uses attributes read from files' info.
+	stats bool
+}
+
+type filterNode interface {
+	eval(f filterFacts) bool
+}
+
+type (
+	andNode  struct{ a, b filterNode }
+	orNode   struct{ a, b filterNode }
+	notNode  struct{ a filterNode }
+	boolNode struct{ attr filterAttr }
+	// compareNode compares an attribute with a literal or another attribute of the same kind.
+	compareNode struct {
+		op          string
+		left, right operand
+		re          *regexp.Regexp
+	}
+)
+
+func (n andNode) eval(f filterFacts) bool  { return n.a.eval(f) && n.b.eval(f) }
+func (n orNode) eval(f filterFacts) bool   { return n.a.eval(f) || n.b.eval(f) }
+func (n notNode) eval(f filterFacts) bool  { return !n.a.eval(f) }
+func (n boolNode) eval(f filterFacts) bool { return n.attr.get(f).(bool) }
+
+func (n compareNode) eval(f filterFacts) bool {
+	left := n.left.value(f)
+	switch n.op {
+	case "=~":
+		return n.re.MatchString(left.(string))
+	case "!~":
+		return !n.re.MatchString(left.(string))
+	}
+	right := n.right.value(f)
+	var c int
+	switch l := left.(type) {
+	case string:
+		c = cmp.Compare(l, right.(string))
+	case float64:
+		c = cmp.Compare(l, right.(float64))
+	}
+	switch n.op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+// operand is either side of a comparison: an attribute, or a literal read according to the attribute's kind.
+type operand struct {
+	attr    *filterAttr
+	literal any
+}
+
+func (o operand) value(f filterFacts) any {
+	if o.attr != nil {
+		return o.attr.get(f)
+	}
+	return o.literal
+}
+
+// parseFilter compiles a --filter expression, returning nil when there's none.
+func parseFilter(expr string) (*filter, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+	tokens, err := lexFilter(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --filter %q: %w", expr, err)
+	}
+	p := &filterParser{tokens: tokens}
+	root, err := p.or()
+	if err == nil && p.peek().kind != tokEOF {
+		err = fmt.Errorf("unexpected %s", p.peek())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid --filter %q: %w", expr, err)
+	}
+	return &filter{root: root, stats: p.stats}, nil
+}
+
+// keep returns the duplicates in g which match the filter, statting them first when it needs their size or age.
+// Duplicates which can't be statted then don't match, so are left alone.
+func (fl *filter) keep(g group, stat func(string) (fs.FileInfo, error), now time.Time) []string {
+	var kept []string
+	for _, d := range g.Duplicates {
+		f := filterFacts{path: d, g: &g, now: now}
+		if fl.stats {
+			info, err := stat(d)
+			if err != nil {
+				continue
+			}
+			f.info = info
+		}
+		if fl.root.eval(f) {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
+
+const (
+	tokEOF = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp
+)
+
+type filterToken struct {
+	kind int
+	text string
+}
+
+func (t filterToken) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// filterOps are the operators of the expression language, longest first so "<=" isn't read as "<".
+var filterOps = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!", "(", ")"}
+
+func lexFilter(expr string) ([]filterToken, error) {
+	var tokens []filterToken
+	for i := 0; i < len(expr); {
+		r := rune(expr[i])
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(expr) && (unicode.IsLetter(rune(expr[j])) || expr[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, filterToken{tokIdent, expr[i:j]})
+			i = j
+		case unicode.IsDigit(r) || r == '.':
+			// a number keeps its unit, e.g. 1.5GB or 30d, to be read once it's known what it's compared with
+			j := i
+			for j < len(expr) && (unicode.IsDigit(rune(expr[j])) || unicode.IsLetter(rune(expr[j])) || expr[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, filterToken{tokNumber, expr[i:j]})
+			i = j
+		case r == '"':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(expr) && expr[j] != '"'; j++ {
+				// only quotes and backslashes are escaped, so regexes like "\d+" can be written as they are
+				if expr[j] == '\\' && j+1 < len(expr) && (expr[j+1] == '"' || expr[j+1] == '\\') {
+					j++
+				}
+				sb.WriteByte(expr[j])
+			}
+			if j == len(expr) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			tokens = append(tokens, filterToken{tokString, sb.String()})
+			i = j + 1
+		default:
+			op := ""
+			for _, o := range filterOps {
+				if strings.HasPrefix(expr[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at %d", r, i)
+			}
+			tokens = append(tokens, filterToken{tokOp, op})
+			i += len(op)
+		}
+	}
+	return append(tokens, filterToken{kind: tokEOF}), nil
+}
+
+// filterParser parses tokens by recursive descent. From loosest to tightest, || binds before &&, then !, then the
+// comparisons.
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+	stats  bool
+}
+
+func (p *filterParser) peek() filterToken { return p.tokens[p.pos] }
+
+func (p *filterParser) next() filterToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *filterParser) isOp(op string) bool {
+	t := p.peek()
+	return t.kind == tokOp && t.text == op
+}
+
+func (p *filterParser) or() (filterNode, error) {
+	n, err := p.and()
+	for err == nil && p.isOp("||") {
+		p.next()
+		var b filterNode
+		if b, err = p.and(); err == nil {
+			n = orNode{n, b}
+		}
+	}
+	return n, err
+}
+
+func (p *filterParser) and() (filterNode, error) {
+	n, err := p.unary()
+	for err == nil && p.isOp("&&") {
+		p.next()
+		var b filterNode
+		if b, err = p.unary(); err == nil {
+			n = andNode{n, b}
+		}
+	}
+	return n, err
+}
+
+func (p *filterParser) unary() (filterNode, error) {
+	switch {
+	case p.isOp("!"):
+		p.next()
+		n, err := p.unary()
+		return notNode{n}, err
+	case p.isOp("("):
+		p.next()
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.isOp(")") {
+			return nil, fmt.Errorf("expected \")\", found %s", p.peek())
+		}
+		p.next()
+		return n, nil
+	}
+	return p.comparison()
+}
+
+var comparisonOps = []string{"==", "!=", "<", "<=", ">", ">=", "=~", "!~"}
+
+// flipped is the operator which compares the same way with its operands swapped.
+var flipped = map[string]string{"==": "==", "!=": "!=", "<": ">", "<=": ">=", ">": "<", ">=": "<="}
+
+func (p *filterParser) comparison() (filterNode, error) {
+	left := p.next()
+	t := p.peek()
+	if t.kind != tokOp || !slices.Contains(comparisonOps, t.text) {
+		attr, err := p.attr(left)
+		if err != nil {
+			return nil, err
+		}
+		if attr.kind != kindBool {
+			return nil, fmt.Errorf("%s isn't true or false, so must be compared with something", left)
+		}
+		return boolNode{*attr}, nil
+	}
+	op := p.next().text
+	right := p.next()
+
+	// the attribute is put on the left, so literals are read as what they're compared with
+	if left.kind != tokIdent {
+		if op == "=~" || op == "!~" {
+			return nil, fmt.Errorf("%s must be matched against a string attribute, e.g. name %s %s", op, op, left)
+		}
+		left, right, op = right, left, flipped[op]
+	}
+	attr, err := p.attr(left)
+	if err != nil {
+		return nil, err
+	}
+	if attr.kind == kindBool {
+		return nil, fmt.Errorf("%s is true or false, so is used on its own, e.g. !%s", left, left.text)
+	}
+	n := compareNode{op: op, left: operand{attr: attr}}
+
+	if op == "=~" || op == "!~" {
+		if attr.kind != kindString || right.kind != tokString {
+			return nil, fmt.Errorf("%s matches a string attribute against a quoted regex, e.g. dir %s \"Work\"", op, op)
+		}
+		if n.re, err = regexp.Compile(right.text); err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+	if right.kind == tokIdent {
+		other, err := p.attr(right)
+		if err != nil {
+			return nil, err
+		}
+		if other.kind != attr.kind {
+			return nil, fmt.Errorf("can't compare %s with %s", left, right)
+		}
+		n.right = operand{attr: other}
+		return n, nil
+	}
+	if n.right.literal, err = literal(attr.kind, right); err != nil {
+		return nil, fmt.Errorf("%s: %w", left, err)
+	}
+	return n, nil
+}
+
+// attr looks up the attribute named by t.
+func (p *filterParser) attr(t filterToken) (*filterAttr, error) {
+	attr, ok := filterAttrs[t.text]
+	if t.kind != tokIdent || !ok {
+		return nil, fmt.Errorf("expected an attribute (%s), found %s", strings.Join(slices.Sorted(maps.Keys(filterAttrs)), ", "), t)
+	}
+	p.stats = p.stats || attr.stats
+	return &attr, nil
+}
+
+// literal reads t as a value of kind: a quoted string, a count, a size such as 1.5GB, or an age such as 30d.
+func literal(kind filterKind, t filterToken) (any, error) {
+	if kind == kindString {
+		if t.kind != tokString {
+			return nil, fmt.Errorf("expected a quoted string, found %s", t)
+		}
+		return t.text, nil
+	}
+	if t.kind != tokNumber {
+		return nil, fmt.Errorf("expected a number, found %s", t)
+	}
+	switch kind {
+	case kindSize:
+		size, err := parseSize(t.text)
+		return float64(size), err
+	case kindAge:
+		return parseAge(t.text)
+	}
+	n, err := strconv.ParseFloat(t.text, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q", t.text)
+	}
+	return n, nil
+}
+
+// parseAge reads an age such as 30d, 12h, or 1.5y, in seconds.
+func parseAge(s string) (float64, error) {
+	i := strings.IndexFunc(s, unicode.IsLetter)
+	if i < 0 {
+		return 0, fmt.Errorf("invalid age %q: give a unit, e.g. 30d", s)
+	}
+	unit, ok := ageUnits[strings.ToLower(s[i:])]
+	if !ok {
+		return 0, fmt.Errorf("invalid age %q: unknown unit %q", s, s[i:])
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return n * unit, nil
+}
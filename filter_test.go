@@ -0,0 +1,74 @@
+package main
+
+import (
+	"io/fs"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestFilter_Keep(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	memory := fstest.MapFS{
+		"media/Work/report (1).pdf": {Data: make([]byte, 2_000_000), ModTime: now.AddDate(0, -3, 0)},
+		"media/old (1).pdf":         {Data: make([]byte, 2_000_000), ModTime: now.AddDate(0, -3, 0)},
+		"media/new (1).pdf":         {Data: make([]byte, 2_000_000), ModTime: now.AddDate(0, 0, -1)},
+		"media/small (1).epub":      {Data: make([]byte, 10), ModTime: now.AddDate(-1, 0, 0)},
+	}
+	stat := func(name string) (fs.FileInfo, error) { return memory.Stat(strings.TrimPrefix(name, "/")) }
+	g := group{Original: "/media/book.pdf", Duplicates: []string{"/media/Work/report (1).pdf", "/media/new (1).pdf", "/media/old (1).pdf", "/media/small (1).epub", "/media/gone (1).pdf"}}
+
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{`size > 1MB && age > 30d && dir !~ "Work"`, []string{"/media/old (1).pdf"}},
+		{`ext == "epub" || name =~ "^new"`, []string{"/media/new (1).pdf", "/media/small (1).epub"}},
+		{`!(ext == "pdf") && 1KB > size`, []string{"/media/small (1).epub"}},
+		{`dupes >= 5 && !orphan && path =~ "\(1\)\.pdf$" && age < 2d`, []string{"/media/new (1).pdf"}},
+		// files which can't be statted never match attributes needing it
+		{`size >= 0`, []string{"/media/Work/report (1).pdf", "/media/new (1).pdf", "/media/old (1).pdf", "/media/small (1).epub"}},
+		{`original == "/media/book.pdf" && name != "gone (1).pdf"`, []string{"/media/Work/report (1).pdf", "/media/new (1).pdf", "/media/old (1).pdf", "/media/small (1).epub"}},
+	}
+	for _, tt := range tests {
+		f, err := parseFilter(tt.expr)
+		if err != nil {
+			t.Fatalf("parseFilter(%q) error = %v", tt.expr, err)
+		}
+		if got := f.keep(g, stat, now); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: kept %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseFilter_Errors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		expr, want string
+	}{
+		{`sise > 1MB`, "expected an attribute"},
+		{`size > "big"`, "expected a number"},
+		{`size > 1XB`, `unknown unit "xb"`},
+		{`age > 30`, "give a unit"},
+		{`size`, "must be compared"},
+		{`orphan == 1`, "used on its own"},
+		{`size =~ "1"`, "quoted regex"},
+		{`name =~ "("`, "missing closing )"},
+		{`(size > 1`, `expected ")"`},
+		{`size > 1 size`, `unexpected "size"`},
+		{`name == "unterminated`, "unterminated string"},
+		{`size > 1 & age > 1d`, `unexpected '&'`},
+		{`name == size`, "can't compare"},
+	}
+	for _, tt := range tests {
+		if _, err := parseFilter(tt.expr); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseFilter(%q) error = %v, want it to contain %q", tt.expr, err, tt.want)
+		}
+	}
+	if f, err := parseFilter(" "); f != nil || err != nil {
+		t.Errorf("parseFilter of nothing = %v, %v; want no filter", f, err)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// formatPrefs ranks file formats by extension for --prefer-format. Each chain lists formats from the most to the least
+// preferred, and formats are only ranked against others in the same chain.
+type formatPrefs []map[string]int
+
+// parseFormatPrefs parses chains of extensions such as "epub>mobi,flac>mp3". Extensions may be given with or without a
+// leading dot, in any case.
+func parseFormatPrefs(s string) (formatPrefs, error) {
+	var prefs formatPrefs
+	for _, chain := range strings.Split(s, ",") {
+		if strings.TrimSpace(chain) == "" {
+			continue
+		}
+		ranks := map[string]int{}
+		formats := strings.Split(chain, ">")
+		if len(formats) < 2 {
+			return nil, fmt.Errorf("invalid format preference %q: expected formats from most to least preferred, e.g. epub>mobi", chain)
+		}
+		for i, f := range formats {
+			ext := normalizeExt(f)
+			if ext == "." {
+				return nil, fmt.Errorf("invalid format preference %q: missing format", chain)
+			}
+			if _, ok := ranks[ext]; ok {
+				return nil, fmt.Errorf("invalid format preference %q: %s is listed twice", chain, ext)
+			}
+			ranks[ext] = i
+		}
+		prefs = append(prefs, ranks)
+	}
+	return prefs, nil
+}
+
+func normalizeExt(ext string) string {
+	return "." + strings.TrimPrefix(strings.ToLower(strings.TrimSpace(ext)), ".")
+}
+
+// prefers reports whether the format of a is preferred over b's.
+func (p formatPrefs) prefers(a, b string) bool {
+	extA, extB := normalizeExt(filepath.Ext(a)), normalizeExt(filepath.Ext(b))
+	for _, ranks := range p {
+		rankA, okA := ranks[extA]
+		rankB, okB := ranks[extB]
+		if okA && okB {
+			return rankA < rankB
+		}
+	}
+	return false
+}
+
+// preferFormat reorders g so that the copy in the most preferred format is kept. A copy is only preferred over the one
+// which would otherwise be kept when its format is ranked higher.
+func preferFormat(g *group, prefs formatPrefs) {
+	kept := &g.Original
+	if g.Orphan {
+		// the first duplicate is the one adopted
+		kept = &g.Duplicates[0]
+	}
+	for i := range g.Duplicates {
+		if prefs.prefers(g.Duplicates[i], *kept) {
+			*kept, g.Duplicates[i] = g.Duplicates[i], *kept
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseFormatPrefs(t *testing.T) {
+	t.Parallel()
+	prefs, err := parseFormatPrefs("epub>mobi>pdf, FLAC>.mp3")
+	if err != nil {
+		t.Fatalf("parseFormatPrefs() error = %v", err)
+	}
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"book.epub", "book.mobi", true},
+		{"book.epub", "book.pdf", true},
+		{"book.PDF", "book.mobi", false},
+		{"song.mp3", "song.flac", false},
+		{"song.flac", "song.mp3", true},
+		// formats in different chains aren't ranked
+		{"song.flac", "book.pdf", false},
+		{"book.pdf", "song.flac", false},
+	}
+	for _, tt := range tests {
+		if got := prefs.prefers(tt.a, tt.b); got != tt.want {
+			t.Errorf("prefers(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	for _, invalid := range []string{"epub", "epub>", "epub>mobi>epub"} {
+		if _, err := parseFormatPrefs(invalid); err == nil {
+			t.Errorf("parseFormatPrefs(%q) expected an error", invalid)
+		}
+	}
+}
+
+func TestPreferFormat(t *testing.T) {
+	t.Parallel()
+	prefs, _ := parseFormatPrefs("epub>mobi>pdf")
+
+	g := group{Original: "/a/book.pdf", Duplicates: []string{"/b/book.mobi", "/c/book.epub", "/d/book.txt"}}
+	preferFormat(&g, prefs)
+	if g.Original != "/c/book.epub" || !slices.Contains(g.Duplicates, "/a/book.pdf") || len(g.Duplicates) != 3 {
+		t.Errorf("preferFormat() = %+v, want book.epub kept", g)
+	}
+
+	orphan := group{Original: "/a/book.pdf", Duplicates: []string{"/a/book.mobi", "/a/book.epub"}, Orphan: true}
+	preferFormat(&orphan, prefs)
+	if orphan.Original != "/a/book.pdf" || orphan.Duplicates[0] != "/a/book.epub" {
+		t.Errorf("preferFormat() of an orphan = %+v, want book.epub adopted", orphan)
+	}
+}
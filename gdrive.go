@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// driveScheme prefixes paths which name folders and files in Google Drive, e.g. gdrive://Books.
+const driveScheme = "gdrive://"
+
+// driveFolderType is the MIME type of Drive folders; other google-apps types (Docs, Sheets, etc.) have no content
+// checksum, so they're never treated as copies.
+const (
+	driveFolderType = "application/vnd.google-apps.folder"
+	driveAppsPrefix = "application/vnd.google-apps."
+)
+
+// isDrive reports whether path names Google Drive files rather than local ones.
+func isDrive(path string) bool {
+	return strings.HasPrefix(path, driveScheme)
+}
+
+// driveFile is a file found by listing Google Drive.
+type driveFile struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	MimeType     string    `json:"mimeType"`
+	Size         int64     `json:"size,string"`
+	MD5Checksum  string    `json:"md5Checksum"`
+	ModifiedTime time.Time `json:"modifiedTime"`
+}
+
+// driveInfo describes a Drive file as a file, so listings can be visited like a walk of the filesystem.
+type driveInfo struct{ driveFile }
+
+func (i driveInfo) Name() string       { return i.driveFile.Name }
+func (i driveInfo) Size() int64        { return i.driveFile.Size }
+func (i driveInfo) Mode() fs.FileMode  { return 0o644 }
+func (i driveInfo) ModTime() time.Time { return i.ModifiedTime }
+func (i driveInfo) IsDir() bool        { return false }
+func (i driveInfo) Sys() any           { return i.driveFile }
+
+// driveClient lists, renames, and trashes files through the Google Drive API.
+type driveClient struct {
+	// base is the API's URL, overridden in tests.
+	base   string
+	token  string
+	client *http.Client
+}
+
+func newDriveClient(token string) (*driveClient, error) {
+	if token == "" {
+		return nil, errors.New("a Google Drive access token is needed for gdrive:// paths; set --gdrive-token or $OHMAN_GDRIVE_TOKEN")
+	}
+	return &driveClient{base: "https://www.googleapis.com/drive/v3", token: token, client: &http.Client{Timeout: time.Minute}}, nil
+}
+
+// children lists the files in the folder with the given id, oldest first, matching the extra query q if it isn't
+// empty.
+func (d *driveClient) children(ctx context.Context, folder, q string) ([]driveFile, error) {
+	query := fmt.Sprintf("'%s' in parents and trashed = false", driveEscape(folder))
+	if q != "" {
+		query += " and " + q
+	}
+	var files []driveFile
+	token := ""
+	for {
+		params := url.Values{
+			"q":                         {query},
+			"fields":                    {"nextPageToken,files(id,name,mimeType,size,md5Checksum,modifiedTime)"},
+			"orderBy":                   {"createdTime"},
+			"pageSize":                  {"1000"},
+			"supportsAllDrives":         {"true"},
+			"includeItemsFromAllDrives": {"true"},
+		}
+		if token != "" {
+			params.Set("pageToken", token)
+		}
+		var page struct {
+			Files         []driveFile `json:"files"`
+			NextPageToken string      `json:"nextPageToken"`
+		}
+		if err := d.do(ctx, http.MethodGet, "/files?"+params.Encode(), nil, &page); err != nil {
+			return nil, err
+		}
+		files = append(files, page.Files...)
+		if page.NextPageToken == "" {
+			return files, nil
+		}
+		token = page.NextPageToken
+	}
+}
+
+// folder resolves a slash-separated path of folder names beneath My Drive to the id of the folder it names.
+func (d *driveClient) folder(ctx context.Context, path string) (string, error) {
+	id := "root"
+	for _, name := range strings.Split(strings.Trim(path, "/"), "/") {
+		if name == "" {
+			continue
+		}
+		found, err := d.children(ctx, id, fmt.Sprintf("name = '%s' and mimeType = '%s'", driveEscape(name), driveFolderType))
+		if err != nil {
+			return "", err
+		}
+		switch len(found) {
+		case 0:
+			return "", fmt.Errorf("no folder named %q in %s%s", name, driveScheme, path)
+		case 1:
+			id = found[0].ID
+		default:
+			return "", fmt.Errorf("%d folders are named %q in %s%s", len(found), name, driveScheme, path)
+		}
+	}
+	return id, nil
+}
+
+// trash moves a file to the Drive trash, from which it can be restored for 30 days.
+func (d *driveClient) trash(ctx context.Context, id string) error {
+	return d.do(ctx, http.MethodPatch, "/files/"+url.PathEscape(id)+"?supportsAllDrives=true", map[string]any{"trashed": true}, nil)
+}
+
+// rename renames a file, leaving it in its folder.
+func (d *driveClient) rename(ctx context.Context, id, name string) error {
+	return d.do(ctx, http.MethodPatch, "/files/"+url.PathEscape(id)+"?supportsAllDrives=true", map[string]any{"name": name}, nil)
+}
+
+func (d *driveClient) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, d.base+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+d.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Message != "" {
+			return fmt.Errorf("Google Drive: %s", apiErr.Error.Message)
+		}
+		return fmt.Errorf("Google Drive: unexpected status %s", resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// driveEscape escapes s for a string literal in a Drive query.
+func driveEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
+}
+
+// setupDrive prepares to scan Google Drive paths, if there are any. Only names can be matched in Drive, as matching
+// by content would mean downloading every file.
+func (c *CLI) setupDrive() error {
+	if !slices.ContainsFunc(c.Path, isDrive) {
+		return nil
+	}
+	if slices.ContainsFunc(c.Path, func(p string) bool { return !isDrive(p) }) {
+		return errors.New("gdrive:// paths can't be scanned along with other paths")
+	}
+	if c.Match != "" && c.Match != "name" {
+		return fmt.Errorf("--match %s needs local files; only name can be matched in Google Drive", c.Match)
+	}
+	if c.Diff {
+		return errors.New("--diff needs local files and can't be used with Google Drive")
+	}
+	if c.drive != nil {
+		return nil
+	}
+	var err error
+	c.drive, err = newDriveClient(c.GDriveToken)
+	return err
+}
+
+// driveFS is the files in Google Drive, listed through the Drive API.
+type driveFS struct {
+	client   *driveClient
+	throttle *throttle
+	// files holds what the last walk listed, by path.
+	files map[string]driveFile
+}
+
+func (d *driveFS) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: errUnreadable}
+}
+
+func (d *driveFS) Stat(name string) (fs.FileInfo, error) {
+	if f, ok := d.files[name]; ok {
+		return driveInfo{f}, nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// walk lists the files beneath root. Drive allows several files with the same name in a folder, so all but the
+// oldest of them are given paths ending in #id to tell them apart.
+func (d *driveFS) walk(ctx context.Context, root string, visit filepath.WalkFunc) error {
+	id, err := d.client.folder(ctx, strings.TrimPrefix(root, driveScheme))
+	if err != nil {
+		return err
+	}
+	var walk func(id, dir string) error
+	walk = func(id, dir string) error {
+		if err := d.throttle.op(ctx); err != nil {
+			return err
+		}
+		files, err := d.client.children(ctx, id, "")
+		if err != nil {
+			return fmt.Errorf("unable to list %s: %w", dir, err)
+		}
+		for _, f := range files {
+			path := strings.TrimSuffix(dir, "/") + "/" + f.Name
+			if f.MimeType == driveFolderType {
+				if err := walk(f.ID, path); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, taken := d.files[path]; taken {
+				path += "#" + f.ID
+			}
+			d.files[path] = f
+			if err := visit(path, driveInfo{f}, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(id, root)
+}
+
+// addCopies adds the files sharing a name with an older file in the same folder to files, as its duplicates. When
+// that name is itself a copy's, e.g. "book (1).pdf", they're grouped under its original instead.
+func (d *driveFS) addCopies(patterns copyPatterns, files map[string][]string) {
+	for path, f := range d.files {
+		original, _, ok := strings.Cut(path, "#"+f.ID)
+		if !ok || strings.HasPrefix(f.MimeType, driveAppsPrefix) {
+			continue
+		}
+		if o, ok := originalFor(patterns, original); ok {
+			original = o
+		}
+		files[original] = append(files[original], path)
+	}
+	for _, duplicates := range files {
+		slices.Sort(duplicates)
+	}
+}
+
+// remove moves the file at path to the trash, as Drive does itself.
+func (d *driveFS) remove(ctx context.Context, path string) error {
+	f, ok := d.files[path]
+	if !ok {
+		return &fs.PathError{Op: "trash", Path: path, Err: fs.ErrNotExist}
+	}
+	if err := d.client.trash(ctx, f.ID); err != nil {
+		return err
+	}
+	delete(d.files, path)
+	return nil
+}
+
+// rename renames the file at from to to's name. Both must be in the same folder.
+func (d *driveFS) rename(ctx context.Context, from, to string) error {
+	f, ok := d.files[from]
+	if !ok {
+		return &fs.PathError{Op: "rename", Path: from, Err: fs.ErrNotExist}
+	}
+	dir, name := to[:strings.LastIndex(to, "/")], to[strings.LastIndex(to, "/")+1:]
+	if !strings.HasPrefix(from, dir+"/") || strings.Contains(from[len(dir)+1:], "/") {
+		return fmt.Errorf("unable to move %s to another folder", from)
+	}
+	if _, taken := d.files[to]; taken {
+		return fmt.Errorf("%s already exists", to)
+	}
+	if err := d.client.rename(ctx, f.ID, name); err != nil {
+		return err
+	}
+	delete(d.files, from)
+	f.Name = name
+	d.files[to] = f
+	return nil
+}
+
+func (d *driveFS) sameContent(_ context.Context, x, y string) (bool, error) {
+	a, ok := d.files[x]
+	if !ok {
+		return false, &fs.PathError{Op: "stat", Path: x, Err: fs.ErrNotExist}
+	}
+	b, ok := d.files[y]
+	if !ok {
+		return false, &fs.PathError{Op: "stat", Path: y, Err: fs.ErrNotExist}
+	}
+	return sameDriveFile(a, b)
+}
+
+// sameDriveFile reports whether two Drive files have the same content, judging by their sizes and MD5 checksums.
+func sameDriveFile(a, b driveFile) (bool, error) {
+	if a.MD5Checksum == "" || b.MD5Checksum == "" {
+		return false, fmt.Errorf("Google Drive has no checksum for %q or %q", a.Name, b.Name)
+	}
+	return a.Size == b.Size && a.MD5Checksum == b.MD5Checksum, nil
+}
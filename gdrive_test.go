@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDriveFile is a file held by fakeDrive, listed in the order the files were created.
+type fakeDriveFile struct {
+	driveFile
+	parent  string
+	trashed bool
+}
+
+// fakeDrive serves the parts of the Drive API ohman uses, listing at most two files per page.
+type fakeDrive struct {
+	t     *testing.T
+	mu    sync.Mutex
+	files []*fakeDriveFile
+}
+
+var (
+	driveParentQuery = regexp.MustCompile(`'([^']*)' in parents`)
+	driveNameQuery   = regexp.MustCompile(`name = '((?:[^'\\]|\\.)*)'`)
+)
+
+func (f *fakeDrive) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":{"code":401,"message":"Invalid Credentials"}}`))
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/files":
+		q := r.URL.Query().Get("q")
+		parent := driveParentQuery.FindStringSubmatch(q)[1]
+		name := driveNameQuery.FindStringSubmatch(q)
+		var matched []driveFile
+		for _, file := range f.files {
+			if file.parent == parent && !file.trashed && (name == nil || file.Name == strings.ReplaceAll(name[1], `\'`, "'")) {
+				matched = append(matched, file.driveFile)
+			}
+		}
+		page := map[string]any{"files": matched}
+		if r.URL.Query().Get("pageToken") == "" && len(matched) > 2 {
+			page = map[string]any{"files": matched[:2], "nextPageToken": "2"}
+		} else if r.URL.Query().Get("pageToken") == "2" {
+			page = map[string]any{"files": matched[2:]}
+		}
+		_ = json.NewEncoder(w).Encode(page)
+	case r.Method == http.MethodPatch:
+		var patch struct {
+			Trashed bool    `json:"trashed"`
+			Name    *string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+			f.t.Errorf("invalid patch: %v", err)
+		}
+		for _, file := range f.files {
+			if "/files/"+file.ID == r.URL.Path {
+				file.trashed = file.trashed || patch.Trashed
+				if patch.Name != nil {
+					file.Name = *patch.Name
+				}
+				_ = json.NewEncoder(w).Encode(file.driveFile)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":{"code":404,"message":"File not found"}}`))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// remaining lists the names of the files which haven't been trashed, with their parents.
+func (f *fakeDrive) remaining() []string {
+	var names []string
+	for _, file := range f.files {
+		if !file.trashed {
+			names = append(names, file.parent+"/"+file.Name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+func TestCLI_Run_Drive(t *testing.T) {
+	file := func(id, parent, name, md5 string) *fakeDriveFile {
+		mimeType := "application/pdf"
+		if md5 == "" {
+			mimeType = "application/vnd.google-apps.document"
+		}
+		return &fakeDriveFile{driveFile: driveFile{ID: id, Name: name, MimeType: mimeType, Size: 7, MD5Checksum: md5}, parent: parent}
+	}
+	folder := func(id, parent, name string) *fakeDriveFile {
+		return &fakeDriveFile{driveFile: driveFile{ID: id, Name: name, MimeType: driveFolderType}, parent: parent}
+	}
+	server := &fakeDrive{t: t, files: []*fakeDriveFile{
+		folder("books", "root", "Book's"),
+		folder("other", "root", "Other"),
+		folder("nested", "books", "Nested"),
+		file("a", "books", "a.pdf", "1"),
+		file("a1", "books", "a (1).pdf", "1"),
+		file("a2", "books", "a.pdf", "1"),
+		file("b", "books", "b.pdf", "8"),
+		file("b1", "books", "b.pdf", "9"),
+		file("doc", "books", "notes", ""),
+		file("doc1", "books", "notes", ""),
+		file("lost1", "nested", "lost (1).pdf", "4"),
+		file("lost2", "nested", "lost (2).pdf", "4"),
+		file("keep", "other", "keep (1).pdf", "5"),
+	}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	cli := &CLI{
+		Path:         []string{"gdrive://Book's"},
+		Delete:       true,
+		Regex:        []string{defaultRegex},
+		AdoptOrphans: "lowest",
+		Out:          filepath.Join(t.TempDir(), "results.txt"),
+		drive:        &driveClient{base: ts.URL, token: "token", client: ts.Client()},
+	}
+	if err := cli.Run(&Context{}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// the copy with other content is kept, as are Google Docs, which have no checksum
+	want := []string{"books/Nested", "books/a.pdf", "books/b.pdf", "books/b.pdf", "books/notes", "books/notes", "nested/lost.pdf", "other/keep (1).pdf", "root/Book's", "root/Other"}
+	if got := server.remaining(); !slices.Equal(got, want) {
+		t.Errorf("expected files %q, got %q", want, got)
+	}
+}
+
+func TestCLI_SetupDrive(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		cli  CLI
+		want string
+	}{
+		{"mixed", CLI{Path: []string{"gdrive://Books", "/media"}}, "along with other paths"},
+		{"match", CLI{Path: []string{"gdrive://Photos"}, Match: "image"}, "--match image needs local files"},
+		{"diff", CLI{Path: []string{"gdrive://Photos"}, Diff: true}, "--diff"},
+		{"token", CLI{Path: []string{"gdrive://Photos"}}, "--gdrive-token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cli.setupDrive(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestCLI_WalkDrive_Errors(t *testing.T) {
+	t.Parallel()
+	server := &fakeDrive{t: t, files: []*fakeDriveFile{
+		{driveFile: driveFile{ID: "a", Name: "Books", MimeType: driveFolderType}, parent: "root"},
+		{driveFile: driveFile{ID: "b", Name: "Books", MimeType: driveFolderType}, parent: "root"},
+	}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	for path, want := range map[string]string{
+		"gdrive://Books":   "2 folders are named",
+		"gdrive://Missing": "no folder named",
+	} {
+		cli := &CLI{Path: []string{path}, drive: &driveClient{base: ts.URL, token: "token", client: ts.Client()}}
+		if err := cli.walk(t.Context(), func(string, os.FileInfo) {}); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected an error containing %q, got %v", path, want, err)
+		}
+	}
+	cli := &CLI{Path: []string{"gdrive://"}, drive: &driveClient{base: ts.URL, token: "expired", client: ts.Client()}}
+	if err := cli.walk(t.Context(), func(string, os.FileInfo) {}); err == nil || !strings.Contains(err.Error(), "Invalid Credentials") {
+		t.Errorf("expected the API's error, got %v", err)
+	}
+}
@@ -1,5 +1,23 @@
 module ohman
 
-go 1.25
+go 1.26.0
 
-require github.com/alecthomas/kong v1.13.0
+require (
+	github.com/alecthomas/kong v1.13.0
+	github.com/fsnotify/fsnotify v1.10.1
+	github.com/robfig/cron/v3 v3.0.1
+)
+
+require (
+	go.starlark.net v0.0.0-20231121155337-90ade8b19d09
+	golang.org/x/image v0.46.0
+	golang.org/x/sys v0.48.0
+	golang.org/x/text v0.42.0
+	google.golang.org/grpc v1.84.0
+	google.golang.org/protobuf v1.36.11
+)
+
+require (
+	golang.org/x/net v0.57.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20260706201446-f0a921348800 // indirect
+)
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"ohman/ohmanpb"
+)
+
+// progressInterval is how often StreamProgress checks a job for changes.
+const progressInterval = 250 * time.Millisecond
+
+var (
+	modeNames = map[ohmanpb.Mode]string{
+		ohmanpb.Mode_MODE_DELETE:             "delete",
+		ohmanpb.Mode_MODE_INVERSE:            "inverse",
+		ohmanpb.Mode_MODE_INVERSE_AND_RENAME: "inverse-and-rename",
+	}
+	jobStatuses = map[string]ohmanpb.Status{
+		jobScanning:  ohmanpb.Status_STATUS_SCANNING,
+		jobScanned:   ohmanpb.Status_STATUS_SCANNED,
+		jobExecuting: ohmanpb.Status_STATUS_EXECUTING,
+		jobCompleted: ohmanpb.Status_STATUS_COMPLETED,
+		jobFailed:    ohmanpb.Status_STATUS_FAILED,
+	}
+)
+
+// grpcService exposes the same jobs as the REST API over gRPC.
+type grpcService struct {
+	ohmanpb.UnimplementedOhmanServer
+	server *server
+}
+
+// newGRPCServer returns a gRPC server for s, requiring token (when set) as bearer authorization metadata.
+func newGRPCServer(s *server, token string) *grpc.Server {
+	var opts []grpc.ServerOption
+	if token != "" {
+		authorized := func(ctx context.Context) error {
+			md, _ := metadata.FromIncomingContext(ctx)
+			for _, v := range md.Get("authorization") {
+				if subtle.ConstantTimeCompare([]byte(v), []byte("Bearer "+token)) == 1 {
+					return nil
+				}
+			}
+			return status.Error(codes.Unauthenticated, "missing or invalid bearer token")
+		}
+		opts = append(opts,
+			grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+				if err := authorized(ctx); err != nil {
+					return nil, err
+				}
+				return handler(ctx, req)
+			}),
+			grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				if err := authorized(ss.Context()); err != nil {
+					return err
+				}
+				return handler(srv, ss)
+			}),
+		)
+	}
+	g := grpc.NewServer(opts...)
+	ohmanpb.RegisterOhmanServer(g, &grpcService{server: s})
+	return g
+}
+
+func (g *grpcService) Scan(_ context.Context, req *ohmanpb.ScanRequest) (*ohmanpb.Job, error) {
+	mode, ok := modeNames[req.GetMode()]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid mode %v", req.GetMode())
+	}
+	j, err := g.server.start(scanRequest{Paths: req.GetPaths(), Regex: req.GetRegex(), Mode: mode})
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return j.proto(), nil
+}
+
+func (g *grpcService) Plan(_ context.Context, req *ohmanpb.JobRequest) (*ohmanpb.PlanResponse, error) {
+	j, err := g.job(req)
+	if err != nil {
+		return nil, err
+	}
+	resp := &ohmanpb.PlanResponse{Job: j.proto()}
+	if resp.Job.GetStatus() == ohmanpb.Status_STATUS_SCANNING {
+		return nil, status.Error(codes.FailedPrecondition, "the scan hasn't finished yet")
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, grp := range j.groups {
+		pg := &ohmanpb.Group{Original: grp.Original, Duplicates: grp.Duplicates, Orphan: grp.Orphan, Mismatched: grp.Mismatched}
+		for _, a := range grp.Actions {
+			pa := &ohmanpb.Action{Op: a.Op, Path: a.Path, Target: a.Target}
+			if a.Err != nil {
+				pa.Error = a.Err.Error()
+			}
+			pg.Actions = append(pg.Actions, pa)
+		}
+		resp.Groups = append(resp.Groups, pg)
+	}
+	return resp, nil
+}
+
+func (g *grpcService) Apply(_ context.Context, req *ohmanpb.JobRequest) (*ohmanpb.Job, error) {
+	j, err := g.job(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.server.executeJob(j); err != nil {
+		if errors.Is(err, errNotScanned) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return nil, status.Error(codes.Aborted, err.Error())
+	}
+	return j.proto(), nil
+}
+
+func (g *grpcService) StreamProgress(req *ohmanpb.JobRequest, stream grpc.ServerStreamingServer[ohmanpb.Job]) error {
+	j, err := g.job(req)
+	if err != nil {
+		return err
+	}
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	var last *ohmanpb.Job
+	for {
+		current := j.proto()
+		if !proto.Equal(current, last) {
+			if err := stream.Send(current); err != nil {
+				return err
+			}
+			last = current
+		}
+		switch current.GetStatus() {
+		case ohmanpb.Status_STATUS_SCANNED, ohmanpb.Status_STATUS_COMPLETED, ohmanpb.Status_STATUS_FAILED:
+			return nil
+		}
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (g *grpcService) job(req *ohmanpb.JobRequest) (*job, error) {
+	j, ok := g.server.lookup(req.GetId())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no scan with id %q", req.GetId())
+	}
+	return j, nil
+}
+
+func (j *job) proto() *ohmanpb.Job {
+	resp := j.response(false)
+	pj := &ohmanpb.Job{
+		Id:      resp.ID,
+		Status:  jobStatuses[resp.Status],
+		Paths:   resp.Paths,
+		Created: timestamppb.New(resp.Created),
+		Error:   resp.Error,
+		Progress: &ohmanpb.Progress{
+			Scanned:  resp.Progress.Scanned,
+			Groups:   int64(resp.Progress.Groups),
+			Actions:  resp.Progress.Actions,
+			Failures: resp.Progress.Failures,
+		},
+	}
+	for mode, name := range modeNames {
+		if name == resp.Mode {
+			pj.Mode = mode
+		}
+	}
+	return pj
+}
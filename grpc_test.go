@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"ohman/ohmanpb"
+)
+
+func newTestGRPCClient(t *testing.T, token string) ohmanpb.OhmanClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	srv := newGRPCServer(newServer(context.Background(), token), token)
+	go func() { _ = srv.Serve(listener) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return ohmanpb.NewOhmanClient(conn)
+}
+
+// streamUntilDone reads progress updates until the stream ends, returning the last one.
+func streamUntilDone(t *testing.T, client ohmanpb.OhmanClient, id string) *ohmanpb.Job {
+	t.Helper()
+	stream, err := client.StreamProgress(context.Background(), &ohmanpb.JobRequest{Id: id})
+	if err != nil {
+		t.Fatalf("StreamProgress failed: %v", err)
+	}
+	var last *ohmanpb.Job
+	for {
+		job, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("StreamProgress failed: %v", err)
+		}
+		last = job
+	}
+	if last == nil {
+		t.Fatal("expected at least one progress update")
+	}
+	return last
+}
+
+func TestGRPC_ScanPlanApply(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "original content")
+	createTestFile(t, filepath.Join(dir, "book (1).pdf"), "original content")
+
+	client := newTestGRPCClient(t, "")
+	ctx := context.Background()
+
+	started, err := client.Scan(ctx, &ohmanpb.ScanRequest{Paths: []string{dir}})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if started.GetId() == "" || started.GetMode() != ohmanpb.Mode_MODE_DELETE {
+		t.Fatalf("unexpected job: %v", started)
+	}
+
+	scanned := streamUntilDone(t, client, started.GetId())
+	if scanned.GetStatus() != ohmanpb.Status_STATUS_SCANNED || scanned.GetProgress().GetScanned() != 2 {
+		t.Fatalf("unexpected scan result: %v", scanned)
+	}
+
+	plan, err := client.Plan(ctx, &ohmanpb.JobRequest{Id: started.GetId()})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(plan.GetGroups()) != 1 || len(plan.GetGroups()[0].GetDuplicates()) != 1 {
+		t.Fatalf("unexpected plan: %v", plan)
+	}
+	if !fileExists(filepath.Join(dir, "book (1).pdf")) {
+		t.Fatal("scanning must not delete anything")
+	}
+
+	if _, err := client.Apply(ctx, &ohmanpb.JobRequest{Id: started.GetId()}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	completed := streamUntilDone(t, client, started.GetId())
+	if completed.GetStatus() != ohmanpb.Status_STATUS_COMPLETED || completed.GetProgress().GetActions() != 1 {
+		t.Errorf("unexpected completion: %v", completed)
+	}
+	if fileExists(filepath.Join(dir, "book (1).pdf")) {
+		t.Error("duplicate should be deleted once the plan is applied")
+	}
+
+	// a completed plan can't be applied again
+	if _, err := client.Apply(ctx, &ohmanpb.JobRequest{Id: started.GetId()}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected FailedPrecondition, got %v", err)
+	}
+}
+
+func TestGRPC_Plan_Mismatched(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "original content")
+	createTestFile(t, filepath.Join(dir, "book (1).pdf"), "another edition")
+
+	client := newTestGRPCClient(t, "")
+	ctx := context.Background()
+	started, err := client.Scan(ctx, &ohmanpb.ScanRequest{Paths: []string{dir}})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	streamUntilDone(t, client, started.GetId())
+
+	plan, err := client.Plan(ctx, &ohmanpb.JobRequest{Id: started.GetId()})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	want := filepath.Join(dir, "book (1).pdf")
+	if len(plan.GetGroups()) != 1 || !slices.Equal(plan.GetGroups()[0].GetMismatched(), []string{want}) {
+		t.Fatalf("expected %s to be reported as differing, got: %v", want, plan)
+	}
+}
+
+func TestGRPC_Errors(t *testing.T) {
+	t.Parallel()
+	client := newTestGRPCClient(t, "")
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+		want codes.Code
+	}{
+		{"no paths", func() error { _, err := client.Scan(ctx, &ohmanpb.ScanRequest{}); return err }, codes.InvalidArgument},
+		{"invalid regex", func() error {
+			_, err := client.Scan(ctx, &ohmanpb.ScanRequest{Paths: []string{"/tmp"}, Regex: "[invalid"})
+			return err
+		}, codes.InvalidArgument},
+		{"invalid mode", func() error {
+			_, err := client.Scan(ctx, &ohmanpb.ScanRequest{Paths: []string{"/tmp"}, Mode: ohmanpb.Mode(42)})
+			return err
+		}, codes.InvalidArgument},
+		{"unknown plan", func() error { _, err := client.Plan(ctx, &ohmanpb.JobRequest{Id: "missing"}); return err }, codes.NotFound},
+		{"unknown apply", func() error { _, err := client.Apply(ctx, &ohmanpb.JobRequest{Id: "missing"}); return err }, codes.NotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(tt.call()); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGRPC_Token(t *testing.T) {
+	t.Parallel()
+	client := newTestGRPCClient(t, "secret")
+	req := &ohmanpb.JobRequest{Id: "missing"}
+
+	if _, err := client.Plan(context.Background(), req); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated without a token, got %v", err)
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+	if _, err := client.Plan(ctx, req); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound with a valid token, got %v", err)
+	}
+	stream, err := client.StreamProgress(context.Background(), req)
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated stream without a token, got %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"slices"
+)
+
+// dropHardLinks removes from g's duplicates those which are hard links to its original, as deleting one would free
+// no space. Only local files are compared, as other backends have no hard links.
+func (c *CLI) dropHardLinks(g *group) {
+	if _, ok := c.files().(localFS); !ok || g.Orphan {
+		return
+	}
+	original, err := os.Lstat(nativePath(g.Original))
+	if err != nil {
+		return
+	}
+	id, ok := fileID(original)
+	if !ok {
+		return
+	}
+	g.Duplicates = slices.DeleteFunc(g.Duplicates, func(d string) bool {
+		info, err := os.Lstat(nativePath(d))
+		if err != nil {
+			return false
+		}
+		other, ok := fileID(info)
+		return ok && other == id
+	})
+}
+
+// freed returns the bytes deleting the file described by info frees: its size, unless other hard links to it remain.
+func freed(info os.FileInfo) int64 {
+	if n, ok := linkCount(info); ok && n > 1 {
+		return 0
+	}
+	return info.Size()
+}
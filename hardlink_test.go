@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCLI_Run_HardLinks(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "content")
+	createTestFile(t, filepath.Join(dir, "book (2).pdf"), "content")
+	createTestFile(t, filepath.Join(dir, "notes.pdf"), "notes")
+	createTestFile(t, filepath.Join(dir, "notes (1).pdf"), "notes")
+	for link, target := range map[string]string{"book (1).pdf": "book.pdf", "notes (2).pdf": "notes (1).pdf"} {
+		if err := os.Link(filepath.Join(dir, target), filepath.Join(dir, link)); err != nil {
+			t.Skipf("hard links aren't available: %v", err)
+		}
+	}
+	if info, err := os.Stat(dir); err != nil {
+		t.Fatal(err)
+	} else if _, ok := fileID(info); !ok {
+		t.Skip("files can't be identified on this platform")
+	}
+
+	out := filepath.Join(t.TempDir(), "results.json")
+	cli := &CLI{Path: []string{dir}, Delete: true, Format: "json", Out: out, Regex: []string{defaultRegex}}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !fileExists(filepath.Join(dir, "book (1).pdf")) {
+		t.Error("a hard link to the original shouldn't be deleted")
+	}
+	for _, name := range []string{"book (2).pdf", "notes (1).pdf", "notes (2).pdf"} {
+		if fileExists(filepath.Join(dir, name)) {
+			t.Errorf("%s should be deleted", name)
+		}
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := parseResults(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := countGroups(results).Reclaimed, int64(len("content")+len("notes")); got != want {
+		t.Errorf("reclaimed %d bytes, want %d, counting linked copies once", got, want)
+	}
+}
+
+func TestTotalsByDir_HardLinks(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	createTestFile(t, filepath.Join(dir, "notes.pdf"), "notes")
+	createTestFile(t, filepath.Join(dir, "notes (1).pdf"), "notes")
+	if err := os.Link(filepath.Join(dir, "notes (1).pdf"), filepath.Join(dir, "notes (2).pdf")); err != nil {
+		t.Skipf("hard links aren't available: %v", err)
+	}
+	if info, err := os.Stat(dir); err != nil {
+		t.Fatal(err)
+	} else if _, ok := fileID(info); !ok {
+		t.Skip("files can't be identified on this platform")
+	}
+
+	g := group{Original: filepath.Join(dir, "notes.pdf"), Duplicates: []string{filepath.Join(dir, "notes (1).pdf"), filepath.Join(dir, "notes (2).pdf")}}
+	totals := totalsByDir(localFS{}, []group{g})
+	if len(totals) != 1 || totals[0].Duplicates != 2 || totals[0].Bytes != int64(len("notes")) {
+		t.Errorf("totalsByDir() = %+v, want both links counted as duplicates, but their bytes once", totals)
+	}
+	g.Duplicates = g.Duplicates[:1]
+	if totals := totalsByDir(localFS{}, []group{g}); totals[0].Bytes != 0 {
+		t.Errorf("totalsByDir() = %+v, want nothing reclaimed while another link remains", totals)
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// historyRecord is a run as saved in the history directory: its summary, and every group and action.
+type historyRecord struct {
+	ID string `json:"id"`
+	// Mode is "dry-run", "delete", "inverse", or "inverse-and-rename".
+	Mode string `json:"mode"`
+	webhookPayload
+}
+
+// HistoryCmd lists and shows previous runs.
+type HistoryCmd struct {
+	List historyListCmd `cmd:"" default:"1" help:"List previous runs, newest first."`
+	Show historyShowCmd `cmd:"" help:"Show the results of a previous run."`
+}
+
+type historyListCmd struct {
+	HistoryDir string `name:"history-dir" help:"Directory holding the history of runs. Defaults to ohman/history in your config directory." type:"path"`
+	Limit      int    `name:"limit" short:"n" help:"Show at most this many runs; 0 shows them all." default:"20"`
+}
+
+type historyShowCmd struct {
+	ID         string `arg:"" name:"id" help:"ID of the run, or a unique prefix of it."`
+	HistoryDir string `name:"history-dir" help:"Directory holding the history of runs. Defaults to ohman/history in your config directory." type:"path"`
+	Format     string `name:"format" help:"Output format: ${enum}." enum:"text,fdupes,markdown,md,csv,html,json" default:"text"`
+}
+
+func (h *historyListCmd) Run() error {
+	dir, err := historyDir(h.HistoryDir)
+	if err != nil {
+		return err
+	}
+	records, err := loadHistory(dir)
+	if err != nil {
+		return err
+	}
+	if h.Limit > 0 && len(records) > h.Limit {
+		records = records[:h.Limit]
+	}
+	return writeHistory(os.Stdout, records)
+}
+
+func (h *historyShowCmd) Run() error {
+	return h.show(os.Stdout)
+}
+
+func (h *historyShowCmd) show(w io.Writer) error {
+	dir, err := historyDir(h.HistoryDir)
+	if err != nil {
+		return err
+	}
+	records, err := loadHistory(dir)
+	if err != nil {
+		return err
+	}
+	var matches []historyRecord
+	for _, r := range records {
+		if r.ID == h.ID {
+			matches = []historyRecord{r}
+			break
+		}
+		if strings.HasPrefix(r.ID, h.ID) {
+			matches = append(matches, r)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return fmt.Errorf("no run with id %q in %s", h.ID, dir)
+	case 1:
+	default:
+		return fmt.Errorf("%d runs have ids starting with %q; use more of the id", len(matches), h.ID)
+	}
+	r := matches[0]
+
+	fmt.Fprintf(w, "Run %s (%s) of %s\n", r.ID, r.Mode, strings.Join(r.Paths, ", "))
+	fmt.Fprintf(w, "Started %s, finished %s, exit code %d\n", r.Started.Format(time.RFC3339), r.Finished.Format(time.RFC3339), r.ExitCode)
+	if r.Error != "" {
+		fmt.Fprintf(w, "Error: %s\n", r.Error)
+	}
+	_, err = fmt.Fprintf(w, "\n%s\n", render(h.Format, r.Results))
+	return err
+}
+
+// historyDir returns dir, or the default history directory when dir is empty.
+func historyDir(dir string) (string, error) {
+	if dir != "" {
+		return dir, nil
+	}
+	config, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to find the history directory, set --history-dir: %w", err)
+	}
+	return filepath.Join(config, "ohman", "history"), nil
+}
+
+// runMode describes what a run does with duplicates.
+func (c *CLI) runMode() string {
+	switch {
+	case c.DryRun || !c.Delete:
+		return "dry-run"
+	case c.InverseAndRename:
+		return "inverse-and-rename"
+	case c.Inverse:
+		return "inverse"
+	}
+	return "delete"
+}
+
+// newRunID returns a unique id for a run started at started. Ids sort in the order runs started.
+func newRunID(started time.Time) string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return started.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(b)
+}
+
+// saveHistory records a run in the history directory, returning its id.
+func saveHistory(dir string, c *CLI, payload webhookPayload, groups []group) (string, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	r := historyRecord{
+		ID:             payload.RunID,
+		Mode:           c.runMode(),
+		webhookPayload: payload,
+	}
+	if r.ID == "" {
+		r.ID = newRunID(payload.Started)
+	}
+	r.Results = groups
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	// written to a temporary file first so a crash never leaves a truncated record
+	tmp, err := os.CreateTemp(dir, ".run-*")
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	return r.ID, os.Rename(tmp.Name(), filepath.Join(dir, r.ID+".json"))
+}
+
+// pruneHistory deletes the oldest runs in dir, leaving the newest keep. A keep of 0 or less leaves every run.
+func pruneHistory(dir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	// IDs begin with the time the run started, and ReadDir sorts by name, so the oldest runs come first
+	var runs []string
+	for _, e := range entries {
+		if !e.IsDir() && filepath.Ext(e.Name()) == ".json" {
+			runs = append(runs, e.Name())
+		}
+	}
+	var errs []error
+	for _, name := range runs[:max(len(runs)-keep, 0)] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// loadHistory reads every run in dir, newest first. Records which can't be read are skipped with a warning.
+func loadHistory(dir string) ([]historyRecord, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var records []historyRecord
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s: %v\n", e.Name(), err)
+			continue
+		}
+		var r historyRecord
+		if err := json.Unmarshal(data, &r); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s: %v\n", e.Name(), err)
+			continue
+		}
+		records = append(records, r)
+	}
+	slices.SortFunc(records, func(a, b historyRecord) int {
+		return strings.Compare(b.ID, a.ID)
+	})
+	return records, nil
+}
+
+func writeHistory(w io.Writer, records []historyRecord) error {
+	if len(records) == 0 {
+		_, err := fmt.Fprintln(w, "No runs recorded yet.")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTARTED\tMODE\tGROUPS\tDELETED\tRENAMED\tFAILED\tEXIT\tPATHS")
+	for _, r := range records {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t%s\n", r.ID, r.Started.Local().Format("2006-01-02 15:04"), r.Mode,
+			r.Groups, r.Deleted, r.Renamed, r.Failures, r.ExitCode, strings.Join(r.Paths, ", "))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveHistory_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	cli := &CLI{Path: []string{"/media/books"}, Delete: true, status: exitPartialFailure}
+	groups := []group{
+		{Original: "/media/books/a.pdf", Duplicates: []string{"/media/books/a (1).pdf", "/media/books/a (2).pdf"}, Actions: []action{
+			{Op: opKeep, Path: "/media/books/a.pdf", implicit: true},
+			{Op: opDelete, Path: "/media/books/a (1).pdf"},
+			{Op: opDelete, Path: "/media/books/a (2).pdf", Err: errors.New("permission denied")},
+		}},
+	}
+	started := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	id, err := saveHistory(dir, cli, newWebhookPayload(cli, started, groups, nil), groups)
+	if err != nil {
+		t.Fatalf("saveHistory() error = %v", err)
+	}
+	if !strings.HasPrefix(id, "20240301T123000Z-") {
+		t.Errorf("id = %q, want it to start with the start time", id)
+	}
+
+	records, err := loadHistory(dir)
+	if err != nil {
+		t.Fatalf("loadHistory() error = %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("loadHistory() returned %d records, want 1", len(records))
+	}
+	r := records[0]
+	if r.ID != id || r.Mode != "delete" || r.Deleted != 1 || r.Failures != 1 || r.ExitCode != exitPartialFailure {
+		t.Errorf("record = %+v", r)
+	}
+	if !reflect.DeepEqual(render("text", r.Results), render("text", groups)) {
+		t.Errorf("rendered results differ after loading:\n%s\nwant:\n%s", render("text", r.Results), render("text", groups))
+	}
+}
+
+func TestLoadHistory(t *testing.T) {
+	dir := t.TempDir()
+	if records, err := loadHistory(filepath.Join(dir, "missing")); err != nil || records != nil {
+		t.Errorf("loadHistory() of a missing directory = %v, %v, want nothing", records, err)
+	}
+
+	cli := &CLI{Path: []string{"/media"}}
+	for _, started := range []time.Time{time.Now().Add(-time.Hour), time.Now(), time.Now().Add(-2 * time.Hour)} {
+		if _, err := saveHistory(dir, cli, newWebhookPayload(cli, started, nil, nil), nil); err != nil {
+			t.Fatalf("saveHistory() error = %v", err)
+		}
+	}
+	createTestFile(t, filepath.Join(dir, "corrupt.json"), "{")
+	createTestFile(t, filepath.Join(dir, "notes.txt"), "not a run")
+
+	records, err := loadHistory(dir)
+	if err != nil {
+		t.Fatalf("loadHistory() error = %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("loadHistory() returned %d records, want 3", len(records))
+	}
+	for i := 1; i < len(records); i++ {
+		if records[i].Started.After(records[i-1].Started) {
+			t.Errorf("records aren't newest first: %v before %v", records[i-1].Started, records[i].Started)
+		}
+	}
+}
+
+func TestCLI_Run_RecordsHistory(t *testing.T) {
+	dir := setupTestDir(t)
+	historyDir := t.TempDir()
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "content")
+	createTestFile(t, filepath.Join(dir, "book (1).pdf"), "content")
+
+	cli := &CLI{Path: []string{dir}, Delete: true, Regex: []string{defaultRegex}, Out: filepath.Join(dir, "results.txt"), History: true, HistoryDir: historyDir}
+	if err := cli.Run(&Context{}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	records, err := loadHistory(historyDir)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("loadHistory() = %v, %v, want 1 record", records, err)
+	}
+
+	var out strings.Builder
+	show := historyShowCmd{ID: records[0].ID[:10], HistoryDir: historyDir, Format: "text"}
+	if err := show.show(&out); err != nil {
+		t.Fatalf("show() error = %v", err)
+	}
+	for _, want := range []string{"(delete) of " + dir, "Deleted " + filepath.Join(dir, "book (1).pdf")} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("show() output is missing %q:\n%s", want, out.String())
+		}
+	}
+
+	// a second run makes the short prefix ambiguous
+	createTestFile(t, filepath.Join(dir, "book (2).pdf"), "content")
+	if err := cli.Run(&Context{}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if err := show.show(&out); err == nil || !strings.Contains(err.Error(), "2 runs") {
+		t.Errorf("show() of an ambiguous prefix error = %v", err)
+	}
+	if err := (&historyShowCmd{ID: "nope", HistoryDir: historyDir}).show(&out); err == nil {
+		t.Error("show() of an unknown id should fail")
+	}
+}
+
+func TestPruneHistory(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	started := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	var ids []string
+	for i := range 4 {
+		id, err := saveHistory(dir, &CLI{}, webhookPayload{Started: started.Add(time.Duration(i) * time.Hour)}, nil)
+		if err != nil {
+			t.Fatalf("saveHistory() error = %v", err)
+		}
+		ids = append(ids, id)
+	}
+	createTestFile(t, filepath.Join(dir, "notes.md"), "not a run")
+
+	if err := pruneHistory(dir, 0); err != nil {
+		t.Fatalf("pruneHistory(0) error = %v", err)
+	}
+	if records, _ := loadHistory(dir); len(records) != 4 {
+		t.Errorf("pruneHistory(0) left %d runs, want 4", len(records))
+	}
+
+	if err := pruneHistory(dir, 2); err != nil {
+		t.Fatalf("pruneHistory(2) error = %v", err)
+	}
+	records, err := loadHistory(dir)
+	if err != nil {
+		t.Fatalf("loadHistory() error = %v", err)
+	}
+	var got []string
+	for _, r := range records {
+		got = append(got, r.ID)
+	}
+	if want := []string{ids[3], ids[2]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("pruneHistory(2) kept %v, want %v", got, want)
+	}
+	if !fileExists(filepath.Join(dir, "notes.md")) {
+		t.Error("pruneHistory() deleted a file which isn't a run")
+	}
+}
+
+func TestWriteHistory(t *testing.T) {
+	var out strings.Builder
+	if err := writeHistory(&out, nil); err != nil || !strings.Contains(out.String(), "No runs") {
+		t.Errorf("writeHistory() of nothing = %q, %v", out.String(), err)
+	}
+
+	out.Reset()
+	records := []historyRecord{{ID: "20240301T123000Z-abcd", Mode: "dry-run", webhookPayload: webhookPayload{Paths: []string{"/a", "/b"}, Groups: 3, ExitCode: exitDuplicatesFound}}}
+	if err := writeHistory(&out, records); err != nil {
+		t.Fatalf("writeHistory() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "ID") || !strings.Contains(lines[1], "20240301T123000Z-abcd") || !strings.HasSuffix(lines[1], "/a, /b") {
+		t.Errorf("writeHistory() = %q", out.String())
+	}
+}
+
+func TestHistoryDir_Default(t *testing.T) {
+	if _, err := os.UserConfigDir(); err != nil {
+		t.Skip("no config directory")
+	}
+	dir, err := historyDir("")
+	if err != nil || filepath.Base(dir) != "history" || filepath.Base(filepath.Dir(dir)) != "ohman" {
+		t.Errorf("historyDir() = %q, %v", dir, err)
+	}
+}
+
+func TestCLI_Run_RunID(t *testing.T) {
+	dir := setupTestDir(t)
+	historyDir, auditLog := t.TempDir(), filepath.Join(t.TempDir(), "audit.jsonl")
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "content")
+	createTestFile(t, filepath.Join(dir, "book (1).pdf"), "content")
+
+	out := filepath.Join(dir, "results.json")
+	cli := &CLI{Path: []string{dir}, Delete: true, Regex: []string{defaultRegex}, Format: "json", Out: out, History: true, HistoryDir: historyDir, AuditLog: auditLog}
+	if err := cli.Run(&Context{}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	records, err := loadHistory(historyDir)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("loadHistory() = %v, %v, want 1 record", records, err)
+	}
+	id := records[0].ID
+	if id == "" || records[0].RunID != id {
+		t.Errorf("history record id %q, run_id %q", id, records[0].RunID)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups, err := parseResults(data)
+	if err != nil || len(groups) != 1 || groups[0].RunID != id {
+		t.Errorf("results = %+v, %v, want a group of run %s", groups, err, id)
+	}
+	if audit := readAuditLog(t, auditLog); len(audit) != 1 || audit[0].RunID != id {
+		t.Errorf("audit records = %+v, want one of run %s", audit, id)
+	}
+}
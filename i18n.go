@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// printer writes messages for people in the language chosen by setLanguage, or is nil while that's English, the
+// language they're written in.
+var printer *message.Printer
+
+func init() {
+	for tag, messages := range translations {
+		for key, msg := range messages {
+			if err := message.SetString(tag, key, msg); err != nil {
+				panic(err)
+			}
+		}
+	}
+}
+
+// setLanguage chooses the first of langs with translations, leaving messages in English when none has any. langs are
+// BCP 47 tags like "de-AT" or POSIX locales like "de_AT.UTF-8", most preferred first.
+func setLanguage(langs ...string) {
+	supported := []language.Tag{language.English}
+	for tag := range translations {
+		supported = append(supported, tag)
+	}
+	tags := make([]string, 0, len(langs))
+	for _, l := range langs {
+		if tag := localeTag(l); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	if _, i := language.MatchStrings(language.NewMatcher(supported), tags...); i > 0 {
+		printer = message.NewPrinter(supported[i])
+	} else {
+		printer = nil
+	}
+}
+
+// localeTag turns a POSIX locale into a BCP 47 tag, e.g. "pt_BR.UTF-8@latin" into "pt-BR". It returns "" for the C
+// and POSIX locales, which don't name a language.
+func localeTag(locale string) string {
+	locale, _, _ = strings.Cut(locale, ".")
+	locale, _, _ = strings.Cut(locale, "@")
+	if locale == "C" || locale == "POSIX" {
+		return ""
+	}
+	return strings.ReplaceAll(locale, "_", "-")
+}
+
+// tr formats a message for people, as fmt.Sprintf does, in the language chosen by setLanguage. Results meant for
+// scripts, like the output of --format, are never translated.
+func tr(format string, a ...any) string {
+	if printer == nil {
+		return fmt.Sprintf(format, a...)
+	}
+	return printer.Sprintf(format, a...)
+}
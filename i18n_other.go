@@ -0,0 +1,25 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// systemLanguages returns the user's languages, most preferred first, from the locale environment variables.
+func systemLanguages() []string {
+	var langs []string
+	// GNU gettext's LANGUAGE is a list of fallbacks, e.g. "de_AT:de"
+	for _, l := range strings.Split(os.Getenv("LANGUAGE"), ":") {
+		if l != "" {
+			langs = append(langs, l)
+		}
+	}
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if l := os.Getenv(name); l != "" {
+			langs = append(langs, l)
+		}
+	}
+	return langs
+}
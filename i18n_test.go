@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLocaleTag(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"de_DE.UTF-8", "de-DE"},
+		{"pt_BR.UTF-8@latin", "pt-BR"},
+		{"de-AT", "de-AT"},
+		{"de", "de"},
+		{"C", ""},
+		{"POSIX", ""},
+		{"C.UTF-8", ""},
+	}
+	for _, tt := range tests {
+		if got := localeTag(tt.locale); got != tt.want {
+			t.Errorf("localeTag(%q) = %q, want %q", tt.locale, got, tt.want)
+		}
+	}
+}
+
+// TestSetLanguage isn't parallel, as it changes the language for every test.
+func TestSetLanguage(t *testing.T) {
+	t.Cleanup(func() { printer = nil })
+	n := runCounts{Groups: 2, Duplicates: 3}
+
+	setLanguage("C", "de_AT.UTF-8")
+	if got, want := n.summary(true), "3 Duplikat(e) von 2 Datei(en) gefunden; es wurde nichts verändert."; got != want {
+		t.Errorf("summary() = %q, want %q", got, want)
+	}
+	if got, want := tr("Results written to %s", "results.txt"), "Ergebnisse nach results.txt geschrieben"; got != want {
+		t.Errorf("tr() = %q, want %q", got, want)
+	}
+
+	setLanguage("fr_FR.UTF-8")
+	if got, want := n.summary(true), "Found 3 duplicate(s) of 2 file(s); nothing was changed."; got != want {
+		t.Errorf("summary() with no French translations = %q, want %q", got, want)
+	}
+}
+
+func TestTranslations(t *testing.T) {
+	t.Parallel()
+	for tag, messages := range translations {
+		for key, msg := range messages {
+			if got, want := strings.Count(msg, "%"), strings.Count(key, "%"); got != want {
+				t.Errorf("%s translation of %q has %d verbs, want %d", tag, key, got, want)
+			}
+		}
+	}
+}
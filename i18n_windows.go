@@ -0,0 +1,20 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// systemLanguages returns the user's languages, most preferred first: LANG when it's set, e.g. by Git Bash, followed
+// by the display languages chosen in Windows' settings.
+func systemLanguages() []string {
+	var langs []string
+	if l := os.Getenv("LANG"); l != "" {
+		langs = append(langs, l)
+	}
+	preferred, _ := windows.GetUserPreferredUILanguages(windows.MUI_LANGUAGE_NAME)
+	return append(langs, preferred...)
+}
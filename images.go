@@ -0,0 +1,167 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	_ "golang.org/x/image/bmp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+// imageExtensions are the formats which can be decoded for --match image.
+var imageExtensions = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true, ".bmp": true, ".tif": true, ".tiff": true,
+}
+
+// imageFile is an image found by the scan, along with what's needed to group it and choose which copy to keep.
+type imageFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+	hash    uint64
+	pixels  int
+}
+
+// scanImages groups images which look alike, even when resized or re-encoded, by comparing perceptual hashes. The
+// highest resolution copy in each group is treated as the original, then the largest file, then the oldest.
+func (c *CLI) scanImages(ctx context.Context) (map[string][]string, error) {
+	var images []imageFile
+	err := c.walk(ctx, func(path string, info os.FileInfo) {
+		if imageExtensions[strings.ToLower(filepath.Ext(path))] {
+			images = append(images, imageFile{path: path, size: info.Size(), modTime: info.ModTime()})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hashed := images[:0]
+	for _, img := range images {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("%w while hashing images; no files were changed", context.Cause(ctx))
+		}
+		hash, pixels, err := c.hashImage(ctx, img.path)
+		if err != nil {
+			if !c.SkipErrors {
+				return nil, fmt.Errorf("unable to hash %s: %w", img.path, err)
+			}
+			c.skip(img.path, err)
+			continue
+		}
+		img.hash, img.pixels = hash, pixels
+		hashed = append(hashed, img)
+	}
+
+	files := make(map[string][]string)
+	for _, cluster := range groupSimilar(len(hashed), func(i, j int) bool {
+		return bits.OnesCount64(hashed[i].hash^hashed[j].hash) <= c.ImageThreshold
+	}) {
+		members := make([]imageFile, len(cluster))
+		for i, idx := range cluster {
+			members[i] = hashed[idx]
+		}
+		slices.SortFunc(members, func(a, b imageFile) int {
+			return cmp.Or(
+				cmp.Compare(b.pixels, a.pixels),
+				cmp.Compare(b.size, a.size),
+				a.modTime.Compare(b.modTime),
+				cmp.Compare(a.path, b.path),
+			)
+		})
+		for _, m := range members[1:] {
+			files[members[0].path] = append(files[members[0].path], m.path)
+		}
+	}
+	return files, nil
+}
+
+// hashImage decodes the image at path, returning its perceptual hash and its number of pixels.
+func (c *CLI) hashImage(ctx context.Context, path string) (uint64, int, error) {
+	f, err := c.files().Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() { _ = f.Close() }()
+	img, _, err := image.Decode(c.throttle.reader(ctx, f))
+	if err != nil {
+		return 0, 0, err
+	}
+	pixels := img.Bounds().Dx() * img.Bounds().Dy()
+	if c.ImageHash == "dhash" {
+		return dHash(img), pixels, nil
+	}
+	return pHash(img), pixels, nil
+}
+
+// grayscale scales img to width x height in grayscale, discarding detail which doesn't survive re-encoding.
+func grayscale(img image.Image, width, height int) *image.Gray {
+	dst := image.NewGray(image.Rect(0, 0, width, height))
+	draw.BiLinear.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return dst
+}
+
+// dHash is a difference hash: each bit records whether a pixel is brighter than its right-hand neighbour in a 9x8
+// thumbnail. It's fast, and tolerant of scaling and compression.
+func dHash(img image.Image) uint64 {
+	g := grayscale(img, 9, 8)
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if g.GrayAt(x, y).Y > g.GrayAt(x+1, y).Y {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// pHash is a perceptual hash: each bit records whether one of the lowest frequencies of a 32x32 thumbnail's discrete
+// cosine transform is above the median. It's more tolerant than dHash of brightness and contrast changes.
+func pHash(img image.Image) uint64 {
+	const size, low = 32, 8
+	g := grayscale(img, size, size)
+
+	var coefficients [low * low]float64
+	for u := 0; u < low; u++ {
+		for v := 0; v < low; v++ {
+			var sum float64
+			for x := 0; x < size; x++ {
+				for y := 0; y < size; y++ {
+					sum += float64(g.GrayAt(x, y).Y) *
+						math.Cos(float64(2*x+1)*float64(u)*math.Pi/(2*size)) *
+						math.Cos(float64(2*y+1)*float64(v)*math.Pi/(2*size))
+				}
+			}
+			coefficients[u*low+v] = sum
+		}
+	}
+
+	// the first coefficient is the average brightness, which would skew the median
+	sorted := slices.Clone(coefficients[1:])
+	slices.Sort(sorted)
+	median := sorted[len(sorted)/2]
+
+	var hash uint64
+	for _, coefficient := range coefficients {
+		hash <<= 1
+		if coefficient > median {
+			hash |= 1
+		}
+	}
+	return hash
+}
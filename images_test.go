@@ -0,0 +1,110 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/image/draw"
+)
+
+// testImage draws a pattern which differs visibly with seed.
+func testImage(size, seed int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			v := uint8((x*255/size + seed*97) % 256)
+			if (x*8/size+y*8/size+seed)%2 == 0 {
+				v = 255 - uint8(y*255/size)
+			}
+			img.Set(x, y, color.RGBA{R: v, G: v / 2, B: 255 - v, A: 255})
+		}
+	}
+	return img
+}
+
+func writeImage(t *testing.T, path string, img image.Image) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create %s: %v", path, err)
+	}
+	defer func() { _ = f.Close() }()
+	if filepath.Ext(path) == ".png" {
+		err = png.Encode(f, img)
+	} else {
+		err = jpeg.Encode(f, img, &jpeg.Options{Quality: 60})
+	}
+	if err != nil {
+		t.Fatalf("failed to encode %s: %v", path, err)
+	}
+}
+
+func resized(img image.Image, size int) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return dst
+}
+
+func TestImageHashes(t *testing.T) {
+	t.Parallel()
+	original := testImage(256, 1)
+	smaller := resized(original, 100)
+	different := testImage(256, 4)
+
+	for name, hash := range map[string]func(image.Image) uint64{"phash": pHash, "dhash": dHash} {
+		if d := bits.OnesCount64(hash(original) ^ hash(smaller)); d > 10 {
+			t.Errorf("%s: expected a resized copy to be similar, distance %d", name, d)
+		}
+		if d := bits.OnesCount64(hash(original) ^ hash(different)); d <= 10 {
+			t.Errorf("%s: expected a different image to be dissimilar, distance %d", name, d)
+		}
+	}
+}
+
+func TestCLI_Run_MatchImages(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	original := testImage(256, 1)
+	writeImage(t, filepath.Join(dir, "IMG_0001.png"), original)
+	writeImage(t, filepath.Join(dir, "thumbnail.jpg"), resized(original, 120))
+	writeImage(t, filepath.Join(dir, "other.png"), testImage(256, 4))
+	createTestFile(t, filepath.Join(dir, "broken.jpg"), "not an image")
+
+	for _, hash := range []string{"phash", "dhash"} {
+		cli := &CLI{Path: []string{dir}, Match: "image", ImageHash: hash, ImageThreshold: 10, Delete: true, Out: filepath.Join(t.TempDir(), "results.txt"), SkipErrors: true, Regex: []string{defaultRegex}}
+		cli.DryRun = hash == "phash"
+		if err := cli.Run(nil); err != nil {
+			t.Fatalf("%s: unexpected error: %v", hash, err)
+		}
+		if cli.skipped != 1 {
+			t.Errorf("%s: expected the undecodable image to be skipped, got %d", hash, cli.skipped)
+		}
+	}
+	if !fileExists(filepath.Join(dir, "IMG_0001.png")) || !fileExists(filepath.Join(dir, "other.png")) {
+		t.Error("the highest resolution copy and unrelated images must be kept")
+	}
+	if fileExists(filepath.Join(dir, "thumbnail.jpg")) {
+		t.Error("the lower resolution copy should be deleted")
+	}
+}
+
+func TestGroupSimilar(t *testing.T) {
+	t.Parallel()
+	values := []int{1, 10, 2, 30, 11, 3}
+	got := groupSimilar(len(values), func(i, j int) bool {
+		d := values[i] - values[j]
+		return d >= -1 && d <= 1
+	})
+	// 1-2-3 are joined through 2, even though 1 and 3 aren't similar
+	want := [][]int{{0, 2, 5}, {1, 4}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// syncPreset is the --preset which also cleans up the temporary files sync tools leave behind.
+const syncPreset = "sync"
+
+// syncTempDirs are the directories rsync keeps unfinished transfers in: .~tmp~ with --delay-updates, and
+// .rsync-partial with --partial-dir. Every file within one is a leftover.
+var syncTempDirs = []string{".~tmp~", ".rsync-partial"}
+
+// syncLeftovers match the names of the temporary files sync tools leave behind when interrupted.
+var syncLeftovers = []*regexp.Regexp{
+	// Unison, as in .unison.book.pdf.a1b2c3.unison.tmp
+	regexp.MustCompile(`^\.unison\..+`),
+	// FUSE filesystems, such as NTFS-3G and sshfs, for files deleted while still open
+	regexp.MustCompile(`^\.fuse_hidden[0-9a-fA-F]+$`),
+	// Syncthing
+	regexp.MustCompile(`^\.syncthing\..+\.tmp$`),
+	regexp.MustCompile(`^~syncthing~.+\.tmp$`),
+}
+
+// leftoverMinAge is how long a leftover must have gone unmodified before it's cleaned up, as a sync which is still
+// running may be writing to it.
+const leftoverMinAge = 24 * time.Hour
+
+// cleansSync reports whether --preset sync was given.
+func (c *CLI) cleansSync() bool {
+	return slices.Contains(c.Preset, syncPreset)
+}
+
+// isSyncTempDir reports whether path is a directory where rsync keeps unfinished transfers.
+func isSyncTempDir(path string) bool {
+	return slices.Contains(syncTempDirs, filepath.Base(path))
+}
+
+// isSyncLeftover reports whether the file at path was left behind by a sync tool, by its name or its directory's.
+func isSyncLeftover(path string) bool {
+	if isSyncTempDir(parentDir(path)) {
+		return true
+	}
+	name := filepath.Base(path)
+	return slices.ContainsFunc(syncLeftovers, func(re *regexp.Regexp) bool { return re.MatchString(name) })
+}
+
+// removeLeftovers deletes the sync leftovers found by the last scan, when files are being deleted. Leftovers which
+// have been written to since the scan are left alone.
+func (c *CLI) removeLeftovers(ctx context.Context) []action {
+	if !c.Delete || c.DryRun || ctx.Err() != nil {
+		return nil
+	}
+	var cleaned []action
+	for _, path := range slices.Sorted(slices.Values(c.leftovers)) {
+		if info, err := c.stat(path); err != nil || time.Since(info.ModTime()) < leftoverMinAge {
+			continue
+		}
+		a := action{Op: opDelete, Path: path, Err: c.discard(ctx, path)}
+		c.progress.acted(a)
+		cleaned = append(cleaned, a)
+	}
+	return cleaned
+}
+
+// renderLeftovers lists the sync leftovers found by --preset sync, or what was done with them.
+func renderLeftovers(paths []string, cleaned []action, markdown bool) string {
+	return renderSetAside("Sync leftovers", paths, cleaned, markdown)
+}
+
+// leftover reports whether the entry at path is a sync leftover, which is set aside from the scan. Those which are
+// old enough to be cleaned up are recorded.
+func (c *CLI) leftover(path string, info os.FileInfo) bool {
+	if !c.cleansSync() || !isSyncLeftover(path) || !info.Mode().IsRegular() {
+		return false
+	}
+	if time.Since(info.ModTime()) >= leftoverMinAge {
+		c.leftovers = append(c.leftovers, path)
+	}
+	return true
+}
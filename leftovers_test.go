@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCLI_Run_SyncPreset(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	if err := os.Mkdir(filepath.Join(dir, ".~tmp~"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * leftoverMinAge)
+	for _, name := range []string{"book.pdf", "book (1).pdf", ".unison.notes.txt.1f2e.unison.tmp", ".~tmp~/song.mp3", ".fuse_hidden000001a400000002", ".syncthing.photo.jpg.tmp", "~syncthing~photo.jpg.tmp", ".fuse_hidden0000001b00000003"} {
+		path := filepath.Join(dir, name)
+		createTestFile(t, path, "content")
+		if name != ".fuse_hidden0000001b00000003" {
+			if err := os.Chtimes(path, old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	out := filepath.Join(t.TempDir(), "results.txt")
+	cli := &CLI{Path: []string{dir}, Preset: []string{syncPreset}, Delete: true, Out: out, Regex: []string{defaultRegex}}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, name := range []string{"book (1).pdf", ".unison.notes.txt.1f2e.unison.tmp", ".~tmp~/song.mp3", ".fuse_hidden000001a400000002", ".syncthing.photo.jpg.tmp", "~syncthing~photo.jpg.tmp"} {
+		if fileExists(filepath.Join(dir, name)) {
+			t.Errorf("%s should be deleted", name)
+		}
+	}
+	for _, name := range []string{"book.pdf", ".fuse_hidden0000001b00000003"} {
+		if !fileExists(filepath.Join(dir, name)) {
+			t.Errorf("%s should be kept", name)
+		}
+	}
+	results, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(results), "Sync leftovers:\n") {
+		t.Errorf("results don't list the sync leftovers:\n%s", results)
+	}
+}
+
+func TestCLI_Run_SyncPreset_PruneEmptyDirs(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	tmp := filepath.Join(dir, "music", ".~tmp~")
+	if err := os.MkdirAll(tmp, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	leftover := filepath.Join(tmp, "song.mp3")
+	createTestFile(t, leftover, "content")
+	old := time.Now().Add(-2 * leftoverMinAge)
+	if err := os.Chtimes(leftover, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(t.TempDir(), "results.txt")
+	cli := &CLI{Path: []string{dir}, Preset: []string{syncPreset}, Delete: true, PruneEmptyDirs: true, Out: out, Regex: []string{defaultRegex}}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, gone := range []string{tmp, filepath.Join(dir, "music")} {
+		if fileExists(gone) {
+			t.Errorf("%s was emptied by cleaning up leftovers, so should be removed", gone)
+		}
+	}
+	results, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(results), tmp) {
+		t.Errorf("results don't list the pruned directory with the sync leftovers:\n%s", results)
+	}
+}
+
+func TestIsSyncLeftover(t *testing.T) {
+	t.Parallel()
+	for path, want := range map[string]bool{
+		"/media/.unison.book.pdf.a1b2.unison.tmp": true,
+		"/media/.~tmp~/book.pdf":                  true,
+		"/media/.rsync-partial/book.pdf":          true,
+		"/media/.fuse_hidden0000000a00000001":     true,
+		"/media/.fuse_hidden_notes":               false,
+		"/media/unison.book.pdf":                  false,
+		"/media/.syncthing.book.pdf":              false,
+		"/media/book.pdf":                         false,
+	} {
+		if got := isSyncLeftover(path); got != want {
+			t.Errorf("isSyncLeftover(%q) = %t, want %t", path, got, want)
+		}
+	}
+}
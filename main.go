@@ -1,178 +1,1080 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"regexp"
-	"sort"
+	"slices"
 	"strings"
+	"syscall"
+	"testing/fstest"
+	"time"
 
 	"github.com/alecthomas/kong"
 )
 
+// defaultPattern matches names like "book (1).pdf", capturing the base name, copy number, and extension.
+const defaultPattern = `(.+)\s\((\d+)\)\.(` + defaultExtensions + `)$`
+
 var (
 	version = "dev"
 	commit  = "none"
 	date    = "unknown"
 )
 
+// App is the root of the command line, with scanning as the default command.
+type App struct {
+	Version versionFlag `help:"Show version information."`
+	JSON    bool        `name:"json" help:"With --version, print version information as JSON."`
+
+	Lang string `name:"lang" env:"OHMAN_LANG" help:"Language of warnings and summaries, e.g. de, in place of the system's. Results are always in English."`
+
+	CPUProfile string `name:"cpuprofile" type:"path" help:"Write a CPU profile to this file, for go tool pprof."`
+	MemProfile string `name:"memprofile" type:"path" help:"Write a heap profile to this file when the run finishes, for go tool pprof."`
+	Trace      string `name:"trace" type:"path" help:"Write an execution trace to this file, for go tool trace."`
+
+	Scan   CLI       `cmd:"" default:"withargs" help:"Find and optionally remove duplicate files (default)."`
+	Watch  WatchCmd  `cmd:"" help:"Watch directories and process new duplicates as they appear."`
+	Daemon DaemonCmd `cmd:"" help:"Keep running and scan on a cron-like schedule."`
+
+	SystemdUnit SystemdUnitCmd `cmd:"" name:"systemd-unit" help:"Print a systemd service unit which runs the daemon."`
+	Serve       ServeCmd       `cmd:"" help:"Serve a REST API to start scans, poll their progress, and approve deletions."`
+	Completion  CompletionCmd  `cmd:"" help:"Print a shell completion script."`
+	History     HistoryCmd     `cmd:"" help:"List previous runs, or show the results of one."`
+	Report      ReportCmd      `cmd:"" help:"Render saved results in another format, without scanning again."`
+	Plan        PlanCmd        `cmd:"" help:"Scan without changing anything, writing a CSV plan of what to keep and delete."`
+	Apply       ApplyCmd       `cmd:"" help:"Delete the files marked delete in a reviewed plan."`
+	TestRegex   TestRegexCmd   `cmd:"" name:"test-regex" help:"Show which files a regex matches and the originals it infers, without touching anything."`
+	Audit       AuditCmd       `cmd:"" help:"Check the records of an --audit-log."`
+	Compare     CompareCmd     `cmd:"" help:"Compare two trees, finding the files at the same path within both and those only within one."`
+}
+
 type CLI struct {
-	Version          kong.VersionFlag `help:"Show version information."`
-	DryRun           bool             `help:"[SAFE MODE] List duplicate files without making changes. Always test with this first!"`
-	Delete           bool             `help:"⚠️  WARNING: Permanently delete duplicate files. USE AT YOUR OWN RISK. No warranty provided."`
-	Inverse          bool             `help:"Inverse deletion, keeping only the newest file and deleting older ones."`
-	InverseAndRename bool             `name:"inverse-and-rename" help:"Inverse deletion and rename, keeping only the newest file and renaming it."`
-	Out              string           `name:"out" short:"o" help:"Output file for results." type:"path"`
-	Path             []string         `arg:"" name:"path" help:"Path(s) to search for duplicates." type:"path"`
-	Regex            string           `name:"regex" help:"⚠️  Custom regex for finding duplicates. USE AT YOUR OWN RISK - test with --dry-run first!" default:"(.+)\\s\\((\\d+)\\)\\.(pdf|mobi|mp4|epub|wav|mp3)$"`
+	DryRun           bool          `help:"[SAFE MODE] List duplicate files without making changes. Always test with this first!"`
+	Delete           bool          `help:"${delete_help}"`
+	Permanent        bool          `name:"permanent" help:"On Windows, delete files outright instead of moving them to the Recycle Bin."`
+	MtimeTolerance   time.Duration `name:"mtime-tolerance" help:"Treat files modified within this long of the newest (e.g. 2s, for FAT32 or cloud sync rounding) as just as new, when choosing the newest copy."`
+	PreferFormat     string        `name:"prefer-format" help:"When copies of the same work are in different formats, keep the preferred one, e.g. epub>mobi,flac>mp3,png>jpg." placeholder:"FORMATS"`
+	PreferPath       []string      `name:"prefer-path" help:"When a file is at the same path within more than one scan path, keep the one within this directory and treat the others as its duplicates, e.g. --prefer-path /mnt/library to clean up /mnt/inbox. Repeatable, most preferred first." type:"path" placeholder:"DIR"`
+	Explain          bool          `name:"explain" help:"Explain why each file was classified as an original, duplicate, keeper, or deletion: which pattern, rule, or policy, and which tie-break."`
+	KeepBestAudio    bool          `name:"keep-best-audio" help:"When deleting, keep the highest quality copy of each song, lossless before lossy and then the highest bitrate, as reported by ffprobe, rather than the original."`
+	Inverse          bool          `help:"Inverse deletion, keeping only the newest file and deleting older ones."`
+	InverseAndRename bool          `name:"inverse-and-rename" help:"Inverse deletion and rename, keeping only the newest file and renaming it."`
+	FailFast         bool          `name:"fail-fast" help:"Stop at the first failed delete or rename instead of continuing with the remaining files."`
+	Timeout          time.Duration `name:"timeout" help:"Stop the run once this much time has passed (e.g. 30m), finishing the current group and writing results. Disabled by default."`
+	MaxIOPS          int           `name:"max-iops" help:"Limit filesystem operations (stats, deletes, renames) per second. Unlimited by default."`
+	Retries          int           `name:"retries" help:"Retry a delete or rename failing with a transient error, such as EBUSY or ESTALE on a network filesystem, up to this many times." default:"3"`
+	RetryDelay       time.Duration `name:"retry-delay" help:"Wait this long before the first retry of a failed delete or rename, doubling the wait before each further one." default:"250ms"`
+	Bandwidth        byteSize      `name:"bandwidth" help:"Limit file reads to this many bytes per second (e.g. 20MB). Unlimited by default."`
+	AdaptiveThrottle bool          `name:"adaptive-throttle" help:"Back off while other processes keep the disks holding the paths busy (Linux only)."`
+	Lock             string        `name:"lock" help:"Prevent concurrent destructive runs over the same files: ${enum}." enum:"root,global,none" default:"root"`
+	LockDir          string        `name:"lock-dir" help:"Directory for lock files. Defaults to ohman-locks in the system temp directory." type:"path"`
+	Format           string        `name:"format" help:"Output format: ${enum}." enum:"text,fdupes,markdown,json,dirs" default:"text"`
+	Jobs             int           `name:"jobs" short:"j" help:"Compare the content of up to this many groups at once. Defaults to the number of CPUs, or 1 with --nice." placeholder:"N"`
+	JobsPerDisk      int           `name:"jobs-per-disk" help:"Compare the content of at most this many groups at once on each disk. Defaults to 1 on spinning disks (Linux only), and only --jobs elsewhere." placeholder:"N"`
+	Nice             bool          `name:"nice" help:"Run at the lowest CPU and I/O priority, comparing one group and listing one directory at a time unless --jobs is given, so a scan on a NAS doesn't slow down its file shares."`
+	Quiet            bool          `name:"quiet" short:"q" help:"Print only a summary of the run and any errors, instead of a line for each file."`
+	NoColor          bool          `name:"no-color" help:"Never color text output, even on a terminal. Setting $NO_COLOR does the same."`
+	Out              string        `name:"out" short:"o" help:"Output file for results, or - for stdout." type:"path"`
+	Append           bool          `name:"append" help:"Add results to the end of --out, or results.txt, under the time of the run, rather than replacing what's there."`
+	Stream           bool          `name:"stream" help:"Process and write out each directory's groups as soon as it has been scanned, rather than holding every group until the end, to keep memory use flat when scanning millions of files."`
+	WriteChecksums   string        `name:"write-checksums" help:"Write the SHA-256 of every file kept in each group to this file, in the format of sha256sum." type:"path" placeholder:"FILE"`
+	Empty            string        `name:"empty" help:"What to do with zero-byte files, which trivially match one another: match them like any other (match), ignore them (skip), list them separately (list), or also delete them with --delete (delete)." enum:"match,skip,list,delete" default:"match"`
+	Filter           string        `name:"filter" help:"Only act on duplicates matching this expression of their size, age, path, name, dir, ext, original, dupes, and orphan, e.g. 'size > 1MB && age > 30d && dir !~ \"Work\"'." placeholder:"EXPR"`
+	Script           string        `name:"script" help:"Starlark script whose on_group(group) hook may choose the file to keep in each group, or leave it alone, and whose post_delete(path) hook is called after each deletion." type:"existingfile" placeholder:"FILE"`
+	PluginMatcher    string        `name:"plugin-matcher" help:"Command which groups the scanned files into duplicates, in place of --match, exchanging JSON over stdin and stdout." placeholder:"COMMAND"`
+	PluginKeep       string        `name:"plugin-keep" help:"Command which chooses the file to keep in each group, exchanging JSON over stdin and stdout." placeholder:"COMMAND"`
+	PluginAction     string        `name:"plugin-action" help:"Command which does away with each duplicate in place of deleting it, e.g. by moving it to a recycle bin, exchanging JSON over stdin and stdout." placeholder:"COMMAND"`
+	PartialDownloads bool          `name:"partial-downloads" help:"Also find the .part, .crdownload, and .!ut files of unfinished downloads whose completed file is next to them, as its duplicates. Only those left unmodified for a day are found."`
+	MinDupes         int           `name:"min-dupes" help:"Only report or act on files with at least this many duplicates." default:"1"`
+	AdoptOrphans     string        `name:"adopt-orphans" help:"When only copies of a file remain, rename one to the original name and delete the rest, choosing the ${enum} copy. Disabled by default." enum:"none,lowest,newest" default:"none"`
+	Match            string        `name:"match" help:"How duplicates are found: by copy suffixes in names (name), by how images look, even when resized or re-encoded (image), by how songs sound, even in other formats or bitrates (audio), by artist, album, and title tags (tags), by duration, resolution, and metadata, even in other encodes (video), by when and with which camera photos were taken (exif), or whole directories named as copies, like \"Photos (1)\", holding the same files as the original (dirs)." enum:"name,image,audio,tags,video,exif,dirs" default:"name"`
+	PruneEmptyDirs   bool          `name:"prune-empty-dirs" help:"Remove directories left empty by deleting duplicates, up to the paths searched."`
+	MergeDirs        bool          `name:"merge-dirs" help:"With --match dirs, move the files only a copied directory holds into the original, so the copy can be removed even when it isn't identical."`
+	ImageHash        string        `name:"image-hash" help:"Perceptual hash for --match image: ${enum}. phash tolerates brightness and contrast changes better; dhash is faster." enum:"phash,dhash" default:"phash"`
+	ImageThreshold   int           `name:"image-threshold" help:"Most bits (of 64) by which two images' hashes may differ for them to be duplicates. Lower is stricter." default:"10"`
+	AudioThreshold   float64       `name:"audio-threshold" help:"Largest fraction (0-1) of two songs' fingerprints which may differ for them to be duplicates. Lower is stricter." default:"0.15"`
+	Fpcalc           string        `name:"fpcalc" help:"Chromaprint's fpcalc, which fingerprints songs for --match audio." default:"fpcalc"`
+	VideoSlack       time.Duration `name:"video-duration-slack" help:"Most by which two videos' durations may differ for them to be duplicates." default:"2s"`
+	Ffprobe          string        `name:"ffprobe" help:"FFmpeg's ffprobe, or a command with compatible JSON output, which probes videos for --match video and songs for --keep-best-audio." default:"ffprobe"`
+	Diff             bool          `name:"diff" help:"Describe how each differing duplicate compares to its original: size, modification time, and for text files, a diff."`
+	MaxSizeDiff      float64       `name:"max-size-diff" help:"Leave groups alone when a copy's size differs from the kept file's by more than this percentage, even with --allow-different or --match other than name. Disabled by default." placeholder:"PERCENT"`
+	AllowDifferent   bool          `name:"allow-different" help:"Act on duplicates even when their content differs from the original's, skipping the comparison."`
+	ForceRoot        bool          `name:"force-root" help:"Allow deleting within a filesystem root, home directory, or mount point."`
+	Protect          []string      `name:"protect" help:"Never delete, rename, or replace this file or anything within this directory. Repeatable; also read from $OHMAN_PROTECT." type:"path"`
+	MediaServer      string        `name:"media-server" help:"Plex or Jellyfin server URL to consult before deleting: the copy it references, with the most plays, is kept, and the library is refreshed afterward."`
+	MediaServerType  string        `name:"media-server-type" help:"Kind of --media-server: ${enum}." enum:"plex,jellyfin" default:"plex"`
+	MediaServerToken string        `name:"media-server-token" env:"OHMAN_MEDIA_SERVER_TOKEN" help:"Access token for --media-server (a Plex token or Jellyfin API key)."`
+	AuditLog         string        `name:"audit-log" env:"OHMAN_AUDIT_LOG" help:"Append a JSON line to this file for every file deleted or renamed, with its size and hash beforehand and the outcome, whatever else is written." type:"path"`
+	History          bool          `name:"history" negatable:"" default:"true" help:"Record the run's results in the history, for ohman history."`
+	HistoryDir       string        `name:"history-dir" help:"Directory holding the history of runs. Defaults to ohman/history in your config directory." type:"path"`
+	HistoryKeep      int           `name:"history-keep" help:"Keep only this many of the newest runs in the history, deleting older ones; 0 keeps every run." default:"100"`
+	Webhook          string        `name:"webhook" help:"POST a JSON summary to this URL when the run completes or fails."`
+	WebhookResults   bool          `name:"webhook-results" help:"Include every group and action in the --webhook payload, not just the summary."`
+	Notify           string        `name:"notify" help:"Post a readable summary of each run to a chat service's incoming webhook, given by --notify-url: ${enum}." enum:"none,slack,discord" default:"none"`
+	NotifyURL        string        `name:"notify-url" env:"OHMAN_NOTIFY_URL" help:"Incoming webhook URL of the Slack or Discord channel to --notify." placeholder:"URL"`
+	DesktopNotify    bool          `name:"desktop-notify" help:"Pop up a desktop notification with the duplicate count when a long run finishes."`
+	DesktopAfter     time.Duration `name:"desktop-notify-after" help:"Only pop up a --desktop-notify notification for runs taking at least this long." default:"30s"`
+	OTLPEndpoint     string        `name:"otlp-endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" help:"Send traces of the scan, compare, and action phases to this OpenTelemetry collector, with OTLP over HTTP (e.g. http://localhost:4318)." placeholder:"URL"`
+	S3Endpoint       string        `name:"s3-endpoint" env:"AWS_ENDPOINT_URL_S3,AWS_ENDPOINT_URL" help:"Endpoint of an S3-compatible service such as MinIO (e.g. http://localhost:9000), for s3:// paths. Defaults to AWS."`
+	S3Region         string        `name:"s3-region" env:"AWS_REGION,AWS_DEFAULT_REGION" help:"Region of the buckets named by s3:// paths." default:"us-east-1"`
+	GDriveToken      string        `name:"gdrive-token" env:"OHMAN_GDRIVE_TOKEN" help:"OAuth access token for gdrive:// paths, with the drive scope (e.g. from gcloud auth print-access-token)."`
+	DropboxToken     string        `name:"dropbox-token" env:"OHMAN_DROPBOX_TOKEN" help:"Access token for dropbox:// paths, from an app with the files.metadata.read and files.content.write scopes."`
+	Remote           string        `name:"remote" help:"Scan and clean user@host:/path over SSH instead of local paths. Only a POSIX shell is needed on the host."`
+	SSH              string        `name:"ssh" help:"The ssh command used for --remote, with any options (e.g. \"ssh -p 2222\")." default:"ssh"`
+	Path             []string      `arg:"" optional:"" name:"path" help:"Path(s) to search for duplicates, s3://bucket/prefix URLs, or gdrive:// or dropbox:// folder paths. Use - to read newline-delimited paths from stdin." type:"location"`
+	PathsFrom        string        `name:"paths-from" help:"Read newline-delimited paths to search from this file, or - for stdin." type:"path"`
+	SkipErrors       bool          `name:"skip-errors" negatable:"" default:"true" help:"Skip files and directories which can't be read during the scan, warning about each instead of aborting."`
+	IncludeHidden    bool          `name:"include-hidden" help:"Also scan dotfiles and dot-directories, like .git, .cache, and .Trash, which are skipped by default."`
+	DefaultExcludes  bool          `name:"default-excludes" negatable:"" default:"true" help:"Skip system and trash directories, like $RECYCLE.BIN, System Volume Information, .Trashes, lost+found, /proc, and /sys."`
+	IncludeSnapshots bool          `name:"include-snapshots" help:"Also scan read-only snapshot directories, like .zfs, .snapshot, #snapshot, and read-only Btrfs subvolumes, which are skipped by default."`
+	PatternsFile     string        `name:"patterns-file" help:"Find copies with the regexes listed in this file, one per line, in place of the default --regex." type:"path"`
+	Regex            []string      `name:"regex" sep:"none" help:"⚠️  Custom regex for finding duplicates. Repeatable. USE AT YOUR OWN RISK - test with --dry-run first!" default:"${default_pattern}"`
+	Preset           []string      `name:"preset" help:"Also find copies named by localized versions of Windows and macOS, like \"book - Kopie.pdf\" or \"book のコピー.pdf\", for these languages: de, es, fr, it, ja, ko, nl, pl, pt, ru, sv, zh, or localized for all of them. The finder preset finds the macOS Finder's copies in any language and of any file, like \"book copy 2.pdf\". The backups preset also finds backups like \"notes.txt.~1~\", \"notes.txt.bak\", and \"notes.txt.orig\". The sync preset also cleans up the temporary files left by rsync, Unison, Syncthing, and FUSE." placeholder:"LANG"`
+
+	// status is the exit code determined by the last call to Run.
+	status int
+	// streamed counts the groups processed by the last run with --stream, which aren't otherwise held.
+	streamed runCounts
+	// skipped counts entries which couldn't be read during the last scan.
+	skipped int
+	// empty holds the zero-byte files set aside by --empty during the last scan.
+	empty []string
+	// mirror groups the files at the same path within each scan path, for compare, rather than copies by name.
+	mirror bool
+	// alone holds the files found within only one scan path by the last scan with mirror set.
+	alone []string
+	// leftovers holds the files left behind by sync tools, found by the last scan with --preset sync.
+	leftovers []string
+	// filter is the compiled --filter expression; nil when none was given.
+	filter *filter
+	// script holds the hooks of --script; nil when none was given.
+	script *script
+	// matcher, keepPolicy, and disposer are the plugins given by --plugin-matcher, --plugin-keep, and --plugin-action;
+	// nil when none were given.
+	matcher    matcher
+	keepPolicy keepPolicy
+	disposer   disposer
+	// throttle paces filesystem operations; nil when no limits are set.
+	throttle *throttle
+	// progress, when set, is updated as files are scanned and acted upon.
+	progress *progress
+	// metrics, when set, totals each run for the daemon's /metrics.
+	metrics *metrics
+	// protected guards --protect paths against every delete and rename.
+	protected protector
+	// desktopNotifier shows --desktop-notify notifications; the platform's notifier is used when nil.
+	desktopNotifier func(ctx context.Context, title, body string) error
+	// fingerprint computes acoustic fingerprints for --match audio; fpcalc is used when nil.
+	fingerprint func(ctx context.Context, path string) (audioPrint, error)
+	// formats ranks the formats named by --prefer-format.
+	formats formatPrefs
+	// copies are the patterns which found copies by name in the last run.
+	copies copyPatterns
+	// runID identifies the run in progress in its results, audit log, notifications, and history.
+	runID string
+	// audit records every delete and rename in the --audit-log while a run is in progress; nil without one.
+	audit *auditLog
+	// library maps the paths known to --media-server to what it knows about them; nil without one.
+	library map[string]mediaItem
+	// probe reads the duration, dimensions, and metadata of videos for --match video; ffprobe is used when nil.
+	probe func(ctx context.Context, path string) (videoInfo, error)
+	// storage holds the files being scanned, chosen by files at the start of each run.
+	storage backend
+	// memory, when set, is scanned in place of the local filesystem.
+	memory fstest.MapFS
+	// s3 lists and changes objects when the paths are s3:// URLs; nil for local paths.
+	s3 *s3Client
+	// drive lists and changes files when the paths are gdrive:// paths; nil otherwise.
+	drive *driveClient
+	// dropbox lists and changes files when the paths are dropbox:// paths; nil otherwise.
+	dropbox *dropboxClient
+	// shell runs a script on the --remote host; ssh is used when nil.
+	shell func(ctx context.Context, target, script string, args ...string) ([]byte, error)
 }
 
-var cli CLI
+var app App
 
 type Context struct {
 	*kong.Context
+	// Ctx is cancelled when the run should stop early, e.g. on SIGINT or SIGTERM, with the reason as its cause. It may be nil.
+	Ctx context.Context
 }
 
-func (c *CLI) Run(_ *Context) error {
+var (
+	// errInterrupted is the cause of cancellation when a run is stopped early by a signal.
+	errInterrupted = errors.New("interrupted")
+	// errTimedOut is the cause of cancellation when a run exceeds --timeout.
+	errTimedOut = errors.New("timed out")
+)
+
+// context returns the context for the run, which is never nil.
+func (ctx *Context) context() context.Context {
+	if ctx == nil || ctx.Ctx == nil {
+		return context.Background()
+	}
+	return ctx.Ctx
+}
+
+func (c *CLI) Run(kctx *Context) error {
+	started := time.Now()
+	c.runID = newRunID(started)
+	tracer, err := newTracer(c.OTLPEndpoint)
+	if err != nil {
+		return err
+	}
+	if tracer != nil && c.progress == nil {
+		// for the number of files scanned
+		c.progress = &progress{}
+	}
+	ctx, span := tracer.root(kctx.context(), "ohman.run", "paths", c.Path, "delete", c.Delete && !c.DryRun)
+	traced := &Context{Ctx: ctx}
+	if kctx != nil {
+		traced.Context = kctx.Context
+	}
+	if c.audit, err = openAuditLog(c.AuditLog); err != nil {
+		return err
+	}
+	groups, err := c.run(traced)
+	if aerr := c.audit.close(); aerr != nil {
+		err = errors.Join(err, fmt.Errorf("failed to write the audit log %s: %w", c.AuditLog, aerr))
+	}
+	c.audit = nil
+	span.set("groups", len(groups))
+	span.finish(err)
+	if terr := tracer.flush(); terr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: unable to export traces: %v\n", terr)
+	}
+	if c.Webhook != "" {
+		// notify even when interrupted or timed out, as that's when a failure notification matters most
+		ctx := context.WithoutCancel(kctx.context())
+		if werr := notifyWebhook(ctx, c.Webhook, newWebhookPayload(c, started, groups, err)); werr != nil {
+			fmt.Fprintln(os.Stderr, tr("Warning: webhook notification failed: %v", werr))
+		}
+	}
+	if c.notifying() && c.NotifyURL != "" {
+		ctx := context.WithoutCancel(kctx.context())
+		if nerr := notifyWebhook(ctx, c.NotifyURL, c.chatPayload(started, groups, err)); nerr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s notification failed: %v\n", c.Notify, nerr)
+		}
+	}
+	c.notifyDesktop(context.WithoutCancel(kctx.context()), time.Since(started), groups, err)
+	if c.History {
+		c.recordHistory(started, groups, err)
+	}
+	c.metrics.recordScan(time.Since(started), c.progress.filesScanned(), groups, err)
+	c.metrics.recordActions(groups)
+	return err
+}
+
+// recordHistory saves the run to the history directory, warning when it can't.
+func (c *CLI) recordHistory(started time.Time, groups []group, runErr error) {
+	dir, err := historyDir(c.HistoryDir)
+	if err == nil {
+		_, err = saveHistory(dir, c, newWebhookPayload(c, started, groups, runErr), groups)
+	}
+	if err == nil {
+		err = pruneHistory(dir, c.HistoryKeep)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: unable to record the run in the history: %v\n", err)
+	}
+}
+
+// setupPolicy compiles the options which decide what's found and what's done with it, for scans and watches alike:
+// the copy patterns, which it returns, --prefer-format, --filter, --script, and the plugins. It rejects options which
+// can't be combined.
+func (c *CLI) setupPolicy() (copyPatterns, error) {
+	patterns, err := c.patterns()
+	if err != nil {
+		return nil, err
+	}
+	c.copies = patterns
+	if c.formats, err = parseFormatPrefs(c.PreferFormat); err != nil {
+		return nil, err
+	}
+	if c.filter, err = parseFilter(c.Filter); err != nil {
+		return nil, err
+	}
+	if c.script, err = loadScript(c.Script); err != nil {
+		return nil, err
+	}
+	if err := c.setupPlugins(); err != nil {
+		return nil, err
+	}
+	if c.KeepBestAudio && (c.Inverse || c.InverseAndRename) {
+		return nil, fmt.Errorf("--keep-best-audio can't be combined with --inverse or --inverse-and-rename, which keep the newest copy")
+	}
+	if c.Match == "dirs" && (c.Inverse || c.InverseAndRename) {
+		return nil, fmt.Errorf("--match dirs can't be combined with --inverse or --inverse-and-rename, as copied directories are merged into the original")
+	}
+	if c.MediaServer != "" && (c.Inverse || c.InverseAndRename) {
+		return nil, fmt.Errorf("--media-server can't be combined with --inverse or --inverse-and-rename, which always keep the newest copy")
+	}
+	if c.PartialDownloads && (c.Inverse || c.InverseAndRename) {
+		return nil, fmt.Errorf("--partial-downloads can't be combined with --inverse or --inverse-and-rename, which could keep a partial download")
+	}
+	if c.PartialDownloads && (c.Match != "" && c.Match != "name" || c.matcher != nil) {
+		return nil, fmt.Errorf("--partial-downloads only works with the default matching by name")
+	}
+	if len(c.PreferPath) > 0 && (c.Inverse || c.InverseAndRename) {
+		return nil, fmt.Errorf("--prefer-path can't be combined with --inverse or --inverse-and-rename, which always keep the newest copy")
+	}
+	if len(c.PreferPath) > 0 && (c.Match != "" && c.Match != "name" || c.matcher != nil) {
+		return nil, fmt.Errorf("--prefer-path only works with the default matching by name")
+	}
+	return patterns, nil
+}
+
+// run performs the scan and any requested operations, returning the groups processed so far along with any error.
+func (c *CLI) run(kctx *Context) ([]group, error) {
+	c.storage = nil
+	if err := c.resolvePaths(os.Stdin); err != nil {
+		return nil, err
+	}
+	if err := c.setupRemote(); err != nil {
+		return nil, err
+	}
 	if len(c.Path) == 0 {
-		return fmt.Errorf("at least one path must be specified")
+		return nil, fmt.Errorf("at least one path must be specified")
+	}
+	patterns, err := c.setupPolicy()
+	if err != nil {
+		return nil, err
+	}
+	if c.notifying() && c.NotifyURL == "" {
+		return nil, fmt.Errorf("--notify %s needs the channel's incoming webhook URL in --notify-url", c.Notify)
+	}
+	if err := c.setupS3(); err != nil {
+		return nil, err
+	}
+	if err := c.setupDrive(); err != nil {
+		return nil, err
+	}
+	if err := c.setupDropbox(); err != nil {
+		return nil, err
+	}
+	if conflict := c.streamConflict(); c.Stream && conflict != "" {
+		return nil, fmt.Errorf("--stream can't be combined with %s, which needs every group at once", conflict)
+	}
+
+	ctx := kctx.context()
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, c.Timeout, fmt.Errorf("%w after %s", errTimedOut, c.Timeout))
+		defer cancel()
+	}
+
+	c.beNice()
+	c.throttle = newThrottle(c.MaxIOPS, c.Bandwidth, c.AdaptiveThrottle, c.Path)
+	if c.protected, err = newProtector(c.Protect); err != nil {
+		return nil, err
+	}
+	if err := c.checkRoots(); err != nil {
+		return nil, err
+	}
+
+	if c.Delete && !c.DryRun {
+		c.warnSnapshots()
+		lock, err := acquireRunLock(c.LockDir, c.Lock, c.Path)
+		if err != nil {
+			return nil, err
+		}
+		defer lock.release()
+	}
+	if c.Stream {
+		return nil, c.runStream(ctx, patterns)
 	}
-	re, err := regexp.Compile(c.Regex)
+
+	server, library, err := c.loadLibrary(ctx)
 	if err != nil {
-		return fmt.Errorf("invalid regex: %w", err)
+		return nil, err
+	}
+	c.library = library
+
+	found, err := (&Scanner{cli: c, patterns: patterns}).Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if c.skipped > 0 {
+		fmt.Fprintln(os.Stderr, tr("Warning: skipped %d inaccessible entries", c.skipped))
+	}
+
+	groups, stopped := c.executor().Execute(ctx, found)
+	if c.Delete && !c.DryRun {
+		refreshLibrary(context.WithoutCancel(ctx), server, groups)
+	}
+	if differing := countMismatched(groups); differing > 0 {
+		fmt.Fprintln(os.Stderr, tr("Warning: %d group(s) have copies whose content differs from the original. These groups are never changed unless --allow-different is given.", differing))
+	}
+
+	emptied := c.removeEmpty(ctx)
+	cleaned := c.removeLeftovers(ctx)
+	if c.PruneEmptyDirs && c.Delete && !c.DryRun {
+		emptied, cleaned = c.pruneEmptyDirs(context.WithoutCancel(ctx), groups, emptied, cleaned)
+	}
+
+	c.status = exitStatus(groups)
+	if slices.ContainsFunc(slices.Concat(emptied, cleaned), func(a action) bool { return a.Err != nil }) {
+		c.status = exitPartialFailure
+	}
+	toStdout := c.Out == stdoutPath || (c.Out == "" && !c.Delete)
+	var colors palette
+	if toStdout {
+		colors = newPalette(os.Stdout, c.NoColor)
+	}
+	output := renderColored(c.Format, groups, colors)
+	if c.Format == "dirs" {
+		output = renderDirs(c.files(), groups)
+	}
+	if c.Diff && countMismatched(groups) > 0 {
+		report := renderDiffs(c.files(), groups, c.Format == "markdown")
+		if c.Format == "fdupes" || c.Format == "json" || c.Format == "plan" {
+			// keep the output parseable
+			fmt.Fprint(os.Stderr, report)
+		} else {
+			output = strings.TrimSuffix(output, "\n") + "\n\n" + strings.TrimSuffix(report, "\n")
+		}
+	}
+	if (c.Empty == "list" || c.Empty == "delete") && len(c.empty) > 0 {
+		report := renderEmpty(c.empty, emptied, c.Format == "markdown")
+		if c.Format == "fdupes" || c.Format == "json" || c.Format == "plan" {
+			fmt.Fprint(os.Stderr, report)
+		} else {
+			output = strings.TrimSuffix(output, "\n") + "\n\n" + strings.TrimSuffix(report, "\n")
+		}
+	}
+	if c.cleansSync() && len(c.leftovers) > 0 {
+		report := renderLeftovers(c.leftovers, cleaned, c.Format == "markdown")
+		if c.Format == "fdupes" || c.Format == "json" || c.Format == "plan" {
+			fmt.Fprint(os.Stderr, report)
+		} else {
+			output = strings.TrimSuffix(output, "\n") + "\n\n" + strings.TrimSuffix(report, "\n")
+		}
+	}
+	if c.mirror && len(c.alone) > 0 {
+		report := renderAlone(c.Path, c.alone, c.Format == "markdown")
+		if c.Format == "fdupes" || c.Format == "json" || c.Format == "plan" {
+			fmt.Fprint(os.Stderr, report)
+		} else {
+			output = strings.TrimSuffix(output, "\n") + "\n\n" + strings.TrimSuffix(report, "\n")
+		}
+	}
+
+	switch {
+	case c.Out != "":
+		err = c.outputResults(c.Out, output)
+	case c.Delete:
+		err = c.outputResults("results.txt", output)
+	case !c.Quiet:
+		fmt.Println(headResults(c.Format, c.runID, output))
+	}
+	if err != nil {
+		return groups, err
+	}
+	if c.Quiet {
+		summary := c.summary(countGroups(groups))
+		if c.Out == stdoutPath {
+			// stdout is for the results, which --out - prints even so
+			fmt.Fprintln(os.Stderr, summary)
+		} else {
+			fmt.Println(summary)
+		}
+	}
+	if c.WriteChecksums != "" {
+		if err := c.writeChecksums(context.WithoutCancel(ctx), c.WriteChecksums, keptFiles(groups)); err != nil {
+			return groups, errors.Join(err, collectFailures(groups))
+		}
 	}
 
-	// Map to store original files and their duplicates
-	files := make(map[string][]string)
+	if stopped {
+		err := fmt.Errorf("%w after processing %d groups; results contain only completed groups", context.Cause(ctx), len(groups))
+		return groups, emptyFailures(errors.Join(err, collectFailures(groups)), slices.Concat(emptied, cleaned))
+	}
+	return groups, emptyFailures(collectFailures(groups), slices.Concat(emptied, cleaned))
+}
+
+// apply acts on each original and its duplicates according to the configured mode, returning the resulting groups.
+// stopped reports whether ctx was done before every group could be processed.
+func (c *CLI) apply(ctx context.Context, files map[string][]string) (groups []group, stopped bool) {
+	return c.executor().Execute(ctx, c.groupFiles(ctx, files))
+}
+
+// groupFiles turns files, mapping originals to their duplicates, into groups, comparing each duplicate's content
+// with its original's. Groups are in order of their original's path, and duplicates in order of theirs, so
+// successive runs report the same way and can be diffed.
+func (c *CLI) groupFiles(ctx context.Context, files map[string][]string) []group {
+	var found []group
+	for _, original := range slices.Sorted(maps.Keys(files)) {
+		duplicates := slices.Sorted(slices.Values(files[original]))
+		if len(duplicates) == 0 || len(duplicates) < c.MinDupes {
+			continue
+		}
+
+		g := group{Original: original, Duplicates: duplicates, RunID: c.runID}
+
+		// Check if the original file actually exists
+		if _, err := c.stat(original); os.IsNotExist(err) {
+			if c.AdoptOrphans == "" || c.AdoptOrphans == "none" {
+				continue
+			}
+			g.Orphan = true
+		}
+		if c.filter != nil {
+			// duplicates which don't match are left alone, as though they hadn't been found
+			g.Duplicates = c.filter.keep(g, c.stat, time.Now())
+			if len(g.Duplicates) == 0 || len(g.Duplicates) < c.MinDupes {
+				continue
+			}
+		}
+		// so are hard links to the original, which are the original
+		c.dropHardLinks(&g)
+		if len(g.Duplicates) == 0 || len(g.Duplicates) < c.MinDupes {
+			continue
+		}
+		c.explainFound(&g)
+		if g.Orphan {
+			orderForAdoption(g.Duplicates, c.AdoptOrphans, c.stat, c.MtimeTolerance)
+			c.explainAdoption(&g)
+		}
+		kept := func() string {
+			if g.Orphan {
+				// the first duplicate is the one adopted
+				return g.Duplicates[0]
+			}
+			return g.Original
+		}
+		if len(c.formats) > 0 {
+			before := kept()
+			preferFormat(&g, c.formats)
+			c.explainSwap(&g, kept(), before, "--prefer-format prefers its format")
+		}
+		if c.library != nil {
+			before := kept()
+			preferReferenced(&g, c.library)
+			c.explainSwap(&g, kept(), before, "--media-server reports it was played the most")
+		}
+		found = append(found, g)
+	}
+	compareCtx, span := startSpan(ctx, "compare", "groups", len(found))
+	c.compareAll(compareCtx, found)
+	span.finish(nil)
+
+	for i := range found {
+		g := &found[i]
+		for _, d := range g.Mismatched {
+			c.explain(g, d, "its content differs from the original's, so the group is left alone")
+		}
+		if c.MaxSizeDiff > 0 && c.Match != "dirs" {
+			for _, d := range c.oversized(*g) {
+				if !slices.Contains(g.Mismatched, d) {
+					g.Mismatched = append(g.Mismatched, d)
+					c.explain(g, d, "its size differs from the original's by more than --max-size-diff, so the group is left alone")
+				}
+			}
+		}
+	}
+	return found
+}
+
+// scan walks each path, mapping inferred original files to the duplicates found for them.
+func (c *CLI) scan(ctx context.Context, patterns copyPatterns) (map[string][]string, error) {
+	index := newCopyIndex(patterns)
+	// files which aren't copies are only needed to find the same path within other scan paths
+	var found, partials []string
+	err := c.walk(ctx, func(path string, info os.FileInfo) {
+		if c.PartialDownloads {
+			if _, ok := partialOf(path); ok {
+				// a download may still be in progress, with its file already in place, as Firefox does
+				if time.Since(info.ModTime()) >= leftoverMinAge {
+					partials = append(partials, path)
+				}
+				return
+			}
+		}
+		if c.mirror {
+			found = append(found, path)
+			return
+		}
+		index.add(path)
+		if len(c.PreferPath) == 0 {
+			return
+		}
+		if _, copied := originalFor(patterns, path); !copied {
+			found = append(found, path)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	files := index.files()
+	if f, ok := c.files().(copyFinder); ok {
+		f.addCopies(patterns, files)
+	}
+	if len(c.PreferPath) > 0 {
+		c.alone = c.preferAcrossRoots(found, files)
+	}
+	if c.PartialDownloads {
+		c.addPartials(partials, files)
+	}
+	return files, nil
+}
 
+// walk calls visit for every file beneath the scan paths, skipping unreadable entries when --skip-errors is set.
+func (c *CLI) walk(ctx context.Context, visit func(path string, info os.FileInfo)) error {
+	c.skipped, c.empty, c.alone, c.leftovers = 0, nil, nil, nil
+	files := c.files()
+	seen := make(map[[2]uint64]string)
 	for _, p := range c.Path {
-		err := filepath.Walk(p, func(path string, info os.FileInfo, err error) error {
+		err := files.walk(ctx, p, func(path string, info os.FileInfo, err error) error {
+			if ctx.Err() != nil {
+				return context.Cause(ctx)
+			}
+			if err == nil && !info.IsDir() {
+				// a file which is gone, or can no longer be read, by the time it's statted is skipped like any other
+				err = statError(info)
+			}
+			if path != p && err == nil && c.cleansSync() {
+				// rsync's temporary directories are hidden, but what's left in them is cleaned up
+				if info.IsDir() && isSyncTempDir(path) {
+					return c.revisit(seen, path, info)
+				}
+				if c.leftover(path, info) {
+					return nil
+				}
+			}
+			// errors without an entry, such as a remote listing's, aren't about any one path
+			if path != p && (err == nil || info != nil) && c.leftOut(path, info) {
+				if info != nil && info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
 			if err != nil {
-				return err
-			}
-			if !info.IsDir() {
-				matches := re.FindStringSubmatch(filepath.Base(path))
-				if len(matches) > 0 {
-					// Compute the original file's full path
-					baseName := matches[1] + "." + matches[3]
-					originalPath := filepath.Join(filepath.Dir(path), baseName)
-					files[originalPath] = append(files[originalPath], path)
+				// A missing or unreadable root is always fatal; anything beneath it may be skipped.
+				if !c.SkipErrors || path == p {
+					return err
 				}
+				c.skip(path, err)
+				if info != nil && info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.IsDir() {
+				return c.revisit(seen, path, info)
+			}
+			if isSpecial(info.Mode()) {
+				// sockets, devices, and pipes are never copies, and reading them could block or have side effects
+				return nil
+			}
+			c.progress.fileScanned(path, info.Size())
+			if info.Size() == 0 && !c.emptyMatched() {
+				c.empty = append(c.empty, path)
+				return nil
 			}
+			visit(path, info)
 			return nil
 		})
 
+		if ctx.Err() != nil {
+			return fmt.Errorf("%w while scanning %s; no files were changed", context.Cause(ctx), p)
+		}
 		if err != nil {
 			return fmt.Errorf("error walking path %s: %v", p, err)
 		}
 	}
+	return nil
+}
+
+// revisit records the directory at path in seen, which maps the directories walked, by device and inode, to the paths
+// they were first reached by. It returns filepath.SkipDir when the directory was reached before, through a bind mount
+// or overlapping scan paths, so no directory is walked twice, or endlessly.
+func (c *CLI) revisit(seen map[[2]uint64]string, path string, info os.FileInfo) error {
+	id, ok := fileID(info)
+	if !ok {
+		return nil
+	}
+	if first, ok := seen[id]; ok {
+		if !c.Quiet {
+			fmt.Fprintln(os.Stderr, tr("Warning: skipping %s, which is %s, already scanned", path, first))
+		}
+		return filepath.SkipDir
+	}
+	seen[id] = path
+	return nil
+}
 
-	var results []string
+// hidden reports whether path is a dotfile or dot-directory to be left out of the scan, as it is unless
+// --include-hidden is set. Paths given to scan are never left out, however they're named.
+func (c *CLI) hidden(path string) bool {
+	name := filepath.Base(path)
+	return !c.IncludeHidden && strings.HasPrefix(name, ".") && name != "." && name != ".."
+}
 
-	for original, duplicates := range files {
-		if len(duplicates) == 0 {
-			continue
+// skip counts path as skipped because of err, warning about it unless --quiet is set.
+func (c *CLI) skip(path string, err error) {
+	c.skipped++
+	c.progress.skipped(path, err)
+	if !c.Quiet {
+		fmt.Fprintln(os.Stderr, tr("Warning: skipping %s: %v", path, err))
+	}
+}
+
+// copyGroups returns the indexes of re's groups capturing the original's name and extension: the groups named "name"
+// and "ext", or else the first and third. ext is -1 when re names its name group but not an ext group, so the name
+// is the whole of the original's.
+func copyGroups(re *regexp.Regexp) (name, ext int) {
+	name, ext = re.SubexpIndex("name"), re.SubexpIndex("ext")
+	if name < 0 {
+		name = 1
+		if ext < 0 {
+			ext = 3
 		}
+	}
+	return name, ext
+}
 
-		// Check if the original file actually exists
-		if _, err := os.Stat(original); os.IsNotExist(err) {
+// checkCopyGroups reports an error when re lacks the groups copyGroups needs.
+func checkCopyGroups(re *regexp.Regexp) error {
+	if name, ext := copyGroups(re); max(name, ext) > re.NumSubexp() {
+		return fmt.Errorf("regex %s must capture the original's name and extension, in groups named name and ext or in the first and third groups", re)
+	}
+	return nil
+}
+
+// copyPattern is a regex which finds copies, from --regex or --patterns-file.
+type copyPattern struct {
+	// label names the pattern in messages, from its line of a patterns file.
+	label string
+	re    *regexp.Regexp
+	// exts maps the lowercase extensions of copies to their originals', e.g. "jpeg" to "jpg", for copies whose
+	// extension was changed along with their name.
+	exts map[string]string
+}
+
+// copyPatterns are the patterns which find copies. Copies found by any of them are grouped together under their
+// original.
+type copyPatterns []copyPattern
+
+// strip returns name without the copy suffix of the first of p which matches it and finds a different name.
+func (p copyPatterns) strip(name string) (string, bool) {
+	_, _, base, ok := p.match(name)
+	return base, ok
+}
+
+// match returns the first of p which matches name and finds a different name, with its submatches and the name found.
+func (p copyPatterns) match(name string) (pattern copyPattern, matches []string, base string, ok bool) {
+	for _, pattern := range p {
+		matches := pattern.re.FindStringSubmatch(name)
+		if len(matches) == 0 {
 			continue
 		}
-
-		if c.DryRun {
-			results = append(results, fmt.Sprintf("Original: %s", original))
-			for _, d := range duplicates {
-				results = append(results, fmt.Sprintf("  - Duplicate: %s", d))
+		nameGroup, extGroup := copyGroups(pattern.re)
+		base := matches[nameGroup]
+		if extGroup >= 0 {
+			if ext := strings.TrimPrefix(matches[extGroup], "."); ext != "" {
+				if mapped, ok := pattern.exts[strings.ToLower(ext)]; ok {
+					ext = mapped
+				}
+				base += "." + ext
 			}
-			continue
 		}
+		if base != name {
+			return pattern, matches, base, true
+		}
+	}
+	return copyPattern{}, nil, "", false
+}
 
-		if c.Delete {
-			if c.Inverse || c.InverseAndRename {
-				// Keep the newest file
-				sort.Slice(duplicates, func(i, j int) bool {
-					infoI, _ := os.Stat(duplicates[i])
-					infoJ, _ := os.Stat(duplicates[j])
-					return infoI.ModTime().After(infoJ.ModTime())
-				})
-
-				newest := duplicates[0]
-				toDelete := duplicates[1:]
-				toDelete = append(toDelete, original)
-
-				for _, f := range toDelete {
-					err := os.Remove(f)
-					if err != nil {
-						results = append(results, fmt.Sprintf("Failed to delete %s: %v", f, err))
-					} else {
-						results = append(results, fmt.Sprintf("Deleted %s", f))
-					}
-				}
+// originalFor returns the original which path is a copy of. Copies of copies, like "book (1) (2).pdf" or
+// "book - Copy (1).pdf", are stripped of every suffix so the whole chain is grouped under the true original.
+func originalFor(patterns copyPatterns, path string) (string, bool) {
+	baseName, ok := patterns.strip(filepath.Base(path))
+	if !ok {
+		return "", false
+	}
+	for {
+		stripped, ok := patterns.strip(baseName)
+		if !ok {
+			break
+		}
+		baseName = stripped
+	}
+	if hasScheme(path) {
+		// joining would clean the URL's double slash away
+		return path[:strings.LastIndex(path, "/")+1] + baseName, true
+	}
+	return filepath.Join(filepath.Dir(path), baseName), true
+}
 
-				if c.InverseAndRename {
-					// The original has been deleted, so we can rename the newest to the original's name
-					err := os.Rename(newest, original)
-					if err != nil {
-						results = append(results, fmt.Sprintf("Failed to rename %s to %s: %v", newest, original, err))
-					} else {
-						results = append(results, fmt.Sprintf("Renamed %s to %s", newest, original))
-					}
-				} else {
-					results = append(results, fmt.Sprintf("Kept newest file: %s", newest))
-				}
+// process plans what's to be done with g by the keep policy and does it, recording each action on g. Failures are
+// only returned when --fail-fast is set; otherwise processing continues and they're collected later.
+func (c *CLI) process(ctx context.Context, g *group) error {
+	return c.executor().executeGroup(ctx, g)
+}
+
+// deleteFile removes the file at path, recording the bytes it frees with the deletion.
+func (c *CLI) deleteFile(ctx context.Context, path string) action {
+	a := action{Op: opDelete, Path: path}
+	if info, err := c.stat(path); err == nil && info.Mode().IsRegular() {
+		a.Size = freed(info)
+	}
+	a.Err = c.remove(ctx, path)
+	return a
+}
+
+// remove deletes the duplicate at path, subject to any throttling, or hands it to the action plugin. Protected paths
+// are never removed.
+func (c *CLI) remove(ctx context.Context, path string) error {
+	return c.removePath(ctx, path, c.disposer)
+}
+
+// discard deletes path, subject to any throttling, like remove, but never hands it to the action plugin, as it isn't a
+// duplicate: it's a directory left empty, an empty file, or a sync leftover.
+func (c *CLI) discard(ctx context.Context, path string) error {
+	return c.removePath(ctx, path, nil)
+}
 
+// removePath deletes path, or hands it to disposer when that's set.
+func (c *CLI) removePath(ctx context.Context, path string, disposer disposer) (err error) {
+	ctx, span := startSpan(ctx, "delete", "path", path)
+	defer func() { span.finish(err) }()
+	if err := c.protected.check(path); err != nil {
+		return err
+	}
+	if err := c.checkSpecial(path); err != nil {
+		return err
+	}
+	if err := c.throttle.op(ctx); err != nil {
+		return err
+	}
+	return c.audited(ctx, opDelete, path, "", func() error {
+		return c.retry(ctx, func(attempt int) error {
+			var err error
+			if disposer != nil {
+				err = disposer.dispose(ctx, path)
 			} else {
-				// Delete all duplicates
-				for _, d := range duplicates {
-					err := os.Remove(d)
-					if err != nil {
-						results = append(results, fmt.Sprintf("Failed to delete %s: %v", d, err))
-					} else {
-						results = append(results, fmt.Sprintf("Deleted %s", d))
-					}
+				err = c.files().remove(ctx, path)
+			}
+			if attempt > 1 && errors.Is(err, os.ErrNotExist) {
+				// an earlier attempt deleted it after all, though it reported failing
+				return nil
+			}
+			return err
+		})
+	})
+}
+
+// rename moves from to to, subject to any throttling. Protected paths are never moved or replaced.
+func (c *CLI) rename(ctx context.Context, from, to string) (err error) {
+	ctx, span := startSpan(ctx, "rename", "path", from, "target", to)
+	defer func() { span.finish(err) }()
+	if err := c.protected.check(from); err != nil {
+		return err
+	}
+	if err := c.protected.check(to); err != nil {
+		return err
+	}
+	if err := c.checkSpecial(from); err != nil {
+		return err
+	}
+	if err := c.throttle.op(ctx); err != nil {
+		return err
+	}
+	return c.audited(ctx, opRename, from, to, func() error {
+		return c.retry(ctx, func(attempt int) error {
+			err := c.files().rename(ctx, from, to)
+			if attempt > 1 && errors.Is(err, os.ErrNotExist) {
+				// an earlier attempt renamed it after all, though it reported failing
+				if _, serr := c.stat(to); serr == nil {
+					return nil
 				}
 			}
-		}
+			return err
+		})
+	})
+}
+
+// errSpecial is returned instead of deleting or renaming a socket, device, named pipe, or other special file.
+var errSpecial = errors.New("refusing to modify a special file")
+
+// isSpecial reports whether mode is that of a socket, device, named pipe, or other file which is neither regular nor
+// a directory or symlink.
+func isSpecial(mode os.FileMode) bool {
+	return mode&(os.ModeSocket|os.ModeDevice|os.ModeCharDevice|os.ModeNamedPipe|os.ModeIrregular) != 0
+}
+
+// checkSpecial returns an error wrapping errSpecial if path is a special file.
+func (c *CLI) checkSpecial(path string) error {
+	if info, err := c.stat(path); err == nil && isSpecial(info.Mode()) {
+		return fmt.Errorf("%w: %s is a %s", errSpecial, path, specialKind(info.Mode()))
+	}
+	return nil
+}
+
+// specialKind names the kind of special file mode describes.
+func specialKind(mode os.FileMode) string {
+	switch {
+	case mode&os.ModeSocket != 0:
+		return "socket"
+	case mode&os.ModeNamedPipe != 0:
+		return "named pipe"
+	case mode&os.ModeDevice != 0:
+		return "device"
 	}
+	return "special file"
+}
 
-	output := strings.Join(results, "\n")
+// stat describes path, in whichever backend holds the scanned files.
+func (c *CLI) stat(path string) (os.FileInfo, error) {
+	return c.files().Stat(path)
+}
 
-	if c.Out != "" {
-		return outputResults(c.Out, output)
-	} else if c.Delete {
-		return outputResults("results.txt", output)
+// act records a on g, returning its error only when the run should stop.
+func (c *CLI) act(g *group, a action) error {
+	g.Actions = append(g.Actions, a)
+	c.progress.acted(a)
+	if a.Op == opDelete && a.Err == nil {
+		c.script.deleted(a.Path)
+	}
+	if a.Err != nil && c.FailFast {
+		return a.Err
 	}
+	return nil
+}
 
-	fmt.Println(output)
+// outputResults writes results to filename, saying so unless --quiet is set.
+func (c *CLI) outputResults(filename string, results string) error {
+	if filename == stdoutPath || !c.Append {
+		results = headResults(c.Format, c.runID, results)
+		if c.Quiet {
+			return writeResults(filename, results)
+		}
+		return outputResults(filename, results)
+	}
+	if err := appendResults(filename, results, c.runID, time.Now()); err != nil {
+		return err
+	}
+	if !c.Quiet {
+		fmt.Fprintln(os.Stderr, tr("Results appended to %s", filename))
+	}
 	return nil
 }
 
+// outputResults writes results to filename, or to stdout when it's "-". Saying where results were written is
+// diagnostic, so it goes to stderr, leaving stdout for results alone.
 func outputResults(filename string, results string) error {
+	if filename == stdoutPath {
+		return writeResults(filename, results)
+	}
+	if err := writeResults(filename, results); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, tr("Results written to %s", filename))
+	return nil
+}
+
+// runHeading introduces the results of a run.
+func runHeading(runID string, at time.Time) string {
+	return fmt.Sprintf("=== ohman run %s at %s ===", runID, at.Format(time.RFC3339))
+}
+
+// headResults starts text and fdupes results with the heading of the run they're from, as JSON results give the run's
+// id with each group. Results appended to a file are headed as they're appended, whatever their format.
+func headResults(format, runID, results string) string {
+	if format != "text" && format != "fdupes" {
+		return results
+	}
+	return runHeading(runID, time.Now()) + "\n" + results
+}
+
+// appendResults adds results to the end of filename, under a heading with the id and time of the run, so one file can
+// keep the record of many runs.
+func appendResults(filename string, results, runID string, at time.Time) error {
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to append results to %s: %v", filename, err)
+	}
+	_, err = fmt.Fprintf(f, "%s\n%s\n\n", runHeading(runID, at), strings.TrimSuffix(results, "\n"))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to append results to %s: %v", filename, err)
+	}
+	return nil
+}
+
+func writeResults(filename string, results string) error {
+	if filename == stdoutPath {
+		_, err := fmt.Println(strings.TrimSuffix(results, "\n"))
+		return err
+	}
 	err := os.WriteFile(filename, []byte(results), 0644)
 	if err != nil {
 		return fmt.Errorf("failed to write results to %s: %v", filename, err)
 	}
-	fmt.Printf("Results written to %s\n", filename)
 	return nil
 }
 
-func main() {
-	ctx := kong.Parse(&cli,
-		kong.Name("ohman"),
-		kong.Description(`⚠️  WARNING: This tool deletes files permanently. USE AT YOUR OWN RISK.
+// description is shown atop --help.
+// deleteHelp is the help for --delete, which is translated like description.
+const deleteHelp = "⚠️  WARNING: Delete duplicate files (on Windows, to the Recycle Bin unless --permanent is given). USE AT YOUR OWN RISK. No warranty provided."
+
+const description = `⚠️  WARNING: This tool deletes files permanently. USE AT YOUR OWN RISK.
 
 This software is provided "as-is", without warranty of any kind.
 
 Always backup your files and test with --dryrun first.
-`),
+`
+
+func main() {
+	setLanguage(systemLanguages()...)
+	parser := kong.Must(&app,
+		kong.Name("ohman"),
+		kong.Description(tr(description)),
 		kong.UsageOnError(),
 		kong.Vars{
 			"version": version,
 			"commit":  commit,
 			"date":    date,
+
+			"default_pattern": defaultPattern,
+			"delete_help":     tr(deleteHelp),
 		},
 	)
-	err := ctx.Run(&Context{Context: ctx})
+	ctx, err := parser.Parse(os.Args[1:])
+	parser.FatalIfErrorf(fatal(err))
+	if app.Lang != "" {
+		setLanguage(app.Lang)
+	}
+	runCtx, cancel := context.WithCancelCause(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		// Restore default signal handling so a second signal terminates immediately.
+		signal.Stop(signals)
+		cancel(errInterrupted)
+		fmt.Fprintln(os.Stderr, tr("Interrupted: finishing the current group and writing results. Press Ctrl+C again to abort immediately."))
+	}()
+
+	stopProfiling, err := app.startProfiling()
+	ctx.FatalIfErrorf(fatal(err))
+	err = ctx.Run(&Context{Context: ctx, Ctx: runCtx})
+	if perr := stopProfiling(); perr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", perr)
+	}
+	var coder kong.ExitCoder
+	if !errors.As(err, &coder) {
+		err = fatal(err)
+	}
 	ctx.FatalIfErrorf(err)
+	ctx.Exit(app.Scan.status)
 }
@@ -1,10 +1,17 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 )
 
@@ -43,7 +50,7 @@ func TestCLI_Run_NoPath(t *testing.T) {
 	t.Parallel()
 	cli := &CLI{
 		Path:  []string{},
-		Regex: defaultRegex,
+		Regex: []string{defaultRegex},
 	}
 
 	err := cli.Run(nil)
@@ -61,7 +68,7 @@ func TestCLI_Run_InvalidRegex(t *testing.T) {
 
 	cli := &CLI{
 		Path:  []string{dir},
-		Regex: "[invalid",
+		Regex: []string{"[invalid"},
 	}
 
 	err := cli.Run(nil)
@@ -77,7 +84,7 @@ func TestCLI_Run_InvalidPath(t *testing.T) {
 	t.Parallel()
 	cli := &CLI{
 		Path:  []string{"/nonexistent/path/that/does/not/exist"},
-		Regex: defaultRegex,
+		Regex: []string{defaultRegex},
 	}
 
 	err := cli.Run(nil)
@@ -101,7 +108,7 @@ func TestCLI_Run_DryRun_FindsDuplicates(t *testing.T) {
 	cli := &CLI{
 		Path:   []string{dir},
 		DryRun: true,
-		Regex:  defaultRegex,
+		Regex:  []string{defaultRegex},
 	}
 
 	if err := cli.Run(nil); err != nil {
@@ -133,7 +140,7 @@ func TestCLI_Run_DryRunWithDelete_DoesNotDelete(t *testing.T) {
 		Path:   []string{dir},
 		DryRun: true,
 		Delete: true,
-		Regex:  defaultRegex,
+		Regex:  []string{defaultRegex},
 	}
 
 	if err := cli.Run(nil); err != nil {
@@ -163,7 +170,7 @@ func TestCLI_Run_DryRun_NoDuplicates(t *testing.T) {
 	cli := &CLI{
 		Path:   []string{dir},
 		DryRun: true,
-		Regex:  defaultRegex,
+		Regex:  []string{defaultRegex},
 	}
 
 	if err := cli.Run(nil); err != nil {
@@ -183,10 +190,11 @@ func TestCLI_Run_Delete_RemovesDuplicates(t *testing.T) {
 	outFile := filepath.Join(dir, "results.txt")
 
 	cli := &CLI{
-		Path:   []string{dir},
-		Delete: true,
-		Out:    outFile,
-		Regex:  defaultRegex,
+		Path:           []string{dir},
+		Delete:         true,
+		AllowDifferent: true,
+		Out:            outFile,
+		Regex:          []string{defaultRegex},
 	}
 
 	if err := cli.Run(nil); err != nil {
@@ -226,11 +234,12 @@ func TestCLI_Run_Delete_Inverse_KeepsNewest(t *testing.T) {
 	outFile := filepath.Join(dir, "results.txt")
 
 	cli := &CLI{
-		Path:    []string{dir},
-		Delete:  true,
-		Inverse: true,
-		Out:     outFile,
-		Regex:   defaultRegex,
+		Path:           []string{dir},
+		Delete:         true,
+		AllowDifferent: true,
+		Inverse:        true,
+		Out:            outFile,
+		Regex:          []string{defaultRegex},
 	}
 
 	if err := cli.Run(nil); err != nil {
@@ -269,9 +278,10 @@ func TestCLI_Run_Delete_InverseAndRename(t *testing.T) {
 	cli := &CLI{
 		Path:             []string{dir},
 		Delete:           true,
+		AllowDifferent:   true,
 		InverseAndRename: true,
 		Out:              outFile,
-		Regex:            defaultRegex,
+		Regex:            []string{defaultRegex},
 	}
 
 	if err := cli.Run(nil); err != nil {
@@ -311,10 +321,11 @@ func TestCLI_Run_OutputToFile(t *testing.T) {
 	outFile := filepath.Join(dir, "custom-output.txt")
 
 	cli := &CLI{
-		Path:   []string{dir},
-		Delete: true,
-		Out:    outFile,
-		Regex:  defaultRegex,
+		Path:           []string{dir},
+		Delete:         true,
+		AllowDifferent: true,
+		Out:            outFile,
+		Regex:          []string{defaultRegex},
 	}
 
 	if err := cli.Run(nil); err != nil {
@@ -355,7 +366,7 @@ func TestCLI_Run_DefaultOutputFile(t *testing.T) {
 	cli := &CLI{
 		Path:   []string{dir},
 		Delete: true,
-		Regex:  defaultRegex,
+		Regex:  []string{defaultRegex},
 	}
 
 	if err := cli.Run(nil); err != nil {
@@ -384,10 +395,11 @@ func TestCLI_Run_MultiplePaths(t *testing.T) {
 	outFile := filepath.Join(dir1, "results.txt")
 
 	cli := &CLI{
-		Path:   []string{dir1, dir2},
-		Delete: true,
-		Out:    outFile,
-		Regex:  defaultRegex,
+		Path:           []string{dir1, dir2},
+		Delete:         true,
+		AllowDifferent: true,
+		Out:            outFile,
+		Regex:          []string{defaultRegex},
 	}
 
 	if err := cli.Run(nil); err != nil {
@@ -423,10 +435,11 @@ func TestCLI_Run_CustomRegex(t *testing.T) {
 	outFile := filepath.Join(dir, "results.txt")
 
 	cli := &CLI{
-		Path:   []string{dir},
-		Delete: true,
-		Out:    outFile,
-		Regex:  `(.+)_copy(\d+)\.(txt)$`,
+		Path:           []string{dir},
+		Delete:         true,
+		AllowDifferent: true,
+		Out:            outFile,
+		Regex:          []string{`(.+)_copy(\d+)\.(txt)$`},
 	}
 
 	if err := cli.Run(nil); err != nil {
@@ -447,6 +460,117 @@ func TestCLI_Run_CustomRegex(t *testing.T) {
 	}
 }
 
+func TestCLI_Run_MultipleRegexes(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	for _, name := range []string{"notes.txt", "notes (1).txt", "notes_copy.txt", "notes - Copy.txt", "notes - Copy (1).txt", "notes_copy (2).txt"} {
+		createTestFile(t, filepath.Join(dir, name), "notes")
+	}
+
+	cli := &CLI{
+		Path:   []string{dir},
+		Delete: true,
+		Out:    filepath.Join(dir, "results.txt"),
+		Regex:  []string{`(.+)\s\((\d+)\)\.(txt)$`, `(?P<name>.+)_copy\.(?P<ext>txt)$`, `(?P<name>.+) - Copy\.(?P<ext>txt)$`},
+	}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !fileExists(filepath.Join(dir, "notes.txt")) {
+		t.Error("original should still exist")
+	}
+	for _, name := range []string{"notes (1).txt", "notes_copy.txt", "notes - Copy.txt", "notes - Copy (1).txt", "notes_copy (2).txt"} {
+		if fileExists(filepath.Join(dir, name)) {
+			t.Errorf("%s should be deleted", name)
+		}
+	}
+}
+
+func TestCLI_Run_Hidden(t *testing.T) {
+	t.Parallel()
+	for _, include := range []bool{false, true} {
+		// a root is scanned however it's named
+		dir := filepath.Join(setupTestDir(t), ".library")
+		for _, name := range []string{"book.pdf", "book (1).pdf", ".git/notes.pdf", ".git/notes (1).pdf", ".book.pdf", ".book (1).pdf"} {
+			if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			createTestFile(t, filepath.Join(dir, name), "content")
+		}
+
+		cli := &CLI{Path: []string{dir}, Delete: true, IncludeHidden: include, Out: filepath.Join(t.TempDir(), "results.txt"), Regex: []string{defaultRegex}}
+		if err := cli.Run(nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if fileExists(filepath.Join(dir, "book (1).pdf")) {
+			t.Error("book (1).pdf should be deleted")
+		}
+		for _, name := range []string{".git/notes (1).pdf", ".book (1).pdf"} {
+			if exists := fileExists(filepath.Join(dir, name)); exists == include {
+				t.Errorf("with --include-hidden=%t, %s exists = %t", include, name, exists)
+			}
+		}
+	}
+}
+
+func TestCLI_Run_SkipsSpecialFiles(t *testing.T) {
+	t.Parallel()
+	cli := &CLI{
+		Path:   []string{"/media"},
+		Delete: true,
+		Out:    filepath.Join(t.TempDir(), "results.txt"),
+		Regex:  []string{defaultRegex},
+		memory: fstest.MapFS{
+			"media/book.pdf":     {Data: []byte("content")},
+			"media/book (1).pdf": {Data: []byte("content")},
+			"media/feed.mp3":     {Data: []byte("content")},
+			"media/feed (1).mp3": {Mode: fs.ModeNamedPipe},
+		},
+	}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := cli.memory["media/book (1).pdf"]; ok {
+		t.Error("book (1).pdf should be deleted")
+	}
+	if _, ok := cli.memory["media/feed (1).mp3"]; !ok {
+		t.Error("the named pipe shouldn't be touched")
+	}
+	if err := cli.remove(context.Background(), "/media/feed (1).mp3"); !errors.Is(err, errSpecial) {
+		t.Errorf("remove() of a named pipe error = %v, want errSpecial", err)
+	}
+}
+
+func TestCLI_Run_OverlappingPaths(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(sub); err != nil {
+		t.Fatal(err)
+	} else if _, ok := fileID(info); !ok {
+		t.Skip("directories can't be identified on this platform")
+	}
+	createTestFile(t, filepath.Join(sub, "book.pdf"), "content")
+	createTestFile(t, filepath.Join(sub, "book (1).pdf"), "content")
+
+	out := filepath.Join(t.TempDir(), "results.json")
+	cli := &CLI{Path: []string{dir, sub}, Delete: true, Quiet: true, Format: "json", Out: out, Regex: []string{defaultRegex}}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups, err := parseResults(data)
+	if err != nil || len(groups) != 1 || len(groups[0].Duplicates) != 1 {
+		t.Errorf("expected the copy to be found once, through the first path, got %+v, %v", groups, err)
+	}
+}
+
 func TestCLI_Run_DuplicateWithoutOriginal(t *testing.T) {
 	t.Parallel()
 	dir := setupTestDir(t)
@@ -458,7 +582,7 @@ func TestCLI_Run_DuplicateWithoutOriginal(t *testing.T) {
 	cli := &CLI{
 		Path:   []string{dir},
 		DryRun: true,
-		Regex:  defaultRegex,
+		Regex:  []string{defaultRegex},
 	}
 
 	if err := cli.Run(nil); err != nil {
@@ -494,10 +618,11 @@ func TestCLI_Run_NestedDirectories(t *testing.T) {
 	outFile := filepath.Join(dir, "results.txt")
 
 	cli := &CLI{
-		Path:   []string{dir},
-		Delete: true,
-		Out:    outFile,
-		Regex:  defaultRegex,
+		Path:           []string{dir},
+		Delete:         true,
+		AllowDifferent: true,
+		Out:            outFile,
+		Regex:          []string{defaultRegex},
 	}
 
 	if err := cli.Run(nil); err != nil {
@@ -535,10 +660,11 @@ func TestCLI_Run_AllSupportedExtensions(t *testing.T) {
 	outFile := filepath.Join(dir, "results.txt")
 
 	cli := &CLI{
-		Path:   []string{dir},
-		Delete: true,
-		Out:    outFile,
-		Regex:  defaultRegex,
+		Path:           []string{dir},
+		Delete:         true,
+		AllowDifferent: true,
+		Out:            outFile,
+		Regex:          []string{defaultRegex},
 	}
 
 	if err := cli.Run(nil); err != nil {
@@ -566,7 +692,7 @@ func TestCLI_Run_UnsupportedExtension(t *testing.T) {
 	cli := &CLI{
 		Path:   []string{dir},
 		Delete: true,
-		Regex:  defaultRegex,
+		Regex:  []string{defaultRegex},
 	}
 
 	if err := cli.Run(nil); err != nil {
@@ -607,6 +733,28 @@ func TestOutputResults(t *testing.T) {
 	}
 }
 
+func TestAppendResults(t *testing.T) {
+	t.Parallel()
+	outFile := filepath.Join(setupTestDir(t), "results.txt")
+	first := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	if err := appendResults(outFile, "Deleted a (1).pdf\n", "run-a", first); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := appendResults(outFile, "Deleted b (1).pdf", "run-b", first.Add(time.Hour)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatalf("failed to read output file: %v", err)
+	}
+	want := "=== ohman run run-a at 2024-03-01T12:30:00Z ===\nDeleted a (1).pdf\n\n=== ohman run run-b at 2024-03-01T13:30:00Z ===\nDeleted b (1).pdf\n\n"
+	if string(data) != want {
+		t.Errorf("expected content %q, got %q", want, string(data))
+	}
+}
+
 func TestOutputResults_InvalidPath(t *testing.T) {
 	t.Parallel()
 	tmp := setupTestDir(t)
@@ -620,3 +768,282 @@ func TestOutputResults_InvalidPath(t *testing.T) {
 		t.Errorf("unexpected error message: %v", err)
 	}
 }
+
+func TestCLI_Run_SkipErrors(t *testing.T) {
+	t.Parallel()
+	if os.Geteuid() == 0 {
+		t.Skip("permission checks don't apply to root")
+	}
+	dir := setupTestDir(t)
+
+	locked := filepath.Join(dir, "locked")
+	if err := os.Mkdir(locked, 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "original content")
+	createTestFile(t, filepath.Join(dir, "book (1).pdf"), "duplicate 1")
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatalf("failed to chmod: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chmod(locked, 0755) })
+
+	cli := &CLI{
+		Path:       []string{dir},
+		DryRun:     true,
+		SkipErrors: true,
+		Out:        filepath.Join(dir, "results.txt"),
+		Regex:      []string{defaultRegex},
+	}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cli.skipped != 1 {
+		t.Errorf("expected 1 skipped entry, got %d", cli.skipped)
+	}
+
+	cli.SkipErrors = false
+	if err := cli.Run(nil); err == nil {
+		t.Error("expected an error when not skipping unreadable entries")
+	}
+}
+
+func TestCLI_Run_Interrupted(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "original content")
+	createTestFile(t, filepath.Join(dir, "book (1).pdf"), "duplicate 1")
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(errInterrupted)
+
+	cli := &CLI{
+		Path:   []string{dir},
+		Delete: true,
+		Out:    filepath.Join(dir, "results.txt"),
+		Regex:  []string{defaultRegex},
+	}
+
+	err := cli.Run(&Context{Ctx: ctx})
+	if !errors.Is(err, errInterrupted) {
+		t.Fatalf("expected interrupted error, got: %v", err)
+	}
+	if !fileExists(filepath.Join(dir, "book (1).pdf")) {
+		t.Error("no files should be deleted once interrupted")
+	}
+}
+
+func TestCLI_Run_Timeout(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "original content")
+	createTestFile(t, filepath.Join(dir, "book (1).pdf"), "duplicate 1")
+
+	cli := &CLI{
+		Path:    []string{dir},
+		Delete:  true,
+		Timeout: time.Nanosecond,
+		Out:     filepath.Join(dir, "results.txt"),
+		Regex:   []string{defaultRegex},
+	}
+
+	err := cli.Run(nil)
+	if !errors.Is(err, errTimedOut) {
+		t.Fatalf("expected timed out error, got: %v", err)
+	}
+	if !fileExists(filepath.Join(dir, "book (1).pdf")) {
+		t.Error("no files should be deleted once timed out")
+	}
+}
+
+func TestOriginalFor(t *testing.T) {
+	t.Parallel()
+	re := regexp.MustCompile(defaultRegex)
+	tests := []struct {
+		path string
+		want string
+		ok   bool
+	}{
+		{path: "/b/book (1).pdf", want: "/b/book.pdf", ok: true},
+		{path: "/b/book (1) (1).pdf", want: "/b/book.pdf", ok: true},
+		{path: "/b/book (2) (1) (3).pdf", want: "/b/book.pdf", ok: true},
+		{path: "/b/book(1) (1).pdf", want: "/b/book(1).pdf", ok: true},
+		{path: "/b/book.pdf", ok: false},
+	}
+	for _, tt := range tests {
+		got, ok := originalFor(copyPatterns{{re: re}}, filepath.FromSlash(tt.path))
+		if ok != tt.ok || (ok && got != filepath.FromSlash(tt.want)) {
+			t.Errorf("originalFor(%s) = %q, %v; want %q, %v", tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestOriginalFor_NamedGroups(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		regex string
+		path  string
+		want  string
+	}{
+		{`^(?P<num>\d+)-(?P<name>.+)(?P<ext>\.pdf)$`, "/b/2-book.pdf", "/b/book.pdf"},
+		{`^(?P<ext>txt)_(?P<name>.+)_copy$`, "/b/txt_notes_copy", "/b/notes.txt"},
+		{`^(?P<name>.+) copy$`, "/b/Makefile copy", "/b/Makefile"},
+	}
+	for _, tt := range tests {
+		re := regexp.MustCompile(tt.regex)
+		if err := checkCopyGroups(re); err != nil {
+			t.Errorf("checkCopyGroups(%s) = %v", tt.regex, err)
+			continue
+		}
+		got, ok := originalFor(copyPatterns{{re: re}}, filepath.FromSlash(tt.path))
+		if !ok || got != filepath.FromSlash(tt.want) {
+			t.Errorf("originalFor(%s) with %s = %q, %v; want %q", tt.path, tt.regex, got, ok, tt.want)
+		}
+	}
+}
+
+func TestCheckCopyGroups(t *testing.T) {
+	t.Parallel()
+	for regex, ok := range map[string]bool{
+		defaultRegex:                       true,
+		`(.+)_copy\.(pdf)$`:                false,
+		`(?P<num>\d+)-(.+)$`:               false,
+		`(?P<name>.+)_copy\.(?P<ext>pdf)$`: true,
+		`(?P<name>.+) copy$`:               true,
+	} {
+		if err := checkCopyGroups(regexp.MustCompile(regex)); (err == nil) != ok {
+			t.Errorf("checkCopyGroups(%s) = %v, want ok %v", regex, err, ok)
+		}
+	}
+}
+
+func TestCLI_Run_NestedSuffixes(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "original content")
+	createTestFile(t, filepath.Join(dir, "book (1).pdf"), "duplicate 1")
+	createTestFile(t, filepath.Join(dir, "book (1) (1).pdf"), "duplicate 1 of 1")
+	createTestFile(t, filepath.Join(dir, "book (1) (2) (1).pdf"), "duplicate of a duplicate of a duplicate")
+
+	cli := &CLI{Path: []string{dir}, Delete: true, AllowDifferent: true, Out: filepath.Join(dir, "results.txt"), Regex: []string{defaultRegex}}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read directory: %v", err)
+	}
+	var remaining []string
+	for _, e := range entries {
+		remaining = append(remaining, e.Name())
+	}
+	if strings.Join(remaining, ",") != "book.pdf,results.txt" {
+		t.Errorf("expected the whole chain to collapse to book.pdf, remaining: %v", remaining)
+	}
+}
+
+func TestCLI_Apply_Sorted(t *testing.T) {
+	t.Parallel()
+	memory := fstest.MapFS{}
+	files := map[string][]string{}
+	for _, name := range []string{"e", "b", "d", "a", "c"} {
+		original := "/media/" + name + ".pdf"
+		memory["media/"+name+".pdf"] = &fstest.MapFile{}
+		files[original] = []string{"/media/" + name + " (2).pdf", "/media/" + name + " (10).pdf", "/media/" + name + " (1).pdf"}
+	}
+	cli := &CLI{DryRun: true, AllowDifferent: true, memory: memory}
+
+	groups, _ := cli.apply(context.Background(), files)
+	var originals []string
+	for _, g := range groups {
+		originals = append(originals, g.Original)
+		if !slices.IsSorted(g.Duplicates) {
+			t.Errorf("duplicates of %s = %q, want them sorted", g.Original, g.Duplicates)
+		}
+	}
+	if want := []string{"/media/a.pdf", "/media/b.pdf", "/media/c.pdf", "/media/d.pdf", "/media/e.pdf"}; !slices.Equal(originals, want) {
+		t.Errorf("originals = %q, want %q", originals, want)
+	}
+}
+
+func TestCLI_Apply_MinDupes(t *testing.T) {
+	t.Parallel()
+	memory := fstest.MapFS{"media/a.pdf": {}, "media/b.pdf": {}}
+	files := map[string][]string{
+		"/media/a.pdf": {"/media/a (1).pdf"},
+		"/media/b.pdf": {"/media/b (1).pdf", "/media/b (2).pdf"},
+	}
+	cli := &CLI{DryRun: true, AllowDifferent: true, MinDupes: 2, memory: memory}
+
+	groups, _ := cli.apply(context.Background(), files)
+	if len(groups) != 1 || groups[0].Original != "/media/b.pdf" {
+		t.Errorf("apply() = %+v, want only b.pdf's group", groups)
+	}
+}
+
+// captureOutput returns what f prints to stdout and stderr. Tests using it mustn't be parallel.
+func captureOutput(t *testing.T, f func()) (stdout, stderr string) {
+	t.Helper()
+	capture := func(file **os.File) func() string {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatalf("failed to create pipe: %v", err)
+		}
+		original := *file
+		*file = w
+		done := make(chan string)
+		go func() {
+			data, _ := io.ReadAll(r)
+			done <- string(data)
+		}()
+		return func() string {
+			*file = original
+			_ = w.Close()
+			return <-done
+		}
+	}
+	stopOut, stopErr := capture(&os.Stdout), capture(&os.Stderr)
+	f()
+	return stopOut(), stopErr()
+}
+
+// withoutHeading returns results without the heading of the run runID, failing t when they don't start with it.
+func withoutHeading(t *testing.T, runID, results string) string {
+	t.Helper()
+	heading, rest, _ := strings.Cut(results, "\n")
+	if !strings.HasPrefix(heading, "=== ohman run "+runID+" at ") {
+		t.Errorf("results %q don't start with the heading of run %s", results, runID)
+	}
+	return rest
+}
+
+func TestCLI_Run_OutStdout(t *testing.T) {
+	dir := setupTestDir(t)
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "content")
+	createTestFile(t, filepath.Join(dir, "book (1).pdf"), "content")
+	createTestFile(t, filepath.Join(dir, "notes (1).pdf"), "content")
+
+	cli := &CLI{
+		Path:   []string{dir},
+		Delete: true,
+		Quiet:  true,
+		Format: "fdupes",
+		Out:    stdoutPath,
+		Regex:  []string{defaultRegex},
+	}
+	var err error
+	stdout, stderr := captureOutput(t, func() { err = cli.Run(nil) })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "   [+] " + filepath.Join(dir, "book.pdf") + "\n   [-] " + filepath.Join(dir, "book (1).pdf") + "\n\n"; withoutHeading(t, cli.runID, stdout) != want {
+		t.Errorf("stdout = %q, want only the results, %q", stdout, want)
+	}
+	if !strings.Contains(stderr, "Found 1 duplicate(s) of 1 file(s)") {
+		t.Errorf("stderr = %q, want the summary", stderr)
+	}
+	if fileExists("-") {
+		t.Error("results should only be written to stdout")
+	}
+}
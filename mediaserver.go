@@ -0,0 +1,296 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// mediaServerTimeout bounds each request to a Plex or Jellyfin server.
+const mediaServerTimeout = 60 * time.Second
+
+// mediaItem is what a media server knows about a file in its library.
+type mediaItem struct {
+	// Plays is the number of times it's been played, across all users where the server reports them.
+	Plays int
+}
+
+// mediaServer is a Plex or Jellyfin server whose libraries are consulted before deleting.
+type mediaServer interface {
+	// items returns every file in the server's libraries, keyed by path.
+	items(ctx context.Context) (map[string]mediaItem, error)
+	// refresh asks the server to rescan the libraries holding paths.
+	refresh(ctx context.Context, paths []string) error
+}
+
+func newMediaServer(kind, baseURL, token string) (mediaServer, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid --media-server URL %q", baseURL)
+	}
+	client := mediaClient{base: strings.TrimSuffix(u.String(), "/"), token: token}
+	if kind == "jellyfin" {
+		client.header = "X-Emby-Token"
+		return &jellyfin{client}, nil
+	}
+	client.header = "X-Plex-Token"
+	return &plex{mediaClient: client}, nil
+}
+
+// preferReferenced reorders g so that the copy the media server knows about, and has been played the most, is kept.
+// A copy is only preferred over the one which would otherwise be kept when the server ranks it higher.
+func preferReferenced(g *group, items map[string]mediaItem) {
+	rank := func(path string) int {
+		item, ok := items[libraryPath(path)]
+		if !ok {
+			return -1
+		}
+		return item.Plays
+	}
+	best := -1
+	for i, d := range g.Duplicates {
+		if best < 0 || rank(d) > rank(g.Duplicates[best]) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return
+	}
+	if g.Orphan {
+		// the first duplicate is the one adopted
+		if rank(g.Duplicates[best]) > rank(g.Duplicates[0]) {
+			g.Duplicates[0], g.Duplicates[best] = g.Duplicates[best], g.Duplicates[0]
+		}
+		return
+	}
+	if rank(g.Duplicates[best]) > rank(g.Original) {
+		g.Original, g.Duplicates[best] = g.Duplicates[best], g.Original
+	}
+}
+
+// changedPaths returns the paths deleted or renamed by successful actions in groups.
+func changedPaths(groups []group) []string {
+	var paths []string
+	for _, g := range groups {
+		for _, a := range g.Actions {
+			if a.Err == nil && (a.Op == opDelete || a.Op == opRename) {
+				paths = append(paths, a.Path)
+			}
+		}
+	}
+	return paths
+}
+
+// libraryPath is the absolute, cleaned form of path, as media servers report them.
+func libraryPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// mediaClient makes authenticated JSON requests to a media server.
+type mediaClient struct {
+	base, token string
+	// header is the name of the header carrying token.
+	header string
+}
+
+func (m mediaClient) do(ctx context.Context, method, path string, out any) error {
+	ctx, cancel := context.WithTimeout(ctx, mediaServerTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, m.base+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "ohman/"+version)
+	if m.token != "" {
+		req.Header.Set(m.header, m.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s responded with %s", method, path, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("unexpected response to %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// plex reads libraries through the Plex Media Server API.
+type plex struct {
+	mediaClient
+	// sections are the library sections found by items, for refresh.
+	sections []plexSection
+}
+
+type plexSection struct {
+	Key      string `json:"key"`
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Location []struct {
+		Path string `json:"path"`
+	} `json:"Location"`
+}
+
+// plexTypes are the item types listed for each kind of library section: movies, episodes, and tracks.
+var plexTypes = map[string]string{"movie": "1", "show": "4", "artist": "10"}
+
+func (p *plex) items(ctx context.Context) (map[string]mediaItem, error) {
+	var sections struct {
+		MediaContainer struct {
+			Directory []plexSection `json:"Directory"`
+		} `json:"MediaContainer"`
+	}
+	if err := p.do(ctx, http.MethodGet, "/library/sections", &sections); err != nil {
+		return nil, err
+	}
+	p.sections = sections.MediaContainer.Directory
+
+	items := make(map[string]mediaItem)
+	for _, s := range p.sections {
+		itemType, ok := plexTypes[s.Type]
+		if !ok {
+			continue
+		}
+		var list struct {
+			MediaContainer struct {
+				Metadata []struct {
+					ViewCount int `json:"viewCount"`
+					Media     []struct {
+						Part []struct {
+							File string `json:"file"`
+						} `json:"Part"`
+					} `json:"Media"`
+				} `json:"Metadata"`
+			} `json:"MediaContainer"`
+		}
+		if err := p.do(ctx, http.MethodGet, "/library/sections/"+url.PathEscape(s.Key)+"/all?type="+itemType, &list); err != nil {
+			return nil, fmt.Errorf("unable to list %s: %w", s.Title, err)
+		}
+		for _, m := range list.MediaContainer.Metadata {
+			for _, media := range m.Media {
+				for _, part := range media.Part {
+					items[filepath.Clean(part.File)] = mediaItem{Plays: m.ViewCount}
+				}
+			}
+		}
+	}
+	return items, nil
+}
+
+func (p *plex) refresh(ctx context.Context, paths []string) error {
+	var errs []error
+	for _, s := range p.sections {
+		if !plexSectionHolds(s, paths) {
+			continue
+		}
+		if err := p.do(ctx, http.MethodGet, "/library/sections/"+url.PathEscape(s.Key)+"/refresh", nil); err != nil {
+			errs = append(errs, fmt.Errorf("unable to refresh %s: %w", s.Title, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func plexSectionHolds(s plexSection, paths []string) bool {
+	for _, l := range s.Location {
+		for _, p := range paths {
+			if within(libraryPath(p), filepath.Clean(l.Path)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jellyfin reads libraries through the Jellyfin API, which reports plays per user.
+type jellyfin struct {
+	mediaClient
+}
+
+func (j *jellyfin) items(ctx context.Context) (map[string]mediaItem, error) {
+	var users []struct {
+		ID   string `json:"Id"`
+		Name string `json:"Name"`
+	}
+	if err := j.do(ctx, http.MethodGet, "/Users", &users); err != nil {
+		return nil, err
+	}
+
+	items := make(map[string]mediaItem)
+	for _, u := range users {
+		var list struct {
+			Items []struct {
+				Path     string `json:"Path"`
+				UserData struct {
+					PlayCount int  `json:"PlayCount"`
+					Played    bool `json:"Played"`
+				} `json:"UserData"`
+			} `json:"Items"`
+		}
+		query := "?Recursive=true&IsFolder=false&Fields=Path&IncludeItemTypes=Movie,Episode,Audio,MusicVideo,Video"
+		if err := j.do(ctx, http.MethodGet, "/Users/"+url.PathEscape(u.ID)+"/Items"+query, &list); err != nil {
+			return nil, fmt.Errorf("unable to list items for %s: %w", u.Name, err)
+		}
+		for _, it := range list.Items {
+			if it.Path == "" {
+				continue
+			}
+			item := items[filepath.Clean(it.Path)]
+			plays := it.UserData.PlayCount
+			if it.UserData.Played {
+				plays = cmp.Or(plays, 1)
+			}
+			item.Plays += plays
+			items[filepath.Clean(it.Path)] = item
+		}
+	}
+	return items, nil
+}
+
+func (j *jellyfin) refresh(ctx context.Context, _ []string) error {
+	return j.do(ctx, http.MethodPost, "/Library/Refresh", nil)
+}
+
+// loadLibrary fetches the media server's library when --media-server is set.
+func (c *CLI) loadLibrary(ctx context.Context) (mediaServer, map[string]mediaItem, error) {
+	if c.MediaServer == "" {
+		return nil, nil, nil
+	}
+	server, err := newMediaServer(c.MediaServerType, c.MediaServer, c.MediaServerToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	items, err := server.items(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read the media server's library: %w", err)
+	}
+	return server, items, nil
+}
+
+// refreshLibrary asks the media server to rescan after files have been deleted or renamed, warning on failure.
+func refreshLibrary(ctx context.Context, server mediaServer, groups []group) {
+	paths := changedPaths(groups)
+	if server == nil || len(paths) == 0 {
+		return
+	}
+	if err := server.refresh(ctx, paths); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: media server refresh failed: %v\n", err)
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakePlex serves a movie section at root, whose files are played the given number of times.
+func fakePlex(t *testing.T, root string, plays map[string]int) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var refreshed []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Plex-Token") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/library/sections":
+			fmt.Fprintf(w, `{"MediaContainer": {"Directory": [
+				{"key": "1", "type": "movie", "title": "Movies", "Location": [{"path": %q}]},
+				{"key": "2", "type": "photo", "title": "Photos", "Location": [{"path": "/photos"}]},
+				{"key": "3", "type": "show", "title": "TV", "Location": [{"path": "/tv"}]}
+			]}}`, root)
+		case r.URL.Path == "/library/sections/1/all" && r.URL.Query().Get("type") == "1":
+			var metadata []string
+			for path, n := range plays {
+				metadata = append(metadata, fmt.Sprintf(`{"viewCount": %d, "Media": [{"Part": [{"file": %q}]}]}`, n, path))
+			}
+			fmt.Fprintf(w, `{"MediaContainer": {"Metadata": [%s]}}`, strings.Join(metadata, ","))
+		case r.URL.Path == "/library/sections/3/all" && r.URL.Query().Get("type") == "4":
+			fmt.Fprint(w, `{"MediaContainer": {"size": 0}}`)
+		case strings.HasSuffix(r.URL.Path, "/refresh"):
+			mu.Lock()
+			refreshed = append(refreshed, r.URL.Path)
+			mu.Unlock()
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(refreshed)
+	}
+}
+
+func TestPlex(t *testing.T) {
+	srv, refreshed := fakePlex(t, "/movies", map[string]int{"/movies/Heat.mkv": 0, "/movies/Heat (1).mkv": 3})
+	server, err := newMediaServer("plex", srv.URL+"/", "secret")
+	if err != nil {
+		t.Fatalf("newMediaServer() error = %v", err)
+	}
+	items, err := server.items(context.Background())
+	if err != nil {
+		t.Fatalf("items() error = %v", err)
+	}
+	want := map[string]mediaItem{filepath.Clean("/movies/Heat.mkv"): {}, filepath.Clean("/movies/Heat (1).mkv"): {Plays: 3}}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("items() = %v, want %v", items, want)
+	}
+
+	if err := server.refresh(context.Background(), []string{"/movies/Heat.mkv"}); err != nil {
+		t.Fatalf("refresh() error = %v", err)
+	}
+	if got := refreshed(); !reflect.DeepEqual(got, []string{"/library/sections/1/refresh"}) {
+		t.Errorf("refreshed %v, want only the movie section", got)
+	}
+
+	bad, _ := newMediaServer("plex", srv.URL, "wrong")
+	if _, err := bad.items(context.Background()); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("items() with the wrong token error = %v, want 401", err)
+	}
+}
+
+func TestJellyfin(t *testing.T) {
+	var refreshed bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Emby-Token") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/Users":
+			fmt.Fprint(w, `[{"Id": "a1", "Name": "alice"}, {"Id": "b2", "Name": "bob"}]`)
+		case "/Users/a1/Items":
+			fmt.Fprint(w, `{"Items": [
+				{"Path": "/tv/Show S01E01.mkv", "UserData": {"PlayCount": 2, "Played": true}},
+				{"Path": "/tv/Show S01E01 (1).mkv", "UserData": {"PlayCount": 0, "Played": false}}
+			]}`)
+		case "/Users/b2/Items":
+			fmt.Fprint(w, `{"Items": [
+				{"Path": "/tv/Show S01E01.mkv", "UserData": {"PlayCount": 0, "Played": true}},
+				{"Path": "", "UserData": {}}
+			]}`)
+		case "/Library/Refresh":
+			if r.Method != http.MethodPost {
+				t.Errorf("refresh method = %s, want POST", r.Method)
+			}
+			refreshed = true
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	server, err := newMediaServer("jellyfin", srv.URL, "secret")
+	if err != nil {
+		t.Fatalf("newMediaServer() error = %v", err)
+	}
+	items, err := server.items(context.Background())
+	if err != nil {
+		t.Fatalf("items() error = %v", err)
+	}
+	want := map[string]mediaItem{filepath.Clean("/tv/Show S01E01.mkv"): {Plays: 3}, filepath.Clean("/tv/Show S01E01 (1).mkv"): {}}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("items() = %v, want %v", items, want)
+	}
+	if err := server.refresh(context.Background(), []string{"/tv/Show S01E01 (1).mkv"}); err != nil || !refreshed {
+		t.Errorf("refresh() error = %v, refreshed = %v", err, refreshed)
+	}
+}
+
+func TestNewMediaServer_InvalidURL(t *testing.T) {
+	for _, u := range []string{"", "plex.local:32400", "ftp://plex.local"} {
+		if _, err := newMediaServer("plex", u, ""); err == nil {
+			t.Errorf("newMediaServer(%q) should fail", u)
+		}
+	}
+}
+
+func TestPreferReferenced(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string { return filepath.Join(dir, name) }
+	items := map[string]mediaItem{path("b"): {}, path("c"): {Plays: 2}, path("d"): {Plays: 5}}
+	tests := []struct {
+		name string
+		in   group
+		want group
+	}{
+		{"unreferenced", group{Original: path("a"), Duplicates: []string{path("x")}}, group{Original: path("a"), Duplicates: []string{path("x")}}},
+		{"original referenced", group{Original: path("b"), Duplicates: []string{path("x")}}, group{Original: path("b"), Duplicates: []string{path("x")}}},
+		{"duplicate referenced", group{Original: path("a"), Duplicates: []string{path("x"), path("b")}}, group{Original: path("b"), Duplicates: []string{path("x"), path("a")}}},
+		{"most played", group{Original: path("b"), Duplicates: []string{path("d"), path("c")}}, group{Original: path("d"), Duplicates: []string{path("b"), path("c")}}},
+		{"orphan", group{Original: path("a"), Duplicates: []string{path("b"), path("c")}, Orphan: true}, group{Original: path("a"), Duplicates: []string{path("c"), path("b")}, Orphan: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := tt.in
+			preferReferenced(&g, items)
+			if !reflect.DeepEqual(g, tt.want) {
+				t.Errorf("preferReferenced() = %+v, want %+v", g, tt.want)
+			}
+		})
+	}
+}
+
+func TestCLI_Run_MediaServer(t *testing.T) {
+	dir := t.TempDir()
+	original, duplicate, other := filepath.Join(dir, "Heat.mp4"), filepath.Join(dir, "Heat (1).mp4"), filepath.Join(dir, "Ronin (1).mp4")
+	for _, f := range []string{original, duplicate, other, filepath.Join(dir, "Ronin.mp4")} {
+		createTestFile(t, f, "movie")
+	}
+	srv, refreshed := fakePlex(t, dir, map[string]int{duplicate: 1})
+
+	cli := &CLI{Path: []string{dir}, Delete: true, Out: filepath.Join(dir, "results.txt"), Regex: []string{defaultRegex}, MediaServer: srv.URL, MediaServerType: "plex", MediaServerToken: "secret"}
+	if err := cli.Run(&Context{}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	for path, exists := range map[string]bool{original: false, duplicate: true, other: false, filepath.Join(dir, "Ronin.mp4"): true} {
+		if fileExists(path) != exists {
+			t.Errorf("%s exists = %v, want %v", filepath.Base(path), !exists, exists)
+		}
+	}
+	if got := refreshed(); !reflect.DeepEqual(got, []string{"/library/sections/1/refresh"}) {
+		t.Errorf("refreshed %v, want the movie section", got)
+	}
+}
+
+func TestCLI_Run_MediaServerUnavailable(t *testing.T) {
+	dir := t.TempDir()
+	createTestFile(t, filepath.Join(dir, "Heat.mp4"), "movie")
+	createTestFile(t, filepath.Join(dir, "Heat (1).mp4"), "movie")
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	cli := &CLI{Path: []string{dir}, Delete: true, Out: filepath.Join(dir, "results.txt"), Regex: []string{defaultRegex}, MediaServer: srv.URL}
+	if err := cli.Run(&Context{}); err == nil {
+		t.Fatal("Run() should fail when the media server can't be reached")
+	}
+	if !fileExists(filepath.Join(dir, "Heat (1).mp4")) {
+		t.Error("nothing should be deleted when the media server can't be reached")
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// fileMeta is metadata which a file loses when another is renamed over it, carried over so the file taking its place
+// has the same permissions and owner, and looks the same in the Finder.
+type fileMeta struct {
+	// mode holds the permission bits, including setuid, setgid, and sticky.
+	mode fs.FileMode
+	// uid and gid are the file's owner and group, or -1 where files have none.
+	uid, gid int
+	// xattrs holds extended attributes by name, such as Finder tags and, on Linux, ACLs.
+	xattrs map[string][]byte
+	// created is when the file was created, if known.
+	created time.Time
+}
+
+// readFileMeta reads the metadata of the file at path.
+func readFileMeta(path string) (fileMeta, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileMeta{}, err
+	}
+	m := fileMeta{mode: info.Mode() & (fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky), created: createdAt(info)}
+	m.uid, m.gid = ownerOf(info)
+	m.xattrs, err = readXattrs(path)
+	return m, err
+}
+
+// writeFileMeta gives the file at path the metadata m, changing only what differs, so unprivileged runs aren't
+// refused changes they don't need. Everything which can't be set is reported.
+func writeFileMeta(path string, m fileMeta) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	if uid, gid := ownerOf(info); m.uid >= 0 && (uid != m.uid || gid != m.gid) {
+		if err := os.Chown(path, m.uid, m.gid); err != nil {
+			errs = append(errs, fmt.Errorf("unable to set the owner of %s: %w", path, err))
+		}
+	}
+	// chown clears the setuid and setgid bits, so the mode is always set afterward
+	if err := os.Chmod(path, m.mode); err != nil {
+		errs = append(errs, fmt.Errorf("unable to set the permissions of %s: %w", path, err))
+	}
+	if err := writeXattrs(path, m.xattrs); err != nil {
+		errs = append(errs, err)
+	}
+	if !m.created.IsZero() && !m.created.Equal(createdAt(info)) {
+		if err := setCreatedAt(path, m.created); err != nil {
+			errs = append(errs, fmt.Errorf("unable to set the creation date of %s: %w", path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
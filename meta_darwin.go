@@ -0,0 +1,29 @@
+package main
+
+import (
+	"io/fs"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// createdAt returns when the file described by info was created.
+func createdAt(info fs.FileInfo) time.Time {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(st.Birthtimespec.Unix())
+}
+
+// setCreatedAt sets the creation date of the file at path, as shown by the Finder.
+func setCreatedAt(path string, t time.Time) error {
+	ts, err := unix.TimeToTimespec(t)
+	if err != nil {
+		return err
+	}
+	attrs := unix.Attrlist{Bitmapcount: unix.ATTR_BIT_MAP_COUNT, Commonattr: unix.ATTR_CMN_CRTIME}
+	return unix.Setattrlist(path, &attrs, unsafe.Slice((*byte)(unsafe.Pointer(&ts)), unsafe.Sizeof(ts)), 0)
+}
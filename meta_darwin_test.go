@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestCLI_Run_InverseAndRename_KeepsFinderMetadata(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	now := time.Now()
+	original := filepath.Join(dir, "book.pdf")
+	createTestFileWithModTime(t, original, "original", now.Add(-time.Hour))
+	tags := []byte("bplist00\xa1\x01UGreen\n2\x08\x0a")
+	if err := unix.Setxattr(original, "com.apple.metadata:_kMDItemUserTags", tags, 0); err != nil {
+		t.Skipf("extended attributes aren't supported here: %v", err)
+	}
+	before, err := os.Stat(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the copy is made after the original, so has a later creation date
+	time.Sleep(10 * time.Millisecond)
+	createTestFileWithModTime(t, filepath.Join(dir, "book (1).pdf"), "newest", now)
+
+	cli := &CLI{
+		Path:             []string{dir},
+		Delete:           true,
+		AllowDifferent:   true,
+		InverseAndRename: true,
+		Out:              filepath.Join(dir, "results.txt"),
+		Regex:            []string{defaultRegex},
+	}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	got, err := getxattr(original, "com.apple.metadata:_kMDItemUserTags")
+	if err != nil || !bytes.Equal(got, tags) {
+		t.Errorf("tags of the renamed file = %q, %v, want %q", got, err, tags)
+	}
+	after, err := os.Stat(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, a := before.Sys().(*syscall.Stat_t).Birthtimespec, after.Sys().(*syscall.Stat_t).Birthtimespec; b != a {
+		t.Errorf("creation date of the renamed file = %v, want %v", a, b)
+	}
+}
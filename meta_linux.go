@@ -0,0 +1,16 @@
+package main
+
+import (
+	"io/fs"
+	"time"
+)
+
+// createdAt returns the zero time, as a file's creation time can't be set on Linux.
+func createdAt(fs.FileInfo) time.Time {
+	return time.Time{}
+}
+
+// setCreatedAt does nothing.
+func setCreatedAt(string, time.Time) error {
+	return nil
+}
@@ -0,0 +1,33 @@
+//go:build !linux && !darwin
+
+package main
+
+import (
+	"io/fs"
+	"time"
+)
+
+// ownerOf returns -1, as only the owners of files on Linux and macOS are carried over.
+func ownerOf(fs.FileInfo) (uid, gid int) {
+	return -1, -1
+}
+
+// readXattrs returns nothing, as only the extended attributes of files on Linux and macOS are carried over.
+func readXattrs(string) (map[string][]byte, error) {
+	return nil, nil
+}
+
+// writeXattrs does nothing.
+func writeXattrs(string, map[string][]byte) error {
+	return nil
+}
+
+// createdAt returns the zero time, as only the creation dates of files on macOS are carried over.
+func createdAt(fs.FileInfo) time.Time {
+	return time.Time{}
+}
+
+// setCreatedAt does nothing.
+func setCreatedAt(string, time.Time) error {
+	return nil
+}
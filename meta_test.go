@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestCLI_Run_InverseAndRename_KeepsPermissions(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows files have no permission bits")
+	}
+	dir := setupTestDir(t)
+	now := time.Now()
+	original := filepath.Join(dir, "book.pdf")
+	createTestFileWithModTime(t, original, "original", now.Add(-time.Hour))
+	createTestFileWithModTime(t, filepath.Join(dir, "book (1).pdf"), "newest", now)
+	if err := os.Chmod(original, 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	cli := &CLI{
+		Path:             []string{dir},
+		Delete:           true,
+		AllowDifferent:   true,
+		InverseAndRename: true,
+		Out:              filepath.Join(dir, "results.txt"),
+		Regex:            []string{defaultRegex},
+	}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	info, err := os.Stat(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o640 {
+		t.Errorf("mode of the renamed file = %v, want %v", info.Mode().Perm(), os.FileMode(0o640))
+	}
+}
+
+func TestWriteFileMeta(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	from, to := filepath.Join(dir, "from"), filepath.Join(dir, "to")
+	createTestFile(t, from, "from")
+	createTestFile(t, to, "to")
+
+	m, err := readFileMeta(from)
+	if err != nil {
+		t.Fatalf("readFileMeta() error = %v", err)
+	}
+	if err := writeFileMeta(to, m); err != nil {
+		t.Errorf("writeFileMeta() of a file with the same owner error = %v", err)
+	}
+	if err := writeFileMeta(filepath.Join(dir, "missing"), m); !os.IsNotExist(err) {
+		t.Errorf("writeFileMeta() of a missing file error = %v, want not exist", err)
+	}
+}
@@ -0,0 +1,78 @@
+//go:build linux || darwin
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// ownerOf returns the owner and group of the file described by info.
+func ownerOf(info fs.FileInfo) (uid, gid int) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return -1, -1
+	}
+	return int(st.Uid), int(st.Gid)
+}
+
+// readXattrs reads every extended attribute of the file at path.
+func readXattrs(path string) (map[string][]byte, error) {
+	size, err := unix.Listxattr(path, nil)
+	if errors.Is(err, unix.ENOTSUP) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the extended attributes of %s: %w", path, err)
+	}
+	names := make([]byte, size)
+	n, err := unix.Listxattr(path, names)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the extended attributes of %s: %w", path, err)
+	}
+	xattrs := map[string][]byte{}
+	for name := range strings.SplitSeq(string(names[:n]), "\x00") {
+		if name == "" {
+			continue
+		}
+		value, err := getxattr(path, name)
+		if err != nil {
+			return xattrs, fmt.Errorf("unable to read %s of %s: %w", name, path, err)
+		}
+		xattrs[name] = value
+	}
+	return xattrs, nil
+}
+
+func getxattr(path, name string) ([]byte, error) {
+	size, err := unix.Getxattr(path, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	value := make([]byte, size)
+	n, err := unix.Getxattr(path, name, value)
+	if err != nil {
+		return nil, err
+	}
+	return value[:n], nil
+}
+
+// writeXattrs sets the extended attributes of the file at path which differ from xattrs.
+func writeXattrs(path string, xattrs map[string][]byte) error {
+	var errs []error
+	for name, value := range xattrs {
+		if current, err := getxattr(path, name); err == nil && bytes.Equal(current, value) {
+			continue
+		}
+		if err := unix.Setxattr(path, name, value, 0); err != nil {
+			errs = append(errs, fmt.Errorf("unable to set %s of %s: %w", name, path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
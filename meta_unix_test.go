@@ -0,0 +1,37 @@
+//go:build linux || darwin
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestXattrs(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	from, to := filepath.Join(dir, "from"), filepath.Join(dir, "to")
+	createTestFile(t, from, "from")
+	createTestFile(t, to, "to")
+	if err := unix.Setxattr(from, "user.ohman.test", []byte("value"), 0); err != nil {
+		if errors.Is(err, unix.ENOTSUP) || errors.Is(err, unix.EPERM) {
+			t.Skipf("extended attributes aren't supported here: %v", err)
+		}
+		t.Fatal(err)
+	}
+
+	xattrs, err := readXattrs(from)
+	if err != nil {
+		t.Fatalf("readXattrs() error = %v", err)
+	}
+	if err := writeXattrs(to, xattrs); err != nil {
+		t.Fatalf("writeXattrs() error = %v", err)
+	}
+	if got, err := getxattr(to, "user.ohman.test"); err != nil || !bytes.Equal(got, []byte("value")) {
+		t.Errorf("user.ohman.test = %q, %v, want %q", got, err, "value")
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// durationBuckets are the upper bounds, in seconds, of the run duration histogram's buckets.
+var durationBuckets = []float64{1, 5, 15, 60, 300, 900, 3600, 14400}
+
+// metrics totals what runs have done since ohman started, for Prometheus to scrape from /metrics. A nil *metrics
+// records nothing.
+type metrics struct {
+	mu         sync.Mutex
+	succeeded  int64
+	failed     int64
+	scanned    int64
+	duplicates int64
+	deleted    int64
+	reclaimed  int64
+	failures   int64
+	// durations counts the runs which took no longer than each of durationBuckets.
+	durations []int64
+	seconds   float64
+}
+
+func newMetrics() *metrics {
+	return &metrics{durations: make([]int64, len(durationBuckets))}
+}
+
+// recordScan records a run which scanned files and found groups, taking took, or failed with err.
+func (m *metrics) recordScan(took time.Duration, scanned int64, groups []group, err error) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		m.failed++
+	} else {
+		m.succeeded++
+	}
+	m.scanned += scanned
+	for _, g := range groups {
+		m.duplicates += int64(len(g.Duplicates))
+	}
+	seconds := took.Seconds()
+	m.seconds += seconds
+	for i, le := range durationBuckets {
+		if seconds <= le {
+			m.durations[i]++
+		}
+	}
+}
+
+// recordActions records the actions taken on groups.
+func (m *metrics) recordActions(groups []group) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, g := range groups {
+		for _, a := range g.Actions {
+			switch {
+			case a.Err != nil:
+				m.failures++
+			case a.Op == opDelete:
+				m.deleted++
+				m.reclaimed += a.Size
+			}
+		}
+	}
+}
+
+// ServeHTTP writes the metrics in Prometheus's text format.
+func (m *metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = m.write(w)
+}
+
+func (m *metrics) write(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var err error
+	printf := func(format string, a ...any) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, a...)
+		}
+	}
+	counter := func(name, help string, v int64) {
+		printf("# HELP %s %s\n# TYPE %s counter\n%s %d\n", name, help, name, name, v)
+	}
+
+	printf("# HELP ohman_runs_total Runs finished, by whether they succeeded.\n# TYPE ohman_runs_total counter\n")
+	printf("ohman_runs_total{result=\"succeeded\"} %d\nohman_runs_total{result=\"failed\"} %d\n", m.succeeded, m.failed)
+	counter("ohman_files_scanned_total", "Files scanned.", m.scanned)
+	counter("ohman_duplicates_found_total", "Duplicates found.", m.duplicates)
+	counter("ohman_files_deleted_total", "Duplicates deleted.", m.deleted)
+	counter("ohman_bytes_reclaimed_total", "Bytes freed by deleting duplicates.", m.reclaimed)
+	counter("ohman_action_failures_total", "Deletions and renames which failed.", m.failures)
+
+	const name = "ohman_run_duration_seconds"
+	printf("# HELP %s How long each run took.\n# TYPE %s histogram\n", name, name)
+	for i, le := range durationBuckets {
+		printf("%s_bucket{le=\"%s\"} %d\n", name, strconv.FormatFloat(le, 'g', -1, 64), m.durations[i])
+	}
+	runs := m.succeeded + m.failed
+	printf("%s_bucket{le=\"+Inf\"} %d\n%s_sum %s\n%s_count %d\n", name, runs, name, strconv.FormatFloat(m.seconds, 'g', -1, 64), name, runs)
+	return err
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics_Write(t *testing.T) {
+	t.Parallel()
+	m := newMetrics()
+	groups := []group{{Original: "/a/book.pdf", Duplicates: []string{"/a/book (1).pdf", "/a/book (2).pdf"}, Actions: []action{
+		{Op: opKeep, Path: "/a/book.pdf", implicit: true},
+		{Op: opDelete, Path: "/a/book (1).pdf", Size: 1024},
+		{Op: opDelete, Path: "/a/book (2).pdf", Err: errors.New("permission denied")},
+	}}}
+	m.recordScan(2*time.Second, 3, groups, nil)
+	m.recordActions(groups)
+	m.recordScan(time.Hour, 10, nil, errors.New("no such directory"))
+
+	var sb strings.Builder
+	if err := m.write(&sb); err != nil {
+		t.Fatalf("write() error = %v", err)
+	}
+	for _, want := range []string{
+		"# TYPE ohman_runs_total counter\n",
+		`ohman_runs_total{result="succeeded"} 1` + "\n",
+		`ohman_runs_total{result="failed"} 1` + "\n",
+		"ohman_files_scanned_total 13\n",
+		"ohman_duplicates_found_total 2\n",
+		"ohman_files_deleted_total 1\n",
+		"ohman_bytes_reclaimed_total 1024\n",
+		"ohman_action_failures_total 1\n",
+		"# TYPE ohman_run_duration_seconds histogram\n",
+		`ohman_run_duration_seconds_bucket{le="1"} 0` + "\n",
+		`ohman_run_duration_seconds_bucket{le="5"} 1` + "\n",
+		`ohman_run_duration_seconds_bucket{le="3600"} 2` + "\n",
+		`ohman_run_duration_seconds_bucket{le="+Inf"} 2` + "\n",
+		"ohman_run_duration_seconds_sum 3602\n",
+		"ohman_run_duration_seconds_count 2\n",
+	} {
+		if !strings.Contains(sb.String(), want) {
+			t.Errorf("metrics are missing %q:\n%s", want, sb.String())
+		}
+	}
+
+	var nothing *metrics
+	nothing.recordScan(time.Second, 1, groups, nil)
+	nothing.recordActions(groups)
+}
+
+func TestServer_Metrics(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "original content")
+	createTestFile(t, filepath.Join(dir, "book (1).pdf"), "original content")
+
+	ts := newTestServer(t, "")
+	var started jobResponse
+	body, _ := json.Marshal(scanRequest{Paths: []string{dir}})
+	doJSON(t, http.MethodPost, ts.URL+"/scans", "", string(body), http.StatusAccepted, &started)
+	waitForStatus(t, ts.URL+"/scans/"+started.ID, jobScanned)
+	doJSON(t, http.MethodPost, ts.URL+"/scans/"+started.ID+"/execute", "", "", http.StatusAccepted, nil)
+	waitForStatus(t, ts.URL+"/scans/"+started.ID, jobCompleted)
+
+	resp, err := http.Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	b, _ := io.ReadAll(resp.Body)
+	for _, want := range []string{"ohman_files_scanned_total 2\n", "ohman_files_deleted_total 1\n", "ohman_bytes_reclaimed_total 16\n"} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("metrics are missing %q:\n%s", want, b)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// CompareCmd compares two trees, such as two old backup drives, finding the files at the same path within both.
+type CompareCmd struct {
+	CLI  `embed:""`
+	Keep string `name:"keep" enum:"a,b" default:"a" help:"Which tree keeps its copy of each identical file: a, the first, or b, the second. With --delete, the other tree's copy is deleted."`
+}
+
+func (m *CompareCmd) Run(kctx *Context) error {
+	if len(m.Path) != 2 || slices.Contains(m.Path, stdinPath) || m.PathsFrom != "" {
+		return errors.New("compare needs exactly two directories, e.g. ohman compare /mnt/old-backup /mnt/new-backup")
+	}
+	if m.Match != "" && m.Match != "name" || m.PluginMatcher != "" {
+		return errors.New("compare always matches files by their path within each tree")
+	}
+	if m.Inverse || m.InverseAndRename {
+		return errors.New("compare always keeps the copy in the tree given by --keep, in place of --inverse")
+	}
+	if within(m.Path[0], m.Path[1]) || within(m.Path[1], m.Path[0]) {
+		return errors.New("compare needs two separate trees, neither within the other")
+	}
+	kept := m.Path[0]
+	if m.Keep == "b" {
+		kept = m.Path[1]
+	}
+	m.PreferPath, m.mirror = []string{kept}, true
+	return m.CLI.Run(kctx)
+}
+
+// renderAlone lists the files found within only one of the trees compared, under a heading for each tree.
+func renderAlone(roots, alone []string, markdown bool) string {
+	var sb strings.Builder
+	for _, root := range roots {
+		var paths []string
+		for _, p := range alone {
+			if within(p, root) {
+				paths = append(paths, p)
+			}
+		}
+		if len(paths) == 0 {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		if markdown {
+			fmt.Fprintf(&sb, "### Only in %s\n\n", markdownCode(root))
+		} else {
+			fmt.Fprintf(&sb, "Only in %s:\n", root)
+		}
+		for _, p := range slices.Sorted(slices.Values(paths)) {
+			if markdown {
+				fmt.Fprintf(&sb, "- %s\n", markdownCode(p))
+			} else {
+				fmt.Fprintf(&sb, "  - %s\n", p)
+			}
+		}
+	}
+	return sb.String()
+}
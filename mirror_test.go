@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompareCmd_Run(t *testing.T) {
+	t.Parallel()
+	a, b := setupTestDir(t), setupTestDir(t)
+	for _, dir := range []string{a, b} {
+		if err := os.Mkdir(filepath.Join(dir, "photos"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		createTestFile(t, filepath.Join(dir, "photos/beach.jpg"), "beach")
+		createTestFile(t, filepath.Join(dir, "notes.txt"), "notes from "+dir)
+	}
+	createTestFile(t, filepath.Join(a, "only-a.pdf"), "a")
+	createTestFile(t, filepath.Join(b, "only-b.pdf"), "b")
+	// a copy by name within one tree is no concern of compare
+	createTestFile(t, filepath.Join(b, "only-b (1).pdf"), "b")
+
+	out := filepath.Join(t.TempDir(), "results.txt")
+	cmd := &CompareCmd{CLI: CLI{Path: []string{a, b}, Delete: true, Out: out, Regex: []string{defaultRegex}}, Keep: "a"}
+	if err := cmd.Run(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fileExists(filepath.Join(b, "photos/beach.jpg")) {
+		t.Error("b's identical copy of photos/beach.jpg should be deleted")
+	}
+	for _, path := range []string{
+		filepath.Join(a, "photos/beach.jpg"),
+		filepath.Join(a, "notes.txt"),
+		// their content differs
+		filepath.Join(b, "notes.txt"),
+		filepath.Join(b, "only-b.pdf"),
+		filepath.Join(b, "only-b (1).pdf"),
+	} {
+		if !fileExists(path) {
+			t.Errorf("%s should be kept", path)
+		}
+	}
+
+	results, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Only in " + a + ":\n  - " + filepath.Join(a, "only-a.pdf"),
+		"Only in " + b + ":\n  - " + filepath.Join(b, "only-b (1).pdf") + "\n  - " + filepath.Join(b, "only-b.pdf"),
+	} {
+		if !strings.Contains(string(results), want) {
+			t.Errorf("results missing %q:\n%s", want, results)
+		}
+	}
+}
+
+func TestCompareCmd_Run_NeedsTwoTrees(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	for _, paths := range [][]string{{dir}, {dir, filepath.Join(dir, "sub")}} {
+		cmd := &CompareCmd{CLI: CLI{Path: paths}, Keep: "a"}
+		if err := cmd.Run(nil); err == nil {
+			t.Errorf("compare %q succeeded, want an error", paths)
+		}
+	}
+}
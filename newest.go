@@ -0,0 +1,100 @@
+package main
+
+import (
+	"cmp"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// The rules which choose the newest of copies which are equally new, in the order they're applied.
+const (
+	tieBySize       = "the largest of the newest copies"
+	tieByCopyNumber = "the lowest numbered of the newest copies"
+	tieByPath       = "the first by path of the newest copies"
+)
+
+// modTimes returns when each file described by infos was modified. Those modified within tolerance of the newest are
+// given the newest's time, as filesystems and sync services which round times (FAT32 to 2 seconds, for one) can
+// otherwise make either of two copies saved together look newer. Files without a description are given the zero time.
+func modTimes(infos []os.FileInfo, tolerance time.Duration) []time.Time {
+	times := make([]time.Time, len(infos))
+	var newest time.Time
+	for i, info := range infos {
+		if info != nil {
+			times[i] = info.ModTime()
+		}
+		if times[i].After(newest) {
+			newest = times[i]
+		}
+	}
+	for i, t := range times {
+		if !t.IsZero() && newest.Sub(t) < tolerance {
+			times[i] = newest
+		}
+	}
+	return times
+}
+
+// newestFirst orders paths from the most to the least recently modified, as judged by modTimes. Copies which are
+// equally new are ordered largest first, then by their copy numbers, lowest first, and then by path. It returns the
+// tie-breaking rule which chose the first, if the modification times alone didn't. Nothing is reordered when any of
+// paths can't be described, so the newest is never chosen without knowing how new each is.
+func newestFirst(paths []string, stat func(string) (os.FileInfo, error), tolerance time.Duration) (rule string, err error) {
+	type candidate struct {
+		path    string
+		size    int64
+		number  int
+		modTime time.Time
+	}
+	infos := make([]os.FileInfo, len(paths))
+	for i, p := range paths {
+		if infos[i], err = stat(p); err != nil {
+			return "", err
+		}
+	}
+	times := modTimes(infos, tolerance)
+	candidates := make([]candidate, len(paths))
+	for i, p := range paths {
+		candidates[i] = candidate{path: p, size: infos[i].Size(), number: copyNumber(p), modTime: times[i]}
+	}
+	byNumber := func(a, b candidate) int {
+		// copies without a recognizable number sort last
+		if a.number < 0 || b.number < 0 {
+			return cmp.Compare(b.number, a.number)
+		}
+		return cmp.Compare(a.number, b.number)
+	}
+	slices.SortFunc(candidates, func(a, b candidate) int {
+		return cmp.Or(b.modTime.Compare(a.modTime), cmp.Compare(b.size, a.size), byNumber(a, b), cmp.Compare(a.path, b.path))
+	})
+	for i, c := range candidates {
+		paths[i] = c.path
+	}
+
+	if len(candidates) < 2 {
+		return "", nil
+	}
+	first, second := candidates[0], candidates[1]
+	switch {
+	case !first.modTime.Equal(second.modTime):
+		return "", nil
+	case first.size != second.size:
+		return tieBySize, nil
+	case byNumber(first, second) != 0:
+		return tieByCopyNumber, nil
+	default:
+		return tieByPath, nil
+	}
+}
+
+// copyNumber returns the copy number closest to path's extension, e.g. 2 for "book (2).pdf", or -1 if it has none.
+func copyNumber(path string) int {
+	if m := copySuffix.FindStringSubmatch(path); m != nil {
+		if n, err := strconv.Atoi(m[1]); err == nil {
+			return n
+		}
+	}
+	return -1
+}
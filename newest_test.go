@@ -0,0 +1,52 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestNewestFirst(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	files := fstest.MapFS{
+		"a.pdf":     {ModTime: now.Add(-time.Second)},
+		"b.pdf":     {ModTime: now},
+		"c.pdf":     {ModTime: now.Add(-time.Hour)},
+		"d.pdf":     {ModTime: now.Add(-1500 * time.Millisecond), Data: []byte("large")},
+		"e (2).pdf": {ModTime: now.Add(-time.Hour)},
+		"e (1).pdf": {ModTime: now.Add(-time.Hour)},
+		"f.pdf":     {ModTime: now.Add(-time.Hour)},
+	}
+	stat := func(path string) (os.FileInfo, error) { return fs.Stat(files, path) }
+
+	tests := []struct {
+		paths     []string
+		tolerance time.Duration
+		want      []string
+		rule      string
+	}{
+		{[]string{"a.pdf", "b.pdf", "c.pdf", "d.pdf"}, 0, []string{"b.pdf", "a.pdf", "d.pdf", "c.pdf"}, ""},
+		{[]string{"a.pdf", "b.pdf", "c.pdf", "d.pdf"}, 2 * time.Second, []string{"d.pdf", "a.pdf", "b.pdf", "c.pdf"}, tieBySize},
+		{[]string{"f.pdf", "e (2).pdf", "e (1).pdf"}, 0, []string{"e (1).pdf", "e (2).pdf", "f.pdf"}, tieByCopyNumber},
+		{[]string{"f.pdf", "c.pdf"}, 0, []string{"c.pdf", "f.pdf"}, tieByPath},
+	}
+	for _, tt := range tests {
+		got := slices.Clone(tt.paths)
+		rule, err := newestFirst(got, stat, tt.tolerance)
+		if err != nil {
+			t.Fatalf("newestFirst(%q) error = %v", tt.paths, err)
+		}
+		if !slices.Equal(got, tt.want) || rule != tt.rule {
+			t.Errorf("newestFirst(%q, %s) = %q, %q, want %q, %q", tt.paths, tt.tolerance, got, rule, tt.want, tt.rule)
+		}
+	}
+
+	paths := []string{"b.pdf", "missing.pdf", "a.pdf"}
+	if _, err := newestFirst(paths, stat, 0); err == nil || !slices.Equal(paths, []string{"b.pdf", "missing.pdf", "a.pdf"}) {
+		t.Errorf("newestFirst() with a missing file = %q, %v, want an error and no change", paths, err)
+	}
+}
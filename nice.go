@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// beNice lowers ohman's CPU and I/O priority for --nice, so a scan on a NAS doesn't slow down the file shares it
+// serves. ohman carries on at normal priority, with a warning, when that isn't permitted.
+func (c *CLI) beNice() {
+	if !c.Nice {
+		return
+	}
+	if err := setLowPriority(); err != nil {
+		fmt.Fprintln(os.Stderr, tr("Warning: couldn't lower ohman's priority: %v", err))
+	}
+}
+
+// jobs returns how many groups may be compared at once: --jobs, or one with --nice, or else one per CPU.
+func (c *CLI) jobs() int {
+	switch {
+	case c.Jobs > 0:
+		return c.Jobs
+	case c.Nice:
+		return 1
+	}
+	return runtime.NumCPU()
+}
+
+// lists returns how many directories a local walk lists at once: just one with --nice, or else listAhead.
+func (c *CLI) lists() int {
+	if c.Nice {
+		return 1
+	}
+	return listAhead
+}
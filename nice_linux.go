@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// ioprioClassIdle is the I/O scheduling class which only gets the disk when nothing else wants it.
+	ioprioClassIdle  = 3
+	ioprioClassShift = 13
+	ioprioWhoProcess = 1
+)
+
+// setLowPriority gives every thread of ohman the lowest CPU priority and the idle I/O class, as ionice -c3 does. Both
+// are per thread on Linux, and threads started later inherit them from the thread starting them.
+func setLowPriority() error {
+	tasks, err := os.ReadDir("/proc/self/task")
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, task := range tasks {
+		tid, err := strconv.Atoi(task.Name())
+		if err != nil {
+			continue
+		}
+		if err := unix.Setpriority(unix.PRIO_PROCESS, tid, 19); err != nil && err != unix.ESRCH {
+			errs = append(errs, err)
+		}
+		_, _, errno := unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), ioprioClassIdle<<ioprioClassShift)
+		if errno != 0 && errno != unix.ESRCH {
+			errs = append(errs, errno)
+		}
+	}
+	return errors.Join(errs...)
+}
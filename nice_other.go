@@ -0,0 +1,8 @@
+//go:build !unix && !windows
+
+package main
+
+// setLowPriority does nothing, as there's no way to lower ohman's priority here.
+func setLowPriority() error {
+	return nil
+}
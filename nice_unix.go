@@ -0,0 +1,10 @@
+//go:build unix && !linux
+
+package main
+
+import "golang.org/x/sys/unix"
+
+// setLowPriority gives ohman the lowest CPU priority. There's no portable way to lower its I/O priority here.
+func setLowPriority() error {
+	return unix.Setpriority(unix.PRIO_PROCESS, 0, 19)
+}
@@ -0,0 +1,12 @@
+package main
+
+import "golang.org/x/sys/windows"
+
+// setLowPriority puts ohman in background mode, which lowers its CPU, I/O, and memory priority together.
+func setLowPriority() error {
+	err := windows.SetPriorityClass(windows.CurrentProcess(), windows.PROCESS_MODE_BACKGROUND_BEGIN)
+	if err == windows.ERROR_PROCESS_MODE_ALREADY_BACKGROUND {
+		return nil
+	}
+	return err
+}
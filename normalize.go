@@ -0,0 +1,70 @@
+package main
+
+import "golang.org/x/text/unicode/norm"
+
+// copyIndex maps originals to their copies as files are found, matching names which differ only in their Unicode
+// normalization. macOS writes "é" decomposed (NFD) while most other systems write it composed (NFC), so a copy of
+// Café.pdf made on a Mac wouldn't otherwise be recognised as one on Linux.
+type copyIndex struct {
+	patterns copyPatterns
+	// copies holds the copies of each original, by the NFC form of the original's path.
+	copies map[string][]string
+	// found holds the paths of files not in NFC, by their NFC form. Files in NFC are found by the form itself.
+	found map[string]string
+	// dirs holds the keys of copies and found for each directory, so a directory's entries can be taken once it has
+	// been walked.
+	dirs map[string][]string
+}
+
+func newCopyIndex(patterns copyPatterns) *copyIndex {
+	return &copyIndex{patterns: patterns, copies: map[string][]string{}, found: map[string]string{}, dirs: map[string][]string{}}
+}
+
+// add records the file at path, and its original if it's a copy.
+func (x *copyIndex) add(path string) {
+	dir := parentDir(path)
+	if nfc := norm.NFC.String(path); nfc != path {
+		x.found[nfc] = path
+		x.dirs[dir] = append(x.dirs[dir], nfc)
+	}
+	if original, ok := originalFor(x.patterns, path); ok {
+		key := norm.NFC.String(original)
+		if _, ok := x.copies[key]; !ok {
+			x.dirs[dir] = append(x.dirs[dir], key)
+		}
+		x.copies[key] = append(x.copies[key], path)
+	}
+}
+
+// take returns the copies found for each original in dir, like files, and forgets them. Copies are always in the
+// same directory as their original, so once dir has been walked its entries are complete.
+func (x *copyIndex) take(dir string) map[string][]string {
+	files := make(map[string][]string)
+	for _, key := range x.dirs[dir] {
+		if copies, ok := x.copies[key]; ok {
+			original := key
+			if path, ok := x.found[key]; ok {
+				original = path
+			}
+			files[original] = copies
+		}
+		delete(x.copies, key)
+		delete(x.found, key)
+	}
+	delete(x.dirs, dir)
+	return files
+}
+
+// files returns the copies found for each original, by the original's path as it was found. Originals which
+// weren't found are named in NFC.
+func (x *copyIndex) files() map[string][]string {
+	files := make(map[string][]string, len(x.copies))
+	for key, copies := range x.copies {
+		original := key
+		if path, ok := x.found[key]; ok {
+			original = path
+		}
+		files[original] = copies
+	}
+	return files
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestCopyIndex(t *testing.T) {
+	t.Parallel()
+	nfc, nfd := "Caf\u00e9", "Cafe\u0301"
+	for name, tc := range map[string]struct {
+		original string
+		copies   []string
+		// originalFound reports whether the original is among the files found
+		originalFound bool
+	}{
+		"NFD copy of an NFC original": {original: nfc + ".pdf", copies: []string{nfd + " (1).pdf"}, originalFound: true},
+		"NFC copy of an NFD original": {original: nfd + ".pdf", copies: []string{nfc + " (1).pdf"}, originalFound: true},
+		"copies in both forms":        {original: nfc + ".pdf", copies: []string{nfd + " (1).pdf", nfc + " (2).pdf"}, originalFound: true},
+		"missing original":            {original: nfc + ".pdf", copies: []string{nfd + " (1).pdf"}},
+	} {
+		index := newCopyIndex(copyPatterns{{re: regexp.MustCompile(defaultRegex)}})
+		if tc.originalFound {
+			index.add(filepath.Join("/media", tc.original))
+		}
+		var copies []string
+		for _, c := range tc.copies {
+			copies = append(copies, filepath.Join("/media", c))
+			index.add(filepath.Join("/media", c))
+		}
+		files := index.files()
+		if got := files[filepath.Join("/media", tc.original)]; len(files) != 1 || !slices.Equal(got, copies) {
+			t.Errorf("%s: files() = %q, want %q under %q", name, files, copies, tc.original)
+		}
+	}
+}
+
+func TestCLI_Run_NormalizedNames(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	createTestFile(t, filepath.Join(dir, "Caf\u00e9.pdf"), "same")
+	createTestFile(t, filepath.Join(dir, "Cafe\u0301 (1).pdf"), "same")
+
+	cli := &CLI{
+		Path:   []string{dir},
+		Delete: true,
+		Out:    filepath.Join(dir, "results.txt"),
+		Regex:  []string{defaultRegex},
+	}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !fileExists(filepath.Join(dir, "Caf\u00e9.pdf")) {
+		t.Error("original should still exist")
+	}
+	if fileExists(filepath.Join(dir, "Cafe\u0301 (1).pdf")) {
+		t.Error("copy with a decomposed name should be deleted")
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// chatFailures bounds how many failures a --notify message lists.
+	chatFailures = 10
+	// discordLimit is the most characters Discord accepts in a message.
+	discordLimit = 2000
+)
+
+// notifying reports whether --notify is set.
+func (c *CLI) notifying() bool {
+	return c.Notify != "" && c.Notify != "none"
+}
+
+// chatPayload is the body POSTed to a --notify-url incoming webhook, summarising the run for people rather than
+// programs.
+func (c *CLI) chatPayload(started time.Time, groups []group, runErr error) any {
+	msg := c.chatMessage(started, groups, runErr)
+	if c.Notify == "discord" {
+		if runes := []rune(msg); len(runes) > discordLimit {
+			msg = string(runes[:discordLimit-1]) + "…"
+		}
+		return map[string]string{"content": msg}
+	}
+	return map[string]string{"text": msg}
+}
+
+// chatMessage summarises the run: where it ran, what it found and did, how much space it freed, and what failed.
+func (c *CLI) chatMessage(started time.Time, groups []group, runErr error) string {
+	bold := "*"
+	if c.Notify == "discord" {
+		bold = "**"
+	}
+	dryRun := c.DryRun || !c.Delete
+	n := countGroups(groups)
+	if c.Stream {
+		// streamed groups aren't held, only counted
+		n = c.streamed
+	}
+
+	var sb strings.Builder
+	event := "completed"
+	if runErr != nil {
+		event = "failed"
+	}
+	fmt.Fprintf(&sb, "%sohman %s%s on %s in %s", bold, event, bold, strings.Join(c.Path, ", "), time.Since(started).Round(time.Second))
+	if dryRun {
+		sb.WriteString(" (dry run)")
+	}
+	sb.WriteString("\n" + c.summary(n))
+	if n.Reclaimed > 0 {
+		fmt.Fprintf(&sb, " Reclaimed %s.", byteSize(n.Reclaimed))
+	}
+	if runErr != nil {
+		fmt.Fprintf(&sb, "\nError: %v", runErr)
+	}
+
+	var failed []action
+	for _, g := range groups {
+		for _, a := range g.Actions {
+			if a.Err != nil {
+				failed = append(failed, a)
+			}
+		}
+	}
+	if len(failed) > 0 {
+		sb.WriteString("\nFailures:")
+		for _, a := range failed[:min(len(failed), chatFailures)] {
+			fmt.Fprintf(&sb, "\n• %s: %v", a.Path, a.Err)
+		}
+		if len(failed) > chatFailures {
+			fmt.Fprintf(&sb, "\n…and %d more", len(failed)-chatFailures)
+		}
+	}
+	return sb.String()
+}
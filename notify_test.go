@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestCLI_Run_Notify(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		service, field, bold string
+	}{
+		{"slack", "text", "*ohman completed*"},
+		{"discord", "content", "**ohman completed**"},
+	} {
+		t.Run(tt.service, func(t *testing.T) {
+			t.Parallel()
+			received := make(chan map[string]string, 1)
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]string
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("invalid payload: %v", err)
+				}
+				received <- body
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer ts.Close()
+
+			cli := &CLI{
+				Path:      []string{"/media"},
+				Delete:    true,
+				Out:       filepath.Join(t.TempDir(), "results.txt"),
+				Regex:     []string{defaultRegex},
+				Notify:    tt.service,
+				NotifyURL: ts.URL,
+				memory: fstest.MapFS{
+					"media/book.pdf":     {Data: []byte("content")},
+					"media/book (1).pdf": {Data: []byte("content")},
+				},
+			}
+			if err := cli.Run(nil); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			msg := (<-received)[tt.field]
+			for _, want := range []string{tt.bold + " on /media", "Found 1 duplicate(s) of 1 file(s): deleted 1, renamed 0, failed 0.", "Reclaimed 7 B."} {
+				if !strings.Contains(msg, want) {
+					t.Errorf("message %q doesn't contain %q", msg, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCLI_ChatMessage_Failures(t *testing.T) {
+	t.Parallel()
+	var groups []group
+	for i := range chatFailures + 2 {
+		path := "/media/book (" + string(rune('a'+i)) + ").pdf"
+		groups = append(groups, group{Original: "/media/book.pdf", Duplicates: []string{path}, Actions: []action{
+			{Op: opDelete, Path: path, Err: errors.New("permission denied")},
+		}})
+	}
+	cli := &CLI{Path: []string{"/media"}, Delete: true, Notify: "slack"}
+	msg := cli.chatMessage(time.Now(), groups, errors.New("timed out"))
+	for _, want := range []string{"*ohman failed* on /media", "Error: timed out", "• /media/book (a).pdf: permission denied", "…and 2 more"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message %q doesn't contain %q", msg, want)
+		}
+	}
+	if strings.Contains(msg, "(l).pdf") {
+		t.Errorf("message lists more than %d failures: %q", chatFailures, msg)
+	}
+
+	if err := (&CLI{Path: []string{"/media"}, Regex: []string{defaultRegex}, Notify: "discord"}).Run(nil); err == nil || !strings.Contains(err.Error(), "--notify-url") {
+		t.Errorf("expected --notify without --notify-url to fail, got %v", err)
+	}
+}
@@ -0,0 +1,4 @@
+// Package ohmanpb contains the generated protobuf and gRPC bindings for ohman's gRPC API (see ohman.proto).
+package ohmanpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative ohman.proto
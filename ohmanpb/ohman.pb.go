@@ -0,0 +1,725 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: ohman.proto
+
+package ohmanpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Mode int32
+
+const (
+	Mode_MODE_DELETE             Mode = 0
+	Mode_MODE_INVERSE            Mode = 1
+	Mode_MODE_INVERSE_AND_RENAME Mode = 2
+)
+
+// Enum value maps for Mode.
+var (
+	Mode_name = map[int32]string{
+		0: "MODE_DELETE",
+		1: "MODE_INVERSE",
+		2: "MODE_INVERSE_AND_RENAME",
+	}
+	Mode_value = map[string]int32{
+		"MODE_DELETE":             0,
+		"MODE_INVERSE":            1,
+		"MODE_INVERSE_AND_RENAME": 2,
+	}
+)
+
+func (x Mode) Enum() *Mode {
+	p := new(Mode)
+	*p = x
+	return p
+}
+
+func (x Mode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Mode) Descriptor() protoreflect.EnumDescriptor {
+	return file_ohman_proto_enumTypes[0].Descriptor()
+}
+
+func (Mode) Type() protoreflect.EnumType {
+	return &file_ohman_proto_enumTypes[0]
+}
+
+func (x Mode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Mode.Descriptor instead.
+func (Mode) EnumDescriptor() ([]byte, []int) {
+	return file_ohman_proto_rawDescGZIP(), []int{0}
+}
+
+type Status int32
+
+const (
+	Status_STATUS_UNSPECIFIED Status = 0
+	Status_STATUS_SCANNING    Status = 1
+	Status_STATUS_SCANNED     Status = 2
+	Status_STATUS_EXECUTING   Status = 3
+	Status_STATUS_COMPLETED   Status = 4
+	Status_STATUS_FAILED      Status = 5
+)
+
+// Enum value maps for Status.
+var (
+	Status_name = map[int32]string{
+		0: "STATUS_UNSPECIFIED",
+		1: "STATUS_SCANNING",
+		2: "STATUS_SCANNED",
+		3: "STATUS_EXECUTING",
+		4: "STATUS_COMPLETED",
+		5: "STATUS_FAILED",
+	}
+	Status_value = map[string]int32{
+		"STATUS_UNSPECIFIED": 0,
+		"STATUS_SCANNING":    1,
+		"STATUS_SCANNED":     2,
+		"STATUS_EXECUTING":   3,
+		"STATUS_COMPLETED":   4,
+		"STATUS_FAILED":      5,
+	}
+)
+
+func (x Status) Enum() *Status {
+	p := new(Status)
+	*p = x
+	return p
+}
+
+func (x Status) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Status) Descriptor() protoreflect.EnumDescriptor {
+	return file_ohman_proto_enumTypes[1].Descriptor()
+}
+
+func (Status) Type() protoreflect.EnumType {
+	return &file_ohman_proto_enumTypes[1]
+}
+
+func (x Status) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Status.Descriptor instead.
+func (Status) EnumDescriptor() ([]byte, []int) {
+	return file_ohman_proto_rawDescGZIP(), []int{1}
+}
+
+type ScanRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Paths         []string               `protobuf:"bytes,1,rep,name=paths,proto3" json:"paths,omitempty"`
+	Regex         string                 `protobuf:"bytes,2,opt,name=regex,proto3" json:"regex,omitempty"`
+	Mode          Mode                   `protobuf:"varint,3,opt,name=mode,proto3,enum=ohman.v1.Mode" json:"mode,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScanRequest) Reset() {
+	*x = ScanRequest{}
+	mi := &file_ohman_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScanRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScanRequest) ProtoMessage() {}
+
+func (x *ScanRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ohman_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScanRequest.ProtoReflect.Descriptor instead.
+func (*ScanRequest) Descriptor() ([]byte, []int) {
+	return file_ohman_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ScanRequest) GetPaths() []string {
+	if x != nil {
+		return x.Paths
+	}
+	return nil
+}
+
+func (x *ScanRequest) GetRegex() string {
+	if x != nil {
+		return x.Regex
+	}
+	return ""
+}
+
+func (x *ScanRequest) GetMode() Mode {
+	if x != nil {
+		return x.Mode
+	}
+	return Mode_MODE_DELETE
+}
+
+type JobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobRequest) Reset() {
+	*x = JobRequest{}
+	mi := &file_ohman_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobRequest) ProtoMessage() {}
+
+func (x *JobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ohman_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobRequest.ProtoReflect.Descriptor instead.
+func (*JobRequest) Descriptor() ([]byte, []int) {
+	return file_ohman_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *JobRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type Progress struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Scanned       int64                  `protobuf:"varint,1,opt,name=scanned,proto3" json:"scanned,omitempty"`
+	Groups        int64                  `protobuf:"varint,2,opt,name=groups,proto3" json:"groups,omitempty"`
+	Actions       int64                  `protobuf:"varint,3,opt,name=actions,proto3" json:"actions,omitempty"`
+	Failures      int64                  `protobuf:"varint,4,opt,name=failures,proto3" json:"failures,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Progress) Reset() {
+	*x = Progress{}
+	mi := &file_ohman_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Progress) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Progress) ProtoMessage() {}
+
+func (x *Progress) ProtoReflect() protoreflect.Message {
+	mi := &file_ohman_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Progress.ProtoReflect.Descriptor instead.
+func (*Progress) Descriptor() ([]byte, []int) {
+	return file_ohman_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Progress) GetScanned() int64 {
+	if x != nil {
+		return x.Scanned
+	}
+	return 0
+}
+
+func (x *Progress) GetGroups() int64 {
+	if x != nil {
+		return x.Groups
+	}
+	return 0
+}
+
+func (x *Progress) GetActions() int64 {
+	if x != nil {
+		return x.Actions
+	}
+	return 0
+}
+
+func (x *Progress) GetFailures() int64 {
+	if x != nil {
+		return x.Failures
+	}
+	return 0
+}
+
+type Job struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Status        Status                 `protobuf:"varint,2,opt,name=status,proto3,enum=ohman.v1.Status" json:"status,omitempty"`
+	Mode          Mode                   `protobuf:"varint,3,opt,name=mode,proto3,enum=ohman.v1.Mode" json:"mode,omitempty"`
+	Paths         []string               `protobuf:"bytes,4,rep,name=paths,proto3" json:"paths,omitempty"`
+	Created       *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created,proto3" json:"created,omitempty"`
+	Error         string                 `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"`
+	Progress      *Progress              `protobuf:"bytes,7,opt,name=progress,proto3" json:"progress,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Job) Reset() {
+	*x = Job{}
+	mi := &file_ohman_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Job) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Job) ProtoMessage() {}
+
+func (x *Job) ProtoReflect() protoreflect.Message {
+	mi := &file_ohman_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Job.ProtoReflect.Descriptor instead.
+func (*Job) Descriptor() ([]byte, []int) {
+	return file_ohman_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Job) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Job) GetStatus() Status {
+	if x != nil {
+		return x.Status
+	}
+	return Status_STATUS_UNSPECIFIED
+}
+
+func (x *Job) GetMode() Mode {
+	if x != nil {
+		return x.Mode
+	}
+	return Mode_MODE_DELETE
+}
+
+func (x *Job) GetPaths() []string {
+	if x != nil {
+		return x.Paths
+	}
+	return nil
+}
+
+func (x *Job) GetCreated() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Created
+	}
+	return nil
+}
+
+func (x *Job) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Job) GetProgress() *Progress {
+	if x != nil {
+		return x.Progress
+	}
+	return nil
+}
+
+type Action struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Op            string                 `protobuf:"bytes,1,opt,name=op,proto3" json:"op,omitempty"`
+	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	Target        string                 `protobuf:"bytes,3,opt,name=target,proto3" json:"target,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Action) Reset() {
+	*x = Action{}
+	mi := &file_ohman_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Action) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Action) ProtoMessage() {}
+
+func (x *Action) ProtoReflect() protoreflect.Message {
+	mi := &file_ohman_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Action.ProtoReflect.Descriptor instead.
+func (*Action) Descriptor() ([]byte, []int) {
+	return file_ohman_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Action) GetOp() string {
+	if x != nil {
+		return x.Op
+	}
+	return ""
+}
+
+func (x *Action) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *Action) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *Action) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type Group struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Original      string                 `protobuf:"bytes,1,opt,name=original,proto3" json:"original,omitempty"`
+	Duplicates    []string               `protobuf:"bytes,2,rep,name=duplicates,proto3" json:"duplicates,omitempty"`
+	Actions       []*Action              `protobuf:"bytes,3,rep,name=actions,proto3" json:"actions,omitempty"`
+	Orphan        bool                   `protobuf:"varint,4,opt,name=orphan,proto3" json:"orphan,omitempty"`
+	Mismatched    []string               `protobuf:"bytes,5,rep,name=mismatched,proto3" json:"mismatched,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Group) Reset() {
+	*x = Group{}
+	mi := &file_ohman_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Group) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Group) ProtoMessage() {}
+
+func (x *Group) ProtoReflect() protoreflect.Message {
+	mi := &file_ohman_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Group.ProtoReflect.Descriptor instead.
+func (*Group) Descriptor() ([]byte, []int) {
+	return file_ohman_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Group) GetOriginal() string {
+	if x != nil {
+		return x.Original
+	}
+	return ""
+}
+
+func (x *Group) GetDuplicates() []string {
+	if x != nil {
+		return x.Duplicates
+	}
+	return nil
+}
+
+func (x *Group) GetActions() []*Action {
+	if x != nil {
+		return x.Actions
+	}
+	return nil
+}
+
+func (x *Group) GetOrphan() bool {
+	if x != nil {
+		return x.Orphan
+	}
+	return false
+}
+
+func (x *Group) GetMismatched() []string {
+	if x != nil {
+		return x.Mismatched
+	}
+	return nil
+}
+
+type PlanResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Job           *Job                   `protobuf:"bytes,1,opt,name=job,proto3" json:"job,omitempty"`
+	Groups        []*Group               `protobuf:"bytes,2,rep,name=groups,proto3" json:"groups,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PlanResponse) Reset() {
+	*x = PlanResponse{}
+	mi := &file_ohman_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PlanResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PlanResponse) ProtoMessage() {}
+
+func (x *PlanResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ohman_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PlanResponse.ProtoReflect.Descriptor instead.
+func (*PlanResponse) Descriptor() ([]byte, []int) {
+	return file_ohman_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *PlanResponse) GetJob() *Job {
+	if x != nil {
+		return x.Job
+	}
+	return nil
+}
+
+func (x *PlanResponse) GetGroups() []*Group {
+	if x != nil {
+		return x.Groups
+	}
+	return nil
+}
+
+var File_ohman_proto protoreflect.FileDescriptor
+
+const file_ohman_proto_rawDesc = "" +
+	"\n" +
+	"\vohman.proto\x12\bohman.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"]\n" +
+	"\vScanRequest\x12\x14\n" +
+	"\x05paths\x18\x01 \x03(\tR\x05paths\x12\x14\n" +
+	"\x05regex\x18\x02 \x01(\tR\x05regex\x12\"\n" +
+	"\x04mode\x18\x03 \x01(\x0e2\x0e.ohman.v1.ModeR\x04mode\"\x1c\n" +
+	"\n" +
+	"JobRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"r\n" +
+	"\bProgress\x12\x18\n" +
+	"\ascanned\x18\x01 \x01(\x03R\ascanned\x12\x16\n" +
+	"\x06groups\x18\x02 \x01(\x03R\x06groups\x12\x18\n" +
+	"\aactions\x18\x03 \x01(\x03R\aactions\x12\x1a\n" +
+	"\bfailures\x18\x04 \x01(\x03R\bfailures\"\xf5\x01\n" +
+	"\x03Job\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12(\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x10.ohman.v1.StatusR\x06status\x12\"\n" +
+	"\x04mode\x18\x03 \x01(\x0e2\x0e.ohman.v1.ModeR\x04mode\x12\x14\n" +
+	"\x05paths\x18\x04 \x03(\tR\x05paths\x124\n" +
+	"\acreated\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\acreated\x12\x14\n" +
+	"\x05error\x18\x06 \x01(\tR\x05error\x12.\n" +
+	"\bprogress\x18\a \x01(\v2\x12.ohman.v1.ProgressR\bprogress\"Z\n" +
+	"\x06Action\x12\x0e\n" +
+	"\x02op\x18\x01 \x01(\tR\x02op\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\x12\x16\n" +
+	"\x06target\x18\x03 \x01(\tR\x06target\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"\xa7\x01\n" +
+	"\x05Group\x12\x1a\n" +
+	"\boriginal\x18\x01 \x01(\tR\boriginal\x12\x1e\n" +
+	"\n" +
+	"duplicates\x18\x02 \x03(\tR\n" +
+	"duplicates\x12*\n" +
+	"\aactions\x18\x03 \x03(\v2\x10.ohman.v1.ActionR\aactions\x12\x16\n" +
+	"\x06orphan\x18\x04 \x01(\bR\x06orphan\x12\x1e\n" +
+	"\n" +
+	"mismatched\x18\x05 \x03(\tR\n" +
+	"mismatched\"X\n" +
+	"\fPlanResponse\x12\x1f\n" +
+	"\x03job\x18\x01 \x01(\v2\r.ohman.v1.JobR\x03job\x12'\n" +
+	"\x06groups\x18\x02 \x03(\v2\x0f.ohman.v1.GroupR\x06groups*F\n" +
+	"\x04Mode\x12\x0f\n" +
+	"\vMODE_DELETE\x10\x00\x12\x10\n" +
+	"\fMODE_INVERSE\x10\x01\x12\x1b\n" +
+	"\x17MODE_INVERSE_AND_RENAME\x10\x02*\x88\x01\n" +
+	"\x06Status\x12\x16\n" +
+	"\x12STATUS_UNSPECIFIED\x10\x00\x12\x13\n" +
+	"\x0fSTATUS_SCANNING\x10\x01\x12\x12\n" +
+	"\x0eSTATUS_SCANNED\x10\x02\x12\x14\n" +
+	"\x10STATUS_EXECUTING\x10\x03\x12\x14\n" +
+	"\x10STATUS_COMPLETED\x10\x04\x12\x11\n" +
+	"\rSTATUS_FAILED\x10\x052\xd2\x01\n" +
+	"\x05Ohman\x12,\n" +
+	"\x04Scan\x12\x15.ohman.v1.ScanRequest\x1a\r.ohman.v1.Job\x124\n" +
+	"\x04Plan\x12\x14.ohman.v1.JobRequest\x1a\x16.ohman.v1.PlanResponse\x12,\n" +
+	"\x05Apply\x12\x14.ohman.v1.JobRequest\x1a\r.ohman.v1.Job\x127\n" +
+	"\x0eStreamProgress\x12\x14.ohman.v1.JobRequest\x1a\r.ohman.v1.Job0\x01B\x0fZ\rohman/ohmanpbb\x06proto3"
+
+var (
+	file_ohman_proto_rawDescOnce sync.Once
+	file_ohman_proto_rawDescData []byte
+)
+
+func file_ohman_proto_rawDescGZIP() []byte {
+	file_ohman_proto_rawDescOnce.Do(func() {
+		file_ohman_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_ohman_proto_rawDesc), len(file_ohman_proto_rawDesc)))
+	})
+	return file_ohman_proto_rawDescData
+}
+
+var file_ohman_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_ohman_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_ohman_proto_goTypes = []any{
+	(Mode)(0),                     // 0: ohman.v1.Mode
+	(Status)(0),                   // 1: ohman.v1.Status
+	(*ScanRequest)(nil),           // 2: ohman.v1.ScanRequest
+	(*JobRequest)(nil),            // 3: ohman.v1.JobRequest
+	(*Progress)(nil),              // 4: ohman.v1.Progress
+	(*Job)(nil),                   // 5: ohman.v1.Job
+	(*Action)(nil),                // 6: ohman.v1.Action
+	(*Group)(nil),                 // 7: ohman.v1.Group
+	(*PlanResponse)(nil),          // 8: ohman.v1.PlanResponse
+	(*timestamppb.Timestamp)(nil), // 9: google.protobuf.Timestamp
+}
+var file_ohman_proto_depIdxs = []int32{
+	0,  // 0: ohman.v1.ScanRequest.mode:type_name -> ohman.v1.Mode
+	1,  // 1: ohman.v1.Job.status:type_name -> ohman.v1.Status
+	0,  // 2: ohman.v1.Job.mode:type_name -> ohman.v1.Mode
+	9,  // 3: ohman.v1.Job.created:type_name -> google.protobuf.Timestamp
+	4,  // 4: ohman.v1.Job.progress:type_name -> ohman.v1.Progress
+	6,  // 5: ohman.v1.Group.actions:type_name -> ohman.v1.Action
+	5,  // 6: ohman.v1.PlanResponse.job:type_name -> ohman.v1.Job
+	7,  // 7: ohman.v1.PlanResponse.groups:type_name -> ohman.v1.Group
+	2,  // 8: ohman.v1.Ohman.Scan:input_type -> ohman.v1.ScanRequest
+	3,  // 9: ohman.v1.Ohman.Plan:input_type -> ohman.v1.JobRequest
+	3,  // 10: ohman.v1.Ohman.Apply:input_type -> ohman.v1.JobRequest
+	3,  // 11: ohman.v1.Ohman.StreamProgress:input_type -> ohman.v1.JobRequest
+	5,  // 12: ohman.v1.Ohman.Scan:output_type -> ohman.v1.Job
+	8,  // 13: ohman.v1.Ohman.Plan:output_type -> ohman.v1.PlanResponse
+	5,  // 14: ohman.v1.Ohman.Apply:output_type -> ohman.v1.Job
+	5,  // 15: ohman.v1.Ohman.StreamProgress:output_type -> ohman.v1.Job
+	12, // [12:16] is the sub-list for method output_type
+	8,  // [8:12] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_ohman_proto_init() }
+func file_ohman_proto_init() {
+	if File_ohman_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_ohman_proto_rawDesc), len(file_ohman_proto_rawDesc)),
+			NumEnums:      2,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_ohman_proto_goTypes,
+		DependencyIndexes: file_ohman_proto_depIdxs,
+		EnumInfos:         file_ohman_proto_enumTypes,
+		MessageInfos:      file_ohman_proto_msgTypes,
+	}.Build()
+	File_ohman_proto = out.File
+	file_ohman_proto_goTypes = nil
+	file_ohman_proto_depIdxs = nil
+}
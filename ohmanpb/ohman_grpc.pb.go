@@ -0,0 +1,239 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: ohman.proto
+
+package ohmanpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Ohman_Scan_FullMethodName           = "/ohman.v1.Ohman/Scan"
+	Ohman_Plan_FullMethodName           = "/ohman.v1.Ohman/Plan"
+	Ohman_Apply_FullMethodName          = "/ohman.v1.Ohman/Apply"
+	Ohman_StreamProgress_FullMethodName = "/ohman.v1.Ohman/StreamProgress"
+)
+
+// OhmanClient is the client API for Ohman service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type OhmanClient interface {
+	Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (*Job, error)
+	Plan(ctx context.Context, in *JobRequest, opts ...grpc.CallOption) (*PlanResponse, error)
+	Apply(ctx context.Context, in *JobRequest, opts ...grpc.CallOption) (*Job, error)
+	StreamProgress(ctx context.Context, in *JobRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Job], error)
+}
+
+type ohmanClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewOhmanClient(cc grpc.ClientConnInterface) OhmanClient {
+	return &ohmanClient{cc}
+}
+
+func (c *ohmanClient) Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (*Job, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Job)
+	err := c.cc.Invoke(ctx, Ohman_Scan_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ohmanClient) Plan(ctx context.Context, in *JobRequest, opts ...grpc.CallOption) (*PlanResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PlanResponse)
+	err := c.cc.Invoke(ctx, Ohman_Plan_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ohmanClient) Apply(ctx context.Context, in *JobRequest, opts ...grpc.CallOption) (*Job, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Job)
+	err := c.cc.Invoke(ctx, Ohman_Apply_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ohmanClient) StreamProgress(ctx context.Context, in *JobRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Job], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Ohman_ServiceDesc.Streams[0], Ohman_StreamProgress_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[JobRequest, Job]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Ohman_StreamProgressClient = grpc.ServerStreamingClient[Job]
+
+// OhmanServer is the server API for Ohman service.
+// All implementations must embed UnimplementedOhmanServer
+// for forward compatibility.
+type OhmanServer interface {
+	Scan(context.Context, *ScanRequest) (*Job, error)
+	Plan(context.Context, *JobRequest) (*PlanResponse, error)
+	Apply(context.Context, *JobRequest) (*Job, error)
+	StreamProgress(*JobRequest, grpc.ServerStreamingServer[Job]) error
+	mustEmbedUnimplementedOhmanServer()
+}
+
+// UnimplementedOhmanServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedOhmanServer struct{}
+
+func (UnimplementedOhmanServer) Scan(context.Context, *ScanRequest) (*Job, error) {
+	return nil, status.Error(codes.Unimplemented, "method Scan not implemented")
+}
+func (UnimplementedOhmanServer) Plan(context.Context, *JobRequest) (*PlanResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Plan not implemented")
+}
+func (UnimplementedOhmanServer) Apply(context.Context, *JobRequest) (*Job, error) {
+	return nil, status.Error(codes.Unimplemented, "method Apply not implemented")
+}
+func (UnimplementedOhmanServer) StreamProgress(*JobRequest, grpc.ServerStreamingServer[Job]) error {
+	return status.Error(codes.Unimplemented, "method StreamProgress not implemented")
+}
+func (UnimplementedOhmanServer) mustEmbedUnimplementedOhmanServer() {}
+func (UnimplementedOhmanServer) testEmbeddedByValue()               {}
+
+// UnsafeOhmanServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to OhmanServer will
+// result in compilation errors.
+type UnsafeOhmanServer interface {
+	mustEmbedUnimplementedOhmanServer()
+}
+
+func RegisterOhmanServer(s grpc.ServiceRegistrar, srv OhmanServer) {
+	// If the following call panics, it indicates UnimplementedOhmanServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Ohman_ServiceDesc, srv)
+}
+
+func _Ohman_Scan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OhmanServer).Scan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Ohman_Scan_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OhmanServer).Scan(ctx, req.(*ScanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ohman_Plan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OhmanServer).Plan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Ohman_Plan_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OhmanServer).Plan(ctx, req.(*JobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ohman_Apply_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OhmanServer).Apply(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Ohman_Apply_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OhmanServer).Apply(ctx, req.(*JobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ohman_StreamProgress_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(JobRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(OhmanServer).StreamProgress(m, &grpc.GenericServerStream[JobRequest, Job]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Ohman_StreamProgressServer = grpc.ServerStreamingServer[Job]
+
+// Ohman_ServiceDesc is the grpc.ServiceDesc for Ohman service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Ohman_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ohman.v1.Ohman",
+	HandlerType: (*OhmanServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Scan",
+			Handler:    _Ohman_Scan_Handler,
+		},
+		{
+			MethodName: "Plan",
+			Handler:    _Ohman_Plan_Handler,
+		},
+		{
+			MethodName: "Apply",
+			Handler:    _Ohman_Apply_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamProgress",
+			Handler:       _Ohman_StreamProgress_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "ohman.proto",
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"cmp"
+	"os"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// copySuffix captures the copy number closest to the extension, e.g. 2 in "book (2).pdf".
+var copySuffix = regexp.MustCompile(`\((\d+)\)\.[^.]*$`)
+
+// orderForAdoption moves the copy to adopt in place of a missing original to the front of duplicates: the one with the
+// lowest copy number, or the most recently modified according to stat, counting those modified within tolerance of
+// it as just as new. Ties are broken by name so the choice is repeatable.
+func orderForAdoption(duplicates []string, policy string, stat func(string) (os.FileInfo, error), tolerance time.Duration) {
+	type candidate struct {
+		path    string
+		number  int
+		modTime int64
+	}
+	candidates := make([]candidate, len(duplicates))
+	infos := make([]os.FileInfo, len(duplicates))
+	for i, d := range duplicates {
+		infos[i], _ = stat(d)
+	}
+	times := modTimes(infos, tolerance)
+	for i, d := range duplicates {
+		c := candidate{path: d, number: copyNumber(d)}
+		if !times[i].IsZero() {
+			c.modTime = times[i].UnixNano()
+		}
+		candidates[i] = c
+	}
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		switch {
+		case policy == "newest" && a.modTime != b.modTime:
+			return cmp.Compare(b.modTime, a.modTime)
+		case policy != "newest" && a.number != b.number:
+			// copies without a recognizable number sort last
+			if a.number < 0 || b.number < 0 {
+				return cmp.Compare(b.number, a.number)
+			}
+			return cmp.Compare(a.number, b.number)
+		}
+		return cmp.Compare(a.path, b.path)
+	})
+	for i, c := range candidates {
+		duplicates[i] = c.path
+	}
+}
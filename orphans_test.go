@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOrderForAdoption(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	now := time.Now()
+	paths := map[string]time.Time{
+		"book (10).pdf":   now.Add(-3 * time.Hour),
+		"book (2).pdf":    now,
+		"book (1).pdf":    now.Add(-2 * time.Hour),
+		"book-copy-a.pdf": now.Add(-time.Hour),
+	}
+	for name, modTime := range paths {
+		createTestFileWithModTime(t, filepath.Join(dir, name), name, modTime)
+	}
+	in := []string{"book (10).pdf", "book-copy-a.pdf", "book (2).pdf", "book (1).pdf"}
+	for i, name := range in {
+		in[i] = filepath.Join(dir, name)
+	}
+
+	tests := map[string][]string{
+		"lowest": {"book (1).pdf", "book (2).pdf", "book (10).pdf", "book-copy-a.pdf"},
+		"newest": {"book (2).pdf", "book-copy-a.pdf", "book (1).pdf", "book (10).pdf"},
+	}
+	for policy, want := range tests {
+		got := append([]string(nil), in...)
+		orderForAdoption(got, policy, os.Stat, 0)
+		for i := range got {
+			got[i] = filepath.Base(got[i])
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected %v, got %v", policy, want, got)
+		}
+	}
+}
+
+func TestCLI_Run_AdoptOrphans(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		policy string
+		want   string
+	}{
+		{policy: "lowest", want: "duplicate 1"},
+		{policy: "newest", want: "duplicate 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			t.Parallel()
+			dir := setupTestDir(t)
+			now := time.Now()
+			createTestFileWithModTime(t, filepath.Join(dir, "book (1).pdf"), "duplicate 1", now.Add(-time.Hour))
+			createTestFileWithModTime(t, filepath.Join(dir, "book (2).pdf"), "duplicate 2", now)
+
+			// the copies differ, so which is adopted can be told from its content
+			cli := &CLI{Path: []string{dir}, Delete: true, AllowDifferent: true, AdoptOrphans: tt.policy, Out: filepath.Join(dir, "results.txt"), Regex: []string{defaultRegex}}
+			if err := cli.Run(nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			content, err := os.ReadFile(filepath.Join(dir, "book.pdf"))
+			if err != nil || string(content) != tt.want {
+				t.Errorf("expected book.pdf to contain %q, got %q, %v", tt.want, content, err)
+			}
+			if fileExists(filepath.Join(dir, "book (1).pdf")) || fileExists(filepath.Join(dir, "book (2).pdf")) {
+				t.Error("expected every copy to be adopted or deleted")
+			}
+		})
+	}
+}
+
+func TestCLI_Run_AdoptOrphans_Differing(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	createTestFile(t, filepath.Join(dir, "book (1).pdf"), "first edition")
+	createTestFile(t, filepath.Join(dir, "book (2).pdf"), "second edition")
+	createTestFile(t, filepath.Join(dir, "song (1).mp3"), "song")
+	createTestFile(t, filepath.Join(dir, "song (2).mp3"), "song")
+
+	cli := &CLI{Path: []string{dir}, Delete: true, AdoptOrphans: "lowest", Out: filepath.Join(dir, "results.txt"), Regex: []string{defaultRegex}}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !fileExists(filepath.Join(dir, "book (1).pdf")) || !fileExists(filepath.Join(dir, "book (2).pdf")) || fileExists(filepath.Join(dir, "book.pdf")) {
+		t.Error("an orphan whose copies differ must be left alone")
+	}
+	if !fileExists(filepath.Join(dir, "song.mp3")) || fileExists(filepath.Join(dir, "song (2).mp3")) {
+		t.Error("an orphan whose copies are identical should be adopted")
+	}
+}
+
+func TestCLI_Run_Orphans_IgnoredByDefault(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	createTestFile(t, filepath.Join(dir, "book (1).pdf"), "duplicate 1")
+
+	cli := &CLI{Path: []string{dir}, Delete: true, AdoptOrphans: "none", Out: filepath.Join(dir, "results.txt"), Regex: []string{defaultRegex}}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !fileExists(filepath.Join(dir, "book (1).pdf")) || fileExists(filepath.Join(dir, "book.pdf")) {
+		t.Error("orphans must be left alone without --adopt-orphans")
+	}
+}
+
+func TestRenderText_Orphan(t *testing.T) {
+	t.Parallel()
+	got := renderText([]group{{Original: "/b/book.pdf", Duplicates: []string{"/b/book (1).pdf", "/b/book (2).pdf"}, Orphan: true}}, palette{})
+	want := strings.Join([]string{
+		"Original (missing): /b/book.pdf",
+		"  - Adopt: /b/book (1).pdf",
+		"  - Duplicate: /b/book (2).pdf",
+	}, "\n")
+	if got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// partialSuffixes are appended to the names of unfinished downloads: by Firefox, Chrome and Edge, and µTorrent.
+// Safari's .download is left out, as it's a bundle directory, not a file.
+var partialSuffixes = []string{".part", ".crdownload", ".!ut"}
+
+// partialOf returns the path the partial download at path was to be saved as, if its name says it's one.
+func partialOf(path string) (string, bool) {
+	name := filepath.Base(path)
+	for _, suffix := range partialSuffixes {
+		if len(name) > len(suffix) && strings.EqualFold(name[len(name)-len(suffix):], suffix) {
+			return path[:len(path)-len(suffix)], true
+		}
+	}
+	return "", false
+}
+
+// addPartials adds each partial download to the group of the completed file it was to be saved as, for
+// --partial-downloads, when that file exists. files maps originals to their duplicates, as scan does.
+func (c *CLI) addPartials(partials []string, files map[string][]string) {
+	for _, path := range partials {
+		complete, _ := partialOf(path)
+		if info, err := c.stat(complete); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		files[complete] = append(files[complete], path)
+	}
+}
+
+// isPartialOf reports whether the duplicate d in a group is a partial download of its original, which is never
+// compared by content, as it's the original's beginning at most. One larger than the original isn't.
+func (c *CLI) isPartialOf(original, d string) bool {
+	if !c.PartialDownloads {
+		return false
+	}
+	if complete, ok := partialOf(d); !ok || complete != original {
+		return false
+	}
+	partial, err := c.stat(d)
+	if err != nil {
+		return false
+	}
+	info, err := c.stat(original)
+	return err == nil && partial.Size() <= info.Size()
+}
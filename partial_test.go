@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCLI_Run_PartialDownloads(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	old := time.Now().Add(-2 * leftoverMinAge)
+	files := map[string]string{
+		"book.pdf":             "the whole book",
+		"book (1).pdf":         "the whole book",
+		"book.pdf.part":        "the whole",
+		"movie.mp4":            "movie",
+		"movie.mp4.CRDOWNLOAD": "movie",
+		// a download still in progress, or one whose file is gone, is left alone
+		"song.mp3":            "song",
+		"song.mp3.crdownload": "so",
+		"orphan.pdf.!ut":      "orphan",
+		"larger.pdf":          "small",
+		"larger.pdf.part":     "larger than the file",
+		// Safari's are bundle directories, so a file named like one isn't a partial
+		"safari.pdf":          "safari",
+		"safari.pdf.download": "safari",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		createTestFile(t, path, content)
+		if name != "song.mp3.crdownload" {
+			if err := os.Chtimes(path, old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	cli := &CLI{Path: []string{dir}, PartialDownloads: true, Delete: true, Out: filepath.Join(t.TempDir(), "results.txt"), Regex: []string{defaultRegex}}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, name := range []string{"book (1).pdf", "book.pdf.part", "movie.mp4.CRDOWNLOAD"} {
+		if fileExists(filepath.Join(dir, name)) {
+			t.Errorf("%s should be deleted", name)
+		}
+	}
+	for _, name := range []string{"book.pdf", "movie.mp4", "song.mp3.crdownload", "orphan.pdf.!ut", "larger.pdf.part", "safari.pdf.download"} {
+		if !fileExists(filepath.Join(dir, name)) {
+			t.Errorf("%s should be kept", name)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/alecthomas/kong"
+)
+
+// stdinPath is the path argument which reads further paths from stdin.
+const stdinPath = "-"
+
+// stdoutPath is the --out file which writes results to stdout.
+const stdoutPath = "-"
+
+// resolvePaths expands the "-" path argument and --paths-from into the paths they list, and glob patterns into the
+// paths they match, so the rest of a run only sees real paths. Paths are read once; calling it again is a no-op.
+func (c *CLI) resolvePaths(stdin io.Reader) error {
+	var paths []string
+	stdinRead := false
+	readStdin := func() error {
+		if stdinRead {
+			return fmt.Errorf("paths can only be read from stdin once")
+		}
+		stdinRead = true
+		listed, err := readPaths(stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read paths from stdin: %w", err)
+		}
+		paths = append(paths, listed...)
+		return nil
+	}
+
+	for _, p := range c.Path {
+		if p != stdinPath {
+			paths = append(paths, expandLocation(p))
+			continue
+		}
+		if err := readStdin(); err != nil {
+			return err
+		}
+	}
+
+	switch c.PathsFrom {
+	case "":
+	case stdinPath:
+		if err := readStdin(); err != nil {
+			return err
+		}
+	default:
+		f, err := os.Open(c.PathsFrom)
+		if err != nil {
+			return fmt.Errorf("failed to read paths: %w", err)
+		}
+		listed, err := readPaths(f)
+		_ = f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read paths from %s: %w", c.PathsFrom, err)
+		}
+		paths = append(paths, listed...)
+	}
+
+	expanded := make([]string, 0, len(paths))
+	for _, p := range paths {
+		matches, err := expandGlob(p)
+		if err != nil {
+			return err
+		}
+		expanded = append(expanded, matches...)
+	}
+
+	c.Path, c.PathsFrom = expanded, ""
+	return nil
+}
+
+// expandLocation makes a local path argument absolute, expanding ~, as kong does for flags of type path. URLs such as
+// s3://bucket/prefix, gdrive://folder, and dropbox://folder are returned unchanged.
+func expandLocation(path string) string {
+	if hasScheme(path) {
+		return path
+	}
+	return kong.ExpandPath(path)
+}
+
+// expandGlob returns the paths matching pattern, e.g. /media/*/Books. This is needed where the shell doesn't expand
+// patterns itself (e.g. on Windows) and for paths read from stdin or a file. Paths which exist as given are never
+// treated as patterns, so names containing brackets still work, and paths without wildcards are returned unchanged
+// so a missing path is reported by the scan as usual.
+func expandGlob(pattern string) ([]string, error) {
+	if !hasGlobMeta(pattern) {
+		return []string{pattern}, nil
+	}
+	if _, err := os.Lstat(pattern); err == nil {
+		return []string{pattern}, nil
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path pattern %s: %w", pattern, err)
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no paths match %s", pattern)
+	}
+	return matches, nil
+}
+
+func hasGlobMeta(path string) bool {
+	magic := `*?[`
+	if runtime.GOOS != "windows" {
+		magic = `*?[\`
+	}
+	return strings.ContainsAny(path, magic)
+}
+
+// readPaths reads newline-delimited paths, as printed by find or fd, ignoring blank lines.
+func readPaths(r io.Reader) ([]string, error) {
+	var paths []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		paths = append(paths, line)
+	}
+	return paths, scanner.Err()
+}
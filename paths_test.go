@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCLI_ResolvePaths(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	listFile := filepath.Join(dir, "paths.txt")
+	createTestFile(t, listFile, "/from/file/a\r\n\n/from/file/b\n")
+
+	tests := []struct {
+		name    string
+		cli     CLI
+		stdin   string
+		want    []string
+		wantErr bool
+	}{
+		{name: "arguments only", cli: CLI{Path: []string{"/a", "/b"}}, want: []string{"/a", "/b"}},
+		{name: "dash reads stdin in place", cli: CLI{Path: []string{"/a", "-", "/b"}}, stdin: "/stdin/1\n\n/stdin/2", want: []string{"/a", "/stdin/1", "/stdin/2", "/b"}},
+		{name: "paths-from file", cli: CLI{Path: []string{"/a"}, PathsFrom: listFile}, want: []string{"/a", "/from/file/a", "/from/file/b"}},
+		{name: "paths-from stdin", cli: CLI{PathsFrom: "-"}, stdin: "/stdin/1\n", want: []string{"/stdin/1"}},
+		{name: "spaces are kept", cli: CLI{Path: []string{"-"}}, stdin: "/media/My Books \n", want: []string{"/media/My Books "}},
+		{name: "stdin twice", cli: CLI{Path: []string{"-"}, PathsFrom: "-"}, wantErr: true},
+		{name: "missing paths-from file", cli: CLI{PathsFrom: filepath.Join(dir, "missing.txt")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.cli
+			err := c.resolvePaths(strings.NewReader(tt.stdin))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(c.Path, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, c.Path)
+			}
+			if c.PathsFrom != "" {
+				t.Error("expected --paths-from to be consumed")
+			}
+		})
+	}
+}
+
+func TestCLI_Run_PathsFrom(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "original content")
+	createTestFile(t, filepath.Join(dir, "book (1).pdf"), "duplicate 1")
+	listFile := filepath.Join(t.TempDir(), "paths.txt")
+	createTestFile(t, listFile, dir+"\n")
+
+	cli := &CLI{PathsFrom: listFile, DryRun: true, Regex: []string{defaultRegex}}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cli.status != exitDuplicatesFound {
+		t.Errorf("expected duplicates to be found in the listed path, got status %d", cli.status)
+	}
+}
+
+func TestExpandGlob(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	for _, d := range []string{"a/Books", "b/Books", "c/Music", "[2020] Books"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", d, err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		pattern string
+		want    []string
+		wantErr bool
+	}{
+		{name: "plain path", pattern: filepath.Join(dir, "missing"), want: []string{filepath.Join(dir, "missing")}},
+		{name: "wildcard", pattern: filepath.Join(dir, "*", "Books"), want: []string{filepath.Join(dir, "a", "Books"), filepath.Join(dir, "b", "Books")}},
+		{name: "existing path with brackets", pattern: filepath.Join(dir, "[2020] Books"), want: []string{filepath.Join(dir, "[2020] Books")}},
+		{name: "no matches", pattern: filepath.Join(dir, "*", "Videos"), wantErr: true},
+		{name: "malformed", pattern: filepath.Join(dir, "[a-"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandGlob(tt.pattern)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
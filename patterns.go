@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// patternLabel matches the label starting a line of a patterns file. A regex starting with a character class followed
+// by a space, like "[ab] c", must be given a label, which may be empty ("[] [ab] c").
+var patternLabel = regexp.MustCompile(`^\[([^\]]*)\][ \t]`)
+
+// readPatternsFile reads the copy patterns listed in the file at path, for --patterns-file.
+func readPatternsFile(path string) (copyPatterns, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read patterns: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	patterns, err := parsePatterns(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return patterns, nil
+}
+
+// parsePatterns reads one copy pattern from each line of r, skipping blank lines and comments starting with #. A
+// pattern is a regex, optionally preceded by a label in brackets and followed by a mapping of the extensions of
+// copies to those of their originals, so regexes needn't be escaped for a shell:
+//
+//	[explorer] (?P<name>.+) - Copy\.(?P<ext>\w+)$ ext=jpeg:jpg,tif:tiff
+func parsePatterns(r io.Reader) (copyPatterns, error) {
+	var patterns copyPatterns
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if n == 1 {
+			// Notepad starts UTF-8 files with a byte order mark
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var p copyPattern
+		if label := patternLabel.FindStringSubmatch(line); label != nil {
+			p.label, line = label[1], strings.TrimSpace(line[len(label[0]):])
+		}
+		if i := strings.LastIndexAny(line, " \t"); i >= 0 && strings.HasPrefix(line[i+1:], "ext=") {
+			exts, err := parseExtMapping(strings.TrimPrefix(line[i+1:], "ext="))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			p.exts, line = exts, strings.TrimSpace(line[:i])
+		}
+		if line == "" {
+			return nil, fmt.Errorf("line %d: no regex given", n)
+		}
+		re, err := regexp.Compile(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid regex: %w", n, err)
+		}
+		if err := checkCopyGroups(re); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		p.re = re
+		patterns = append(patterns, p)
+	}
+	return patterns, scanner.Err()
+}
+
+// parseExtMapping parses extension mappings like "jpeg:jpg,tif:tiff", from the extensions of copies to those of
+// their originals.
+func parseExtMapping(s string) (map[string]string, error) {
+	exts := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		from, to, ok := strings.Cut(pair, ":")
+		from, to = strings.TrimPrefix(from, "."), strings.TrimPrefix(to, ".")
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid extension mapping %q; expected from:to, e.g. jpeg:jpg", pair)
+		}
+		exts[strings.ToLower(from)] = to
+	}
+	return exts, nil
+}
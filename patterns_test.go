@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParsePatterns(t *testing.T) {
+	t.Parallel()
+	patterns, err := parsePatterns(strings.NewReader("\ufeff# copies\n\n" +
+		`(.+)\s\((\d+)\)\.(pdf)$` + "\r\n" +
+		`[explorer] (?P<name>.+) - Copy\.(?P<ext>\w+)$ ext=.JPEG:jpg,tif:tiff` + "\n" +
+		`[] [ab] (?P<name>.+)$` + "\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(patterns) != 3 {
+		t.Fatalf("expected 3 patterns, got %d", len(patterns))
+	}
+	if p := patterns[1]; p.label != "explorer" || p.re.String() != `(?P<name>.+) - Copy\.(?P<ext>\w+)$` || p.exts["jpeg"] != "jpg" || p.exts["tif"] != "tiff" {
+		t.Errorf("unexpected pattern %+v", p)
+	}
+	if p := patterns[2]; p.label != "" || p.re.String() != `[ab] (?P<name>.+)$` {
+		t.Errorf("unexpected pattern %+v", p)
+	}
+
+	for name, want := range map[string]string{
+		"book (1).pdf":        "book.pdf",
+		"photo - Copy.JPEG":   "photo.jpg",
+		"scan - Copy.tif":     "scan.tiff",
+		"notes - Copy.txt":    "notes.txt",
+		"a notes - Copy.jpeg": "notes.jpg",
+	} {
+		if got, ok := originalFor(patterns, name); !ok || got != want {
+			t.Errorf("originalFor(%s) = %q, %v; want %q", name, got, ok, want)
+		}
+	}
+
+	for _, invalid := range []string{
+		`[label] (.+) \((\d+)\)\.(pdf)$ ext=jpeg`,
+		`[label] `,
+		`(.+`,
+		`(.+)\.pdf$`,
+	} {
+		if _, err := parsePatterns(strings.NewReader("# ok\n" + invalid)); err == nil || !strings.Contains(err.Error(), "line 2") {
+			t.Errorf("parsePatterns(%q) = %v, want an error on line 2", invalid, err)
+		}
+	}
+}
+
+func TestCLI_Run_PatternsFile(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	for _, name := range []string{"notes.txt", "notes_copy.txt", "book.pdf", "book (1).pdf"} {
+		createTestFile(t, filepath.Join(dir, name), "same")
+	}
+	patternsFile := filepath.Join(t.TempDir(), "patterns.txt")
+	if err := os.WriteFile(patternsFile, []byte(`(?P<name>.+)_copy\.(?P<ext>txt)$`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cli := &CLI{
+		Path:         []string{dir},
+		Delete:       true,
+		Out:          filepath.Join(dir, "results.txt"),
+		Regex:        []string{defaultRegex},
+		PatternsFile: patternsFile,
+	}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fileExists(filepath.Join(dir, "notes_copy.txt")) {
+		t.Error("notes_copy.txt should be deleted")
+	}
+	if !fileExists(filepath.Join(dir, "book (1).pdf")) {
+		t.Error("the patterns file should replace the default regex")
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// planHeader names the columns of a plan. Only group, action, and path are read back; the rest help reviewers.
+var planHeader = []string{"group", "action", "path", "size", "modified", "note"}
+
+// PlanCmd scans without changing anything, writing a plan which can be reviewed in a spreadsheet and applied.
+type PlanCmd struct {
+	CLI `embed:""`
+}
+
+func (p *PlanCmd) Run(kctx *Context) error {
+	if p.Inverse || p.InverseAndRename {
+		return errors.New("a plan always keeps the original; change the action column to keep another copy instead of using --inverse")
+	}
+	p.DryRun = true
+	p.Format = "plan"
+	// --delete would write the plan to results.txt, replacing the last run's results
+	p.Delete = false
+	return p.CLI.Run(kctx)
+}
+
+// renderPlan emits a CSV plan with a row per file, marking the file ohman would keep in each group "keep" and the
+// rest "delete". Groups whose copies differ are marked to keep every file, as ohman wouldn't act on them.
+func renderPlan(groups []group) string {
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+	_ = w.Write(planHeader)
+	row := func(id int, op, path, note string) {
+		var size, modified string
+		if info, err := os.Stat(nativePath(path)); err == nil {
+			size, modified = strconv.FormatInt(info.Size(), 10), info.ModTime().Format(time.DateTime)
+		}
+		_ = w.Write([]string{strconv.Itoa(id), op, path, size, modified, note})
+	}
+	for i, g := range groups {
+		differs := len(g.Mismatched) > 0
+		if !g.Orphan {
+			row(i+1, opKeep, g.Original, "original")
+		}
+		for j, d := range g.Duplicates {
+			switch {
+			case g.Orphan && j == 0:
+				row(i+1, opKeep, d, "original missing")
+			case slices.Contains(g.Mismatched, d):
+				row(i+1, opKeep, d, "content differs")
+			case differs:
+				row(i+1, opKeep, d, "group has copies whose content differs")
+			default:
+				row(i+1, opDelete, d, "duplicate")
+			}
+		}
+	}
+	w.Flush()
+	return sb.String()
+}
+
+// ApplyCmd deletes the files marked "delete" in a reviewed plan.
+type ApplyCmd struct {
+	CSV       string   `name:"csv" required:"" help:"Plan to apply, as written by ohman plan. Only rows whose action is delete are deleted." type:"existingfile"`
+	DryRun    bool     `help:"[SAFE MODE] List what the plan would delete without making changes."`
+	FailFast  bool     `name:"fail-fast" help:"Stop at the first failed delete instead of continuing with the remaining files."`
+	Permanent bool     `name:"permanent" help:"On Windows, delete files outright instead of moving them to the Recycle Bin."`
+	MaxIOPS   int      `name:"max-iops" help:"Limit filesystem operations (stats, deletes) per second. Unlimited by default."`
+	Lock      string   `name:"lock" help:"Prevent concurrent destructive runs over the same files: ${enum}." enum:"root,global,none" default:"root"`
+	LockDir   string   `name:"lock-dir" help:"Directory for lock files. Defaults to ohman-locks in the system temp directory." type:"path"`
+	Protect   []string `name:"protect" help:"Never delete this file or anything within this directory. Repeatable; also read from $OHMAN_PROTECT." type:"path"`
+	Format    string   `name:"format" help:"Output format: ${enum}." enum:"text,fdupes,markdown,json" default:"text"`
+	NoColor   bool     `name:"no-color" help:"Never color text output, even on a terminal. Setting $NO_COLOR does the same."`
+	Out       string   `name:"out" short:"o" help:"Output file for results." type:"path"`
+	AuditLog  string   `name:"audit-log" env:"OHMAN_AUDIT_LOG" help:"Append a JSON line to this file for every file deleted, with its size and hash beforehand and the outcome." type:"path"`
+
+	Retries    int           `name:"retries" help:"Retry a delete failing with a transient error, such as EBUSY or ESTALE on a network filesystem, up to this many times." default:"3"`
+	RetryDelay time.Duration `name:"retry-delay" help:"Wait this long before the first retry of a failed delete, doubling the wait before each further one." default:"250ms"`
+}
+
+// plannedGroup is a group of files in a plan, split by the action chosen for each.
+type plannedGroup struct {
+	keep, delete []string
+}
+
+func (a *ApplyCmd) Run(kctx *Context) error {
+	f, err := os.Open(a.CSV)
+	if err != nil {
+		return err
+	}
+	planned, err := readPlan(f)
+	_ = f.Close()
+	if err != nil {
+		return fmt.Errorf("invalid plan %s: %w", a.CSV, err)
+	}
+
+	c := &CLI{DryRun: a.DryRun, Delete: true, FailFast: a.FailFast, Permanent: a.Permanent, Format: a.Format, Retries: a.Retries, RetryDelay: a.RetryDelay}
+	c.runID = newRunID(time.Now())
+	c.throttle = newThrottle(a.MaxIOPS, 0, false, nil)
+	if c.protected, err = newProtector(a.Protect); err != nil {
+		return err
+	}
+	if !a.DryRun {
+		var dirs []string
+		for _, p := range planned {
+			for _, path := range slices.Concat(p.keep, p.delete) {
+				if dir := filepath.Dir(path); !slices.Contains(dirs, dir) {
+					dirs = append(dirs, dir)
+				}
+			}
+		}
+		lock, err := acquireRunLock(a.LockDir, a.Lock, dirs)
+		if err != nil {
+			return err
+		}
+		defer lock.release()
+	}
+
+	if c.audit, err = openAuditLog(a.AuditLog); err != nil {
+		return err
+	}
+	groups, stopped := c.applyPlan(kctx.context(), planned)
+	var auditErr error
+	if err := c.audit.close(); err != nil {
+		auditErr = fmt.Errorf("failed to write the audit log %s: %w", a.AuditLog, err)
+	}
+	var colors palette
+	if a.Out == stdoutPath || (a.Out == "" && a.DryRun) {
+		colors = newPalette(os.Stdout, a.NoColor)
+	}
+	output := headResults(a.Format, c.runID, renderColored(a.Format, groups, colors))
+	if a.Out != "" {
+		err = outputResults(a.Out, output)
+	} else if !a.DryRun {
+		err = outputResults("results.txt", output)
+	} else {
+		fmt.Println(output)
+	}
+	if err != nil {
+		return errors.Join(err, auditErr)
+	}
+	if stopped {
+		err := fmt.Errorf("%w after processing %d groups; results contain only completed groups", context.Cause(kctx.context()), len(groups))
+		return errors.Join(err, collectFailures(groups), auditErr)
+	}
+	return errors.Join(collectFailures(groups), auditErr)
+}
+
+// applyPlan deletes the files planned for deletion, group by group. A group's deletions are refused when none of
+// the files it keeps still exists, so a stale plan can't remove the last copy of anything.
+func (c *CLI) applyPlan(ctx context.Context, planned []plannedGroup) (groups []group, stopped bool) {
+	for _, p := range planned {
+		if ctx.Err() != nil {
+			return groups, true
+		}
+		g := group{Original: p.keep[0], Duplicates: p.delete, RunID: c.runID}
+		if c.DryRun {
+			groups = append(groups, g)
+			continue
+		}
+
+		var kept error
+		if !slices.ContainsFunc(p.keep, func(path string) bool {
+			_, err := c.stat(path)
+			return err == nil
+		}) {
+			kept = fmt.Errorf("no file marked keep in its group still exists, so it was left in place")
+		}
+		failed := false
+		for _, path := range p.keep {
+			_ = c.act(&g, action{Op: opKeep, Path: path, implicit: true})
+		}
+		for _, path := range p.delete {
+			a := action{Op: opDelete, Path: path, Err: kept}
+			if kept == nil {
+				a = c.deleteFile(ctx, path)
+			}
+			if c.act(&g, a) != nil {
+				failed = true
+				break
+			}
+		}
+		groups = append(groups, g)
+		if failed {
+			// only when --fail-fast is set
+			break
+		}
+	}
+	return groups, false
+}
+
+// readPlan reads a plan, returning the groups which have files to delete. Columns are found by their header, so
+// reviewers may reorder them or add their own. The whole plan is checked before anything is deleted: every action
+// must be keep or delete, and every group must keep at least one file.
+func readPlan(r io.Reader) ([]plannedGroup, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		// spreadsheets often save a byte order mark
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, name := range []string{"group", "action", "path"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing %q column", name)
+		}
+	}
+
+	var order []string
+	byGroup := map[string]*plannedGroup{}
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		field := func(name string) string {
+			if i := columns[name]; i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+		// paths are used as written, as names may start or end with spaces
+		id, path := strings.TrimSpace(field("group")), field("path")
+		if path == "" {
+			if strings.Join(record, "") == "" {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: missing path", line)
+		}
+		if id == "" {
+			return nil, fmt.Errorf("line %d: missing group", line)
+		}
+		p, ok := byGroup[id]
+		if !ok {
+			p = &plannedGroup{}
+			byGroup[id] = p
+			order = append(order, id)
+		}
+		switch strings.ToLower(strings.TrimSpace(field("action"))) {
+		case opKeep:
+			p.keep = append(p.keep, path)
+		case opDelete:
+			p.delete = append(p.delete, path)
+		default:
+			return nil, fmt.Errorf("line %d: action for %s must be keep or delete, not %q", line, path, field("action"))
+		}
+	}
+
+	var planned []plannedGroup
+	for _, id := range order {
+		p := byGroup[id]
+		if len(p.delete) == 0 {
+			continue
+		}
+		if len(p.keep) == 0 {
+			return nil, fmt.Errorf("group %s marks every file delete; mark at least one keep", id)
+		}
+		for _, path := range p.delete {
+			if slices.Contains(p.keep, path) {
+				return nil, fmt.Errorf("group %s marks %s both keep and delete", id, path)
+			}
+		}
+		planned = append(planned, *p)
+	}
+	return planned, nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRenderPlan(t *testing.T) {
+	t.Parallel()
+	groups := []group{
+		{Original: "/a/book.pdf", Duplicates: []string{"/a/book (1).pdf"}},
+		{Original: "/b/song.mp3", Duplicates: []string{"/b/song (1).mp3", "/b/song (2).mp3"}, Orphan: true},
+		{Original: "/c/a.txt", Duplicates: []string{"/c/a (1).txt", "/c/a (2).txt"}, Mismatched: []string{"/c/a (2).txt"}},
+	}
+
+	records, err := csv.NewReader(strings.NewReader(render("plan", groups))).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read plan: %v", err)
+	}
+	var got [][]string
+	for _, r := range records {
+		// size and modified are blank, as the files don't exist
+		got = append(got, []string{r[0], r[1], r[2], r[5]})
+	}
+	want := [][]string{
+		{"group", "action", "path", "note"},
+		{"1", "keep", "/a/book.pdf", "original"},
+		{"1", "delete", "/a/book (1).pdf", "duplicate"},
+		{"2", "keep", "/b/song (1).mp3", "original missing"},
+		{"2", "delete", "/b/song (2).mp3", "duplicate"},
+		{"3", "keep", "/c/a.txt", "original"},
+		{"3", "keep", "/c/a (1).txt", "group has copies whose content differs"},
+		{"3", "keep", "/c/a (2).txt", "content differs"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestReadPlan(t *testing.T) {
+	t.Parallel()
+	plan := "\ufeffPath,Group,Action,Reviewer\n" +
+		"/a/book.pdf,1,keep,sam\n" +
+		"/a/book (1).pdf,1, Delete ,sam\n" +
+		"/a/book (2).pdf,1,keep,sam\n" +
+		",,,\n" +
+		"/b/song.mp3,2,keep,\n" +
+		"/b/song (1).mp3,2,keep,\n" +
+		"/c/ spaced.txt ,3,delete,\n" +
+		"/c/a.txt,3,keep,\n"
+
+	got, err := readPlan(strings.NewReader(plan))
+	if err != nil {
+		t.Fatalf("readPlan() error = %v", err)
+	}
+	want := []plannedGroup{
+		{keep: []string{"/a/book.pdf", "/a/book (2).pdf"}, delete: []string{"/a/book (1).pdf"}},
+		{keep: []string{"/c/a.txt"}, delete: []string{"/c/ spaced.txt "}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readPlan() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReadPlan_Invalid(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		plan string
+		want string
+	}{
+		"empty":          {"", "unable to read header"},
+		"missing column": {"group,path\n1,/a\n", `missing "action" column`},
+		"unknown action": {"group,action,path\n1,keep,/a\n1,remove,/b\n", `line 3: action for /b must be keep or delete, not "remove"`},
+		"missing group":  {"group,action,path\n,keep,/a\n", "line 2: missing group"},
+		"nothing kept":   {"group,action,path\n1,delete,/a\n1,delete,/b\n", "group 1 marks every file delete"},
+		"both":           {"group,action,path\n1,keep,/a\n1,delete,/a\n", "group 1 marks /a both keep and delete"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := readPlan(strings.NewReader(tt.plan)); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("readPlan() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanCmd_Apply(t *testing.T) {
+	dir := setupTestDir(t)
+	for _, name := range []string{"book.pdf", "book (1).pdf", "book (2).pdf", "song.mp3", "song (1).mp3"} {
+		createTestFile(t, filepath.Join(dir, name), "content")
+	}
+
+	plan := filepath.Join(t.TempDir(), "plan.csv")
+	cmd := &PlanCmd{CLI: CLI{Path: []string{dir}, Regex: []string{defaultRegex}, Out: plan}}
+	if err := cmd.Run(&Context{}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	for _, name := range []string{"book (1).pdf", "book (2).pdf", "song (1).mp3"} {
+		if !fileExists(filepath.Join(dir, name)) {
+			t.Fatalf("planning shouldn't delete %s", name)
+		}
+	}
+
+	// the reviewer keeps one of the copies
+	data, err := os.ReadFile(plan)
+	if err != nil {
+		t.Fatalf("failed to read plan: %v", err)
+	}
+	records, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse plan: %v", err)
+	}
+	var deletes int
+	for _, r := range records[1:] {
+		if r[1] == "delete" {
+			deletes++
+		}
+		if r[2] == filepath.Join(dir, "book (2).pdf") {
+			r[1] = "keep"
+		}
+	}
+	if deletes != 3 {
+		t.Errorf("expected the plan to delete 3 files, got %d:\n%s", deletes, data)
+	}
+	var edited strings.Builder
+	w := csv.NewWriter(&edited)
+	_ = w.WriteAll(records)
+	createTestFile(t, plan, edited.String())
+
+	out := filepath.Join(t.TempDir(), "results.txt")
+	if err := (&ApplyCmd{CSV: plan, DryRun: true, Lock: lockNone, Format: "text", Out: out}).Run(&Context{}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !fileExists(filepath.Join(dir, "book (1).pdf")) {
+		t.Fatal("a dry run shouldn't delete anything")
+	}
+
+	apply := &ApplyCmd{CSV: plan, Lock: lockNone, Format: "text", Out: out, Protect: []string{filepath.Join(dir, "song (1).mp3")}}
+	err = apply.Run(&Context{})
+	if err == nil || !strings.Contains(err.Error(), "song (1).mp3") {
+		t.Errorf("expected the protected file to fail, got %v", err)
+	}
+	for name, want := range map[string]bool{"book.pdf": true, "book (1).pdf": false, "book (2).pdf": true, "song.mp3": true, "song (1).mp3": true} {
+		if got := fileExists(filepath.Join(dir, name)); got != want {
+			t.Errorf("expected %s to exist: %v, got %v", name, want, got)
+		}
+	}
+	results, _ := os.ReadFile(out)
+	if !strings.Contains(string(results), "Deleted "+filepath.Join(dir, "book (1).pdf")) {
+		t.Errorf("unexpected results:\n%s", results)
+	}
+}
+
+func TestApplyPlan_KeptFileMissing(t *testing.T) {
+	dir := setupTestDir(t)
+	createTestFile(t, filepath.Join(dir, "book (1).pdf"), "content")
+
+	c := &CLI{Delete: true}
+	groups, _ := c.applyPlan(t.Context(), []plannedGroup{{keep: []string{filepath.Join(dir, "book.pdf")}, delete: []string{filepath.Join(dir, "book (1).pdf")}}})
+	if !fileExists(filepath.Join(dir, "book (1).pdf")) {
+		t.Error("the last copy shouldn't be deleted")
+	}
+	if err := collectFailures(groups); err == nil || !strings.Contains(err.Error(), "still exists") {
+		t.Errorf("expected a failure, got %v", err)
+	}
+}
+
+func TestPlanCmd_DeleteWritesNoResults(t *testing.T) {
+	dir := setupTestDir(t)
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "content")
+	createTestFile(t, filepath.Join(dir, "book (1).pdf"), "content")
+	t.Chdir(t.TempDir())
+
+	cmd := &PlanCmd{CLI: CLI{Path: []string{dir}, Regex: []string{defaultRegex}, Delete: true}}
+	var err error
+	stdout, _ := captureOutput(t, func() { err = cmd.Run(&Context{}) })
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if fileExists("results.txt") {
+		t.Error("a plan shouldn't be written to results.txt")
+	}
+	if !strings.HasPrefix(stdout, strings.Join(planHeader, ",")) || !fileExists(filepath.Join(dir, "book (1).pdf")) {
+		t.Errorf("expected the plan on stdout and nothing deleted, got %q", stdout)
+	}
+}
+
+func TestPlanCmd_RejectsInverse(t *testing.T) {
+	cmd := &PlanCmd{CLI: CLI{Path: []string{t.TempDir()}, Inverse: true}}
+	if err := cmd.Run(&Context{}); err == nil {
+		t.Error("expected --inverse to be rejected")
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// matcher finds groups of duplicates among the scanned files, in place of --match.
+type matcher interface {
+	match(ctx context.Context, files []pluginFile) (map[string][]string, error)
+}
+
+// keepPolicy chooses which file of a group to keep, returning "" to leave the choice to ohman.
+type keepPolicy interface {
+	keep(ctx context.Context, g group, files []pluginFile) (string, error)
+}
+
+// disposer does away with a duplicate in place of deleting it, e.g. by moving it to a NAS's recycle bin.
+type disposer interface {
+	dispose(ctx context.Context, path string) error
+}
+
+// pluginFile describes a file to a plugin.
+type pluginFile struct {
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+// pluginRequest is written to a plugin's stdin, as a single JSON object. Hook says which of the fields are set:
+// "match" sets Files, "keep" sets Group and Files, and "action" sets Path.
+type pluginRequest struct {
+	Hook  string       `json:"hook"`
+	Files []pluginFile `json:"files,omitempty"`
+	Group *group       `json:"group,omitempty"`
+	Path  string       `json:"path,omitempty"`
+}
+
+// pluginResponse is read from a plugin's stdout. A plugin fails by setting Error, or by exiting with a non-zero
+// status.
+type pluginResponse struct {
+	Error  string  `json:"error,omitempty"`
+	Groups []group `json:"groups,omitempty"`
+	Keep   string  `json:"keep,omitempty"`
+}
+
+// execPlugin is an external executable implementing matcher, keepPolicy, and disposer. It's run once for each call,
+// with a pluginRequest on stdin, and must write a pluginResponse to stdout.
+type execPlugin struct {
+	command []string
+}
+
+// newExecPlugin returns the plugin run by command, which may include arguments, e.g. "synology-recycle --dry-run".
+func newExecPlugin(command string) (*execPlugin, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no plugin command given")
+	}
+	return &execPlugin{command: fields}, nil
+}
+
+func (p *execPlugin) call(ctx context.Context, req pluginRequest) (pluginResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return pluginResponse{}, err
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.command[0], p.command[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = bytes.NewReader(body), &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return pluginResponse{}, fmt.Errorf("plugin %s: %w: %s", p.command[0], err, msg)
+		}
+		return pluginResponse{}, fmt.Errorf("plugin %s: %w", p.command[0], err)
+	}
+	var resp pluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return pluginResponse{}, fmt.Errorf("unexpected output from plugin %s: %w", p.command[0], err)
+	}
+	if resp.Error != "" {
+		return pluginResponse{}, fmt.Errorf("plugin %s: %s", p.command[0], resp.Error)
+	}
+	return resp, nil
+}
+
+func (p *execPlugin) match(ctx context.Context, files []pluginFile) (map[string][]string, error) {
+	resp, err := p.call(ctx, pluginRequest{Hook: "match", Files: files})
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[string][]string, len(resp.Groups))
+	for _, g := range resp.Groups {
+		groups[g.Original] = append(groups[g.Original], g.Duplicates...)
+	}
+	return groups, nil
+}
+
+func (p *execPlugin) keep(ctx context.Context, g group, files []pluginFile) (string, error) {
+	resp, err := p.call(ctx, pluginRequest{Hook: "keep", Group: &g, Files: files})
+	return resp.Keep, err
+}
+
+func (p *execPlugin) dispose(ctx context.Context, path string) error {
+	_, err := p.call(ctx, pluginRequest{Hook: "action", Path: path})
+	return err
+}
+
+// setupPlugins starts using the plugins named by --plugin-matcher, --plugin-keep, and --plugin-action.
+func (c *CLI) setupPlugins() error {
+	for _, plugin := range []struct {
+		command string
+		set     func(p *execPlugin)
+	}{
+		{c.PluginMatcher, func(p *execPlugin) { c.matcher = p }},
+		{c.PluginKeep, func(p *execPlugin) { c.keepPolicy = p }},
+		{c.PluginAction, func(p *execPlugin) { c.disposer = p }},
+	} {
+		if plugin.command == "" {
+			continue
+		}
+		p, err := newExecPlugin(plugin.command)
+		if err != nil {
+			return err
+		}
+		plugin.set(p)
+	}
+	if c.matcher != nil && c.Match != "" && c.Match != "name" {
+		return fmt.Errorf("--plugin-matcher can't be combined with --match %s, as it finds duplicates itself", c.Match)
+	}
+	return nil
+}
+
+// scanPlugin walks each path, handing every file found to the matcher plugin to be grouped. The groups may only hold
+// files which were scanned, so a plugin can't have anything else deleted.
+func (c *CLI) scanPlugin(ctx context.Context) (map[string][]string, error) {
+	var files []pluginFile
+	scanned := make(map[string]bool)
+	err := c.walk(ctx, func(path string, info os.FileInfo) {
+		files = append(files, pluginFile{Path: path, Size: info.Size(), Modified: info.ModTime()})
+		scanned[path] = true
+	})
+	if err != nil {
+		return nil, err
+	}
+	groups, err := c.matcher.match(ctx, files)
+	if err != nil {
+		return nil, err
+	}
+	for original, duplicates := range groups {
+		for _, path := range append([]string{original}, duplicates...) {
+			if !scanned[path] {
+				return nil, fmt.Errorf("the matcher plugin grouped %s, which wasn't scanned", path)
+			}
+		}
+	}
+	return groups, nil
+}
+
+// describe describes paths for a plugin, skipping any which can't be statted.
+func (c *CLI) describe(paths []string) []pluginFile {
+	files := make([]pluginFile, 0, len(paths))
+	for _, p := range paths {
+		if info, err := c.stat(p); err == nil {
+			files = append(files, pluginFile{Path: p, Size: info.Size(), Modified: info.ModTime()})
+		}
+	}
+	return files
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+type fakeMatcher map[string][]string
+
+func (m fakeMatcher) match(context.Context, []pluginFile) (map[string][]string, error) { return m, nil }
+
+// largestKept keeps the largest file in each group.
+type largestKept struct{}
+
+func (largestKept) keep(_ context.Context, _ group, files []pluginFile) (string, error) {
+	largest := slices.MaxFunc(files, func(a, b pluginFile) int { return int(a.Size - b.Size) })
+	return largest.Path, nil
+}
+
+// recycler records the files it's given, rather than deleting them.
+type recycler struct{ disposed []string }
+
+func (r *recycler) dispose(_ context.Context, path string) error {
+	r.disposed = append(r.disposed, path)
+	return nil
+}
+
+func TestCLI_Run_Plugins(t *testing.T) {
+	t.Parallel()
+	memory := fstest.MapFS{
+		"media/a.txt":        {Data: []byte("short")},
+		"media/b.txt":        {Data: []byte("much longer")},
+		"media/c.txt":        {Data: []byte("mid length")},
+		"media/book.pdf":     {Data: []byte("content")},
+		"media/book (1).pdf": {Data: []byte("content")},
+	}
+	bin := &recycler{}
+	cli := &CLI{
+		Path:       []string{"/media"},
+		Delete:     true,
+		Out:        filepath.Join(t.TempDir(), "results.txt"),
+		Regex:      []string{defaultRegex},
+		memory:     memory,
+		matcher:    fakeMatcher{"/media/a.txt": {"/media/b.txt", "/media/c.txt"}},
+		keepPolicy: largestKept{},
+		disposer:   bin,
+	}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	// the matcher replaces matching by name, so book (1).pdf isn't found
+	if want := []string{"/media/c.txt", "/media/a.txt"}; !slices.Equal(bin.disposed, want) {
+		t.Errorf("disposed of %q, want %q", bin.disposed, want)
+	}
+	if len(memory) != 5 {
+		t.Errorf("files were deleted rather than handed to the action plugin: %v", memory)
+	}
+}
+
+// remover deletes the files it's given, recording them.
+type remover struct{ disposed []string }
+
+func (r *remover) dispose(_ context.Context, path string) error {
+	r.disposed = append(r.disposed, path)
+	return os.Remove(path)
+}
+
+func TestCLI_Run_PluginActionOnlyDuplicates(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	for _, d := range []string{"a", "b"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+	}
+	createTestFile(t, filepath.Join(dir, "a", "book.pdf"), "content")
+	createTestFile(t, filepath.Join(dir, "b", "book (1).pdf"), "content")
+	createTestFile(t, filepath.Join(dir, "empty.txt"), "")
+
+	bin := &remover{}
+	cli := &CLI{
+		Path:           []string{dir},
+		Delete:         true,
+		Empty:          "delete",
+		PruneEmptyDirs: true,
+		Out:            filepath.Join(t.TempDir(), "results.txt"),
+		Regex:          []string{defaultRegex},
+		matcher:        fakeMatcher{filepath.Join(dir, "a", "book.pdf"): {filepath.Join(dir, "b", "book (1).pdf")}},
+		disposer:       bin,
+	}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if want := []string{filepath.Join(dir, "b", "book (1).pdf")}; !slices.Equal(bin.disposed, want) {
+		t.Errorf("disposed of %q, want only the duplicate %q", bin.disposed, want)
+	}
+	for _, gone := range []string{"b", "empty.txt"} {
+		if fileExists(filepath.Join(dir, gone)) {
+			t.Errorf("%s should be removed", gone)
+		}
+	}
+}
+
+func TestCLI_Run_PluginMatcherUnscanned(t *testing.T) {
+	t.Parallel()
+	memory := fstest.MapFS{
+		"media/a.txt": {Data: []byte("content")},
+		"other/b.txt": {Data: []byte("content")},
+	}
+	cli := &CLI{
+		Path:    []string{"/media"},
+		Delete:  true,
+		Out:     filepath.Join(t.TempDir(), "results.txt"),
+		Regex:   []string{defaultRegex},
+		memory:  memory,
+		matcher: fakeMatcher{"/media/a.txt": {"/other/b.txt"}},
+	}
+	if err := cli.Run(nil); err == nil || !strings.Contains(err.Error(), "/other/b.txt") {
+		t.Errorf("Run() error = %v, want /other/b.txt rejected", err)
+	}
+	if len(memory) != 2 {
+		t.Errorf("a file the matcher wasn't given was deleted: %v", memory)
+	}
+}
+
+func TestExecPlugin(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "actions.log")
+	path := filepath.Join(dir, "plugin")
+	script := "#!/bin/sh\nreq=$(cat)\ncase \"$req\" in\n" +
+		"*'\"hook\":\"match\"'*) echo '{\"groups\":[{\"original\":\"/media/a.txt\",\"duplicates\":[\"/media/b.txt\"]}]}' ;;\n" +
+		"*'\"hook\":\"keep\"'*) echo '{\"keep\":\"/media/b.txt\"}' ;;\n" +
+		"*'\"path\":\"/media/locked.txt\"'*) echo '{\"error\":\"locked\"}' ;;\n" +
+		"*'\"hook\":\"action\"'*) echo \"$req\" >> " + shellQuote(log) + "; echo '{}' ;;\n" +
+		"*) echo 'unknown hook' >&2; exit 1 ;;\nesac\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatalf("failed to create %s: %v", path, err)
+	}
+	p, err := newExecPlugin(path + " --verbose")
+	if err != nil {
+		t.Fatalf("newExecPlugin() error = %v", err)
+	}
+	ctx := context.Background()
+
+	groups, err := p.match(ctx, []pluginFile{{Path: "/media/a.txt"}, {Path: "/media/b.txt"}})
+	if err != nil || !slices.Equal(groups["/media/a.txt"], []string{"/media/b.txt"}) {
+		t.Errorf("match() = %v, %v", groups, err)
+	}
+	if keep, err := p.keep(ctx, group{Original: "/media/a.txt"}, nil); err != nil || keep != "/media/b.txt" {
+		t.Errorf("keep() = %q, %v", keep, err)
+	}
+	if err := p.dispose(ctx, "/media/b.txt"); err != nil {
+		t.Errorf("dispose() error = %v", err)
+	}
+	if data, _ := os.ReadFile(log); !strings.Contains(string(data), `"path":"/media/b.txt"`) {
+		t.Errorf("the action plugin was given %q", data)
+	}
+	if err := p.dispose(ctx, "/media/locked.txt"); err == nil || !strings.Contains(err.Error(), "locked") {
+		t.Errorf("dispose() error = %v, want the plugin's error", err)
+	}
+	if _, err := p.call(ctx, pluginRequest{Hook: "other"}); err == nil || !strings.Contains(err.Error(), "unknown hook") {
+		t.Errorf("call() error = %v, want the plugin's stderr", err)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+)
+
+// preferAcrossRoots groups files found at the same path within more than one scan path, for --prefer-path. The file
+// under the first --prefer-path holding one becomes the original, and each other file at that path becomes one of
+// its duplicates, along with that file's own copies. found lists the files which aren't copies. Paths whose files
+// are all outside --prefer-path, or are under the same one, are left as they were. The files found within only one
+// scan path are returned.
+func (c *CLI) preferAcrossRoots(found []string, files map[string][]string) (alone []string) {
+	exists := make(map[string]bool, len(found))
+	byRel := make(map[string][]string)
+	add := func(path string) {
+		root := c.rootOf(path)
+		if root == "" {
+			return
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil || slices.Contains(byRel[rel], path) {
+			return
+		}
+		byRel[rel] = append(byRel[rel], path)
+	}
+	for _, path := range found {
+		exists[path] = true
+		add(path)
+	}
+	for original := range files {
+		// originals which weren't found still carry their copies across
+		add(original)
+	}
+
+	for _, paths := range byRel {
+		if len(paths) < 2 {
+			if exists[paths[0]] {
+				alone = append(alone, paths[0])
+			}
+			continue
+		}
+		keep, best, tied := "", len(c.PreferPath), false
+		for _, path := range paths {
+			rank := c.preferRank(path)
+			switch {
+			case !exists[path] || rank > best:
+			case rank == best:
+				tied = true
+			default:
+				keep, best, tied = path, rank, false
+			}
+		}
+		if keep == "" || tied {
+			continue
+		}
+		for _, path := range paths {
+			if path == keep || c.preferRank(path) == best {
+				continue
+			}
+			if exists[path] {
+				files[keep] = append(files[keep], path)
+			}
+			files[keep] = append(files[keep], files[path]...)
+			delete(files, path)
+		}
+	}
+	return alone
+}
+
+// rootOf returns the scan path which path was found beneath, the innermost when they're nested, or "" if none was.
+func (c *CLI) rootOf(path string) string {
+	var root string
+	for _, p := range c.Path {
+		if within(path, p) && len(p) > len(root) {
+			root = p
+		}
+	}
+	return root
+}
+
+// preferRank returns the index of the first --prefer-path holding path, or the number of them if none does.
+func (c *CLI) preferRank(path string) int {
+	for i, p := range c.PreferPath {
+		if within(path, p) {
+			return i
+		}
+	}
+	return len(c.PreferPath)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCLI_Run_PreferPath(t *testing.T) {
+	t.Parallel()
+	library, inbox := setupTestDir(t), setupTestDir(t)
+	for _, dir := range []string{library, inbox} {
+		if err := os.Mkdir(filepath.Join(dir, "Author"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"Author/book.pdf", "Author/edition.pdf", "only.pdf"} {
+		createTestFile(t, filepath.Join(library, name), name)
+	}
+	for _, name := range []string{"Author/book.pdf", "Author/book (1).pdf", "Author/edition.pdf", "only (1).pdf", "new.pdf"} {
+		content := name
+		switch name {
+		case "Author/book (1).pdf":
+			content = "Author/book.pdf"
+		case "Author/edition.pdf":
+			content = "another edition"
+		}
+		createTestFile(t, filepath.Join(inbox, name), content)
+	}
+
+	cli := &CLI{Path: []string{library, inbox}, PreferPath: []string{library}, Delete: true, Out: filepath.Join(t.TempDir(), "results.txt"), Regex: []string{defaultRegex}}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, name := range []string{"Author/book.pdf", "Author/book (1).pdf"} {
+		if fileExists(filepath.Join(inbox, name)) {
+			t.Errorf("inbox/%s should be deleted as a duplicate of the library's copy", name)
+		}
+	}
+	for _, path := range []string{
+		filepath.Join(library, "Author/book.pdf"),
+		filepath.Join(library, "Author/edition.pdf"),
+		// its content differs, so it's left alone
+		filepath.Join(inbox, "Author/edition.pdf"),
+		// only in the same directory as its original, which isn't in the inbox
+		filepath.Join(inbox, "only (1).pdf"),
+		filepath.Join(inbox, "new.pdf"),
+	} {
+		if !fileExists(path) {
+			t.Errorf("%s should be kept", path)
+		}
+	}
+}
+
+func TestCLI_PreferAcrossRoots_Unpreferred(t *testing.T) {
+	t.Parallel()
+	cli := &CLI{Path: []string{"/media/a", "/media/b", "/media/c"}, PreferPath: []string{"/media/a"}}
+	files := map[string][]string{}
+	cli.preferAcrossRoots([]string{"/media/b/book.pdf", "/media/c/book.pdf"}, files)
+	if len(files) != 0 {
+		t.Errorf("files at the same path outside --prefer-path were grouped: %v", files)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// defaultExtensions are the extensions of the files defaultPattern matches.
+const defaultExtensions = "pdf|mobi|mp4|epub|wav|mp3"
+
+// copyWord is the word a localized file manager adds to the names of copies.
+type copyWord struct {
+	// explorer is the word Windows Explorer adds after a dash, as in "book - Kopie.pdf" or "book - Kopie (2).pdf".
+	explorer string
+	// finder is the word the macOS Finder adds after a space, as in "book Kopie.pdf" or "book Kopie 2.pdf".
+	finder string
+}
+
+// localizedCopies are the words copies are named with in each language's versions of Windows and macOS, for --preset.
+var localizedCopies = map[string]copyWord{
+	"de": {explorer: "Kopie", finder: "Kopie"},
+	"es": {explorer: "copia", finder: "copia"},
+	"fr": {explorer: "copie", finder: "copie"},
+	"it": {explorer: "copia", finder: "copia"},
+	"ja": {explorer: "コピー", finder: "のコピー"},
+	"ko": {explorer: "복사본", finder: "복사본"},
+	"nl": {explorer: "kopie", finder: "kopie"},
+	"pl": {explorer: "kopia", finder: "kopia"},
+	"pt": {explorer: "cópia", finder: "cópia"},
+	"ru": {explorer: "копия", finder: "копия"},
+	"sv": {explorer: "kopia", finder: "kopia"},
+	"zh": {explorer: "副本", finder: "副本"},
+}
+
+// backupsPreset is the --preset which also finds the backups editors and tools make, like "notes.txt.~1~".
+const backupsPreset = "backups"
+
+// backupPattern matches the names of backups: Emacs's and GNU cp's numbered "notes.txt.~1~" and simple "notes.txt~",
+// and "notes.txt.bak" and "notes.txt.orig", as left by editors, patch, and merge tools. The original's name is the
+// whole of the name group.
+var backupPattern = regexp.MustCompile(`^(?P<name>.+?)(?:\.~\d+~|~|\.(?i:bak|orig))$`)
+
+// finderPreset is the --preset which also finds copies made by the macOS Finder in any language, like "book copy.pdf",
+// whatever their extension.
+const finderPreset = "finder"
+
+// finderPattern matches the names the Finder gives copies: the original's name, then a space and the copy word, then
+// a space and a number for every copy after the first, then the original's extension, if it has one, as in
+// "book copy.pdf", "book copy 2.pdf", "notes copy", or, in French, "livre copie 2.epub". Copies of copies, like
+// "book copy copy.pdf", have a copy word for each.
+func finderPattern() *regexp.Regexp {
+	words := []string{regexp.QuoteMeta("copy")}
+	for _, lang := range slices.Sorted(maps.Keys(localizedCopies)) {
+		if w := regexp.QuoteMeta(localizedCopies[lang].finder); !slices.Contains(words, w) {
+			words = append(words, w)
+		}
+	}
+	return regexp.MustCompile(`^(?P<name>.+?)\s(?i:` + strings.Join(words, "|") + `)(?:\s\d+)?(?P<ext>\.[^.\s]+)?$`)
+}
+
+// presetNames lists the values --preset accepts: each language, or localized for every one of them, and sync, backups,
+// and finder.
+func presetNames() []string {
+	return append(slices.Sorted(maps.Keys(localizedCopies)), "localized", syncPreset, backupsPreset, finderPreset)
+}
+
+// patterns compiles the regexes which find copies: each --regex and those of --patterns-file, or the default when
+// none is given, or, with --preset, one matching the presets' copy names as well as the default's "book (1).pdf". As
+// with the default, the preset's first group is the original's base name and the third its extension; the second is
+// the copy's suffix, rather than its number. --preset backups and finder add backupPattern and finderPattern to any of
+// them.
+func (c *CLI) patterns() (copyPatterns, error) {
+	// the default regex is replaced by any other, including those of the patterns file
+	custom := c.Regex
+	if slices.Equal(custom, []string{defaultPattern}) {
+		custom = nil
+	}
+	// sync finds leftovers rather than copies, and backups and finder add patterns of their own
+	var languages []string
+	var extra copyPatterns
+	for _, name := range c.Preset {
+		switch name {
+		case syncPreset:
+		case backupsPreset:
+			extra = append(extra, copyPattern{re: backupPattern})
+		case finderPreset:
+			extra = append(extra, copyPattern{re: finderPattern()})
+		default:
+			languages = append(languages, name)
+		}
+	}
+	if len(languages) == 0 {
+		var patterns copyPatterns
+		for _, pattern := range custom {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regex: %w", err)
+			}
+			if err := checkCopyGroups(re); err != nil {
+				return nil, err
+			}
+			patterns = append(patterns, copyPattern{re: re})
+		}
+		if c.PatternsFile != "" {
+			listed, err := readPatternsFile(c.PatternsFile)
+			if err != nil {
+				return nil, err
+			}
+			patterns = append(patterns, listed...)
+		}
+		if len(patterns) == 0 {
+			return append(copyPatterns{{re: regexp.MustCompile(defaultPattern)}}, extra...), nil
+		}
+		return append(patterns, extra...), nil
+	}
+	if len(custom) > 0 || c.PatternsFile != "" {
+		return nil, fmt.Errorf("--preset can't be combined with a custom --regex or --patterns-file")
+	}
+
+	suffixes := []string{`\s\(\d+\)`}
+	seen := make(map[copyWord]bool)
+	for _, name := range languages {
+		var words []copyWord
+		switch w, ok := localizedCopies[name]; {
+		case name == "localized":
+			for _, lang := range slices.Sorted(maps.Keys(localizedCopies)) {
+				words = append(words, localizedCopies[lang])
+			}
+		case ok:
+			words = append(words, w)
+		default:
+			return nil, fmt.Errorf("unknown --preset %q; expected one of %s", name, strings.Join(presetNames(), ", "))
+		}
+		for _, w := range words {
+			if seen[w] {
+				continue
+			}
+			seen[w] = true
+			suffixes = append(suffixes,
+				`\s[-–—]\s(?i:`+regexp.QuoteMeta(w.explorer)+`)(?:\s\(\d+\))?`,
+				`\s(?i:`+regexp.QuoteMeta(w.finder)+`)(?:\s\d+)?`)
+		}
+	}
+	re, err := regexp.Compile(`(.+?)(` + strings.Join(suffixes, "|") + `)\.(` + defaultExtensions + `)$`)
+	if err != nil {
+		return nil, err
+	}
+	return append(copyPatterns{{re: re}}, extra...), nil
+}
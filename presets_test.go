@@ -0,0 +1,148 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCLI_Pattern_Presets(t *testing.T) {
+	t.Parallel()
+	re, err := (&CLI{Regex: []string{defaultPattern}, Preset: []string{"localized"}}).patterns()
+	if err != nil {
+		t.Fatalf("patterns() error = %v", err)
+	}
+	for name, want := range map[string]string{
+		"book (1).pdf":             "book.pdf",
+		"book - Kopie.pdf":         "book.pdf",
+		"book - kopie (2).pdf":     "book.pdf",
+		"book Kopie 3.pdf":         "book.pdf",
+		"book - Copie.epub":        "book.epub",
+		"book – copia.mp3":         "book.mp3",
+		"book — копия.pdf":         "book.pdf",
+		"book - Cópia (4).pdf":     "book.pdf",
+		"本 - コピー.pdf":              "本.pdf",
+		"本 のコピー 2.pdf":             "本.pdf",
+		"book - 副本.mobi":           "book.mobi",
+		"book - Kopie (2) (1).pdf": "book.pdf",
+		"book - Kopie - Kopie.pdf": "book.pdf",
+		"book.pdf":                 "",
+		"Chapter 2.pdf":            "",
+		"book - Kopie.docx":        "",
+	} {
+		got, ok := originalFor(re, "/media/"+name)
+		if want == "" {
+			if ok {
+				t.Errorf("%q should not be a copy, got %q", name, got)
+			}
+			continue
+		}
+		if got != "/media/"+want {
+			t.Errorf("original of %q = %q, want %q", name, got, want)
+		}
+	}
+
+	if _, err := (&CLI{Regex: []string{defaultPattern}, Preset: []string{"xx"}}).patterns(); err == nil || !strings.Contains(err.Error(), "de, es, fr") {
+		t.Errorf("expected an unknown preset to list the presets, got %v", err)
+	}
+	if _, err := (&CLI{Regex: []string{`(.+)_copy\.(pdf)$`}, Preset: []string{"de"}}).patterns(); err == nil {
+		t.Error("expected --preset with a custom --regex to fail")
+	}
+}
+
+func TestCLI_Run_Preset(t *testing.T) {
+	t.Parallel()
+	memory := fstest.MapFS{
+		"media/book.pdf":         {Data: []byte("content")},
+		"media/book - Kopie.pdf": {Data: []byte("content")},
+		"media/book - copie.pdf": {Data: []byte("content")},
+	}
+	cli := &CLI{
+		Path:   []string{"/media"},
+		Delete: true,
+		Out:    filepath.Join(t.TempDir(), "results.txt"),
+		Regex:  []string{defaultRegex},
+		Preset: []string{"de"},
+		memory: memory,
+	}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if _, ok := memory["media/book - Kopie.pdf"]; ok {
+		t.Error("the German copy should be deleted")
+	}
+	if _, ok := memory["media/book - copie.pdf"]; !ok {
+		t.Error("the French copy should be left alone without --preset fr")
+	}
+}
+
+func TestCLI_Run_BackupsPreset(t *testing.T) {
+	t.Parallel()
+	memory := fstest.MapFS{
+		"media/book.pdf":        {Data: []byte("content")},
+		"media/book (1).pdf":    {Data: []byte("content")},
+		"media/notes.txt":       {Data: []byte("notes")},
+		"media/notes.txt.~1~":   {Data: []byte("notes")},
+		"media/notes.txt.~2~":   {Data: []byte("notes")},
+		"media/notes.txt~":      {Data: []byte("notes")},
+		"media/notes.txt.BAK":   {Data: []byte("notes")},
+		"media/app.conf":        {Data: []byte("setting = new")},
+		"media/app.conf.orig":   {Data: []byte("setting = old")},
+		"media/backup.tar.gz":   {Data: []byte("archive")},
+		"media/bakery.txt":      {Data: []byte("bread")},
+		"media/bakery.txt.bak2": {Data: []byte("bread")},
+	}
+	cli := &CLI{
+		Path:   []string{"/media"},
+		Delete: true,
+		Out:    filepath.Join(t.TempDir(), "results.txt"),
+		Regex:  []string{defaultRegex},
+		Preset: []string{backupsPreset},
+		memory: memory,
+	}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	for _, name := range []string{"book (1).pdf", "notes.txt.~1~", "notes.txt.~2~", "notes.txt~", "notes.txt.BAK"} {
+		if _, ok := memory["media/"+name]; ok {
+			t.Errorf("%s should be deleted", name)
+		}
+	}
+	// the original's backup differs from it, and the others aren't backups
+	for _, name := range []string{"book.pdf", "notes.txt", "app.conf", "app.conf.orig", "backup.tar.gz", "bakery.txt.bak2"} {
+		if _, ok := memory["media/"+name]; !ok {
+			t.Errorf("%s should be kept", name)
+		}
+	}
+}
+
+func TestCLI_Pattern_FinderPreset(t *testing.T) {
+	t.Parallel()
+	patterns, err := (&CLI{Regex: []string{defaultPattern}, Preset: []string{finderPreset}}).patterns()
+	if err != nil {
+		t.Fatalf("patterns() error = %v", err)
+	}
+	for name, want := range map[string]string{
+		"book copy.pdf":        "book.pdf",
+		"book copy 2.pdf":      "book.pdf",
+		"Book Copy 12.docx":    "Book.docx",
+		"book copy copy.pdf":   "book.pdf",
+		"book copy 2 (1).pdf":  "book.pdf",
+		"notes copy":           "notes",
+		"my.trip copy 3.jpeg":  "my.trip.jpeg",
+		"livre copie 2.epub":   "livre.epub",
+		"Datei Kopie.txt":      "Datei.txt",
+		"本 のコピー.pdf":           "本.pdf",
+		"book (1).pdf":         "book.pdf",
+		"book.pdf":             "",
+		"copy.pdf":             "",
+		"book copyedit.pdf":    "",
+		"carbon copy notes.md": "",
+	} {
+		got, _ := originalFor(patterns, name)
+		if got != want {
+			t.Errorf("original of %q = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// startProfiling starts the CPU profile and execution trace asked for by --cpuprofile and --trace. The returned stop
+// func ends them and writes the heap profile asked for by --memprofile; it must be called before the process exits, or
+// the files will be incomplete.
+func (a *App) startProfiling() (stop func() error, err error) {
+	var stops []func() error
+	stop = func() error {
+		var err error
+		for i := len(stops) - 1; i >= 0; i-- {
+			if e := stops[i](); e != nil && err == nil {
+				err = e
+			}
+		}
+		if a.MemProfile != "" {
+			if e := writeHeapProfile(a.MemProfile); e != nil && err == nil {
+				err = e
+			}
+		}
+		return err
+	}
+
+	if a.CPUProfile != "" {
+		f, err := os.Create(a.CPUProfile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create CPU profile: %w", err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("failed to start CPU profile: %w", err)
+		}
+		stops = append(stops, func() error {
+			pprof.StopCPUProfile()
+			return f.Close()
+		})
+	}
+	if a.Trace != "" {
+		f, err := os.Create(a.Trace)
+		if err != nil {
+			_ = stop()
+			return nil, fmt.Errorf("failed to create trace: %w", err)
+		}
+		if err := trace.Start(f); err != nil {
+			_ = f.Close()
+			_ = stop()
+			return nil, fmt.Errorf("failed to start trace: %w", err)
+		}
+		stops = append(stops, func() error {
+			trace.Stop()
+			return f.Close()
+		})
+	}
+	return stop, nil
+}
+
+// writeHeapProfile writes a profile of the memory in use to filename, after a collection so it's up to date.
+func writeHeapProfile(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create memory profile: %w", err)
+	}
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write memory profile: %w", err)
+	}
+	return f.Close()
+}
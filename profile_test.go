@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApp_StartProfiling(t *testing.T) {
+	// not parallel: only one CPU profile and trace may run in a process at a time
+	dir := t.TempDir()
+	a := &App{
+		CPUProfile: filepath.Join(dir, "cpu.pprof"),
+		MemProfile: filepath.Join(dir, "mem.pprof"),
+		Trace:      filepath.Join(dir, "trace.out"),
+	}
+	stop, err := a.startProfiling()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := stop(); err != nil {
+		t.Fatalf("unexpected error stopping: %v", err)
+	}
+	for _, f := range []string{a.CPUProfile, a.MemProfile, a.Trace} {
+		if info, err := os.Stat(f); err != nil || info.Size() == 0 {
+			t.Errorf("expected %s to be written, got %v", f, err)
+		}
+	}
+
+	a = &App{CPUProfile: filepath.Join(dir, "missing", "cpu.pprof")}
+	if _, err := a.startProfiling(); err == nil {
+		t.Error("expected an error creating a profile in a missing directory")
+	}
+}
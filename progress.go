@@ -0,0 +1,74 @@
+package main
+
+import "sync/atomic"
+
+// The types of event reported as a run proceeds.
+const (
+	eventFileScanned = "file_scanned"
+	eventGroupFound  = "group_found"
+	eventFileDeleted = "file_deleted"
+	eventError       = "error"
+)
+
+// event is something which happened during a run, reported to a progress's observer so it can be shown as it
+// happens. Type says which of the fields are set: file_scanned sets Path and Size, group_found sets Group,
+// file_deleted sets Path, and error sets Error and, when it concerns a file, Path.
+type event struct {
+	Type  string `json:"type"`
+	Path  string `json:"path,omitempty"`
+	Size  int64  `json:"size,omitempty"`
+	Group *group `json:"group,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// progress counts work as a run proceeds, so it can be observed from other goroutines. A nil *progress counts nothing.
+type progress struct {
+	scanned  atomic.Int64
+	actions  atomic.Int64
+	failures atomic.Int64
+	// observe, when set, is called with each event from the goroutine which caused it, so it mustn't block.
+	observe func(event)
+}
+
+func (p *progress) emit(e event) {
+	if p != nil && p.observe != nil {
+		p.observe(e)
+	}
+}
+
+// filesScanned is how many files have been scanned.
+func (p *progress) filesScanned() int64 {
+	if p == nil {
+		return 0
+	}
+	return p.scanned.Load()
+}
+
+func (p *progress) fileScanned(path string, size int64) {
+	if p != nil {
+		p.scanned.Add(1)
+		p.emit(event{Type: eventFileScanned, Path: path, Size: size})
+	}
+}
+
+func (p *progress) groupFound(g group) {
+	p.emit(event{Type: eventGroupFound, Group: &g})
+}
+
+func (p *progress) skipped(path string, err error) {
+	p.emit(event{Type: eventError, Path: path, Error: err.Error()})
+}
+
+func (p *progress) acted(a action) {
+	if p == nil || a.implicit {
+		return
+	}
+	p.actions.Add(1)
+	switch {
+	case a.Err != nil:
+		p.failures.Add(1)
+		p.emit(event{Type: eventError, Path: a.Path, Error: a.Err.Error()})
+	case a.Op == opDelete:
+		p.emit(event{Type: eventFileDeleted, Path: a.Path})
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCLI_Run_Events(t *testing.T) {
+	t.Parallel()
+	var events []event
+	cli := &CLI{
+		Path:   []string{"/media"},
+		Delete: true,
+		Out:    filepath.Join(t.TempDir(), "results.txt"),
+		Regex:  []string{defaultRegex},
+		memory: fstest.MapFS{
+			"media/book.pdf":     {Data: []byte("content")},
+			"media/book (1).pdf": {Data: []byte("content")},
+			"media/notes.pdf":    {Data: []byte("notes")},
+		},
+		progress: &progress{observe: func(e event) { events = append(events, e) }},
+	}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var types []string
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	want := []string{eventFileScanned, eventFileScanned, eventFileScanned, eventGroupFound, eventFileDeleted}
+	if !slices.Equal(types, want) {
+		t.Fatalf("events = %q, want %q", types, want)
+	}
+	if g := events[3].Group; g == nil || g.Original != "/media/book.pdf" {
+		t.Errorf("group_found event = %+v", events[3])
+	}
+	if e := events[4]; e.Path != "/media/book (1).pdf" {
+		t.Errorf("file_deleted event = %+v", e)
+	}
+}
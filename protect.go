@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// protectEnv lists additional protected paths, separated like $PATH, so they can be configured once per machine.
+const protectEnv = "OHMAN_PROTECT"
+
+// errProtected is returned instead of deleting or renaming a protected path.
+var errProtected = errors.New("refusing to modify a protected path")
+
+// protector guards paths which must never be deleted or renamed, nor replaced by a rename.
+type protector []string
+
+// newProtector protects paths, along with any listed in $OHMAN_PROTECT.
+func newProtector(paths []string) (protector, error) {
+	var p protector
+	for _, path := range append(append([]string(nil), paths...), filepath.SplitList(os.Getenv(protectEnv))...) {
+		if path == "" {
+			continue
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid protected path %s: %w", path, err)
+		}
+		// protect what a symlink points to, as well as the link
+		p = append(p, abs)
+		if resolved, err := filepath.EvalSymlinks(abs); err == nil && resolved != abs {
+			p = append(p, resolved)
+		}
+	}
+	return p, nil
+}
+
+// check returns an error wrapping errProtected if path is, or is within, a protected path.
+func (p protector) check(path string) error {
+	if len(p) == 0 {
+		return nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	candidates := []string{abs}
+	// resolve the parent only: removing a symlink removes the link, not its target
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(abs)); err == nil {
+		candidates = append(candidates, filepath.Join(dir, filepath.Base(abs)))
+	}
+	for _, protected := range p {
+		for _, c := range candidates {
+			if within(c, protected) {
+				return fmt.Errorf("%w: %s is protected by %s", errProtected, path, protected)
+			}
+		}
+	}
+	return nil
+}
+
+// within reports whether path is root or beneath it.
+func within(path, root string) bool {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		// the default filesystems are case-insensitive
+		path, root = strings.ToLower(path), strings.ToLower(root)
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProtector_Check(t *testing.T) {
+	dir := setupTestDir(t)
+	originals := filepath.Join(dir, "originals")
+	if err := os.Mkdir(originals, 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(originals, link); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	t.Setenv(protectEnv, filepath.Join(dir, "env-protected.pdf"))
+
+	p, err := newProtector([]string{originals})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{originals, true},
+		{filepath.Join(originals, "book (1).pdf"), true},
+		{filepath.Join(originals, "nested", "book (1).pdf"), true},
+		{filepath.Join(link, "book (1).pdf"), true},
+		{filepath.Join(dir, "env-protected.pdf"), true},
+		{filepath.Join(dir, "originals-copy", "book (1).pdf"), false},
+		{filepath.Join(dir, "book (1).pdf"), false},
+		{link, false},
+	}
+	for _, tt := range tests {
+		err := p.check(tt.path)
+		if got := errors.Is(err, errProtected); got != tt.want {
+			t.Errorf("check(%s): expected protected=%v, got %v", tt.path, tt.want, err)
+		}
+	}
+}
+
+func TestCLI_Run_Protect(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	originals := filepath.Join(dir, "originals")
+	if err := os.Mkdir(originals, 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	createTestFile(t, filepath.Join(originals, "book.pdf"), "original content")
+	createTestFile(t, filepath.Join(originals, "book (1).pdf"), "duplicate 1")
+	createTestFile(t, filepath.Join(dir, "song.mp3"), "original content")
+	createTestFile(t, filepath.Join(dir, "song (1).mp3"), "duplicate 1")
+
+	cli := &CLI{
+		Path:           []string{dir},
+		Delete:         true,
+		AllowDifferent: true,
+		Protect:        []string{originals},
+		Out:            filepath.Join(dir, "results.txt"),
+		Regex:          []string{defaultRegex},
+	}
+	err := cli.Run(nil)
+	if !errors.Is(err, errProtected) {
+		t.Fatalf("expected the protected delete to be reported, got: %v", err)
+	}
+	if cli.status != exitPartialFailure {
+		t.Errorf("expected status %d, got %d", exitPartialFailure, cli.status)
+	}
+	if !fileExists(filepath.Join(originals, "book (1).pdf")) {
+		t.Error("protected duplicate must not be deleted")
+	}
+	if fileExists(filepath.Join(dir, "song (1).mp3")) {
+		t.Error("unprotected duplicate should still be deleted")
+	}
+}
+
+func TestCLI_Run_Protect_Rename(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	original := filepath.Join(dir, "book.pdf")
+	createTestFile(t, original, "original content")
+	createTestFile(t, filepath.Join(dir, "book (1).pdf"), "duplicate 1")
+
+	cli := &CLI{
+		Path:             []string{dir},
+		Delete:           true,
+		AllowDifferent:   true,
+		InverseAndRename: true,
+		Protect:          []string{original},
+		Out:              filepath.Join(dir, "results.txt"),
+		Regex:            []string{defaultRegex},
+	}
+	if err := cli.Run(nil); !errors.Is(err, errProtected) {
+		t.Fatalf("expected the protected original to be reported, got: %v", err)
+	}
+	content, err := os.ReadFile(original)
+	if err != nil || string(content) != "original content" {
+		t.Errorf("protected original must be neither deleted nor replaced, got %q, %v", content, err)
+	}
+}
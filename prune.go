@@ -0,0 +1,66 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"io/fs"
+	"maps"
+	"slices"
+)
+
+// pruneEmptyDirs removes the directories left empty by the deletions and renames in groups and by deleting empty
+// files and sync leftovers, deepest first, up to but not including the scanned paths. Each removal is recorded on the
+// group which emptied the directory, or with the empty files or the leftovers, which are returned. Protected
+// directories, and any which can't be listed, are left alone.
+func (c *CLI) pruneEmptyDirs(ctx context.Context, groups []group, emptied, cleaned []action) ([]action, []action) {
+	const (
+		ownerEmptied = -1
+		ownerCleaned = -2
+	)
+	// owner holds the index of the group which emptied each directory, or ownerEmptied or ownerCleaned
+	owner := map[string]int{}
+	record := func(i int, a action) {
+		if a.Err != nil || (a.Op != opDelete && a.Op != opRename) {
+			return
+		}
+		for dir := parentDir(a.Path); c.withinPaths(dir); dir = parentDir(dir) {
+			if _, ok := owner[dir]; ok {
+				return
+			}
+			owner[dir] = i
+		}
+	}
+	for i, g := range groups {
+		for _, a := range g.Actions {
+			record(i, a)
+		}
+	}
+	for _, a := range emptied {
+		record(ownerEmptied, a)
+	}
+	for _, a := range cleaned {
+		record(ownerCleaned, a)
+	}
+
+	dirs := slices.SortedFunc(maps.Keys(owner), func(a, b string) int { return cmp.Compare(len(b), len(a)) })
+	for _, dir := range dirs {
+		if c.protected.check(dir) != nil {
+			continue
+		}
+		if entries, err := fs.ReadDir(c.files(), dir); err != nil || len(entries) > 0 {
+			continue
+		}
+		a := action{Op: opDelete, Path: dir, Err: c.discard(ctx, dir)}
+		switch i := owner[dir]; i {
+		case ownerEmptied:
+			c.progress.acted(a)
+			emptied = append(emptied, a)
+		case ownerCleaned:
+			c.progress.acted(a)
+			cleaned = append(cleaned, a)
+		default:
+			_ = c.act(&groups[i], a)
+		}
+	}
+	return emptied, cleaned
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCLI_PruneEmptyDirs(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	for _, d := range []string{"a/b", "kept/c", "protected/d", "full"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+	}
+	createTestFile(t, filepath.Join(dir, "kept", "file.pdf"), "content")
+	createTestFile(t, filepath.Join(dir, "full", "file.pdf"), "content")
+
+	protected, err := newProtector([]string{filepath.Join(dir, "protected")})
+	if err != nil {
+		t.Fatalf("newProtector() error = %v", err)
+	}
+	cli := &CLI{Path: []string{dir}, protected: protected}
+	groups := []group{{Original: "x", Actions: []action{
+		{Op: opDelete, Path: filepath.Join(dir, "a", "b", "book (1).pdf")},
+		{Op: opDelete, Path: filepath.Join(dir, "kept", "c", "book (1).pdf")},
+		{Op: opDelete, Path: filepath.Join(dir, "protected", "d", "book (1).pdf")},
+	}}}
+	emptied := []action{{Op: opDelete, Path: filepath.Join(dir, "full", "empty.txt")}}
+
+	emptied, _ = cli.pruneEmptyDirs(context.Background(), groups, emptied, nil)
+	for _, gone := range []string{"a/b", "a", "kept/c"} {
+		if fileExists(filepath.Join(dir, gone)) {
+			t.Errorf("%s should be removed", gone)
+		}
+	}
+	for _, kept := range []string{"kept", "protected/d", "full"} {
+		if !fileExists(filepath.Join(dir, kept)) {
+			t.Errorf("%s should be kept", kept)
+		}
+	}
+	if !fileExists(dir) {
+		t.Error("the scanned path should be kept")
+	}
+	if len(groups[0].Actions) != 6 || len(emptied) != 1 {
+		t.Errorf("actions = %+v, emptied = %+v", groups[0].Actions, emptied)
+	}
+}
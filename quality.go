@@ -0,0 +1,44 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os/exec"
+	"slices"
+)
+
+// bestAudio returns the highest quality of paths, for --keep-best-audio: lossless formats before lossy ones, then the
+// highest bitrate. Copies of equal quality keep their order, so the original is kept unless a copy is better.
+func (c *CLI) bestAudio(ctx context.Context, paths []string) (string, error) {
+	probe := c.probe
+	if probe == nil {
+		if _, err := exec.LookPath(c.ffprobePath()); err != nil {
+			return "", fmt.Errorf("--keep-best-audio needs FFmpeg's ffprobe (see --ffprobe): %w", err)
+		}
+		probe = c.ffprobe
+	}
+
+	bitrates := make(map[string]float64, len(paths))
+	for _, p := range paths {
+		if err := c.throttle.op(ctx); err != nil {
+			return "", err
+		}
+		info, err := probe(ctx, p)
+		if err != nil {
+			return "", fmt.Errorf("unable to probe %s: %w", p, err)
+		}
+		bitrates[p] = float64(info.BitRate)
+		if info.BitRate == 0 && info.Duration > 0 {
+			if stat, err := c.stat(p); err == nil {
+				bitrates[p] = float64(stat.Size()*8) / info.Duration.Seconds()
+			}
+		}
+	}
+
+	ranked := slices.Clone(paths)
+	slices.SortStableFunc(ranked, func(a, b string) int {
+		return cmp.Or(compareLossless(a, b), cmp.Compare(bitrates[b], bitrates[a]))
+	})
+	return ranked[0], nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// reservedWindowsNames are the device names Windows won't open as files, with or without an extension.
+var reservedWindowsNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM0": true, "COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT0": true, "LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// isWin32Name reports whether Windows resolves name to itself when it's part of an ordinary path. A reserved device
+// name like con.pdf names the console instead, and trailing spaces and dots are stripped, so "book.pdf " names
+// book.pdf. Such files, made by WSL, Samba, or macOS, can only be reached by \\?\ paths.
+func isWin32Name(name string) bool {
+	if strings.TrimRight(name, " .") != name {
+		return false
+	}
+	stem, _, _ := strings.Cut(name, ".")
+	return !reservedWindowsNames[strings.ToUpper(strings.TrimRight(stem, " "))]
+}
+
+// win32Name returns a name for a file named name which Windows can resolve, for moving it before the shell recycles
+// it.
+func win32Name(name string) string {
+	name = strings.TrimRight(name, " .")
+	if !isWin32Name(name) {
+		name = "_" + name
+	}
+	if name == "" {
+		name = "_"
+	}
+	return name
+}
+
+// maxPath is MAX_PATH, the length of the longest ordinary Windows path, including its terminating NUL.
+const maxPath = 260
+
+// shellPath returns where the file at path is moved for the Windows shell, which only accepts ordinary paths, to
+// recycle it. That's path itself when the shell can reach it. Otherwise, the file keeps its place as nearly as it
+// can, in the deepest directory above it which the shell can reach, under a name Windows can resolve; restoring it
+// from the Recycle Bin puts it there. It's empty when the shell can't reach even the volume's root.
+func shellPath(path string) string {
+	dir, name := filepath.Split(path)
+	dir = filepath.Clean(dir)
+	if isWin32Name(name) && isWin32Dir(dir) && len(path) < maxPath {
+		return path
+	}
+	name = win32Name(name)
+	for {
+		if target := filepath.Join(dir, name); isWin32Dir(dir) && len(target) < maxPath {
+			return target
+		}
+		if filepath.Dir(dir) == dir {
+			return ""
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// isWin32Dir reports whether Windows resolves every directory in dir to itself.
+func isWin32Dir(dir string) bool {
+	for part := range strings.SplitSeq(dir[len(filepath.VolumeName(dir)):], string(filepath.Separator)) {
+		if part != "" && !isWin32Name(part) {
+			return false
+		}
+	}
+	return true
+}
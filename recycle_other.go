@@ -0,0 +1,20 @@
+//go:build !windows
+
+package main
+
+import "os"
+
+// nativePath returns path unchanged; only Windows needs paths rewritten to reach every file.
+func nativePath(path string) string {
+	return path
+}
+
+// displayPath returns path unchanged.
+func displayPath(path string) string {
+	return path
+}
+
+// removeFile deletes the file at path. There's no system-wide trash to move it to, so permanent has no effect.
+func removeFile(path string, _ bool) error {
+	return os.Remove(path)
+}
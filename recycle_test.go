@@ -0,0 +1,63 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsWin32Name(t *testing.T) {
+	t.Parallel()
+	for name, want := range map[string]bool{
+		"book.pdf":      true,
+		"console.pdf":   true,
+		"con":           false,
+		"CON.pdf":       false,
+		"nul .tar.gz":   false,
+		"lpt1.mp3":      false,
+		"lpt10.mp3":     true,
+		"book.pdf ":     false,
+		"book.pdf.":     false,
+		"Books ":        false,
+		" leading.pdf":  true,
+		"book (1).epub": true,
+	} {
+		if got := isWin32Name(name); got != want {
+			t.Errorf("isWin32Name(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestWin32Name(t *testing.T) {
+	t.Parallel()
+	for name, want := range map[string]string{
+		"book.pdf ": "book.pdf",
+		"con.pdf":   "_con.pdf",
+		"aux. ":     "_aux",
+		"...":       "_",
+	} {
+		got := win32Name(name)
+		if got != want {
+			t.Errorf("win32Name(%q) = %q, want %q", name, got, want)
+		}
+		if !isWin32Name(got) {
+			t.Errorf("win32Name(%q) = %q, which Windows can't resolve", name, got)
+		}
+	}
+}
+
+func TestShellPath(t *testing.T) {
+	t.Parallel()
+	deep := filepath.Join("/media", strings.Repeat("d", 120), strings.Repeat("e", 120))
+	for name, tc := range map[string]struct{ path, want string }{
+		"ordinary":            {path: filepath.Join("/media", "book.pdf"), want: filepath.Join("/media", "book.pdf")},
+		"reserved name":       {path: filepath.Join("/media", "con.pdf"), want: filepath.Join("/media", "_con.pdf")},
+		"unresolvable dir":    {path: filepath.Join("/media", "Books ", "Sub", "book.pdf"), want: filepath.Join("/media", "book.pdf")},
+		"too long":            {path: filepath.Join(deep, strings.Repeat("f", 30)+".pdf"), want: filepath.Join(filepath.Dir(deep), strings.Repeat("f", 30)+".pdf")},
+		"too long everywhere": {path: filepath.Join("/media", strings.Repeat("g", maxPath)+".pdf")},
+	} {
+		if got := shellPath(tc.path); got != tc.want {
+			t.Errorf("%s: shellPath() = %q, want %q", name, got, tc.want)
+		}
+	}
+}
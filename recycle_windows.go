@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	foDelete          = 0x3
+	fofSilent         = 0x4
+	fofNoConfirmation = 0x10
+	fofAllowUndo      = 0x40
+	fofNoErrorUI      = 0x400
+)
+
+// shFileOpStruct is SHFILEOPSTRUCTW, as laid out on 64-bit Windows.
+type shFileOpStruct struct {
+	hwnd                  uintptr
+	wFunc                 uint32
+	pFrom                 *uint16
+	pTo                   *uint16
+	fFlags                uint16
+	fAnyOperationsAborted int32
+	hNameMappings         uintptr
+	lpszProgressTitle     *uint16
+}
+
+var procSHFileOperationW = windows.NewLazySystemDLL("shell32.dll").NewProc("SHFileOperationW")
+
+// nativePath returns path in the \\?\ form, which Windows passes to the filesystem without stripping trailing spaces
+// and dots or resolving device names, so every file is reached by the name it has. Without it, removing "book.pdf "
+// would remove book.pdf. Relative paths are returned unchanged.
+func nativePath(path string) string {
+	if strings.HasPrefix(path, `\\?\`) || !filepath.IsAbs(path) {
+		return path
+	}
+	path = filepath.Clean(path)
+	if unc, ok := strings.CutPrefix(path, `\\`); ok {
+		return `\\?\UNC\` + unc
+	}
+	return `\\?\` + path
+}
+
+// displayPath returns path without the prefix added by nativePath, as it's reported.
+func displayPath(path string) string {
+	if unc, ok := strings.CutPrefix(path, `\\?\UNC\`); ok {
+		return `\\` + unc
+	}
+	return strings.TrimPrefix(path, `\\?\`)
+}
+
+// removeFile moves the file at path to the Recycle Bin, or deletes it outright when permanent is set.
+func removeFile(path string, permanent bool) error {
+	if permanent {
+		return os.Remove(nativePath(path))
+	}
+	return recycle(path)
+}
+
+// recycle moves the file at path to the Recycle Bin through the shell, which only accepts ordinary paths shorter
+// than MAX_PATH. A file the shell can't reach is first moved to where it can, as chosen by shellPath, and moved back
+// if it can't be recycled.
+func recycle(path string) error {
+	if unsafe.Sizeof(uintptr(0)) != 8 {
+		return errors.New("the Recycle Bin is only supported by 64-bit builds; pass --permanent to delete files")
+	}
+	path = filepath.Clean(path)
+	if _, err := os.Lstat(nativePath(path)); err != nil {
+		return err
+	}
+	target := shellPath(path)
+	if target == "" {
+		return fmt.Errorf("unable to move %s to the Recycle Bin, as there's nowhere nearby the shell can reach it; pass --permanent to delete it", path)
+	}
+	if target != path {
+		if _, err := os.Lstat(nativePath(target)); err == nil {
+			return fmt.Errorf("unable to move %s to the Recycle Bin, as %s is in the way of moving it there first", path, target)
+		}
+		if err := os.Rename(nativePath(path), nativePath(target)); err != nil {
+			return err
+		}
+	}
+
+	// pFrom is a list of paths, ended by an empty one
+	from, err := windows.UTF16FromString(target)
+	if err != nil {
+		return err
+	}
+	from = append(from, 0)
+	op := shFileOpStruct{
+		wFunc:  foDelete,
+		pFrom:  &from[0],
+		fFlags: fofAllowUndo | fofNoConfirmation | fofSilent | fofNoErrorUI,
+	}
+	r, _, _ := procSHFileOperationW.Call(uintptr(unsafe.Pointer(&op)))
+	switch {
+	case r != 0:
+		err = fmt.Errorf("unable to move %s to the Recycle Bin: error %#x", path, r)
+	case op.fAnyOperationsAborted != 0:
+		err = fmt.Errorf("moving %s to the Recycle Bin was cancelled", path)
+	}
+	if err != nil && target != path {
+		_ = os.Rename(nativePath(target), nativePath(path))
+	}
+	return err
+}
@@ -0,0 +1,461 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+const (
+	opDelete = "delete"
+	opRename = "rename"
+	opKeep   = "keep"
+)
+
+// group is an original file along with the duplicates found for it, and any actions taken against them.
+type group struct {
+	Original   string   `json:"original"`
+	Duplicates []string `json:"duplicates"`
+	// Orphan marks groups whose original is missing, so the first duplicate is adopted in its place.
+	Orphan bool `json:"orphan,omitempty"`
+	// Mismatched lists the duplicates whose content differs from the original's. Such groups aren't acted upon.
+	Mismatched []string `json:"mismatched,omitempty"`
+	Actions    []action `json:"actions,omitempty"`
+	// Reasons explains, with --explain, why each file was classified as it was, by its path.
+	Reasons map[string]string `json:"reasons,omitempty"`
+	// RunID identifies the run which found the group.
+	RunID string `json:"run_id,omitempty"`
+}
+
+// action records a single operation performed against a file in a group.
+type action struct {
+	Op     string
+	Path   string
+	Target string
+	Err    error
+	// Size is the bytes freed by deleting the file: its size, when it was known beforehand, or 0 when other hard links
+	// to it remain.
+	Size int64
+	// Reason explains why the file was chosen to keep, when it isn't obvious, e.g. which rule chose the newest of
+	// copies modified at the same time.
+	Reason string
+	// implicit marks actions which weren't explicitly performed (e.g. keeping the original in a plain delete),
+	// and which the text format has never reported.
+	implicit bool
+}
+
+// render formats groups according to the requested output format.
+func render(format string, groups []group) string {
+	return renderColored(format, groups, palette{})
+}
+
+// renderColored is render with text output colored by p. The other formats are meant for programs, so are never
+// colored.
+func renderColored(format string, groups []group, p palette) string {
+	switch format {
+	case "fdupes":
+		return renderFdupes(groups)
+	case "markdown", "md":
+		return renderMarkdown(groups)
+	case "json":
+		return renderJSON(groups)
+	case "csv":
+		return renderCSV(groups)
+	case "html":
+		return renderHTML(groups)
+	case "plan":
+		return renderPlan(groups)
+	default:
+		return renderText(groups, p)
+	}
+}
+
+func renderText(groups []group, p palette) string {
+	var results []string
+	for _, g := range groups {
+		results = append(results, textGroup(g, p)...)
+		if reasons := g.reasons(); len(reasons) > 0 {
+			results = append(results, "  Why:")
+			for _, r := range reasons {
+				results = append(results, "    "+r)
+			}
+		}
+	}
+	return strings.Join(results, "\n")
+}
+
+// textGroup returns the lines of the text format for g.
+func textGroup(g group, p palette) []string {
+	var results []string
+	if g.Actions == nil {
+		if g.Orphan {
+			results = append(results, fmt.Sprintf("Original (missing): %s", g.Original))
+			results = append(results, p.kept(fmt.Sprintf("  - Adopt: %s", g.Duplicates[0])))
+			for _, d := range g.Duplicates[1:] {
+				if slices.Contains(g.Mismatched, d) {
+					results = append(results, fmt.Sprintf("  - Duplicate: %s (content differs)", d))
+					continue
+				}
+				results = append(results, p.pending(fmt.Sprintf("  - Duplicate: %s", d)))
+			}
+			return results
+		}
+		results = append(results, p.kept(fmt.Sprintf("Original: %s", g.Original)))
+		for _, d := range g.Duplicates {
+			if slices.Contains(g.Mismatched, d) {
+				// left alone, so not colored as though it would be deleted
+				results = append(results, fmt.Sprintf("  - Duplicate: %s (content differs)", d))
+				continue
+			}
+			results = append(results, p.pending(fmt.Sprintf("  - Duplicate: %s", d)))
+		}
+		return results
+	}
+	for _, a := range g.Actions {
+		if a.implicit {
+			continue
+		}
+		results = append(results, p.action(a))
+	}
+	return results
+}
+
+// renderFdupes mirrors fdupes/jdupes output: one file per line, with each group terminated by a blank line.
+// When files have been acted upon, lines are prefixed like `fdupes -dN`: [+] kept, [-] deleted, [!] failed.
+func renderFdupes(groups []group) string {
+	var sb strings.Builder
+	for _, g := range groups {
+		if g.Actions == nil {
+			// a missing original can't be listed as one of the group's files
+			if !g.Orphan {
+				sb.WriteString(g.Original + "\n")
+			}
+			for _, d := range g.Duplicates {
+				sb.WriteString(d + "\n")
+			}
+			sb.WriteString("\n")
+			continue
+		}
+		for _, a := range g.Actions {
+			switch {
+			case a.Err != nil:
+				fmt.Fprintf(&sb, "   [!] %s -- %v\n", a.Path, a.Err)
+			case a.Op == opDelete:
+				fmt.Fprintf(&sb, "   [-] %s\n", a.Path)
+			case a.Op == opRename:
+				fmt.Fprintf(&sb, "   [+] %s\n", a.Target)
+			default:
+				fmt.Fprintf(&sb, "   [+] %s\n", a.Path)
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// renderMarkdown emits one section per group, suitable for pasting into GitHub issues or wikis.
+// Groups which have only been listed are rendered as bullet lists, and groups which have been acted upon as tables.
+func renderMarkdown(groups []group) string {
+	if len(groups) == 0 {
+		return "_No duplicates found._\n"
+	}
+	var sb strings.Builder
+	for i, g := range groups {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		if g.Orphan {
+			fmt.Fprintf(&sb, "### %s (missing)\n\n", markdownCode(g.Original))
+		} else {
+			fmt.Fprintf(&sb, "### %s\n\n", markdownCode(g.Original))
+		}
+		if g.Actions == nil {
+			for i, d := range g.Duplicates {
+				switch {
+				case g.Orphan && i == 0:
+					fmt.Fprintf(&sb, "- %s (adopt)\n", markdownCode(d))
+					continue
+				case slices.Contains(g.Mismatched, d):
+					fmt.Fprintf(&sb, "- %s (content differs)\n", markdownCode(d))
+					continue
+				}
+				fmt.Fprintf(&sb, "- %s\n", markdownCode(d))
+			}
+			writeMarkdownReasons(&sb, g)
+			continue
+		}
+		sb.WriteString("| File | Action | Result |\n")
+		sb.WriteString("|------|--------|--------|\n")
+		for _, a := range g.Actions {
+			file := markdownCode(a.Path)
+			if a.Op == opRename {
+				file += " → " + markdownCode(a.Target)
+			}
+			result := "ok"
+			if a.Err != nil {
+				result = "**failed**: " + a.Err.Error()
+			}
+			fmt.Fprintf(&sb, "| %s | %s | %s |\n", markdownCell(file), a.Op, markdownCell(result))
+		}
+		writeMarkdownReasons(&sb, g)
+	}
+	return sb.String()
+}
+
+// writeMarkdownReasons writes why g's files were classified as they were, if --explain was given.
+func writeMarkdownReasons(sb *strings.Builder, g group) {
+	if len(g.Reasons) == 0 {
+		return
+	}
+	sb.WriteString("\n**Why:**\n\n")
+	for _, path := range append([]string{g.Original}, g.Duplicates...) {
+		if reason, ok := g.Reasons[path]; ok {
+			fmt.Fprintf(sb, "- %s: %s\n", markdownCode(path), reason)
+		}
+	}
+}
+
+// renderJSON emits the groups and their actions, which `ohman report --from` can read back.
+func renderJSON(groups []group) string {
+	if groups == nil {
+		groups = []group{}
+	}
+	data, _ := json.MarshalIndent(groups, "", "  ")
+	return string(data)
+}
+
+// csvHeader names the columns of the CSV format, which has one row per file.
+var csvHeader = []string{"group", "original", "path", "action", "target", "error"}
+
+// renderCSV emits a row per file, for spreadsheets. Groups which have only been listed report each file's role in
+// the action column: original, duplicate, adopt, or differs; those which have been acted upon report what was done.
+func renderCSV(groups []group) string {
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+	_ = w.Write(csvHeader)
+	for i, g := range groups {
+		id := fmt.Sprint(i + 1)
+		if g.Actions != nil {
+			for _, a := range g.Actions {
+				var msg string
+				if a.Err != nil {
+					msg = a.Err.Error()
+				}
+				_ = w.Write([]string{id, g.Original, a.Path, a.Op, a.Target, msg})
+			}
+			continue
+		}
+		if !g.Orphan {
+			_ = w.Write([]string{id, g.Original, g.Original, "original", "", ""})
+		}
+		for j, d := range g.Duplicates {
+			role := "duplicate"
+			switch {
+			case g.Orphan && j == 0:
+				role = "adopt"
+			case slices.Contains(g.Mismatched, d):
+				role = "differs"
+			}
+			_ = w.Write([]string{id, g.Original, d, role, "", ""})
+		}
+	}
+	w.Flush()
+	return sb.String()
+}
+
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"contains": slices.Contains[[]string],
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>ohman report</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: .3em .6em; text-align: left; }
+code { word-break: break-all; }
+.failed { color: #b00020; }
+.note { color: #666; }
+</style>
+</head>
+<body>
+<h1>ohman report</h1>
+{{- if not . }}
+<p>No duplicates found.</p>
+{{- end }}
+{{- range . }}
+<h2><code>{{ .Original }}</code>{{ if .Orphan }} <span class="note">(missing)</span>{{ end }}</h2>
+{{- if .Actions }}
+<table>
+<tr><th>File</th><th>Action</th><th>Result</th></tr>
+{{- range .Actions }}
+<tr><td><code>{{ .Path }}</code>{{ if .Target }} → <code>{{ .Target }}</code>{{ end }}</td><td>{{ .Op }}</td>
+{{- if .Err }}<td class="failed">failed: {{ .Err }}</td>{{ else }}<td>ok</td>{{ end }}</tr>
+{{- end }}
+</table>
+{{- else }}
+{{- $g := . }}
+<ul>
+{{- range $i, $d := .Duplicates }}
+<li><code>{{ $d }}</code>
+{{- if and $g.Orphan (eq $i 0) }} <span class="note">(adopt)</span>
+{{- else if contains $g.Mismatched $d }} <span class="note">(content differs)</span>{{ end }}</li>
+{{- end }}
+</ul>
+{{- end }}
+{{- end }}
+</body>
+</html>
+`))
+
+// renderHTML emits a standalone page, one section per group, for sharing or archiving a run's results.
+func renderHTML(groups []group) string {
+	var buf bytes.Buffer
+	if err := htmlReport.Execute(&buf, groups); err != nil {
+		return fmt.Sprintf("<!-- unable to render report: %v -->\n", err)
+	}
+	return buf.String()
+}
+
+// markdownCode wraps s in a code span, widening the fence when s itself contains backticks.
+func markdownCode(s string) string {
+	fence := "`"
+	for strings.Contains(s, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		return fence + " " + s + " " + fence
+	}
+	return fence + s + fence
+}
+
+// markdownCell escapes characters which would otherwise break a table row.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+// actionJSON is the JSON form of an action.
+type actionJSON struct {
+	Op       string `json:"op"`
+	Path     string `json:"path"`
+	Target   string `json:"target,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Size     int64  `json:"size,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	Implicit bool   `json:"implicit,omitempty"`
+	errorInfo
+}
+
+func (a action) MarshalJSON() ([]byte, error) {
+	v := actionJSON{Op: a.Op, Path: a.Path, Target: a.Target, Size: a.Size, Reason: a.Reason, Implicit: a.implicit}
+	if a.Err != nil {
+		v.Error, v.errorInfo = a.Err.Error(), describeError(a.Err)
+	}
+	return json.Marshal(v)
+}
+
+func (a *action) UnmarshalJSON(data []byte) error {
+	var v actionJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*a = action{Op: v.Op, Path: v.Path, Target: v.Target, Size: v.Size, Reason: v.Reason, implicit: v.Implicit}
+	if v.Error != "" {
+		a.Err = &savedError{msg: v.Error, info: v.errorInfo}
+	}
+	return nil
+}
+
+func (a action) String() string {
+	switch a.Op {
+	case opDelete:
+		if a.Err != nil {
+			return fmt.Sprintf("Failed to delete %s: %v", a.Path, a.Err)
+		}
+		return fmt.Sprintf("Deleted %s", a.Path)
+	case opRename:
+		if a.Err != nil {
+			return fmt.Sprintf("Failed to rename %s to %s: %v", a.Path, a.Target, a.Err)
+		}
+		return fmt.Sprintf("Renamed %s to %s%s", a.Path, a.Target, a.because())
+	default:
+		if a.Err != nil {
+			return fmt.Sprintf("Failed to choose which of %s's copies to keep: %v", a.Path, a.Err)
+		}
+		if a.Reason != "" {
+			return fmt.Sprintf("Kept %s%s", a.Path, a.because())
+		}
+		return fmt.Sprintf("Kept newest file: %s", a.Path)
+	}
+}
+
+// because returns a's reason as a parenthetical, if it has one.
+func (a action) because() string {
+	if a.Reason == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", a.Reason)
+}
+
+// ReportCmd renders the results saved by an earlier run, so one scan can be reported on in several ways.
+type ReportCmd struct {
+	From   string `name:"from" required:"" help:"Results to report on: the output of --format json, a run recorded in the history, or - for stdin." placeholder:"FILE"`
+	Format string `name:"format" help:"Output format: ${enum}." enum:"text,fdupes,markdown,md,csv,html,json,plan,dirs" default:"text"`
+	Out    string `name:"out" short:"o" help:"Output file for the report. Defaults to stdout." type:"path"`
+}
+
+func (r *ReportCmd) Run() error {
+	var data []byte
+	var err error
+	if r.From == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(r.From)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read results: %w", err)
+	}
+	groups, err := parseResults(data)
+	if err != nil {
+		return fmt.Errorf("unable to read results from %s: %w", r.From, err)
+	}
+
+	output := render(r.Format, groups)
+	if r.Format == "dirs" {
+		output = renderDirs(localFS{}, groups)
+	}
+	if r.Out != "" {
+		return outputResults(r.Out, output)
+	}
+	fmt.Println(strings.TrimSuffix(output, "\n"))
+	return nil
+}
+
+// parseResults reads the groups written by --format json, or the results of a run recorded in the history or sent
+// with --webhook-results.
+func parseResults(data []byte) ([]group, error) {
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("[")) {
+		var groups []group
+		return groups, json.Unmarshal(data, &groups)
+	}
+	var run struct {
+		Results *[]group `json:"results"`
+	}
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, err
+	}
+	if run.Results == nil {
+		return nil, errors.New("no results found; save them with --format json, or use a recorded run")
+	}
+	return *run.Results, nil
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRenderText_DryRun(t *testing.T) {
+	t.Parallel()
+	groups := []group{{Original: "/a/book.pdf", Duplicates: []string{"/a/book (1).pdf", "/a/book (2).pdf"}}}
+
+	got := render("text", groups)
+	want := "Original: /a/book.pdf\n  - Duplicate: /a/book (1).pdf\n  - Duplicate: /a/book (2).pdf"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestRenderFdupes_DryRun(t *testing.T) {
+	t.Parallel()
+	groups := []group{
+		{Original: "/a/book.pdf", Duplicates: []string{"/a/book (1).pdf"}},
+		{Original: "/b/song.mp3", Duplicates: []string{"/b/song (1).mp3", "/b/song (2).mp3"}},
+	}
+
+	got := render("fdupes", groups)
+	want := "/a/book.pdf\n/a/book (1).pdf\n\n/b/song.mp3\n/b/song (1).mp3\n/b/song (2).mp3\n\n"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestRenderFdupes_Delete(t *testing.T) {
+	t.Parallel()
+	groups := []group{{
+		Original:   "/a/book.pdf",
+		Duplicates: []string{"/a/book (1).pdf", "/a/book (2).pdf"},
+		Actions: []action{
+			{Op: opKeep, Path: "/a/book.pdf", implicit: true},
+			{Op: opDelete, Path: "/a/book (1).pdf"},
+			{Op: opDelete, Path: "/a/book (2).pdf", Err: errors.New("permission denied")},
+		},
+	}}
+
+	got := render("fdupes", groups)
+	want := "   [+] /a/book.pdf\n   [-] /a/book (1).pdf\n   [!] /a/book (2).pdf -- permission denied\n\n"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestCLI_Run_FdupesFormat(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "original content")
+	createTestFile(t, filepath.Join(dir, "book (1).pdf"), "duplicate 1")
+
+	outFile := filepath.Join(dir, "results.txt")
+
+	cli := &CLI{
+		Path:   []string{dir},
+		DryRun: true,
+		Format: "fdupes",
+		Out:    outFile,
+		Regex:  []string{defaultRegex},
+	}
+
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	content, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatalf("failed to read output file: %v", err)
+	}
+	want := filepath.Join(dir, "book.pdf") + "\n" + filepath.Join(dir, "book (1).pdf") + "\n\n"
+	if withoutHeading(t, cli.runID, string(content)) != want {
+		t.Errorf("expected %q, got %q", want, string(content))
+	}
+	if strings.Contains(string(content), "Original:") {
+		t.Error("fdupes output should not contain text format labels")
+	}
+}
+
+func TestRenderMarkdown_DryRun(t *testing.T) {
+	t.Parallel()
+	groups := []group{{Original: "/a/book.pdf", Duplicates: []string{"/a/book (1).pdf"}}}
+
+	got := render("markdown", groups)
+	want := "### `/a/book.pdf`\n\n- `/a/book (1).pdf`\n"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestRenderMarkdown_Delete(t *testing.T) {
+	t.Parallel()
+	groups := []group{{
+		Original:   "/a/book.pdf",
+		Duplicates: []string{"/a/book (1).pdf", "/a/a|b (1).pdf"},
+		Actions: []action{
+			{Op: opDelete, Path: "/a/book (1).pdf"},
+			{Op: opDelete, Path: "/a/a|b (1).pdf", Err: errors.New("busy")},
+		},
+	}}
+
+	got := render("markdown", groups)
+	for _, want := range []string{
+		"### `/a/book.pdf`",
+		"| File | Action | Result |",
+		"| `/a/book (1).pdf` | delete | ok |",
+		"| `/a/a\\|b (1).pdf` | delete | **failed**: busy |",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, got)
+		}
+	}
+}
+
+func TestRenderMarkdown_NoGroups(t *testing.T) {
+	t.Parallel()
+	if got := render("markdown", nil); !strings.Contains(got, "No duplicates found") {
+		t.Errorf("expected empty report message, got %q", got)
+	}
+}
+
+func TestMarkdownCode_Backticks(t *testing.T) {
+	t.Parallel()
+	if got := markdownCode("a`b.pdf"); got != "``a`b.pdf``" {
+		t.Errorf("unexpected code span: %q", got)
+	}
+}
+
+func TestRenderCSV(t *testing.T) {
+	t.Parallel()
+	groups := []group{
+		{Original: "/a/book.pdf", Duplicates: []string{"/a/book (1).pdf", "/a/book, 2nd.pdf"}, Mismatched: []string{"/a/book, 2nd.pdf"}},
+		{Original: "/b/song.mp3", Duplicates: []string{"/b/song (1).mp3"}, Orphan: true},
+		{Original: "/c/a.txt", Duplicates: []string{"/c/a (1).txt"}, Actions: []action{
+			{Op: opKeep, Path: "/c/a.txt", implicit: true},
+			{Op: opDelete, Path: "/c/a (1).txt", Err: errors.New("permission denied")},
+		}},
+	}
+
+	got := render("csv", groups)
+	want := `group,original,path,action,target,error
+1,/a/book.pdf,/a/book.pdf,original,,
+1,/a/book.pdf,/a/book (1).pdf,duplicate,,
+1,/a/book.pdf,"/a/book, 2nd.pdf",differs,,
+2,/b/song.mp3,/b/song (1).mp3,adopt,,
+3,/c/a.txt,/c/a.txt,keep,,
+3,/c/a.txt,/c/a (1).txt,delete,,permission denied
+`
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestRenderHTML(t *testing.T) {
+	t.Parallel()
+	groups := []group{
+		{Original: "/a/<book>.pdf", Duplicates: []string{"/a/<book> (1).pdf"}},
+		{Original: "/b/a.txt", Duplicates: []string{"/b/a (1).txt"}, Actions: []action{
+			{Op: opRename, Path: "/b/a (1).txt", Target: "/b/a.txt", Err: errors.New("file exists")},
+		}},
+	}
+
+	got := render("html", groups)
+	for _, want := range []string{"<!DOCTYPE html>", "<code>/a/&lt;book&gt;.pdf</code>", "<li><code>/a/&lt;book&gt; (1).pdf</code></li>", "→ <code>/b/a.txt</code>", "failed: file exists"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, got)
+		}
+	}
+	if got := render("html", nil); !strings.Contains(got, "No duplicates found") {
+		t.Errorf("expected an empty report, got:\n%s", got)
+	}
+}
+
+func TestParseResults(t *testing.T) {
+	t.Parallel()
+	groups := []group{{Original: "/a/book.pdf", Duplicates: []string{"/a/book (1).pdf"}, Actions: []action{
+		{Op: opKeep, Path: "/a/book.pdf", implicit: true},
+		{Op: opDelete, Path: "/a/book (1).pdf", Err: errors.New("permission denied")},
+	}}}
+
+	for name, data := range map[string]string{
+		"scan":    render("json", groups),
+		"history": `{"id": "20240301T123000Z-abcd", "event": "completed", "results": ` + render("json", groups) + `}`,
+	} {
+		got, err := parseResults([]byte(data))
+		if err != nil {
+			t.Fatalf("%s: parseResults() error = %v", name, err)
+		}
+		if render("text", got) != render("text", groups) {
+			t.Errorf("%s: expected %q, got %q", name, render("text", groups), render("text", got))
+		}
+	}
+
+	if got, err := parseResults([]byte(render("json", nil))); err != nil || len(got) != 0 {
+		t.Errorf("expected no groups, got %v, %v", got, err)
+	}
+	if _, err := parseResults([]byte(`{"event": "completed", "groups": 1}`)); err == nil {
+		t.Error("expected an error for a payload without results")
+	}
+	if _, err := parseResults([]byte("Original: /a/book.pdf")); err == nil {
+		t.Error("expected an error for text output")
+	}
+}
+
+func TestReportCmd(t *testing.T) {
+	dir := setupTestDir(t)
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "content")
+	createTestFile(t, filepath.Join(dir, "book (1).pdf"), "content")
+
+	scan := filepath.Join(dir, "scan.json")
+	cli := &CLI{Path: []string{dir}, DryRun: true, Regex: []string{defaultRegex}, Format: "json", Out: scan}
+	if err := cli.Run(&Context{}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	out := filepath.Join(dir, "report.md")
+	if err := (&ReportCmd{From: scan, Format: "md", Out: out}).Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read report: %v", err)
+	}
+	if want := "- `" + filepath.Join(dir, "book (1).pdf") + "`"; !strings.Contains(string(data), want) {
+		t.Errorf("expected report to contain %q, got:\n%s", want, data)
+	}
+	if !fileExists(filepath.Join(dir, "book (1).pdf")) {
+		t.Error("a report shouldn't change any files")
+	}
+
+	if err := (&ReportCmd{From: filepath.Join(dir, "missing.json"), Format: "text"}).Run(); err == nil {
+		t.Error("expected an error for missing results")
+	}
+}
+
+func TestCLI_Run_JSONSizes(t *testing.T) {
+	t.Parallel()
+	out := filepath.Join(t.TempDir(), "results.json")
+	cli := &CLI{
+		Path:   []string{"/media"},
+		Delete: true,
+		Format: "json",
+		Out:    out,
+		Regex:  []string{defaultRegex},
+		memory: fstest.MapFS{
+			"media/book.pdf":     {Data: []byte("content")},
+			"media/book (1).pdf": {Data: []byte("content")},
+		},
+	}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read results: %v", err)
+	}
+	groups, err := parseResults(data)
+	if err != nil {
+		t.Fatalf("parseResults() error = %v", err)
+	}
+	if len(groups) != 1 || len(groups[0].Actions) != 2 {
+		t.Fatalf("unexpected results: %+v", groups)
+	}
+	if a := groups[0].Actions[1]; a.Op != opDelete || a.Size != int64(len("content")) {
+		t.Errorf("deletion = %+v, want the deleted file's size", a)
+	}
+}
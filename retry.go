@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// maxRetryDelay caps the wait between retries of a delete or rename.
+const maxRetryDelay = 10 * time.Second
+
+// retriedError is a transient error which persisted however often the operation was retried, distinguishing it in
+// results from errors retrying can't fix.
+type retriedError struct {
+	err      error
+	attempts int
+}
+
+func (e *retriedError) Error() string {
+	return fmt.Sprintf("%v (transient; failed %d time(s))", e.err, e.attempts)
+}
+
+func (e *retriedError) Unwrap() error {
+	return e.err
+}
+
+// retry performs op, trying again up to --retries times while it fails with a transient error, waiting --retry-delay
+// before the first retry and twice as long before each further one.
+func (c *CLI) retry(ctx context.Context, op func(attempt int) error) error {
+	delay := c.RetryDelay
+	for attempt := 1; ; attempt++ {
+		err := op(attempt)
+		if err == nil || !isTransient(err) {
+			return err
+		}
+		if attempt > c.Retries {
+			return &retriedError{err: err, attempts: attempt}
+		}
+		select {
+		case <-ctx.Done():
+			return &retriedError{err: err, attempts: attempt}
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+// isTransient reports whether err may go away by itself, such as a busy file or a network filesystem's stale handle.
+func isTransient(err error) bool {
+	switch errorKind(err) {
+	case "busy", "stale", "interrupted", "timeout", "network":
+		return true
+	}
+	return false
+}
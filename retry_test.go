@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flakyDisposer fails with errs in turn, then succeeds.
+type flakyDisposer struct {
+	errs  []error
+	calls int
+}
+
+func (d *flakyDisposer) dispose(_ context.Context, _ string) error {
+	d.calls++
+	if d.calls <= len(d.errs) {
+		return d.errs[d.calls-1]
+	}
+	return nil
+}
+
+func TestCLI_Remove_Retries(t *testing.T) {
+	t.Parallel()
+	timedOut := &fs.PathError{Op: "remove", Path: "/media/a (1).pdf", Err: os.ErrDeadlineExceeded}
+	for _, tt := range []struct {
+		name    string
+		errs    []error
+		calls   int
+		failure string
+	}{
+		{name: "recovers", errs: []error{timedOut, timedOut}, calls: 3},
+		{name: "deleted by a failed attempt", errs: []error{timedOut, fs.ErrNotExist}, calls: 2},
+		{name: "gives up", errs: []error{timedOut, timedOut, timedOut, timedOut}, calls: 3, failure: "transient"},
+		{name: "permanent", errs: []error{fs.ErrPermission}, calls: 1, failure: "permanent"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			d := &flakyDisposer{errs: tt.errs}
+			c := &CLI{Retries: 2, RetryDelay: time.Millisecond, disposer: d}
+			err := c.remove(context.Background(), "/media/a (1).pdf")
+			if d.calls != tt.calls {
+				t.Errorf("tried %d times, want %d", d.calls, tt.calls)
+			}
+			if tt.failure == "" {
+				if err != nil {
+					t.Errorf("remove() error = %v", err)
+				}
+				return
+			}
+			if err == nil || describeError(err).Failure != tt.failure {
+				t.Errorf("remove() error = %v, want a %s failure", err, tt.failure)
+			}
+		})
+	}
+}
+
+func TestAction_JSON_Failure(t *testing.T) {
+	t.Parallel()
+	a := action{Op: opDelete, Path: "/media/a (1).pdf", Err: &retriedError{err: errors.New("device or resource busy"), attempts: 4}}
+	data, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"error":"device or resource busy (transient; failed 4 time(s))","failure":"transient"`) {
+		t.Errorf("unexpected JSON %s", data)
+	}
+	var back action
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := json.Marshal(back); string(again) != string(data) {
+		t.Errorf("round trip gave %s, want %s", again, data)
+	}
+}
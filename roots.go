@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// dangerousRoot reports why deleting within path would be reckless, e.g. because a stray space or unset variable
+// turned a specific directory into / or $HOME, or an empty string if it's an ordinary directory.
+func dangerousRoot(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	if filepath.Dir(abs) == abs {
+		return tr("the root of a filesystem")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		switch abs {
+		case filepath.Clean(home):
+			return tr("a home directory")
+		case filepath.Dir(filepath.Clean(home)):
+			return tr("the directory containing home directories")
+		}
+	}
+	if isMountPoint(abs) {
+		return tr("a mount point")
+	}
+	return ""
+}
+
+// checkRoots refuses destructive runs over dangerous roots unless --force-root is given.
+func (c *CLI) checkRoots() error {
+	if !c.Delete || c.DryRun || c.ForceRoot {
+		return nil
+	}
+	if _, local := c.files().(localFS); !local {
+		return nil
+	}
+	for _, p := range c.Path {
+		if reason := dangerousRoot(p); reason != "" {
+			return errors.New(tr("refusing to delete within %s, which is %s; pass --force-root if this is intended", p, reason))
+		}
+	}
+	return nil
+}
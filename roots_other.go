@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+// isMountPoint is unsupported here; drive roots are still caught as filesystem roots.
+func isMountPoint(string) bool {
+	return false
+}
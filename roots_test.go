@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setHome points the user's home directory at dir.
+func setHome(t *testing.T, dir string) {
+	t.Helper()
+	t.Setenv("HOME", dir)
+	t.Setenv("USERPROFILE", dir)
+}
+
+func TestDangerousRoot(t *testing.T) {
+	home := filepath.Join(setupTestDir(t), "home", "media")
+	if err := os.MkdirAll(filepath.Join(home, "Books"), 0755); err != nil {
+		t.Fatalf("failed to create directories: %v", err)
+	}
+	setHome(t, home)
+	root, err := filepath.Abs(string(filepath.Separator))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{root, "the root of a filesystem"},
+		{home, "a home directory"},
+		{home + string(filepath.Separator), "a home directory"},
+		{filepath.Dir(home), "the directory containing home directories"},
+		{filepath.Join(home, "Books"), ""},
+	}
+	for _, tt := range tests {
+		if got := dangerousRoot(tt.path); got != tt.want {
+			t.Errorf("dangerousRoot(%s) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCLI_Run_RefusesDangerousRoot(t *testing.T) {
+	home := setupTestDir(t)
+	setHome(t, home)
+	createTestFile(t, filepath.Join(home, "book.pdf"), "original content")
+	createTestFile(t, filepath.Join(home, "book (1).pdf"), "duplicate 1")
+	out := filepath.Join(t.TempDir(), "results.txt")
+
+	cli := &CLI{Path: []string{home}, Delete: true, AllowDifferent: true, Out: out, Regex: []string{defaultRegex}}
+	err := cli.Run(nil)
+	if err == nil || !strings.Contains(err.Error(), "--force-root") {
+		t.Fatalf("expected the home directory to be refused, got: %v", err)
+	}
+	if !fileExists(filepath.Join(home, "book (1).pdf")) {
+		t.Fatal("nothing should be deleted when a root is refused")
+	}
+
+	// dry runs are always allowed
+	cli = &CLI{Path: []string{home}, Delete: true, AllowDifferent: true, DryRun: true, Regex: []string{defaultRegex}}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("unexpected error for a dry run: %v", err)
+	}
+
+	cli = &CLI{Path: []string{home}, Delete: true, AllowDifferent: true, ForceRoot: true, Out: out, Regex: []string{defaultRegex}}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("unexpected error with --force-root: %v", err)
+	}
+	if fileExists(filepath.Join(home, "book (1).pdf")) {
+		t.Error("duplicate should be deleted with --force-root")
+	}
+}
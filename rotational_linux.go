@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// isRotational reports whether the block device dev is a spinning disk, according to sysfs. A partition's own
+// directory has no queue, so its disk's is read instead.
+func isRotational(dev uint64) bool {
+	base := fmt.Sprintf("/sys/dev/block/%d:%d", unix.Major(dev), unix.Minor(dev))
+	for _, path := range []string{base + "/queue/rotational", base + "/../queue/rotational"} {
+		if data, err := os.ReadFile(path); err == nil {
+			return strings.TrimSpace(string(data)) == "1"
+		}
+	}
+	return false
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// isRotational reports that no disk is known to be spinning, as that can't be told on this platform.
+func isRotational(uint64) bool {
+	return false
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	lockRoot   = "root"
+	lockGlobal = "global"
+	lockNone   = "none"
+)
+
+// errLocked is returned by tryLock when another process holds a conflicting lock.
+var errLocked = errors.New("locked by another process")
+
+// runLock holds the advisory locks preventing concurrent destructive runs over the same files.
+type runLock struct {
+	files []*os.File
+}
+
+// acquireRunLock locks the given roots so that no other ohman process can delete or rename files within them
+// until release is called.
+//
+// In root mode, each root is locked exclusively and each of its ancestors is locked shared. Runs over the same or
+// nested trees (e.g. /media and /media/books) therefore conflict, while runs over unrelated trees don't.
+// In global mode, a single exclusive lock is held regardless of roots.
+func acquireRunLock(dir, mode string, roots []string) (*runLock, error) {
+	if mode == lockNone {
+		return &runLock{}, nil
+	}
+	if dir == "" {
+		dir = defaultLockDir()
+	}
+	if err := makeLockDir(dir); err != nil {
+		return nil, fmt.Errorf("unable to create lock directory %s: %w", dir, err)
+	}
+
+	exclusive := map[string]bool{}
+	if mode == lockGlobal {
+		exclusive["global"] = true
+	} else {
+		for _, root := range roots {
+			abs, err := filepath.Abs(root)
+			if err != nil {
+				return nil, err
+			}
+			exclusive[abs] = true
+		}
+	}
+	shared := map[string]bool{}
+	if mode != lockGlobal {
+		for key := range exclusive {
+			for p := filepath.Dir(key); ; p = filepath.Dir(p) {
+				if !exclusive[p] {
+					shared[p] = true
+				}
+				if p == filepath.Dir(p) {
+					break
+				}
+			}
+		}
+	}
+
+	l := &runLock{}
+	for _, keys := range []struct {
+		set       map[string]bool
+		exclusive bool
+	}{{exclusive, true}, {shared, false}} {
+		for key := range keys.set {
+			if err := l.lock(dir, key, keys.exclusive); err != nil {
+				l.release()
+				if errors.Is(err, errLocked) {
+					return nil, errors.New(tr("another ohman run is already processing %s; try again once it has finished", key))
+				}
+				return nil, err
+			}
+		}
+	}
+	return l, nil
+}
+
+func (l *runLock) lock(dir, key string, exclusive bool) error {
+	sum := sha256.Sum256([]byte(key))
+	name := filepath.Join(dir, "ohman-"+hex.EncodeToString(sum[:8])+".lock")
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_RDWR, lockFileMode)
+	if err == nil {
+		// the umask would otherwise stop other accounts from opening it to lock
+		err = f.Chmod(lockFileMode)
+	} else if errors.Is(err, os.ErrExist) {
+		f, err = os.OpenFile(name, os.O_RDWR, 0)
+	}
+	if err != nil {
+		if f != nil {
+			_ = f.Close()
+		}
+		return fmt.Errorf("unable to open lock file %s: %w", name, err)
+	}
+	if err := tryLock(f, exclusive); err != nil {
+		_ = f.Close()
+		return err
+	}
+	l.files = append(l.files, f)
+	return nil
+}
+
+// release drops every lock held. Lock files are left in place, as removing them would race with other processes.
+func (l *runLock) release() {
+	for _, f := range l.files {
+		_ = f.Close()
+	}
+	l.files = nil
+}
+
+const (
+	// lockDirMode lets every account create lock files, like /tmp, while only removing its own.
+	lockDirMode = 0o777 | os.ModeSticky
+	// lockFileMode lets every account open each lock file to lock it.
+	lockFileMode = 0o666
+)
+
+// makeLockDir creates the lock directory, setting its mode explicitly, as the umask would otherwise stop other
+// accounts from creating lock files within it. A directory which already exists is left as it is.
+func makeLockDir(dir string) error {
+	if _, err := os.Stat(dir); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(dir, lockDirMode); err != nil {
+		return err
+	}
+	return os.Chmod(dir, lockDirMode)
+}
+
+// defaultLockDir is shared by all users, so runs by different accounts (e.g. cron as root and a manual run) see
+// each other's locks.
+func defaultLockDir() string {
+	return filepath.Join(os.TempDir(), "ohman-locks")
+}
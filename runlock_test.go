@@ -0,0 +1,118 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAcquireRunLock_SameRootConflicts(t *testing.T) {
+	t.Parallel()
+	lockDir := setupTestDir(t)
+	root := setupTestDir(t)
+
+	first, err := acquireRunLock(lockDir, lockRoot, []string{root})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := acquireRunLock(lockDir, lockRoot, []string{root}); err == nil || !strings.Contains(err.Error(), "already processing") {
+		t.Fatalf("expected a conflicting lock error, got: %v", err)
+	}
+
+	first.release()
+	second, err := acquireRunLock(lockDir, lockRoot, []string{root})
+	if err != nil {
+		t.Fatalf("expected the lock to be available after release: %v", err)
+	}
+	second.release()
+}
+
+func TestAcquireRunLock_NestedRootsConflict(t *testing.T) {
+	t.Parallel()
+	lockDir := setupTestDir(t)
+	root := setupTestDir(t)
+	nested := filepath.Join(root, "books")
+
+	outer, err := acquireRunLock(lockDir, lockRoot, []string{root})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := acquireRunLock(lockDir, lockRoot, []string{nested}); err == nil {
+		t.Error("expected a run over a nested tree to conflict")
+	}
+	outer.release()
+
+	inner, err := acquireRunLock(lockDir, lockRoot, []string{nested})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer inner.release()
+	if _, err := acquireRunLock(lockDir, lockRoot, []string{root}); err == nil {
+		t.Error("expected a run over an enclosing tree to conflict")
+	}
+}
+
+func TestAcquireRunLock_UnrelatedRootsAndSelfNesting(t *testing.T) {
+	t.Parallel()
+	lockDir := setupTestDir(t)
+	root := setupTestDir(t)
+
+	// a single run may include nested roots without conflicting with itself
+	a, err := acquireRunLock(lockDir, lockRoot, []string{root, filepath.Join(root, "books")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer a.release()
+
+	b, err := acquireRunLock(lockDir, lockRoot, []string{setupTestDir(t)})
+	if err != nil {
+		t.Fatalf("expected unrelated trees not to conflict: %v", err)
+	}
+	b.release()
+}
+
+func TestAcquireRunLock_Global(t *testing.T) {
+	t.Parallel()
+	lockDir := setupTestDir(t)
+
+	first, err := acquireRunLock(lockDir, lockGlobal, []string{setupTestDir(t)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer first.release()
+	if _, err := acquireRunLock(lockDir, lockGlobal, []string{setupTestDir(t)}); err == nil {
+		t.Error("expected global locks to conflict regardless of roots")
+	}
+	if l, err := acquireRunLock(lockDir, lockNone, nil); err != nil || len(l.files) != 0 {
+		t.Errorf("expected no locks to be taken, got %v, %v", l, err)
+	}
+}
+
+func TestCLI_Run_Delete_Locked(t *testing.T) {
+	t.Parallel()
+	lockDir := setupTestDir(t)
+	dir := setupTestDir(t)
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "original content")
+	createTestFile(t, filepath.Join(dir, "book (1).pdf"), "duplicate 1")
+
+	held, err := acquireRunLock(lockDir, lockRoot, []string{dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer held.release()
+
+	cli := &CLI{
+		Path:    []string{dir},
+		Delete:  true,
+		LockDir: lockDir,
+		Out:     filepath.Join(dir, "results.txt"),
+		Regex:   []string{defaultRegex},
+	}
+	if err := cli.Run(nil); err == nil {
+		t.Fatal("expected the run to be refused while another holds the lock")
+	}
+	if !fileExists(filepath.Join(dir, "book (1).pdf")) {
+		t.Error("no files should be deleted while locked")
+	}
+}
@@ -0,0 +1,42 @@
+//go:build linux || darwin
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestAcquireRunLock_Modes(t *testing.T) {
+	// not parallel, as it changes the process's umask
+	old := syscall.Umask(0o077)
+	defer syscall.Umask(old)
+
+	lockDir := filepath.Join(setupTestDir(t), "locks")
+	l, err := acquireRunLock(lockDir, lockGlobal, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer l.release()
+
+	info, err := os.Stat(lockDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := info.Mode() & (os.ModePerm | os.ModeSticky); got != lockDirMode {
+		t.Errorf("lock directory mode = %v, want %v", got, lockDirMode)
+	}
+	if got := l.files[0].Name(); !strings.HasPrefix(got, lockDir) {
+		t.Fatalf("lock file %s isn't within %s", got, lockDir)
+	}
+	info, err = l.files[0].Stat()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := info.Mode().Perm(); got != lockFileMode {
+		t.Errorf("lock file mode = %v, want %v", got, os.FileMode(lockFileMode))
+	}
+}
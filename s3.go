@@ -0,0 +1,418 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// s3Scheme prefixes paths which name objects in an S3-compatible bucket, e.g. s3://bucket/prefix.
+const s3Scheme = "s3://"
+
+// emptySHA256 is the SHA-256 of an empty body; every request ohman sends to S3 has one.
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// isS3 reports whether path names S3 objects rather than local files.
+func isS3(path string) bool {
+	return strings.HasPrefix(path, s3Scheme)
+}
+
+// splitS3 splits an S3 URL into its bucket and key, or key prefix.
+func splitS3(path string) (bucket, key string, err error) {
+	bucket, key, _ = strings.Cut(strings.TrimPrefix(path, s3Scheme), "/")
+	if bucket == "" {
+		return "", "", fmt.Errorf("invalid S3 URL %s: missing bucket", path)
+	}
+	return bucket, key, nil
+}
+
+// s3Object is an object found by listing a bucket.
+type s3Object struct {
+	Key          string    `xml:"Key"`
+	Size         int64     `xml:"Size"`
+	ETag         string    `xml:"ETag"`
+	LastModified time.Time `xml:"LastModified"`
+}
+
+// s3Info describes an object as a file, so listings can be visited like a walk of the filesystem.
+type s3Info struct{ s3Object }
+
+func (i s3Info) Name() string       { return i.Key[strings.LastIndex(i.Key, "/")+1:] }
+func (i s3Info) Size() int64        { return i.s3Object.Size }
+func (i s3Info) Mode() fs.FileMode  { return 0o644 }
+func (i s3Info) ModTime() time.Time { return i.LastModified }
+func (i s3Info) IsDir() bool        { return false }
+func (i s3Info) Sys() any           { return i.s3Object }
+
+// s3Client lists, copies, and deletes objects through the S3 API, signing requests with AWS Signature Version 4.
+type s3Client struct {
+	// endpoint is a custom endpoint such as MinIO's, addressed path-style; AWS is addressed virtual-hosted-style when nil.
+	endpoint     *url.URL
+	region       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	client       *http.Client
+	// now returns the time requests are signed at.
+	now func() time.Time
+}
+
+// newS3Client configures a client from --s3-endpoint and --s3-region, with credentials from the standard AWS
+// environment variables.
+func newS3Client(endpoint, region string) (*s3Client, error) {
+	c := &s3Client{
+		region:       region,
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		client:       &http.Client{Timeout: 5 * time.Minute},
+		now:          time.Now,
+	}
+	if c.region == "" {
+		c.region = "us-east-1"
+	}
+	if c.accessKey == "" || c.secretKey == "" {
+		return nil, errors.New("S3 credentials not found; set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	if endpoint != "" {
+		u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid --s3-endpoint %q", endpoint)
+		}
+		c.endpoint = u
+	}
+	return c, nil
+}
+
+// objectURL returns the URL of key in bucket, with key escaped as S3 expects.
+func (c *s3Client) objectURL(bucket, key string) *url.URL {
+	var u url.URL
+	escaped := "/" + s3Escape(key, false)
+	if c.endpoint != nil {
+		u = *c.endpoint
+		u.Path += "/" + bucket + "/" + key
+		u.RawPath = c.endpoint.EscapedPath() + "/" + s3Escape(bucket, false) + escaped
+	} else {
+		u = url.URL{Scheme: "https", Host: bucket + ".s3." + c.region + ".amazonaws.com", Path: "/" + key, RawPath: escaped}
+	}
+	return &u
+}
+
+// list returns every object in bucket whose key starts with prefix.
+func (c *s3Client) list(ctx context.Context, bucket, prefix string) ([]s3Object, error) {
+	var objects []s3Object
+	token := ""
+	for {
+		u := c.objectURL(bucket, "")
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		u.RawQuery = s3Query(query)
+		var page struct {
+			Contents              []s3Object `xml:"Contents"`
+			IsTruncated           bool       `xml:"IsTruncated"`
+			NextContinuationToken string     `xml:"NextContinuationToken"`
+		}
+		if err := c.do(ctx, http.MethodGet, u, nil, &page); err != nil {
+			return nil, fmt.Errorf("unable to list s3://%s/%s: %w", bucket, prefix, err)
+		}
+		objects = append(objects, page.Contents...)
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// remove deletes key from bucket.
+func (c *s3Client) remove(ctx context.Context, bucket, key string) error {
+	return c.do(ctx, http.MethodDelete, c.objectURL(bucket, key), nil, nil)
+}
+
+// copy copies the object at from to to, within bucket. S3 copies objects of up to 5 GB this way.
+func (c *s3Client) copy(ctx context.Context, bucket, from, to string) error {
+	header := http.Header{"X-Amz-Copy-Source": {"/" + s3Escape(bucket, false) + "/" + s3Escape(from, false)}}
+	// a copy can fail after S3 has started responding, in which case the error is in a 200 response
+	var result struct {
+		XMLName xml.Name
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if err := c.do(ctx, http.MethodPut, c.objectURL(bucket, to), header, &result); err != nil {
+		return err
+	}
+	if result.XMLName.Local == "Error" {
+		return fmt.Errorf("%s: %s", result.Code, result.Message)
+	}
+	return nil
+}
+
+// do sends a signed request with an empty body, decoding an XML response into out when it isn't nil.
+func (c *s3Client) do(ctx context.Context, method string, u *url.URL, header http.Header, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	c.sign(req)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= 300 {
+		var s3Err struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		if xml.Unmarshal(body, &s3Err) == nil && s3Err.Code != "" {
+			return fmt.Errorf("%s: %s", s3Err.Code, s3Err.Message)
+		}
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
+
+// sign adds an AWS Signature Version 4 authorization to req, signing its host and every header it has.
+func (c *s3Client) sign(req *http.Request) {
+	now := c.now().UTC()
+	stamp, day := now.Format("20060102T150405Z"), now.Format("20060102")
+	req.Header.Set("X-Amz-Date", stamp)
+	req.Header.Set("X-Amz-Content-Sha256", emptySHA256)
+	if c.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signed,
+		emptySHA256,
+	}, "\n")
+	scope := day + "/" + c.region + "/s3/aws4_request"
+	digest := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(digest[:])
+
+	key := []byte("AWS4" + c.secretKey)
+	for _, part := range []string{day, c.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.accessKey, scope, signed, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3Escape percent-encodes s as AWS Signature Version 4 requires: everything but unreserved characters, and slashes
+// unless they're within a query.
+func s3Escape(s string, query bool) string {
+	var sb strings.Builder
+	for _, b := range []byte(s) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '_', b == '.', b == '~':
+			sb.WriteByte(b)
+		case b == '/' && !query:
+			sb.WriteByte(b)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", b)
+		}
+	}
+	return sb.String()
+}
+
+// s3Query encodes query sorted by name, as both the request and its signature need.
+func s3Query(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var parts []string
+	for _, name := range names {
+		for _, v := range query[name] {
+			parts = append(parts, s3Escape(name, true)+"="+s3Escape(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// setupS3 prepares to scan S3 paths, if there are any. Only names can be matched in buckets, as matching by
+// content would mean downloading every object.
+func (c *CLI) setupS3() error {
+	if !slices.ContainsFunc(c.Path, isS3) {
+		return nil
+	}
+	if slices.ContainsFunc(c.Path, func(p string) bool { return !isS3(p) }) {
+		return errors.New("s3:// URLs can't be scanned along with local paths")
+	}
+	if c.Match != "" && c.Match != "name" {
+		return fmt.Errorf("--match %s needs local files; only name can be matched in S3", c.Match)
+	}
+	if c.Diff {
+		return errors.New("--diff needs local files and can't be used with S3")
+	}
+	if c.s3 != nil {
+		return nil
+	}
+	var err error
+	c.s3, err = newS3Client(c.S3Endpoint, c.S3Region)
+	return err
+}
+
+// s3FS is the objects in S3 buckets, listed through the S3 API.
+type s3FS struct {
+	client   *s3Client
+	throttle *throttle
+	// objects holds what the last walk listed, by URL.
+	objects map[string]s3Object
+}
+
+func (b *s3FS) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: errUnreadable}
+}
+
+func (b *s3FS) Stat(name string) (fs.FileInfo, error) {
+	if o, ok := b.objects[name]; ok {
+		return s3Info{o}, nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// walk lists the objects beneath root, remembering them for later stats and comparisons.
+func (b *s3FS) walk(ctx context.Context, root string, visit filepath.WalkFunc) error {
+	bucket, prefix, err := splitS3(root)
+	if err != nil {
+		return err
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		// s3://bucket/books holds books/..., not booksellers/...
+		prefix += "/"
+	}
+	if err := b.throttle.op(ctx); err != nil {
+		return err
+	}
+	objects, err := b.client.list(ctx, bucket, prefix)
+	if err != nil {
+		return err
+	}
+	for _, o := range objects {
+		// keys ending in a slash are folder placeholders made by consoles and sync tools
+		if strings.HasSuffix(o.Key, "/") {
+			continue
+		}
+		path := s3Scheme + bucket + "/" + o.Key
+		b.objects[path] = o
+		if err := visit(path, s3Info{o}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *s3FS) remove(ctx context.Context, path string) error {
+	bucket, key, err := splitS3(path)
+	if err != nil {
+		return err
+	}
+	if err := b.client.remove(ctx, bucket, key); err != nil {
+		return err
+	}
+	delete(b.objects, path)
+	return nil
+}
+
+// rename copies the object to its new key and deletes the old one, as S3 has no rename.
+func (b *s3FS) rename(ctx context.Context, from, to string) error {
+	bucket, fromKey, _ := splitS3(from)
+	toBucket, toKey, err := splitS3(to)
+	if err != nil {
+		return err
+	}
+	if toBucket != bucket {
+		return fmt.Errorf("unable to move %s to another bucket", from)
+	}
+	if err := b.client.copy(ctx, bucket, fromKey, toKey); err != nil {
+		return err
+	}
+	if o, ok := b.objects[from]; ok {
+		o.Key = toKey
+		b.objects[to] = o
+	}
+	if err := b.client.remove(ctx, bucket, fromKey); err != nil {
+		return err
+	}
+	delete(b.objects, from)
+	return nil
+}
+
+func (b *s3FS) sameContent(_ context.Context, x, y string) (bool, error) {
+	a, ok := b.objects[x]
+	if !ok {
+		return false, &fs.PathError{Op: "stat", Path: x, Err: fs.ErrNotExist}
+	}
+	o, ok := b.objects[y]
+	if !ok {
+		return false, &fs.PathError{Op: "stat", Path: y, Err: fs.ErrNotExist}
+	}
+	return sameObject(a, o)
+}
+
+// sameObject reports whether two objects have the same content, judging by their sizes and ETags. Objects uploaded
+// in parts have ETags which aren't a digest of their content, so they can only be matched by identical ETags.
+func sameObject(a, b s3Object) (bool, error) {
+	if a.Size != b.Size {
+		return false, nil
+	}
+	if a.ETag == b.ETag {
+		return true, nil
+	}
+	if strings.Contains(a.ETag, "-") || strings.Contains(b.ETag, "-") {
+		return false, errors.New("objects uploaded in parts can only be compared when their ETags match")
+	}
+	return false, nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// scriptSteps bounds the work a --script hook may do on each call, so a runaway loop can't stall a run.
+const scriptSteps = 10_000_000
+
+// script holds the hooks of a --script file, written in Starlark. Starlark has no access to files, the network, or
+// the clock, so scripts can only decide, from what ohman tells them, and print to stderr.
+type script struct {
+	filename string
+	// onGroup is called with each group before it's acted on: on_group(group).
+	onGroup starlark.Callable
+	// postDelete is called after each file is deleted: post_delete(path).
+	postDelete starlark.Callable
+}
+
+// scriptDecision is what on_group chose for a group.
+type scriptDecision struct {
+	// leave is set when the group should be left alone.
+	leave bool
+	// keep is the file to keep, deleting the rest, or "" for ohman to decide.
+	keep string
+}
+
+// loadScript runs the script at filename, returning its hooks, or nil when there's no script.
+func loadScript(filename string) (*script, error) {
+	if filename == "" {
+		return nil, nil
+	}
+	s := &script{filename: filename}
+	globals, err := starlark.ExecFile(s.thread(), filename, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load --script %s: %w", filename, err)
+	}
+	for name, hook := range map[string]*starlark.Callable{"on_group": &s.onGroup, "post_delete": &s.postDelete} {
+		v, ok := globals[name]
+		if !ok {
+			continue
+		}
+		fn, ok := v.(starlark.Callable)
+		if !ok {
+			return nil, fmt.Errorf("--script %s: %s must be a function, not %s", filename, name, v.Type())
+		}
+		*hook = fn
+	}
+	if s.onGroup == nil && s.postDelete == nil {
+		return nil, fmt.Errorf("--script %s defines neither on_group nor post_delete", filename)
+	}
+	return s, nil
+}
+
+// thread returns a thread for one call into the script, printing to stderr.
+func (s *script) thread() *starlark.Thread {
+	t := &starlark.Thread{
+		Name:  s.filename,
+		Print: func(_ *starlark.Thread, msg string) { fmt.Fprintf(os.Stderr, "%s: %s\n", s.filename, msg) },
+	}
+	t.SetMaxExecutionSteps(scriptSteps)
+	return t
+}
+
+// decide calls on_group with g, which returns None for ohman to decide, False to leave the group alone, or the path
+// of the one file to keep. The group is passed as a struct with the original's path, the duplicates' paths, and its
+// files: the original's, then each duplicate's, with their path, size, modification time in Unix seconds, and whether
+// their content differs from the original.
+func (s *script) decide(g group, stat func(string) (os.FileInfo, error)) (scriptDecision, error) {
+	if s == nil || s.onGroup == nil {
+		return scriptDecision{}, nil
+	}
+	paths := append([]string{g.Original}, g.Duplicates...)
+	files := make([]starlark.Value, 0, len(paths))
+	duplicates := make([]starlark.Value, 0, len(g.Duplicates))
+	for _, p := range paths {
+		info, err := stat(p)
+		if err != nil {
+			return scriptDecision{}, err
+		}
+		files = append(files, starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+			"path":     starlark.String(p),
+			"size":     starlark.MakeInt64(info.Size()),
+			"modified": starlark.MakeInt64(info.ModTime().Unix()),
+			"differs":  starlark.Bool(slices.Contains(g.Mismatched, p)),
+		}))
+	}
+	for _, d := range g.Duplicates {
+		duplicates = append(duplicates, starlark.String(d))
+	}
+	arg := starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"original":   starlark.String(g.Original),
+		"duplicates": starlark.NewList(duplicates),
+		"files":      starlark.NewList(files),
+	})
+
+	v, err := starlark.Call(s.thread(), s.onGroup, starlark.Tuple{arg}, nil)
+	if err != nil {
+		return scriptDecision{}, fmt.Errorf("on_group: %w", err)
+	}
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return scriptDecision{}, nil
+	case starlark.Bool:
+		if v {
+			return scriptDecision{}, nil
+		}
+		return scriptDecision{leave: true}, nil
+	case starlark.String:
+		keep := string(v)
+		if keep != g.Original && !slices.Contains(g.Duplicates, keep) {
+			return scriptDecision{}, fmt.Errorf("on_group chose to keep %s, which isn't in the group", keep)
+		}
+		return scriptDecision{keep: keep}, nil
+	}
+	return scriptDecision{}, fmt.Errorf("on_group returned %s; it should return None, False, or the path of the file to keep", v.Type())
+}
+
+// deleted calls post_delete with path, warning when it fails.
+func (s *script) deleted(path string) {
+	if s == nil || s.postDelete == nil {
+		return
+	}
+	if _, err := starlark.Call(s.thread(), s.postDelete, starlark.Tuple{starlark.String(path)}, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: --script %s: post_delete(%s): %v\n", s.filename, path, err)
+	}
+}
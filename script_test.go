@@ -0,0 +1,122 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func writeScript(t *testing.T, src string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.star")
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+	return path
+}
+
+func TestCLI_Run_Script(t *testing.T) {
+	t.Parallel()
+	memory := fstest.MapFS{
+		"media/book.pdf":      {Data: []byte("content")},
+		"media/book (1).pdf":  {Data: []byte("content")},
+		"media/book (2).pdf":  {Data: []byte("content")},
+		"media/keep.pdf":      {Data: []byte("content")},
+		"media/keep (1).pdf":  {Data: []byte("content")},
+		"media/notes.pdf":     {Data: []byte("notes")},
+		"media/notes (1).pdf": {Data: []byte("notes")},
+	}
+	script := writeScript(t, `
+deleted = []
+
+def on_group(group):
+    if group.original.endswith("keep.pdf"):
+        return False
+    if group.original.endswith("book.pdf"):
+        return group.duplicates[-1]
+    return None
+
+def post_delete(path):
+    print("deleted", path)
+`)
+	cli := &CLI{
+		Path:   []string{"/media"},
+		Delete: true,
+		Script: script,
+		Out:    filepath.Join(t.TempDir(), "results.txt"),
+		Regex:  []string{defaultRegex},
+		memory: memory,
+	}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var names []string
+	for name := range memory {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	want := []string{"media/book (2).pdf", "media/keep (1).pdf", "media/keep.pdf", "media/notes.pdf"}
+	if !slices.Equal(names, want) {
+		t.Errorf("files = %q, want %q", names, want)
+	}
+}
+
+func TestLoadScript_Errors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		src, want string
+	}{
+		{"x = 1\n", "defines neither on_group nor post_delete"},
+		{"on_group = 1\n", "on_group must be a function"},
+		{"def on_group(:\n", "failed to load"},
+		{"open('/etc/passwd')\n", "undefined: open"},
+	}
+	for _, tt := range tests {
+		if _, err := loadScript(writeScript(t, tt.src)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("loadScript(%q) error = %v, want it to contain %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestScript_Decide(t *testing.T) {
+	t.Parallel()
+	memory := fstest.MapFS{
+		"media/book.pdf":     {Data: []byte("content")},
+		"media/book (1).pdf": {Data: []byte("content, but longer")},
+	}
+	m := &memFS{files: memory}
+	g := group{Original: "/media/book.pdf", Duplicates: []string{"/media/book (1).pdf"}}
+
+	tests := []struct {
+		body    string
+		want    scriptDecision
+		wantErr string
+	}{
+		{"return None", scriptDecision{}, ""},
+		{"return False", scriptDecision{leave: true}, ""},
+		{"return max(group.files, key=lambda f: f.size).path", scriptDecision{keep: "/media/book (1).pdf"}, ""},
+		{`return "/elsewhere.pdf"`, scriptDecision{}, "isn't in the group"},
+		{"return 1", scriptDecision{}, "should return None, False, or the path"},
+		{"for i in range(1000000000):\n        pass", scriptDecision{}, "too many steps"},
+	}
+	for _, tt := range tests {
+		s, err := loadScript(writeScript(t, "def on_group(group):\n    "+tt.body+"\n"))
+		if err != nil {
+			t.Fatalf("loadScript() error = %v", err)
+		}
+		got, err := s.decide(g, m.Stat)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: error = %v, want it to contain %q", tt.body, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: decide() = %+v, %v; want %+v", tt.body, got, err, tt.want)
+		}
+	}
+}
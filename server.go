@@ -0,0 +1,478 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	jobScanning  = "scanning"
+	jobScanned   = "scanned"
+	jobExecuting = "executing"
+	jobCompleted = "completed"
+	jobFailed    = "failed"
+)
+
+// ServeCmd exposes scanning and deletion over a REST API, and optionally gRPC.
+type ServeCmd struct {
+	Listen     string `name:"listen" help:"Address to listen on." default:"127.0.0.1:8080"`
+	GRPCListen string `name:"grpc-listen" help:"Also serve the gRPC API on this address (e.g. 127.0.0.1:9090)."`
+	Token      string `name:"token" env:"OHMAN_TOKEN" help:"Require this bearer token on every request. Strongly recommended when listening beyond localhost."`
+
+	server *server
+}
+
+func (s *ServeCmd) Run(kctx *Context) error {
+	ctx := kctx.context()
+	s.server = newServer(ctx, s.Token)
+
+	listener, err := net.Listen("tcp", s.Listen)
+	if err != nil {
+		return fmt.Errorf("unable to listen on %s: %w", s.Listen, err)
+	}
+	srv := &http.Server{Handler: s.server.handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	if s.GRPCListen != "" {
+		grpcListener, err := net.Listen("tcp", s.GRPCListen)
+		if err != nil {
+			_ = listener.Close()
+			return fmt.Errorf("unable to listen on %s: %w", s.GRPCListen, err)
+		}
+		grpcServer := newGRPCServer(s.server, s.Token)
+		go func() {
+			<-ctx.Done()
+			// streams end once their job's phase finishes, so give them the same grace period as HTTP
+			timer := time.AfterFunc(5*time.Second, grpcServer.Stop)
+			defer timer.Stop()
+			grpcServer.GracefulStop()
+		}()
+		go func() { _ = grpcServer.Serve(grpcListener) }()
+		fmt.Fprintf(os.Stderr, "Serving gRPC on %s\n", grpcListener.Addr())
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(os.Stderr, "Listening on http://%s\n", listener.Addr())
+	if err := srv.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	if cause := context.Cause(ctx); !errors.Is(cause, errInterrupted) {
+		return cause
+	}
+	return nil
+}
+
+// server tracks the scans started through the API. Scans run in the background, and their deletion plans are only
+// executed once explicitly approved.
+type server struct {
+	ctx     context.Context
+	token   string
+	metrics *metrics
+
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+// job is a single scan and, once approved, the execution of its deletion plan.
+type job struct {
+	mu      sync.Mutex
+	id      string
+	status  string
+	err     error
+	created time.Time
+	mode    string
+	cli     *CLI
+	groups  []group
+	events  *feed
+}
+
+// scanRequest is the body accepted by POST /scans.
+type scanRequest struct {
+	Paths []string `json:"paths"`
+	Regex string   `json:"regex,omitempty"`
+	// Mode selects what executing the plan does: "delete" (the default) keeps originals and deletes duplicates,
+	// "inverse" keeps the newest file, and "inverse-and-rename" keeps the newest under the original's name.
+	Mode string `json:"mode,omitempty"`
+}
+
+// jobResponse describes a job's state.
+type jobResponse struct {
+	ID       string    `json:"id"`
+	Status   string    `json:"status"`
+	Mode     string    `json:"mode"`
+	Paths    []string  `json:"paths"`
+	Created  time.Time `json:"created"`
+	Error    string    `json:"error,omitempty"`
+	Progress struct {
+		Scanned  int64 `json:"scanned"`
+		Groups   int   `json:"groups"`
+		Actions  int64 `json:"actions"`
+		Failures int64 `json:"failures"`
+	} `json:"progress"`
+	Groups []group `json:"groups,omitempty"`
+}
+
+func newServer(ctx context.Context, token string) *server {
+	return &server{ctx: ctx, token: token, metrics: newMetrics(), jobs: make(map[string]*job)}
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /scans", s.startScan)
+	mux.HandleFunc("GET /scans", s.listScans)
+	mux.HandleFunc("GET /scans/{id}", s.getScan)
+	mux.HandleFunc("GET /scans/{id}/results", s.getResults)
+	mux.HandleFunc("GET /scans/{id}/events", s.streamEvents)
+	mux.HandleFunc("POST /scans/{id}/execute", s.execute)
+	mux.Handle("GET /metrics", s.metrics)
+	return s.authenticate(mux)
+}
+
+func (s *server) authenticate(next http.Handler) http.Handler {
+	if s.token == "" {
+		return next
+	}
+	want := []byte("Bearer " + s.token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *server) startScan(w http.ResponseWriter, r *http.Request) {
+	var req scanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	j, err := s.start(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, j.response(false))
+}
+
+// start validates req and begins scanning in the background.
+func (s *server) start(req scanRequest) (*job, error) {
+	if len(req.Paths) == 0 {
+		return nil, errors.New("at least one path must be specified")
+	}
+	if req.Regex == "" {
+		req.Regex = defaultPattern
+	}
+	re, err := regexp.Compile(req.Regex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex: %w", err)
+	}
+	if err := checkCopyGroups(re); err != nil {
+		return nil, err
+	}
+	if req.Mode == "" {
+		req.Mode = "delete"
+	}
+	protected, err := newProtector(nil)
+	if err != nil {
+		return nil, err
+	}
+	events := &feed{}
+	c := &CLI{Path: req.Paths, Regex: []string{req.Regex}, SkipErrors: true, DefaultExcludes: true, progress: &progress{observe: events.publish}, protected: protected}
+	switch req.Mode {
+	case "delete":
+	case "inverse":
+		c.Inverse = true
+	case "inverse-and-rename":
+		c.InverseAndRename = true
+	default:
+		return nil, fmt.Errorf("invalid mode %q", req.Mode)
+	}
+
+	j := &job{id: newJobID(), status: jobScanning, created: time.Now(), mode: req.Mode, cli: c, events: events}
+	s.mu.Lock()
+	s.jobs[j.id] = j
+	s.mu.Unlock()
+
+	go func() {
+		found, err := (&Scanner{cli: c, patterns: copyPatterns{{re: re}}}).Scan(s.ctx)
+		var groups []group
+		if err == nil {
+			// only listed until the plan is approved
+			groups, _ = (&Executor{cli: c, dryRun: true}).Execute(s.ctx, found)
+		}
+		s.metrics.recordScan(time.Since(j.created), c.progress.filesScanned(), groups, err)
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		j.groups, j.err = groups, err
+		j.status = jobScanned
+		if err != nil {
+			j.status = jobFailed
+		}
+	}()
+	return j, nil
+}
+
+func (s *server) listScans(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	jobs := make([]*job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j)
+	}
+	s.mu.Unlock()
+	slices.SortFunc(jobs, func(a, b *job) int { return a.created.Compare(b.created) })
+
+	resp := make([]jobResponse, len(jobs))
+	for i, j := range jobs {
+		resp[i] = j.response(false)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *server) getScan(w http.ResponseWriter, r *http.Request) {
+	if j := s.job(w, r); j != nil {
+		writeJSON(w, http.StatusOK, j.response(true))
+	}
+}
+
+func (s *server) getResults(w http.ResponseWriter, r *http.Request) {
+	j := s.job(w, r)
+	if j == nil {
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "text"
+	}
+	if !slices.Contains([]string{"text", "fdupes", "markdown"}, format) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid format %q", format))
+		return
+	}
+	j.mu.Lock()
+	output := render(format, j.groups)
+	j.mu.Unlock()
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = fmt.Fprintln(w, output)
+}
+
+// streamEvents writes a job's events as newline-delimited JSON as they happen, until it has completed or failed or the
+// client goes away, so a scan can be followed through its approval and execution. Only events from after the request
+// are written.
+func (s *server) streamEvents(w http.ResponseWriter, r *http.Request) {
+	j := s.job(w, r)
+	if j == nil {
+		return
+	}
+	events, unsubscribe := j.events.subscribe()
+	defer unsubscribe()
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+	_ = rc.Flush()
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case e := <-events:
+			if enc.Encode(e) != nil {
+				return
+			}
+			if len(events) == 0 {
+				_ = rc.Flush()
+			}
+		case <-ticker.C:
+			if j.finished() {
+				// anything published before the job finished has been buffered
+				for len(events) > 0 {
+					_ = enc.Encode(<-events)
+				}
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// execute approves a scanned job's plan and performs it in the background.
+func (s *server) execute(w http.ResponseWriter, r *http.Request) {
+	j := s.job(w, r)
+	if j == nil {
+		return
+	}
+	if err := s.executeJob(j); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, j.response(false))
+}
+
+// errNotScanned is returned when executing a job whose scan hasn't finished, or whose plan was already executed.
+var errNotScanned = errors.New("only scanned jobs can be executed")
+
+// executeJob performs j's plan in the background.
+func (s *server) executeJob(j *job) error {
+	j.mu.Lock()
+	if j.status != jobScanned {
+		status := j.status
+		j.mu.Unlock()
+		return fmt.Errorf("%w; this job is %s", errNotScanned, status)
+	}
+	j.cli.Delete = true
+	if err := j.cli.checkRoots(); err != nil {
+		j.cli.Delete = false
+		j.mu.Unlock()
+		return err
+	}
+	lock, err := acquireRunLock(j.cli.LockDir, j.cli.Lock, j.cli.Path)
+	if err != nil {
+		j.cli.Delete = false
+		j.mu.Unlock()
+		return err
+	}
+	j.status = jobExecuting
+	groups := j.groups
+	j.mu.Unlock()
+
+	go func() {
+		defer lock.release()
+		var done []group
+		for _, g := range groups {
+			if s.ctx.Err() != nil {
+				break
+			}
+			if len(g.Mismatched) > 0 {
+				done = append(done, g)
+				continue
+			}
+			g.Actions = nil
+			_ = j.cli.process(s.ctx, &g)
+			done = append(done, g)
+		}
+		s.metrics.recordActions(done)
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		j.groups = done
+		j.status = jobCompleted
+		if s.ctx.Err() != nil {
+			j.status, j.err = jobFailed, context.Cause(s.ctx)
+		}
+	}()
+	return nil
+}
+
+// finished reports whether j has completed or failed.
+func (j *job) finished() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status == jobCompleted || j.status == jobFailed
+}
+
+// lookup returns the job with the given id, if any.
+func (s *server) lookup(id string) (*job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	return j, ok
+}
+
+// job finds the job named in the request path, writing a 404 and returning nil if there's no such job.
+func (s *server) job(w http.ResponseWriter, r *http.Request) *job {
+	j, ok := s.lookup(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no scan with id %q", r.PathValue("id")))
+		return nil
+	}
+	return j
+}
+
+func (j *job) response(withGroups bool) jobResponse {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	resp := jobResponse{ID: j.id, Status: j.status, Mode: j.mode, Paths: j.cli.Path, Created: j.created}
+	if j.err != nil {
+		resp.Error = j.err.Error()
+	}
+	resp.Progress.Scanned = j.cli.progress.scanned.Load()
+	resp.Progress.Groups = len(j.groups)
+	resp.Progress.Actions = j.cli.progress.actions.Load()
+	resp.Progress.Failures = j.cli.progress.failures.Load()
+	if withGroups {
+		resp.Groups = j.groups
+	}
+	return resp
+}
+
+// feedBuffer is how many events a client following a job may fall behind by before it misses some.
+const feedBuffer = 1024
+
+// feed fans a job's events out to the clients following them. A client which falls too far behind misses events,
+// rather than holding up the job.
+type feed struct {
+	mu   sync.Mutex
+	subs map[chan event]struct{}
+}
+
+func (f *feed) publish(e event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// subscribe returns the events published from now on, until unsubscribe is called.
+func (f *feed) subscribe() (events <-chan event, unsubscribe func()) {
+	ch := make(chan event, feedBuffer)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.subs == nil {
+		f.subs = make(map[chan event]struct{})
+	}
+	f.subs[ch] = struct{}{}
+	return ch, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.subs, ch)
+	}
+}
+
+func newJobID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": strings.TrimSpace(err.Error())})
+}
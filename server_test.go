@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T, token string) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(newServer(context.Background(), token).handler())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func doJSON(t *testing.T, method, url, token, body string, want int, into any) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != want {
+		b, _ := io.ReadAll(resp.Body)
+		t.Fatalf("%s %s: expected status %d, got %d: %s", method, url, want, resp.StatusCode, b)
+	}
+	if into != nil {
+		if err := json.NewDecoder(resp.Body).Decode(into); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+	}
+}
+
+// waitForStatus polls a job until it reaches status.
+func waitForStatus(t *testing.T, url, status string) jobResponse {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var job jobResponse
+		doJSON(t, http.MethodGet, url, "", "", http.StatusOK, &job)
+		if job.Status == status {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job never reached status %q, last: %+v", status, job)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServer_ScanAndExecute(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "original content")
+	createTestFile(t, filepath.Join(dir, "book (1).pdf"), "original content")
+
+	ts := newTestServer(t, "")
+
+	var started jobResponse
+	body, _ := json.Marshal(scanRequest{Paths: []string{dir}})
+	doJSON(t, http.MethodPost, ts.URL+"/scans", "", string(body), http.StatusAccepted, &started)
+	if started.ID == "" {
+		t.Fatal("expected a job id")
+	}
+
+	scanned := waitForStatus(t, ts.URL+"/scans/"+started.ID, jobScanned)
+	if len(scanned.Groups) != 1 || scanned.Progress.Scanned != 2 {
+		t.Fatalf("unexpected scan result: %+v", scanned)
+	}
+	if !fileExists(filepath.Join(dir, "book (1).pdf")) {
+		t.Fatal("scanning must not delete anything")
+	}
+
+	doJSON(t, http.MethodPost, ts.URL+"/scans/"+started.ID+"/execute", "", "", http.StatusAccepted, nil)
+	completed := waitForStatus(t, ts.URL+"/scans/"+started.ID, jobCompleted)
+	if completed.Progress.Actions != 1 || completed.Progress.Failures != 0 {
+		t.Errorf("unexpected progress: %+v", completed.Progress)
+	}
+	if fileExists(filepath.Join(dir, "book (1).pdf")) {
+		t.Error("duplicate should be deleted once the plan is executed")
+	}
+
+	// a completed plan can't be executed again
+	doJSON(t, http.MethodPost, ts.URL+"/scans/"+started.ID+"/execute", "", "", http.StatusConflict, nil)
+
+	resp, err := http.Get(ts.URL + "/scans/" + started.ID + "/results?format=markdown")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	b, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(b), "| delete | ok |") {
+		t.Errorf("expected markdown results, got: %s", b)
+	}
+}
+
+func TestServer_Validation(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t, "")
+
+	doJSON(t, http.MethodPost, ts.URL+"/scans", "", `{"paths": []}`, http.StatusBadRequest, nil)
+	doJSON(t, http.MethodPost, ts.URL+"/scans", "", `{"paths": ["/tmp"], "regex": "[invalid"}`, http.StatusBadRequest, nil)
+	doJSON(t, http.MethodPost, ts.URL+"/scans", "", `{"paths": ["/tmp"], "mode": "shred"}`, http.StatusBadRequest, nil)
+	doJSON(t, http.MethodGet, ts.URL+"/scans/missing", "", "", http.StatusNotFound, nil)
+}
+
+func TestServer_Token(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t, "secret")
+
+	doJSON(t, http.MethodGet, ts.URL+"/scans", "", "", http.StatusUnauthorized, nil)
+	doJSON(t, http.MethodGet, ts.URL+"/scans", "wrong", "", http.StatusUnauthorized, nil)
+	var jobs []jobResponse
+	doJSON(t, http.MethodGet, ts.URL+"/scans", "secret", "", http.StatusOK, &jobs)
+}
+
+func TestServer_Events(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "original content")
+	createTestFile(t, filepath.Join(dir, "book (1).pdf"), "original content")
+
+	ts := newTestServer(t, "")
+	var started jobResponse
+	body, _ := json.Marshal(scanRequest{Paths: []string{dir}})
+	doJSON(t, http.MethodPost, ts.URL+"/scans", "", string(body), http.StatusAccepted, &started)
+	waitForStatus(t, ts.URL+"/scans/"+started.ID, jobScanned)
+
+	// the response's headers are written once the client is following the job's events
+	resp, err := http.Get(ts.URL + "/scans/" + started.ID + "/events")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if got := resp.Header.Get("Content-Type"); got != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	doJSON(t, http.MethodPost, ts.URL+"/scans/"+started.ID+"/execute", "", "", http.StatusAccepted, nil)
+
+	var events []event
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var e event
+		if err := dec.Decode(&e); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("failed to decode event: %v", err)
+		}
+		events = append(events, e)
+	}
+	want := []event{{Type: eventFileDeleted, Path: filepath.Join(dir, "book (1).pdf")}}
+	if len(events) != 1 || events[0].Type != want[0].Type || events[0].Path != want[0].Path {
+		t.Errorf("events = %+v, want %+v", events, want)
+	}
+}
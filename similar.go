@@ -0,0 +1,41 @@
+package main
+
+// groupSimilar clusters the items 0..n-1, joining any two for which similar returns true, and returns the clusters
+// with more than one item. Similarity is treated as transitive, so a chain of similar items forms a single cluster.
+func groupSimilar(n int, similar func(i, j int) bool) [][]int {
+	parent := make([]int, n)
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			if find(i) != find(j) && similar(i, j) {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+
+	members := make(map[int][]int)
+	var roots []int
+	for i := 0; i < n; i++ {
+		root := find(i)
+		if _, ok := members[root]; !ok {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], i)
+	}
+	var clusters [][]int
+	for _, root := range roots {
+		if len(members[root]) > 1 {
+			clusters = append(clusters, members[root])
+		}
+	}
+	return clusters
+}
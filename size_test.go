@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		in      string
+		want    byteSize
+		wantErr bool
+	}{
+		{in: "1024", want: 1024},
+		{in: "10MB", want: 10 * 1000 * 1000},
+		{in: "10 mb", want: 10 * 1000 * 1000},
+		{in: "512KiB", want: 512 * 1024},
+		{in: "1.5GiB", want: 3 << 29},
+		{in: "12XB", wantErr: true},
+		{in: "MB", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseSize(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSize(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseSize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestByteSize_String(t *testing.T) {
+	t.Parallel()
+	tests := map[byteSize]string{
+		512:           "512 B",
+		2048:          "2.0 KiB",
+		5 * (1 << 20): "5.0 MiB",
+	}
+	for in, want := range tests {
+		if got := in.String(); got != want {
+			t.Errorf("byteSize(%d).String() = %q, want %q", int64(in), got, want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// snapshotDirs name the directories which hold read-only snapshots: NetApp's and Synology's, snapper's for Btrfs, and
+// ZFS's .zfs, whose snapshot directory holds them.
+var snapshotDirs = []string{".snapshot", "#snapshot", ".snapshots", ".zfs"}
+
+// snapshot reports whether the directory at path, described by info, holds or is a read-only snapshot, so is left out
+// of the scan unless --include-snapshots is set: nothing in a snapshot can be deleted.
+func (c *CLI) snapshot(path string, info os.FileInfo) bool {
+	if c.IncludeSnapshots || info == nil || !info.IsDir() {
+		return false
+	}
+	name := filepath.Base(path)
+	if slices.ContainsFunc(snapshotDirs, func(dir string) bool { return strings.EqualFold(name, dir) }) {
+		return true
+	}
+	_, local := c.files().(localFS)
+	return local && readOnlySubvolume(path, info)
+}
+
+// warnSnapshots warns about each scanned path within a read-only snapshot, where every delete and rename will fail.
+func (c *CLI) warnSnapshots() {
+	if _, local := c.files().(localFS); !local {
+		return
+	}
+	for _, p := range c.Path {
+		if inSnapshot(p) {
+			fmt.Fprintln(os.Stderr, tr("Warning: %s is within a read-only snapshot, where nothing can be deleted or renamed", p))
+		}
+	}
+}
+
+// inSnapshot reports whether the local path is within a read-only snapshot, by its name or, for Btrfs, its subvolume.
+func inSnapshot(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	for dir := abs; ; dir = filepath.Dir(dir) {
+		name := filepath.Base(dir)
+		if slices.ContainsFunc(snapshotDirs, func(snapshots string) bool { return strings.EqualFold(name, snapshots) }) {
+			return true
+		}
+		if info, err := os.Lstat(nativePath(dir)); err == nil && readOnlySubvolume(dir, info) {
+			return true
+		}
+		if filepath.Dir(dir) == dir {
+			return false
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// btrfsFirstFreeObjectID is the inode number of the root directory of every Btrfs subvolume.
+	btrfsFirstFreeObjectID = 256
+	// btrfsIocSubvolGetflags is BTRFS_IOC_SUBVOL_GETFLAGS, _IOR(0x94, 25, __u64).
+	btrfsIocSubvolGetflags = 0x80089419
+	btrfsSubvolRdonly      = 1 << 1
+)
+
+// readOnlySubvolume reports whether the directory at path, described by info, is a read-only Btrfs subvolume, as
+// snapshots usually are.
+func readOnlySubvolume(path string, info os.FileInfo) bool {
+	if id, ok := fileID(info); !ok || id[1] != btrfsFirstFreeObjectID {
+		return false
+	}
+	f, err := os.Open(nativePath(path))
+	if err != nil {
+		return false
+	}
+	defer func() { _ = f.Close() }()
+	var flags uint64
+	// fails with ENOTTY on filesystems other than Btrfs
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), btrfsIocSubvolGetflags, uintptr(unsafe.Pointer(&flags)))
+	return errno == 0 && flags&btrfsSubvolRdonly != 0
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "os"
+
+// readOnlySubvolume reports false, as Btrfs is only found on Linux.
+func readOnlySubvolume(string, os.FileInfo) bool {
+	return false
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInSnapshot(t *testing.T) {
+	t.Parallel()
+	for path, want := range map[string]bool{
+		"/tank/.zfs/snapshot/daily/media": true,
+		"/volume1/#snapshot/media":        true,
+		"/vol0/.snapshot/hourly.0/media":  true,
+		"/ohman-test/media/books":         false,
+	} {
+		if got := inSnapshot(path); got != want {
+			t.Errorf("inSnapshot(%q) = %t, want %t", path, got, want)
+		}
+	}
+}
+
+func TestCLI_Run_Snapshots(t *testing.T) {
+	t.Parallel()
+	for _, include := range []bool{false, true} {
+		dir := setupTestDir(t)
+		if err := os.MkdirAll(filepath.Join(dir, "#snapshot", "daily"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"book.pdf", "book (1).pdf", "#snapshot/daily/book.pdf", "#snapshot/daily/book (1).pdf"} {
+			createTestFile(t, filepath.Join(dir, name), "content")
+		}
+
+		cli := &CLI{Path: []string{dir}, Delete: true, IncludeSnapshots: include, Out: filepath.Join(t.TempDir(), "results.txt"), Regex: []string{defaultRegex}}
+		if err := cli.Run(nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if fileExists(filepath.Join(dir, "book (1).pdf")) {
+			t.Error("book (1).pdf should be deleted")
+		}
+		if exists := fileExists(filepath.Join(dir, "#snapshot", "daily", "book (1).pdf")); exists == include {
+			t.Errorf("with --include-snapshots=%t, the snapshot's copy exists = %t", include, exists)
+		}
+	}
+}
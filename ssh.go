@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Shell scripts run on the --remote host, each given its paths as arguments. Only POSIX sh and common tools are
+// needed there, so ohman doesn't have to be installed.
+const (
+	// remoteListScript prints the size, modification time, and path of every file beneath $1, NUL-terminated. GNU
+	// find can print these itself; elsewhere (e.g. BSD and macOS), stat is used.
+	remoteListScript = `if find "$1" -maxdepth 0 -printf '' >/dev/null 2>&1; then
+	find "$1" -type f -printf '%s\t%T@\t%p\0'
+else
+	find "$1" -type f -exec stat -f '%z%t%m%t%N' {} + | tr '\n' '\0'
+fi`
+	// remoteHashScript prints the SHA-256 of each argument, in order.
+	remoteHashScript   = `if command -v sha256sum >/dev/null 2>&1; then sha256sum -- "$@"; else shasum -a 256 -- "$@"; fi`
+	remoteRemoveScript = `rm -- "$1"`
+	remoteRenameScript = `mv -f -- "$1" "$2"`
+)
+
+// remoteFile is a file found by listing the --remote host.
+type remoteFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+func (f remoteFile) Name() string       { return f.path[strings.LastIndex(f.path, "/")+1:] }
+func (f remoteFile) Size() int64        { return f.size }
+func (f remoteFile) Mode() fs.FileMode  { return 0o644 }
+func (f remoteFile) ModTime() time.Time { return f.modTime }
+func (f remoteFile) IsDir() bool        { return false }
+func (f remoteFile) Sys() any           { return nil }
+
+// splitRemote splits user@host:/path into its ssh destination and path. The path defaults to the home directory.
+func splitRemote(remote string) (target, path string, err error) {
+	target, path, ok := strings.Cut(remote, ":")
+	if !ok || target == "" {
+		return "", "", fmt.Errorf("invalid --remote %q: expected user@host:/path", remote)
+	}
+	if path == "" {
+		path = "."
+	}
+	return target, path, nil
+}
+
+// setupRemote prepares to scan the --remote host, if one is set, in place of local paths. Like S3, only names can
+// be matched remotely, as other matches would mean transferring every file.
+func (c *CLI) setupRemote() error {
+	if c.Remote == "" {
+		return nil
+	}
+	// paths are only set here, and a daemon runs this again each time
+	if len(c.Path) > 0 && !slices.Equal(c.Path, []string{c.Remote}) {
+		return errors.New("--remote can't be combined with local paths")
+	}
+	target, path, err := splitRemote(c.Remote)
+	if err != nil {
+		return err
+	}
+	if c.Match != "" && c.Match != "name" {
+		return fmt.Errorf("--match %s needs local files; only name can be matched with --remote", c.Match)
+	}
+	if c.Diff {
+		return errors.New("--diff needs local files and can't be used with --remote")
+	}
+	c.Remote = target + ":" + path
+	c.Path = []string{c.Remote}
+	return nil
+}
+
+// runRemote runs script on the --remote host with args, returning what it prints.
+func (c *CLI) runRemote(ctx context.Context, script string, args ...string) ([]byte, error) {
+	target, _, _ := strings.Cut(c.Remote, ":")
+	if c.shell != nil {
+		return c.shell(ctx, target, script, args...)
+	}
+	return c.ssh(ctx, target, script, args...)
+}
+
+// ssh runs script with sh on target, through the --ssh command.
+func (c *CLI) ssh(ctx context.Context, target, script string, args ...string) ([]byte, error) {
+	command := strings.Fields(c.SSH)
+	if len(command) == 0 {
+		command = []string{"ssh"}
+	}
+	// ssh passes the command to the remote shell as a single string, so everything is quoted
+	remote := "sh -c " + shellQuote(script) + " sh"
+	for _, arg := range args {
+		remote += " " + shellQuote(arg)
+	}
+	var stderr strings.Builder
+	cmd := exec.CommandContext(ctx, command[0], append(command[1:], "--", target, remote)...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return out, fmt.Errorf("%w: %s", err, msg)
+		}
+		return out, err
+	}
+	return out, nil
+}
+
+// shellQuote single-quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// sshFS is the files on the --remote host, listed and changed by scripts run there.
+type sshFS struct {
+	run      func(ctx context.Context, script string, args ...string) ([]byte, error)
+	throttle *throttle
+	// files holds what the last walk listed, by path.
+	files map[string]remoteFile
+}
+
+// hostPath returns the path on the host of a file found there.
+func hostPath(file string) string {
+	_, path, _ := strings.Cut(file, ":")
+	return path
+}
+
+func (r *sshFS) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: errUnreadable}
+}
+
+func (r *sshFS) Stat(name string) (fs.FileInfo, error) {
+	if f, ok := r.files[name]; ok {
+		return f, nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// walk lists the files beneath root, remembering them for later stats.
+func (r *sshFS) walk(ctx context.Context, root string, visit filepath.WalkFunc) error {
+	target, dir, _ := strings.Cut(root, ":")
+	if err := r.throttle.op(ctx); err != nil {
+		return err
+	}
+	out, err := r.run(ctx, remoteListScript, dir)
+	if err != nil {
+		// find reports entries it can't read but lists the rest; a root which can't be listed at all is always fatal
+		if len(out) == 0 {
+			return fmt.Errorf("unable to list %s: %w", root, err)
+		}
+		if err := visit(strings.TrimSuffix(root, "/")+"/...", nil, err); err != nil {
+			return err
+		}
+	}
+
+	for _, record := range bytes.Split(out, []byte{0}) {
+		if len(record) == 0 {
+			continue
+		}
+		fields := strings.SplitN(string(record), "\t", 3)
+		if len(fields) != 3 {
+			return fmt.Errorf("unexpected listing from %s: %q", root, record)
+		}
+		size, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("unexpected listing from %s: %q", root, record)
+		}
+		seconds, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return fmt.Errorf("unexpected listing from %s: %q", root, record)
+		}
+		f := remoteFile{path: fields[2], size: size, modTime: time.Unix(0, int64(seconds*float64(time.Second)))}
+		path := target + ":" + f.path
+		r.files[path] = f
+		if err := visit(path, f, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *sshFS) remove(ctx context.Context, path string) error {
+	_, err := r.run(ctx, remoteRemoveScript, hostPath(path))
+	return err
+}
+
+func (r *sshFS) rename(ctx context.Context, from, to string) error {
+	_, err := r.run(ctx, remoteRenameScript, hostPath(from), hostPath(to))
+	return err
+}
+
+// sameContent compares files by their SHA-256, computed on the host so their content is never transferred.
+func (r *sshFS) sameContent(ctx context.Context, a, b string) (bool, error) {
+	infoA, err := r.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	infoB, err := r.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	if infoA.Size() != infoB.Size() {
+		return false, nil
+	}
+	hashes, err := r.hashes(ctx, a, b)
+	if err != nil {
+		return false, err
+	}
+	return hashes[0] == hashes[1], nil
+}
+
+// hashes returns the SHA-256 of each file on the host.
+func (r *sshFS) hashes(ctx context.Context, files ...string) ([]string, error) {
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = hostPath(f)
+	}
+	if err := r.throttle.op(ctx); err != nil {
+		return nil, err
+	}
+	out, err := r.run(ctx, remoteHashScript, paths...)
+	if err != nil {
+		return nil, err
+	}
+	var hashes []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		// names with newlines or backslashes are escaped, which is marked by a leading backslash
+		hash, _, _ := strings.Cut(strings.TrimPrefix(line, `\`), " ")
+		hashes = append(hashes, hash)
+	}
+	if len(hashes) != len(files) {
+		return nil, fmt.Errorf("unexpected output from the remote hash: %q", out)
+	}
+	return hashes, nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeSSH writes a stand-in for ssh which runs the command locally, so the quoting ohman relies on is exercised.
+func fakeSSH(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	path := filepath.Join(t.TempDir(), "ssh")
+	// options come before the -- which ends them
+	script := "#!/bin/sh\nwhile [ $# -gt 0 ] && [ \"$1\" != -- ]; do shift; done\n" +
+		"[ \"$2\" = nas@example.com ] || exit 255\nexec sh -c \"$3\"\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatalf("failed to create %s: %v", path, err)
+	}
+	return path
+}
+
+func TestCLI_Run_Remote(t *testing.T) {
+	dir := setupTestDir(t)
+	for name, content := range map[string]string{
+		"book.pdf":       "content",
+		"book (1).pdf":   "content",
+		"it's.pdf":       "quoted",
+		"it's (1).pdf":   "quoted",
+		"song.mp3":       "abcdefg",
+		"song (1).mp3":   "1234567",
+		"lost (1).epub":  "orphan",
+		"lost (2).epub":  "orphan",
+		"notes (1).txt":  "unmatched",
+		"$(touch x).pdf": "safe",
+	} {
+		createTestFile(t, filepath.Join(dir, name), content)
+	}
+	// a name which the remote shell expanded would create x here
+	t.Chdir(dir)
+
+	cli := &CLI{
+		Remote:       "nas@example.com:" + dir,
+		SSH:          fakeSSH(t) + " -o BatchMode=yes",
+		Delete:       true,
+		Regex:        []string{defaultRegex},
+		AdoptOrphans: "lowest",
+		Out:          filepath.Join(t.TempDir(), "results.txt"),
+	}
+	if err := cli.Run(&Context{}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	for name, want := range map[string]bool{
+		"book.pdf":      true,
+		"book (1).pdf":  false,
+		"it's.pdf":      true,
+		"it's (1).pdf":  false,
+		"song.mp3":      true,
+		"song (1).mp3":  true,
+		"lost.epub":     true,
+		"lost (1).epub": false,
+		"lost (2).epub": false,
+		"x":             false,
+	} {
+		if got := fileExists(filepath.Join(dir, name)); got != want {
+			t.Errorf("expected %s to exist: %v, got %v", name, want, got)
+		}
+	}
+
+	results, _ := os.ReadFile(cli.Out)
+	if want := "Deleted nas@example.com:" + filepath.Join(dir, "book (1).pdf"); !strings.Contains(string(results), want) {
+		t.Errorf("expected results to contain %q, got:\n%s", want, results)
+	}
+}
+
+func TestCLI_SetupRemote(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		cli  CLI
+		want string
+	}{
+		{"paths", CLI{Remote: "nas:/media", Path: []string{"/media"}}, "can't be combined with local paths"},
+		{"invalid", CLI{Remote: "/media"}, "expected user@host:/path"},
+		{"match", CLI{Remote: "nas:/media", Match: "audio"}, "--match audio needs local files"},
+		{"diff", CLI{Remote: "nas:/media", Diff: true}, "--diff"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cli.setupRemote(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	// the home directory by default, and again for each scheduled run
+	cli := CLI{Remote: "nas:"}
+	for range 2 {
+		if err := cli.setupRemote(); err != nil || cli.Remote != "nas:." || len(cli.Path) != 1 || cli.Path[0] != "nas:." {
+			t.Errorf("setupRemote() = %v, with %q and %q", err, cli.Remote, cli.Path)
+		}
+	}
+}
+
+func TestCLI_WalkRemote(t *testing.T) {
+	t.Parallel()
+	listing := func(out string, err error) func(context.Context, string, string, ...string) ([]byte, error) {
+		return func(context.Context, string, string, ...string) ([]byte, error) {
+			return []byte(out), err
+		}
+	}
+	denied := errors.New("exit status 1: find: '/media/private': Permission denied")
+
+	var visited []string
+	cli := &CLI{Remote: "nas:/media", Path: []string{"nas:/media"}, SkipErrors: true, shell: listing("7\t1700000000.5\t/media/a b.pdf\x003\t1700000000\t/media/tab\there.pdf\x00", denied)}
+	err := cli.walk(context.Background(), func(path string, info os.FileInfo) {
+		visited = append(visited, path)
+	})
+	if err != nil {
+		t.Fatalf("walk() error = %v", err)
+	}
+	if len(visited) != 2 || visited[0] != "nas:/media/a b.pdf" || visited[1] != "nas:/media/tab\there.pdf" || cli.skipped != 1 {
+		t.Errorf("walk() visited %q, skipping %d", visited, cli.skipped)
+	}
+	info, err := cli.stat("nas:/media/a b.pdf")
+	if err != nil || info.Size() != 7 || info.ModTime().UnixMilli() != 1700000000500 {
+		t.Errorf("stat() = %v, %v", info, err)
+	}
+
+	for name, cli := range map[string]*CLI{
+		"no skipping": {Remote: "nas:/media", Path: []string{"nas:/media"}, shell: listing("7\t1700000000\t/media/a.pdf\x00", denied)},
+		"missing":     {Remote: "nas:/missing", Path: []string{"nas:/missing"}, SkipErrors: true, shell: listing("", errors.New("exit status 1: find: '/missing': No such file or directory"))},
+		"garbled":     {Remote: "nas:/media", Path: []string{"nas:/media"}, shell: listing("total 0\n", nil)},
+	} {
+		if err := cli.walk(context.Background(), func(string, os.FileInfo) {}); err == nil {
+			t.Errorf("%s: expected walk() to fail", name)
+		}
+	}
+}
+
+func TestRemoteScripts(t *testing.T) {
+	if _, err := exec.LookPath("sha256sum"); err != nil {
+		t.Skip("sha256sum isn't available")
+	}
+	dir := setupTestDir(t)
+	createTestFile(t, filepath.Join(dir, "a"), "same")
+	createTestFile(t, filepath.Join(dir, "-b"), "same")
+	createTestFile(t, filepath.Join(dir, "c\\d"), "other")
+
+	cli := &CLI{Remote: "nas@example.com:" + dir, SSH: fakeSSH(t)}
+	hashes, err := cli.files().(*sshFS).hashes(context.Background(), "nas@example.com:"+filepath.Join(dir, "a"), "nas@example.com:"+filepath.Join(dir, "-b"), "nas@example.com:"+filepath.Join(dir, "c\\d"))
+	if err != nil {
+		t.Fatalf("hashes() error = %v", err)
+	}
+	if hashes[0] != hashes[1] || hashes[0] == hashes[2] || len(hashes[2]) != 64 {
+		t.Errorf("hashes() = %q", hashes)
+	}
+}
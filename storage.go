@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing/fstest"
+)
+
+// backend holds the files being scanned: the local filesystem, an S3 bucket, Google Drive, Dropbox, a host reached
+// over SSH, or memory. Files are read through io/fs and changed through the backend's own methods, each named by the
+// path ohman reports for it (e.g. /media/book.pdf or s3://bucket/book.pdf) rather than an fs.ValidPath.
+type backend interface {
+	fs.StatFS
+	// walk calls visit for each file and directory beneath root, as filepath.Walk does. Entries which can't be read
+	// are passed with their error, and returning nil skips them.
+	walk(ctx context.Context, root string, visit filepath.WalkFunc) error
+	// remove deletes the file at path.
+	remove(ctx context.Context, path string) error
+	// rename moves the file at from to to, replacing any file there.
+	rename(ctx context.Context, from, to string) error
+}
+
+// contentComparer is implemented by backends which can tell whether two files are identical without reading them,
+// e.g. by the checksums a storage service keeps.
+type contentComparer interface {
+	sameContent(ctx context.Context, a, b string) (bool, error)
+}
+
+// copyFinder is implemented by backends which recognise copies the --regex can't, e.g. Dropbox's conflicted copies.
+type copyFinder interface {
+	// addCopies adds the copies found by the last walk to files, under their originals.
+	addCopies(patterns copyPatterns, files map[string][]string)
+}
+
+// metadataCarrier is implemented by backends keeping metadata which a file loses when another is renamed over it.
+type metadataCarrier interface {
+	// metadata returns what's to be carried over from the file at path.
+	metadata(path string) (fileMeta, error)
+	// setMetadata gives the file at path the metadata m.
+	setMetadata(path string, m fileMeta) error
+}
+
+// errUnreadable is returned when opening files in backends which are only compared by their checksums.
+var errUnreadable = errors.New("file content can't be read from this backend")
+
+// openSeekable opens path for reading from any offset, as parsing tags and EXIF metadata requires.
+func openSeekable(files fs.FS, path string) (io.ReadSeekCloser, error) {
+	f, err := files.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	rs, ok := f.(io.ReadSeekCloser)
+	if !ok {
+		_ = f.Close()
+		return nil, &fs.PathError{Op: "seek", Path: path, Err: errors.ErrUnsupported}
+	}
+	return rs, nil
+}
+
+// hasScheme reports whether path is a URL naming files in a storage service, rather than a local path.
+func hasScheme(path string) bool {
+	return isS3(path) || isDrive(path) || isDropbox(path)
+}
+
+// files returns the backend holding the scanned files, choosing it from the paths on first use in each run.
+func (c *CLI) files() backend {
+	if c.storage != nil {
+		return c.storage
+	}
+	switch {
+	case c.memory != nil:
+		c.storage = &memFS{files: c.memory, throttle: c.throttle}
+	case c.s3 != nil:
+		c.storage = &s3FS{client: c.s3, objects: make(map[string]s3Object), throttle: c.throttle}
+	case c.drive != nil:
+		c.storage = &driveFS{client: c.drive, files: make(map[string]driveFile), throttle: c.throttle}
+	case c.dropbox != nil:
+		c.storage = &dropboxFS{client: c.dropbox, files: make(map[string]dropboxFile), throttle: c.throttle}
+	case c.Remote != "":
+		c.storage = &sshFS{run: c.runRemote, files: make(map[string]remoteFile), throttle: c.throttle}
+	default:
+		c.storage = localFS{throttle: c.throttle, permanent: c.Permanent, lists: c.lists()}
+	}
+	return c.storage
+}
+
+// localFS is the local filesystem.
+type localFS struct {
+	throttle *throttle
+	// permanent deletes files outright rather than moving them to the Recycle Bin, on Windows.
+	permanent bool
+	// lists is how many directories a walk lists at once, or listAhead when 0.
+	lists int
+}
+
+func (localFS) Open(name string) (fs.File, error)     { return os.Open(nativePath(name)) }
+func (localFS) Stat(name string) (fs.FileInfo, error) { return os.Stat(nativePath(name)) }
+
+func (l localFS) walk(ctx context.Context, root string, visit filepath.WalkFunc) error {
+	lists := l.lists
+	if lists <= 0 {
+		lists = listAhead
+	}
+	return walkAhead(nativePath(root), lists, func(path string, info os.FileInfo, err error) error {
+		if err := l.throttle.op(ctx); err != nil {
+			return err
+		}
+		return visit(displayPath(path), info, err)
+	})
+}
+
+func (l localFS) remove(_ context.Context, path string) error { return removeFile(path, l.permanent) }
+
+func (localFS) rename(_ context.Context, from, to string) error {
+	return os.Rename(nativePath(from), nativePath(to))
+}
+
+func (localFS) mkdirAll(path string) error { return os.MkdirAll(nativePath(path), 0755) }
+
+func (localFS) metadata(path string) (fileMeta, error) { return readFileMeta(nativePath(path)) }
+
+func (localFS) setMetadata(path string, m fileMeta) error { return writeFileMeta(nativePath(path), m) }
+
+// memFS holds files in memory, so runs can be tested without a temporary directory. Paths are slash-separated and
+// rooted, e.g. /media/book.pdf, and held without their leading slash.
+type memFS struct {
+	files    fstest.MapFS
+	throttle *throttle
+}
+
+func memName(path string) string {
+	return strings.TrimPrefix(filepath.ToSlash(strings.TrimPrefix(path, filepath.VolumeName(path))), "/")
+}
+
+func (m *memFS) Open(name string) (fs.File, error) {
+	return m.files.Open(memName(name))
+}
+
+func (m *memFS) Stat(name string) (fs.FileInfo, error) {
+	return m.files.Stat(memName(name))
+}
+
+func (m *memFS) walk(ctx context.Context, root string, visit filepath.WalkFunc) error {
+	name := memName(root)
+	if name == "" {
+		name = "."
+	}
+	return fs.WalkDir(m.files, name, func(p string, d fs.DirEntry, err error) error {
+		if err := m.throttle.op(ctx); err != nil {
+			return err
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(p, name), "/")
+		var info fs.FileInfo
+		if err == nil {
+			info, err = d.Info()
+		}
+		return visit(filepath.Join(root, filepath.FromSlash(rel)), info, err)
+	})
+}
+
+func (m *memFS) remove(_ context.Context, p string) error {
+	name := memName(p)
+	if _, ok := m.files[name]; !ok {
+		return &fs.PathError{Op: "remove", Path: p, Err: fs.ErrNotExist}
+	}
+	delete(m.files, name)
+	return nil
+}
+
+func (m *memFS) mkdirAll(p string) error {
+	m.files[memName(p)] = &fstest.MapFile{Mode: fs.ModeDir | 0755}
+	return nil
+}
+
+func (m *memFS) rename(_ context.Context, from, to string) error {
+	f, ok := m.files[memName(from)]
+	if !ok {
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: fs.ErrNotExist}
+	}
+	if _, err := m.files.Stat(path.Dir(memName(to))); err != nil {
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: fs.ErrNotExist}
+	}
+	delete(m.files, memName(from))
+	m.files[memName(to)] = f
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestCLI_Run_Memory(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	memory := fstest.MapFS{
+		"media/book.pdf":           {Data: []byte("content"), ModTime: now.Add(-2 * time.Hour)},
+		"media/book (1).pdf":       {Data: []byte("content"), ModTime: now.Add(-time.Hour)},
+		"media/Music/song.mp3":     {Data: []byte("old"), ModTime: now.Add(-2 * time.Hour)},
+		"media/Music/song (1).mp3": {Data: []byte("new"), ModTime: now},
+		"media/notes.txt":          {Data: []byte("notes")},
+	}
+
+	cli := &CLI{
+		Path:             []string{"/media"},
+		Delete:           true,
+		AllowDifferent:   true,
+		InverseAndRename: true,
+		Out:              filepath.Join(t.TempDir(), "results.txt"),
+		Regex:            []string{defaultRegex},
+		memory:           memory,
+	}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var names []string
+	for name := range memory {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	if want := []string{"media/Music/song.mp3", "media/book.pdf", "media/notes.txt"}; !slices.Equal(names, want) {
+		t.Errorf("files = %q, want %q", names, want)
+	}
+	if got := string(memory["media/Music/song.mp3"].Data); got != "new" {
+		t.Errorf("song.mp3 = %q, want the newest copy", got)
+	}
+}
+
+func TestMemFS(t *testing.T) {
+	t.Parallel()
+	m := &memFS{files: fstest.MapFS{
+		"media/a.pdf":     {Data: []byte("a")},
+		"media/sub/b.pdf": {Data: []byte("bb")},
+	}}
+	ctx := context.Background()
+
+	var visited []string
+	err := m.walk(ctx, "/media", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			visited = append(visited, filepath.ToSlash(path))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk() error = %v", err)
+	}
+	if want := []string{"/media/a.pdf", "/media/sub/b.pdf"}; !slices.Equal(visited, want) {
+		t.Errorf("walk() visited %q, want %q", visited, want)
+	}
+
+	if info, err := m.Stat("/media/sub/b.pdf"); err != nil || info.Size() != 2 {
+		t.Errorf("Stat() = %v, %v", info, err)
+	}
+	if err := m.rename(ctx, "/media/sub/b.pdf", "/media/b.pdf"); err != nil {
+		t.Errorf("rename() error = %v", err)
+	}
+	if err := m.rename(ctx, "/media/a.pdf", "/missing/a.pdf"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("rename() into a missing directory error = %v, want not exist", err)
+	}
+	if err := m.remove(ctx, "/media/a.pdf"); err != nil {
+		t.Errorf("remove() error = %v", err)
+	}
+	if err := m.remove(ctx, "/media/a.pdf"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("remove() of a missing file error = %v, want not exist", err)
+	}
+	if _, err := m.Stat("/media/b.pdf"); err != nil {
+		t.Errorf("Stat() of the renamed file error = %v", err)
+	}
+}
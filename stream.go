@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// errFailedFast stops a streaming walk once a group has failed and --fail-fast is set.
+var errFailedFast = errors.New("stopped after a failure")
+
+// streamConflict names the option given which --stream can't be combined with, if any, as it needs every group at
+// once.
+func (c *CLI) streamConflict() string {
+	switch {
+	case c.Match != "" && c.Match != "name":
+		return "--match " + c.Match
+	case c.Format != "text" && c.Format != "fdupes":
+		return "--format " + c.Format
+	case c.Diff:
+		return "--diff"
+	case c.Empty == "list" || c.Empty == "delete":
+		return "--empty " + c.Empty
+	case c.PruneEmptyDirs:
+		return "--prune-empty-dirs"
+	case c.WriteChecksums != "":
+		return "--write-checksums"
+	case c.MediaServer != "":
+		return "--media-server"
+	case c.WebhookResults:
+		return "--webhook-results"
+	case len(c.PreferPath) > 0:
+		return "--prefer-path"
+	case c.PartialDownloads:
+		return "--partial-downloads"
+	case c.cleansSync():
+		return "--preset " + syncPreset
+	case c.matcher != nil:
+		return "--plugin-matcher"
+	}
+	if _, ok := c.files().(copyFinder); ok {
+		// these find copies among every file listed
+		return "Google Drive or Dropbox paths"
+	}
+	return ""
+}
+
+// runStream scans and processes like run, but acts on each directory's groups as soon as the walk has left it and
+// writes their results as it goes, so memory use doesn't grow with the number of files or groups. Groups are reported
+// in the order their directories are finished, rather than sorted, and only counted for the webhook and history.
+func (c *CLI) runStream(ctx context.Context, patterns copyPatterns) error {
+	c.streamed = runCounts{}
+	w, closeOutput, err := c.streamOutput()
+	if err != nil {
+		return err
+	}
+	var colors palette
+	if c.Out == stdoutPath || (c.Out == "" && !c.Delete) {
+		colors = newPalette(os.Stdout, c.NoColor)
+	}
+
+	stopped, failures, err := c.stream(ctx, patterns, w, colors)
+	if cerr := closeOutput(); err == nil {
+		err = cerr
+	}
+	if c.skipped > 0 {
+		fmt.Fprintln(os.Stderr, tr("Warning: skipped %d inaccessible entries", c.skipped))
+	}
+	if err != nil {
+		return errors.Join(err, failures)
+	}
+	if c.Quiet {
+		summary := c.summary(c.streamed)
+		if c.Out == stdoutPath {
+			fmt.Fprintln(os.Stderr, summary)
+		} else {
+			fmt.Println(summary)
+		}
+	}
+	if stopped {
+		err := fmt.Errorf("%w after processing %d groups; results contain only completed groups", context.Cause(ctx), c.streamed.Groups)
+		return errors.Join(err, failures)
+	}
+	return failures
+}
+
+// stream walks the scan paths, handing each directory's groups to apply once the walk has moved on from it and
+// writing their results to w. stopped reports whether ctx was done before the walk finished. The failed actions of
+// every group are returned, in the form of collectFailures.
+func (c *CLI) stream(ctx context.Context, patterns copyPatterns, w io.Writer, colors palette) (stopped bool, failures error, err error) {
+	index := newCopyIndex(patterns)
+	walkCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var failed operationErrors
+	differing := 0
+	c.status = exitOK
+	flush := func(dir string) {
+		if walkCtx.Err() != nil {
+			return
+		}
+		groups, _ := c.apply(ctx, index.take(dir))
+		if len(groups) == 0 {
+			return
+		}
+		n := countGroups(groups)
+		c.streamed.Groups += n.Groups
+		c.streamed.Duplicates += n.Duplicates
+		c.streamed.Deleted += n.Deleted
+		c.streamed.Renamed += n.Renamed
+		c.streamed.Failures += n.Failures
+		c.streamed.Reclaimed += n.Reclaimed
+		c.status = max(c.status, exitStatus(groups))
+		differing += countMismatched(groups)
+		if err := collectFailures(groups); err != nil {
+			failed = append(failed, err.(operationErrors)...)
+		}
+
+		if output := renderColored(c.Format, groups, colors); output != "" {
+			if c.Format == "text" {
+				output += "\n"
+			}
+			if _, err := io.WriteString(w, output); err != nil {
+				cancel(fmt.Errorf("failed to write results: %w", err))
+				return
+			}
+		}
+		if c.FailFast && n.Failures > 0 {
+			cancel(errFailedFast)
+		}
+	}
+
+	// Each directory is open until the walk reaches a file outside it, after which none of its copies can be found.
+	var open []string
+	err = c.walk(walkCtx, func(path string, _ os.FileInfo) {
+		dir := parentDir(path)
+		for len(open) > 0 && dir != open[len(open)-1] && !isWithin(dir, open[len(open)-1]) {
+			flush(open[len(open)-1])
+			open = open[:len(open)-1]
+		}
+		if len(open) == 0 || open[len(open)-1] != dir {
+			open = append(open, dir)
+		}
+		index.add(path)
+	})
+	if err == nil {
+		for i := len(open) - 1; i >= 0; i-- {
+			flush(open[i])
+		}
+	}
+
+	if differing > 0 {
+		fmt.Fprintln(os.Stderr, tr("Warning: %d group(s) have copies whose content differs from the original. These groups are never changed unless --allow-different is given.", differing))
+	}
+	if len(failed) > 0 {
+		failures = failed
+	}
+	switch cause := context.Cause(walkCtx); {
+	case ctx.Err() != nil:
+		return true, failures, nil
+	case errors.Is(cause, errFailedFast):
+		return false, failures, nil
+	case walkCtx.Err() != nil:
+		return false, failures, cause
+	}
+	return false, failures, err
+}
+
+// streamOutput opens where streamed results are written: --out, results.txt when deleting, or stdout unless --quiet
+// is set. close closes it, saying where results were written unless --quiet is set.
+func (c *CLI) streamOutput() (w io.Writer, close func() error, err error) {
+	filename := c.Out
+	if filename == "" && c.Delete {
+		filename = "results.txt"
+	}
+	switch {
+	case filename == "" && c.Quiet:
+		return io.Discard, func() error { return nil }, nil
+	case filename == "" || filename == stdoutPath:
+		// streamed results are text or fdupes, which are headed with the run
+		if _, err := fmt.Println(runHeading(c.runID, time.Now())); err != nil {
+			return nil, nil, fmt.Errorf("failed to write results: %w", err)
+		}
+		return os.Stdout, func() error { return nil }, nil
+	}
+
+	flags, done := os.O_WRONLY|os.O_CREATE|os.O_TRUNC, tr("Results written to %s", filename)
+	if c.Append {
+		flags, done = os.O_WRONLY|os.O_CREATE|os.O_APPEND, tr("Results appended to %s", filename)
+	}
+	f, err := os.OpenFile(filename, flags, 0644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to write results to %s: %v", filename, err)
+	}
+	if _, err := fmt.Fprintln(f, runHeading(c.runID, time.Now())); err != nil {
+		_ = f.Close()
+		return nil, nil, fmt.Errorf("failed to write results to %s: %v", filename, err)
+	}
+	return f, func() error {
+		var err error
+		if c.Append {
+			// separate this run from the next, as appendResults does
+			_, err = io.WriteString(f, "\n")
+		}
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write results to %s: %v", filename, err)
+		}
+		if !c.Quiet {
+			fmt.Fprintln(os.Stderr, done)
+		}
+		return nil
+	}, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCopyIndex_Take(t *testing.T) {
+	t.Parallel()
+	index := newCopyIndex(copyPatterns{{re: regexp.MustCompile(defaultRegex)}})
+	for _, p := range []string{"/media/a/book.pdf", "/media/a/book (1).pdf", "/media/a/sub/notes (1).epub", "/media/b/x (2).pdf"} {
+		index.add(p)
+	}
+	got := index.take("/media/a")
+	if want := map[string][]string{"/media/a/book.pdf": {"/media/a/book (1).pdf"}}; !maps.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("take(/media/a) = %v, want %v", got, want)
+	}
+	if got := index.take("/media/a"); len(got) != 0 {
+		t.Errorf("take(/media/a) again = %v, want nothing", got)
+	}
+	if got := index.files(); len(got) != 2 {
+		t.Errorf("files() = %v, want the copies in the other directories", got)
+	}
+}
+
+func TestCLI_Run_Stream(t *testing.T) {
+	t.Parallel()
+	memory := fstest.MapFS{
+		"media/a/book.pdf":           {Data: []byte("content")},
+		"media/a/book (1).pdf":       {Data: []byte("content")},
+		"media/a/sub/notes.epub":     {Data: []byte("notes")},
+		"media/a/sub/notes (1).epub": {Data: []byte("notes")},
+		"media/a/zine.pdf":           {Data: []byte("zine")},
+		"media/a/zine (1).pdf":       {Data: []byte("other")},
+		"media/b/song.mp3":           {Data: []byte("song")},
+		"media/b/song (1).mp3":       {Data: []byte("song")},
+	}
+	out := filepath.Join(t.TempDir(), "results.txt")
+	cli := &CLI{
+		Path:   []string{"/media"},
+		Delete: true,
+		Stream: true,
+		Format: "fdupes",
+		Out:    out,
+		Regex:  []string{defaultRegex},
+		memory: memory,
+	}
+	err := cli.Run(nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var names []string
+	for name := range memory {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	want := []string{"media/a/book.pdf", "media/a/sub/notes.epub", "media/a/zine (1).pdf", "media/a/zine.pdf", "media/b/song.mp3"}
+	if !slices.Equal(names, want) {
+		t.Errorf("files = %q, want %q", names, want)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read results: %v", err)
+	}
+	// a directory's groups are written once the walk has left it, so after those of its subdirectories
+	wantOut := "   [+] /media/a/sub/notes.epub\n   [-] /media/a/sub/notes (1).epub\n\n" +
+		"   [+] /media/a/book.pdf\n   [-] /media/a/book (1).pdf\n\n" +
+		"/media/a/zine.pdf\n/media/a/zine (1).pdf\n\n" +
+		"   [+] /media/b/song.mp3\n   [-] /media/b/song (1).mp3\n\n"
+	if withoutHeading(t, cli.runID, string(data)) != wantOut {
+		t.Errorf("results = %q, want %q", data, wantOut)
+	}
+	if want := (runCounts{Groups: 4, Duplicates: 4, Deleted: 3, Reclaimed: 16}); cli.streamed != want {
+		t.Errorf("streamed = %+v, want %+v", cli.streamed, want)
+	}
+	if cli.status != exitDuplicatesFound {
+		t.Errorf("status = %d, want %d for the group left alone", cli.status, exitDuplicatesFound)
+	}
+}
+
+func TestCLI_Run_StreamConflicts(t *testing.T) {
+	t.Parallel()
+	cli := &CLI{
+		Path:   []string{"/media"},
+		Stream: true,
+		Format: "json",
+		Regex:  []string{defaultRegex},
+		memory: fstest.MapFS{"media/book.pdf": {Data: []byte("content")}},
+	}
+	err := cli.Run(nil)
+	if err == nil || !strings.Contains(err.Error(), "--stream can't be combined with --format json") {
+		t.Errorf("Run() error = %v, want --format json to be refused", err)
+	}
+}
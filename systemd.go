@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// sdNotify sends state to the systemd service manager (see sd_notify(3)). It is a no-op when not running under
+// systemd, i.e. when $NOTIFY_SOCKET is unset.
+func sdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	// abstract namespace sockets are given with a leading @
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// watchdogInterval returns how often systemd expects a watchdog ping, or 0 if the watchdog isn't enabled for
+// this process (see sd_watchdog_enabled(3)).
+func watchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// runWatchdog pings systemd at half the required interval until ctx is done.
+func runWatchdog(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = sdNotify("WATCHDOG=1")
+		}
+	}
+}
+
+// journalHandler is a slog.Handler which prefixes each line with its syslog priority (e.g. "<3>"), which journald
+// parses from a service's stderr to set the entry's priority. Timestamps are omitted as the journal records them.
+type journalHandler struct {
+	mu    *sync.Mutex
+	w     io.Writer
+	inner slog.Handler
+}
+
+func newJournalHandler(w io.Writer) *journalHandler {
+	return &journalHandler{
+		mu: &sync.Mutex{},
+		w:  w,
+		inner: slog.NewTextHandler(w, &slog.HandlerOptions{
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey) {
+					return slog.Attr{}
+				}
+				return a
+			},
+		}),
+	}
+}
+
+func (h *journalHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+func (h *journalHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, err := fmt.Fprintf(h.w, "<%d>", journalPriority(r.Level)); err != nil {
+		return err
+	}
+	return h.inner.Handle(ctx, r)
+}
+
+func (h *journalHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &journalHandler{mu: h.mu, w: h.w, inner: h.inner.WithAttrs(attrs)}
+}
+
+func (h *journalHandler) WithGroup(name string) slog.Handler {
+	return &journalHandler{mu: h.mu, w: h.w, inner: h.inner.WithGroup(name)}
+}
+
+// journalPriority maps slog levels to syslog priorities.
+func journalPriority(level slog.Level) int {
+	switch {
+	case level >= slog.LevelError:
+		return 3 // err
+	case level >= slog.LevelWarn:
+		return 4 // warning
+	case level >= slog.LevelInfo:
+		return 6 // info
+	default:
+		return 7 // debug
+	}
+}
+
+// newDaemonLogger logs to the journal when stderr is connected to it, and as plain text otherwise.
+func newDaemonLogger() *slog.Logger {
+	if stderrIsJournal() {
+		return slog.New(newJournalHandler(os.Stderr))
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, nil))
+}
+
+// SystemdUnitCmd prints a systemd service unit which runs `ohman daemon` with the given arguments.
+type SystemdUnitCmd struct {
+	User     string        `name:"user" help:"User the service runs as. Defaults to root."`
+	Watchdog time.Duration `name:"watchdog" help:"Restart the service if it stops responding for this long. 0 disables the watchdog." default:"2m"`
+	Args     []string      `arg:"" name:"daemon-args" passthrough:"" help:"Arguments for 'ohman daemon', after --, e.g. -- --schedule \"0 3 * * *\" --delete /path."`
+}
+
+var unitTemplate = template.Must(template.New("unit").Parse(`[Unit]
+Description=ohman duplicate file cleanup
+Documentation=https://github.com/jimschubert/ohman
+Wants=local-fs.target
+After=local-fs.target
+
+[Service]
+Type=notify
+NotifyAccess=main
+ExecStart={{ .ExecStart }}
+{{- if .User }}
+User={{ .User }}
+{{- end }}
+{{- if .WatchdogSec }}
+WatchdogSec={{ .WatchdogSec }}
+{{- end }}
+Restart=on-failure
+RestartSec=30
+
+[Install]
+WantedBy=multi-user.target
+`))
+
+func (s *SystemdUnitCmd) Run(_ *Context) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("unable to determine the path to ohman: %w", err)
+	}
+	return s.write(os.Stdout, exe)
+}
+
+func (s *SystemdUnitCmd) write(w io.Writer, exe string) error {
+	daemonArgs := s.Args
+	if len(daemonArgs) > 0 && daemonArgs[0] == "--" {
+		daemonArgs = daemonArgs[1:]
+	}
+	args := append([]string{exe, "daemon"}, daemonArgs...)
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = systemdQuote(a)
+	}
+	var watchdogSec int64
+	if s.Watchdog > 0 {
+		watchdogSec = int64(max(s.Watchdog.Round(time.Second), time.Second) / time.Second)
+	}
+	return unitTemplate.Execute(w, map[string]any{
+		"ExecStart":   strings.Join(quoted, " "),
+		"User":        s.User,
+		"WatchdogSec": watchdogSec,
+	})
+}
+
+// systemdQuote quotes s for an ExecStart= line, escaping the specifiers and variables systemd would otherwise expand.
+func systemdQuote(s string) string {
+	s = strings.ReplaceAll(s, "%", "%%")
+	s = strings.ReplaceAll(s, "$", "$$")
+	if s != "" && !strings.ContainsAny(s, " \t\"'\\;") {
+		return s
+	}
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
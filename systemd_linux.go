@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// stderrIsJournal reports whether stderr is connected to the journal, per $JOURNAL_STREAM (see systemd.exec(5)).
+func stderrIsJournal() bool {
+	stream := os.Getenv("JOURNAL_STREAM")
+	if stream == "" {
+		return false
+	}
+	var st syscall.Stat_t
+	if err := syscall.Fstat(int(os.Stderr.Fd()), &st); err != nil {
+		return false
+	}
+	return stream == fmt.Sprintf("%d:%d", st.Dev, st.Ino)
+}
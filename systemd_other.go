@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// stderrIsJournal always reports false, as the journal only exists on Linux.
+func stderrIsJournal() bool {
+	return false
+}
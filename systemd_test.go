@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSdNotify(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unix datagram sockets unavailable: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	t.Setenv("NOTIFY_SOCKET", socket)
+
+	if err := sdNotify("READY=1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	buf := make([]byte, 64)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("failed to read notification: %v", err)
+	}
+	if got := string(buf[:n]); got != "READY=1" {
+		t.Errorf("expected READY=1, got %q", got)
+	}
+}
+
+func TestSdNotify_NotUnderSystemd(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if err := sdNotify("READY=1"); err != nil {
+		t.Errorf("expected no-op without NOTIFY_SOCKET, got %v", err)
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	t.Setenv("WATCHDOG_USEC", "30000000")
+	t.Setenv("WATCHDOG_PID", "")
+	if got := watchdogInterval(); got != 30*time.Second {
+		t.Errorf("expected 30s, got %s", got)
+	}
+
+	t.Setenv("WATCHDOG_PID", "1")
+	if got := watchdogInterval(); got != 0 {
+		t.Errorf("expected watchdog to be disabled for another pid, got %s", got)
+	}
+}
+
+func TestJournalHandler(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	logger := slog.New(newJournalHandler(&buf)).With("run", 1)
+
+	logger.Info("run started")
+	logger.Error("run failed", "error", "boom")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", buf.String())
+	}
+	if lines[0] != `<6>msg="run started" run=1` {
+		t.Errorf("unexpected info line: %q", lines[0])
+	}
+	if lines[1] != `<3>msg="run failed" run=1 error=boom` {
+		t.Errorf("unexpected error line: %q", lines[1])
+	}
+}
+
+func TestSystemdUnitCmd(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	s := &SystemdUnitCmd{
+		User:     "media",
+		Watchdog: 2 * time.Minute,
+		Args:     []string{"--", "--schedule", "0 3 * * *", "--delete", "/media/100% books"},
+	}
+	if err := s.write(&buf, "/usr/local/bin/ohman"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"Type=notify",
+		`ExecStart=/usr/local/bin/ohman daemon --schedule "0 3 * * *" --delete "/media/100%% books"`,
+		"User=media",
+		"WatchdogSec=120",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected unit to contain %q, got:\n%s", want, buf.String())
+		}
+	}
+}
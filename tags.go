@@ -0,0 +1,429 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf16"
+)
+
+const (
+	// maxTagFrame is the largest tag field which is read; bigger ones are artwork or lyrics, which are skipped.
+	maxTagFrame = 64 << 10
+	// maxTagBlock bounds the ID3 tags and FLAC comment blocks which have to be read whole.
+	maxTagBlock = 16 << 20
+)
+
+// tagExtensions are the formats whose tags can be read for --match tags.
+var tagExtensions = map[string]bool{".mp3": true, ".flac": true}
+
+// errNoTags is returned by readTags when a file has no tags it understands.
+var errNoTags = errors.New("no ID3 or FLAC tags")
+
+// audioTags are the tags used to recognise the same track.
+type audioTags struct {
+	Artist, Album, Title string
+}
+
+// key identifies the track once its tags are normalized, or is empty when there's no artist or title to go by.
+func (t audioTags) key() string {
+	artist, title := normalizeTag(t.Artist), normalizeTag(t.Title)
+	if artist == "" || title == "" {
+		return ""
+	}
+	return artist + "\x00" + normalizeTag(t.Album) + "\x00" + title
+}
+
+// normalizeTag folds case, punctuation, and spacing, so "AC/DC" and "ac-dc " compare equal.
+func normalizeTag(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}), " ")
+}
+
+// taggedFile is a track found by the scan, along with what's needed to choose which copy to keep.
+type taggedFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// scanTags groups MP3 and FLAC files with the same artist, album, and title tags, whatever they're named. A lossless
+// copy in each group is treated as the original, then the largest file, then the oldest.
+func (c *CLI) scanTags(ctx context.Context) (map[string][]string, error) {
+	var tracks []taggedFile
+	err := c.walk(ctx, func(path string, info os.FileInfo) {
+		if tagExtensions[strings.ToLower(filepath.Ext(path))] {
+			tracks = append(tracks, taggedFile{path: path, size: info.Size(), modTime: info.ModTime()})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string][]taggedFile)
+	for _, track := range tracks {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("%w while reading tags; no files were changed", context.Cause(ctx))
+		}
+		if err := c.throttle.op(ctx); err != nil {
+			return nil, fmt.Errorf("%w while reading tags; no files were changed", context.Cause(ctx))
+		}
+		tags, err := readTagsFile(c.files(), track.path)
+		if errors.Is(err, errNoTags) {
+			continue
+		}
+		if err != nil {
+			if !c.SkipErrors {
+				return nil, fmt.Errorf("unable to read tags of %s: %w", track.path, err)
+			}
+			c.skip(track.path, err)
+			continue
+		}
+		if key := tags.key(); key != "" {
+			byKey[key] = append(byKey[key], track)
+		}
+	}
+
+	files := make(map[string][]string)
+	for _, members := range byKey {
+		if len(members) < 2 {
+			continue
+		}
+		slices.SortFunc(members, func(a, b taggedFile) int {
+			return cmp.Or(
+				compareLossless(a.path, b.path),
+				cmp.Compare(b.size, a.size),
+				a.modTime.Compare(b.modTime),
+				cmp.Compare(a.path, b.path),
+			)
+		})
+		for _, m := range members[1:] {
+			files[members[0].path] = append(files[members[0].path], m.path)
+		}
+	}
+	return files, nil
+}
+
+func readTagsFile(files fs.FS, path string) (audioTags, error) {
+	f, err := openSeekable(files, path)
+	if err != nil {
+		return audioTags{}, err
+	}
+	defer func() { _ = f.Close() }()
+	return readTags(f)
+}
+
+// readTags reads the artist, album, and title from FLAC Vorbis comments, or from ID3v2 or ID3v1 tags.
+func readTags(r io.ReadSeeker) (audioTags, error) {
+	var header [10]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return audioTags{}, errNoTags
+		}
+		return audioTags{}, err
+	}
+	if string(header[:4]) == "fLaC" {
+		if _, err := r.Seek(4, io.SeekStart); err != nil {
+			return audioTags{}, err
+		}
+		return readFLACTags(r)
+	}
+	if string(header[:3]) == "ID3" {
+		tags, err := readID3v2(r, header)
+		if err == nil && tags.key() != "" {
+			return tags, nil
+		}
+	}
+	return readID3v1(r)
+}
+
+// id3Frames maps the ID3v2.2 and ID3v2.3+ frame IDs for the tags used to the field they fill.
+var id3Frames = map[string]func(*audioTags) *string{
+	"TP1": func(t *audioTags) *string { return &t.Artist }, "TPE1": func(t *audioTags) *string { return &t.Artist },
+	"TAL": func(t *audioTags) *string { return &t.Album }, "TALB": func(t *audioTags) *string { return &t.Album },
+	"TT2": func(t *audioTags) *string { return &t.Title }, "TIT2": func(t *audioTags) *string { return &t.Title },
+}
+
+// readID3v2 reads an ID3v2.2, 2.3, or 2.4 tag, with r positioned just after its 10 byte header.
+func readID3v2(r io.ReadSeeker, header [10]byte) (audioTags, error) {
+	version, flags, remaining := header[3], header[5], int64(syncsafe(header[6:10]))
+	if version < 2 || version > 4 {
+		return audioTags{}, fmt.Errorf("unsupported ID3v2.%d tag", version)
+	}
+	if flags&0x80 != 0 && version < 4 {
+		// the whole tag is unsynchronised, frame headers included
+		if remaining > maxTagBlock {
+			return audioTags{}, fmt.Errorf("ID3 tag of %d bytes is too large", remaining)
+		}
+		data := make([]byte, remaining)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return audioTags{}, err
+		}
+		data = removeUnsync(data)
+		r, remaining = bytes.NewReader(data), int64(len(data))
+	}
+	if flags&0x40 != 0 && version > 2 {
+		var size [4]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return audioTags{}, err
+		}
+		skip := int64(binary.BigEndian.Uint32(size[:]))
+		if version == 4 {
+			skip = int64(syncsafe(size[:])) - 4
+		}
+		if _, err := r.Seek(skip, io.SeekCurrent); err != nil {
+			return audioTags{}, err
+		}
+		remaining -= 4 + skip
+	}
+
+	headerSize := int64(10)
+	if version == 2 {
+		headerSize = 6
+	}
+	var tags audioTags
+	frame := make([]byte, headerSize)
+	for remaining >= headerSize {
+		if _, err := io.ReadFull(r, frame); err != nil {
+			return tags, err
+		}
+		remaining -= headerSize
+		if frame[0] == 0 {
+			// padding
+			break
+		}
+		var id string
+		var size int64
+		switch version {
+		case 2:
+			id, size = string(frame[:3]), int64(frame[3])<<16|int64(frame[4])<<8|int64(frame[5])
+		case 3:
+			id, size = string(frame[:4]), int64(binary.BigEndian.Uint32(frame[4:8]))
+		default:
+			id, size = string(frame[:4]), int64(syncsafe(frame[4:8]))
+		}
+		if size > remaining {
+			break
+		}
+		remaining -= size
+
+		field, ok := id3Frames[id]
+		if !ok || size > maxTagFrame || *field(&tags) != "" {
+			if _, err := r.Seek(size, io.SeekCurrent); err != nil {
+				return tags, err
+			}
+			continue
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return tags, err
+		}
+		if version > 2 {
+			if data = id3FrameData(version, flags, frame[9], data); data == nil {
+				continue
+			}
+		}
+		*field(&tags) = decodeID3Text(data)
+	}
+	return tags, nil
+}
+
+// id3FrameData strips what ID3v2.3 and 2.4 frame flags add to a frame's text, returning nil for compressed or
+// encrypted frames.
+func id3FrameData(version, tagFlags, frameFlags byte, data []byte) []byte {
+	if version == 3 {
+		if frameFlags&0xc0 != 0 {
+			return nil
+		}
+		if frameFlags&0x20 != 0 && len(data) > 0 {
+			data = data[1:]
+		}
+		return data
+	}
+	if frameFlags&0x0c != 0 {
+		return nil
+	}
+	if frameFlags&0x40 != 0 && len(data) > 0 {
+		data = data[1:]
+	}
+	if frameFlags&0x01 != 0 && len(data) >= 4 {
+		data = data[4:]
+	}
+	if frameFlags&0x02 != 0 || tagFlags&0x80 != 0 {
+		data = removeUnsync(data)
+	}
+	return data
+}
+
+// decodeID3Text decodes the first value of an ID3v2 text frame.
+func decodeID3Text(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	encoding, text := data[0], data[1:]
+	var s string
+	switch encoding {
+	case 1, 2:
+		bigEndian := encoding == 2
+		if len(text) >= 2 {
+			switch {
+			case text[0] == 0xfe && text[1] == 0xff:
+				bigEndian, text = true, text[2:]
+			case text[0] == 0xff && text[1] == 0xfe:
+				bigEndian, text = false, text[2:]
+			}
+		}
+		units := make([]uint16, 0, len(text)/2)
+		for i := 0; i+1 < len(text); i += 2 {
+			u := binary.LittleEndian.Uint16(text[i:])
+			if bigEndian {
+				u = binary.BigEndian.Uint16(text[i:])
+			}
+			if u == 0 {
+				break
+			}
+			units = append(units, u)
+		}
+		s = string(utf16.Decode(units))
+	case 3:
+		s, _, _ = strings.Cut(string(text), "\x00")
+	default:
+		text, _, _ = bytes.Cut(text, []byte{0})
+		s = latin1(text)
+	}
+	return strings.TrimSpace(s)
+}
+
+// readID3v1 reads the ID3v1 tag in the last 128 bytes of an MP3.
+func readID3v1(r io.ReadSeeker) (audioTags, error) {
+	if _, err := r.Seek(-128, io.SeekEnd); err != nil {
+		// shorter than a tag
+		return audioTags{}, errNoTags
+	}
+	var tag [128]byte
+	if _, err := io.ReadFull(r, tag[:]); err != nil {
+		return audioTags{}, err
+	}
+	if string(tag[:3]) != "TAG" {
+		return audioTags{}, errNoTags
+	}
+	field := func(b []byte) string {
+		b, _, _ = bytes.Cut(b, []byte{0})
+		return strings.TrimSpace(latin1(b))
+	}
+	return audioTags{Title: field(tag[3:33]), Artist: field(tag[33:63]), Album: field(tag[63:93])}, nil
+}
+
+// readFLACTags reads the Vorbis comments of a FLAC file, with r positioned just after its "fLaC" marker.
+func readFLACTags(r io.ReadSeeker) (audioTags, error) {
+	var header [4]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return audioTags{}, err
+		}
+		last, blockType := header[0]&0x80 != 0, header[0]&0x7f
+		size := int64(header[1])<<16 | int64(header[2])<<8 | int64(header[3])
+		if blockType != 4 {
+			if last {
+				return audioTags{}, errNoTags
+			}
+			if _, err := r.Seek(size, io.SeekCurrent); err != nil {
+				return audioTags{}, err
+			}
+			continue
+		}
+		if size > maxTagBlock {
+			return audioTags{}, fmt.Errorf("FLAC comment block of %d bytes is too large", size)
+		}
+		block := make([]byte, size)
+		if _, err := io.ReadFull(r, block); err != nil {
+			return audioTags{}, err
+		}
+		return parseVorbisComments(block)
+	}
+}
+
+// parseVorbisComments reads the artist, album, and title from a Vorbis comment block.
+func parseVorbisComments(block []byte) (audioTags, error) {
+	errCorrupt := errors.New("corrupt FLAC comment block")
+	next := func() ([]byte, bool) {
+		if len(block) < 4 {
+			return nil, false
+		}
+		n := binary.LittleEndian.Uint32(block)
+		if uint64(n) > uint64(len(block)-4) {
+			return nil, false
+		}
+		value := block[4 : 4+n]
+		block = block[4+n:]
+		return value, true
+	}
+	// the vendor string
+	if _, ok := next(); !ok || len(block) < 4 {
+		return audioTags{}, errCorrupt
+	}
+	count := binary.LittleEndian.Uint32(block)
+	block = block[4:]
+
+	var tags audioTags
+	for range count {
+		comment, ok := next()
+		if !ok {
+			return audioTags{}, errCorrupt
+		}
+		key, value, _ := strings.Cut(string(comment), "=")
+		var field *string
+		switch strings.ToUpper(key) {
+		case "ARTIST":
+			field = &tags.Artist
+		case "ALBUM":
+			field = &tags.Album
+		case "TITLE":
+			field = &tags.Title
+		default:
+			continue
+		}
+		if *field == "" {
+			*field = strings.TrimSpace(value)
+		}
+	}
+	return tags, nil
+}
+
+// syncsafe decodes a 4 byte ID3v2 integer, which uses only the low 7 bits of each byte.
+func syncsafe(b []byte) uint32 {
+	return uint32(b[0]&0x7f)<<21 | uint32(b[1]&0x7f)<<14 | uint32(b[2]&0x7f)<<7 | uint32(b[3]&0x7f)
+}
+
+// removeUnsync reverses ID3v2 unsynchronisation, which inserts a zero byte after every 0xFF.
+func removeUnsync(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		out = append(out, data[i])
+		if data[i] == 0xff && i+1 < len(data) && data[i+1] == 0 {
+			i++
+		}
+	}
+	return out
+}
+
+// latin1 decodes ISO-8859-1 text, whose bytes are the first 256 code points.
+func latin1(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func syncsafeBytes(n int) []byte {
+	return []byte{byte(n >> 21 & 0x7f), byte(n >> 14 & 0x7f), byte(n >> 7 & 0x7f), byte(n & 0x7f)}
+}
+
+// id3v2 builds an ID3v2 tag of the given version from frame IDs and their (already encoded) contents, followed by
+// some padding and audio.
+func id3v2(version byte, frames ...string) []byte {
+	var body bytes.Buffer
+	for i := 0; i+1 < len(frames); i += 2 {
+		id, data := frames[i], frames[i+1]
+		body.WriteString(id)
+		switch version {
+		case 2:
+			body.Write([]byte{byte(len(data) >> 16), byte(len(data) >> 8), byte(len(data))})
+		case 3:
+			body.Write(binary.BigEndian.AppendUint32(nil, uint32(len(data))))
+			body.Write([]byte{0, 0})
+		default:
+			body.Write(syncsafeBytes(len(data)))
+			body.Write([]byte{0, 0})
+		}
+		body.WriteString(data)
+	}
+	body.Write(make([]byte, 32))
+
+	tag := append([]byte{'I', 'D', '3', version, 0, 0}, syncsafeBytes(body.Len())...)
+	tag = append(tag, body.Bytes()...)
+	return append(tag, 0xff, 0xfb, 0x90, 0x64)
+}
+
+// id3v1 builds an MP3 whose only tag is ID3v1.
+func id3v1(title, artist, album string) []byte {
+	tag := make([]byte, 128)
+	copy(tag, "TAG")
+	copy(tag[3:33], title)
+	copy(tag[33:63], artist)
+	copy(tag[63:93], album)
+	return append([]byte{0xff, 0xfb, 0x90, 0x64, 0, 0, 0, 0, 0, 0, 0, 0}, tag...)
+}
+
+// flac builds a FLAC file with a stream info block and the given Vorbis comments.
+func flac(comments ...string) []byte {
+	var block bytes.Buffer
+	block.Write(binary.LittleEndian.AppendUint32(nil, 9))
+	block.WriteString("reference")
+	block.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(comments))))
+	for _, c := range comments {
+		block.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(c))))
+		block.WriteString(c)
+	}
+
+	out := []byte("fLaC")
+	out = append(out, 0, 0, 0, 34)
+	out = append(out, make([]byte, 34)...)
+	out = append(out, 0x80|4, byte(block.Len()>>16), byte(block.Len()>>8), byte(block.Len()))
+	return append(out, block.Bytes()...)
+}
+
+func utf16Text(bigEndian bool, s string) string {
+	var b []byte
+	if bigEndian {
+		b = []byte{1, 0xfe, 0xff}
+	} else {
+		b = []byte{1, 0xff, 0xfe}
+	}
+	for _, r := range s {
+		if bigEndian {
+			b = binary.BigEndian.AppendUint16(b, uint16(r))
+		} else {
+			b = binary.LittleEndian.AppendUint16(b, uint16(r))
+		}
+	}
+	return string(append(b, 0, 0))
+}
+
+func TestReadTags(t *testing.T) {
+	want := audioTags{Artist: "Björk", Album: "Post", Title: "Hyperballad"}
+	tests := []struct {
+		name string
+		data []byte
+		want audioTags
+	}{
+		{"ID3v2.2", id3v2(2, "TT2", "\x00Hyperballad", "TP1", "\x00Bj\xf6rk", "TAL", "\x00Post"), want},
+		{"ID3v2.3 latin1", id3v2(3, "TIT2", "\x00Hyperballad\x00", "TPE1", "\x00Bj\xf6rk", "TALB", "\x00Post"), want},
+		{"ID3v2.3 UTF-16", id3v2(3, "TIT2", utf16Text(false, "Hyperballad"), "TPE1", utf16Text(true, "Björk"), "TALB", utf16Text(false, "Post")), want},
+		{"ID3v2.4 UTF-8", id3v2(4, "APIC", string(make([]byte, 300)), "TIT2", "\x03Hyperballad", "TPE1", "\x03Björk\x00Other", "TALB", "\x03Post"), want},
+		{"ID3v1", id3v1("Hyperballad", "Bj\xf6rk", "Post"), want},
+		{"FLAC", flac("ARTIST=Björk", "album=Post", "TITLE=Hyperballad", "ARTIST=Someone Else"), want},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readTags(bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatalf("readTags() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("readTags() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadTags_None(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"empty":      nil,
+		"untagged":   bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x64}, 100),
+		"FLAC":       flac(),
+		"ID3v2 only": id3v2(3, "TLEN", "\x00180000"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			tags, err := readTags(bytes.NewReader(data))
+			if err == nil && tags.key() != "" {
+				t.Errorf("readTags() = %+v, want no usable tags", tags)
+			}
+		})
+	}
+}
+
+func TestRemoveUnsync(t *testing.T) {
+	got := removeUnsync([]byte{0xff, 0x00, 0xe0, 0x01, 0xff, 0x00, 0x00, 0xff})
+	if want := []byte{0xff, 0xe0, 0x01, 0xff, 0x00, 0xff}; !bytes.Equal(got, want) {
+		t.Errorf("removeUnsync() = %x, want %x", got, want)
+	}
+}
+
+func TestAudioTags_Key(t *testing.T) {
+	a := audioTags{Artist: "AC/DC", Album: "Back in Black", Title: "Hells Bells"}
+	b := audioTags{Artist: "ac-dc ", Album: "BACK IN BLACK", Title: "Hells  Bells"}
+	if a.key() != b.key() {
+		t.Errorf("keys differ: %q and %q", a.key(), b.key())
+	}
+	if (audioTags{Album: "Back in Black", Title: "Hells Bells"}).key() != "" {
+		t.Error("a track without an artist shouldn't have a key")
+	}
+}
+
+func TestCLI_ScanTags(t *testing.T) {
+	dir := t.TempDir()
+	tracks := map[string][]byte{
+		"Track 01.mp3":         id3v2(3, "TIT2", "\x00Hells Bells", "TPE1", "\x00AC/DC", "TALB", "\x00Back in Black"),
+		"Track 01 (1).mp3":     id3v2(4, "TIT2", "\x03Hells Bells", "TPE1", "\x03AC-DC", "TALB", "\x03Back In Black"),
+		"Hells Bells.flac":     flac("ARTIST=AC/DC", "ALBUM=Back in Black", "TITLE=Hells Bells"),
+		"Track 02.mp3":         id3v1("Shoot to Thrill", "AC/DC", "Back in Black"),
+		"live/Hells Bells.mp3": id3v2(3, "TIT2", "\x00Hells Bells", "TPE1", "\x00AC/DC", "TALB", "\x00Live"),
+		"untagged.mp3":         {0xff, 0xfb, 0x90, 0x64},
+		"notes.txt":            []byte("Hells Bells"),
+	}
+	for name, data := range tracks {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+
+	cli := &CLI{Path: []string{dir}, Match: "tags", SkipErrors: true}
+	files, err := cli.scanTags(context.Background())
+	if err != nil {
+		t.Fatalf("scanTags() error = %v", err)
+	}
+	for _, duplicates := range files {
+		slices.Sort(duplicates)
+	}
+	want := map[string][]string{
+		filepath.Join(dir, "Hells Bells.flac"): {filepath.Join(dir, "Track 01 (1).mp3"), filepath.Join(dir, "Track 01.mp3")},
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("scanTags() = %v, want %v", files, want)
+	}
+	if cli.skipped != 0 {
+		t.Errorf("skipped = %d, want untagged files ignored", cli.skipped)
+	}
+}
+
+func TestReadTagsFile_Missing(t *testing.T) {
+	_, err := readTagsFile(localFS{}, filepath.Join(t.TempDir(), "missing.mp3"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("readTagsFile() error = %v, want not exist", err)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// TestRegexCmd shows what the copy patterns make of file names without touching any file, so custom patterns can be
+// developed safely.
+type TestRegexCmd struct {
+	Regex        []string `name:"regex" sep:"none" help:"Regex to try. Repeatable." default:"${default_pattern}"`
+	PatternsFile string   `name:"patterns-file" help:"Try the regexes listed in this file, in place of the default --regex." type:"path"`
+	Preset       []string `name:"preset" help:"Try the copy names of these languages' Windows Explorer and macOS Finder too."`
+	Names        []string `arg:"" name:"name" help:"File names to try, or directories whose files are tried."`
+}
+
+func (t *TestRegexCmd) Run() error {
+	patterns, err := (&CLI{Regex: t.Regex, PatternsFile: t.PatternsFile, Preset: t.Preset}).patterns()
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, name := range t.Names {
+		if info, err := os.Stat(name); err != nil || !info.IsDir() {
+			names = append(names, name)
+			continue
+		}
+		err := filepath.WalkDir(name, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				names = append(names, path)
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return writeRegexTest(os.Stdout, patterns, names)
+}
+
+// writeRegexTest writes which of patterns matches each of names, the groups it captured, and the original inferred.
+func writeRegexTest(w io.Writer, patterns copyPatterns, names []string) error {
+	var sb strings.Builder
+	for _, name := range names {
+		sb.WriteString(name + "\n")
+		pattern, matches, _, ok := patterns.match(filepath.Base(name))
+		if !ok {
+			sb.WriteString("  no match\n")
+			continue
+		}
+		fmt.Fprintf(&sb, "  pattern:  %s\n  groups:  ", pattern)
+		for i, group := range pattern.re.SubexpNames()[1:] {
+			if group == "" {
+				group = strconv.Itoa(i + 1)
+			}
+			fmt.Fprintf(&sb, " %s=%q", group, matches[i+1])
+		}
+		original, _ := originalFor(patterns, name)
+		fmt.Fprintf(&sb, "\n  original: %s\n", original)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestWriteRegexTest(t *testing.T) {
+	t.Parallel()
+	patterns := copyPatterns{
+		{re: regexp.MustCompile(defaultRegex)},
+		{label: "explorer", re: regexp.MustCompile(`(?P<name>.+) - Copy\.(?P<ext>\w+)$`), exts: map[string]string{"jpeg": "jpg"}},
+	}
+	var sb strings.Builder
+	names := []string{"book (1) (2).pdf", filepath.FromSlash("photos/cat - Copy.jpeg"), "notes.txt"}
+	if err := writeRegexTest(&sb, patterns, names); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `book (1) (2).pdf
+  pattern:  ` + defaultRegex + `
+  groups:   1="book (1)" 2="2" 3="pdf"
+  original: book.pdf
+` + filepath.FromSlash("photos/cat - Copy.jpeg") + `
+  pattern:  [explorer] (?P<name>.+) - Copy\.(?P<ext>\w+)$
+  groups:   name="cat" ext="jpeg"
+  original: ` + filepath.FromSlash("photos/cat.jpg") + `
+notes.txt
+  no match
+`
+	if got := sb.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
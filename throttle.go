@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// busyThreshold is the disk utilization, by other processes than ohman, above which adaptive throttling backs off.
+	busyThreshold = 0.5
+	// busySampleInterval is the minimum time between disk utilization samples.
+	busySampleInterval = time.Second
+	// maxBackoff caps how long adaptive throttling waits between re-checking disk activity.
+	maxBackoff = 5 * time.Second
+)
+
+// limiter is a token bucket which lets callers borrow against future tokens, so requests larger than the bucket
+// (e.g. a big read) still proceed, just after a proportionally longer wait.
+type limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(perSecond float64) *limiter {
+	return &limiter{rate: perSecond, tokens: perSecond, last: time.Now()}
+}
+
+// wait blocks until n tokens are available or ctx is done.
+func (l *limiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.rate, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens -= float64(n)
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+	return sleep(ctx, delay)
+}
+
+// sleep waits for d, returning early with the context's cause if ctx is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-timer.C:
+		return nil
+	}
+}
+
+// ioCount counts filesystem operations and bytes read.
+type ioCount struct {
+	ops   uint64
+	bytes uint64
+}
+
+// throttle paces filesystem operations and reads so ohman can run alongside other workloads.
+// A nil *throttle doesn't limit anything.
+type throttle struct {
+	iops      *limiter
+	bandwidth *limiter
+	adaptive  bool
+	// ownOps and ownBytes count ohman's own I/O since the last sample, to be told apart from other processes'
+	ownOps   atomic.Uint64
+	ownBytes atomic.Uint64
+
+	mu sync.Mutex
+	// busy samples the utilization of the disks by other processes, given ohman's own I/O since the last sample
+	busy       func(own ioCount) (float64, bool)
+	lastSample time.Time
+	lastBusy   float64
+}
+
+// newThrottle returns a throttle for the given limits, or nil if no limits are set. Adaptive throttling watches the
+// disks holding paths.
+func newThrottle(maxIOPS int, bandwidth byteSize, adaptive bool, paths []string) *throttle {
+	if maxIOPS <= 0 && bandwidth <= 0 && !adaptive {
+		return nil
+	}
+	t := &throttle{adaptive: adaptive, busy: newDiskBusy(paths)}
+	if maxIOPS > 0 {
+		t.iops = newLimiter(float64(maxIOPS))
+	}
+	if bandwidth > 0 {
+		t.bandwidth = newLimiter(float64(bandwidth))
+	}
+	return t
+}
+
+// op waits until one more filesystem operation (stat, remove, rename, ...) may be performed.
+func (t *throttle) op(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	if err := t.backoff(ctx); err != nil {
+		return err
+	}
+	t.ownOps.Add(1)
+	if t.iops == nil {
+		return nil
+	}
+	return t.iops.wait(ctx, 1)
+}
+
+// reader wraps r so reads count against the bandwidth limit, and stop once ctx is done, so comparing or hashing a
+// large file can be cancelled part way through.
+func (t *throttle) reader(ctx context.Context, r io.Reader) io.Reader {
+	if t == nil || (t.bandwidth == nil && !t.adaptive) {
+		return &contextReader{ctx: ctx, r: r}
+	}
+	return &throttledReader{ctx: ctx, t: t, r: r}
+}
+
+// backoff waits, with increasing delays, while other processes are keeping the disks busy.
+func (t *throttle) backoff(ctx context.Context) error {
+	if !t.adaptive {
+		return nil
+	}
+	delay := 250 * time.Millisecond
+	for t.diskBusy() {
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+		delay = min(delay*2, maxBackoff)
+	}
+	return nil
+}
+
+func (t *throttle) diskBusy() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if time.Since(t.lastSample) >= busySampleInterval {
+		own := ioCount{ops: t.ownOps.Swap(0), bytes: t.ownBytes.Swap(0)}
+		if utilization, ok := t.busy(own); ok {
+			t.lastBusy = utilization
+		}
+		t.lastSample = time.Now()
+	}
+	return t.lastBusy > busyThreshold
+}
+
+type throttledReader struct {
+	ctx context.Context
+	t   *throttle
+	r   io.Reader
+}
+
+func (tr *throttledReader) Read(p []byte) (int, error) {
+	if tr.ctx.Err() != nil {
+		return 0, context.Cause(tr.ctx)
+	}
+	if err := tr.t.backoff(tr.ctx); err != nil {
+		return 0, err
+	}
+	n, err := tr.r.Read(p)
+	tr.t.ownBytes.Add(uint64(n))
+	if n > 0 && tr.t.bandwidth != nil {
+		if werr := tr.t.bandwidth.wait(tr.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// contextReader reads from r until ctx is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if cr.ctx.Err() != nil {
+		return 0, context.Cause(cr.ctx)
+	}
+	return cr.r.Read(p)
+}
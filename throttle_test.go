@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestNewThrottle_NoLimits(t *testing.T) {
+	t.Parallel()
+	if th := newThrottle(0, 0, false, nil); th != nil {
+		t.Fatal("expected nil throttle when no limits are set")
+	}
+	var th *throttle
+	if err := th.op(context.Background()); err != nil {
+		t.Errorf("nil throttle should never block: %v", err)
+	}
+	if n, err := io.Copy(io.Discard, th.reader(context.Background(), bytes.NewReader(make([]byte, 1200)))); err != nil || n != 1200 {
+		t.Errorf("nil throttle should read everything without limits: %d, %v", n, err)
+	}
+}
+
+func TestThrottle_ReaderCancelled(t *testing.T) {
+	t.Parallel()
+	for _, th := range []*throttle{nil, newThrottle(0, 1<<20, false, nil)} {
+		ctx, cancel := context.WithCancelCause(context.Background())
+		r := th.reader(ctx, bytes.NewReader(make([]byte, 1200)))
+		if _, err := r.Read(make([]byte, 100)); err != nil {
+			t.Fatalf("unexpected error before cancelling: %v", err)
+		}
+		cancel(errInterrupted)
+		if _, err := io.Copy(io.Discard, r); !errors.Is(err, errInterrupted) {
+			t.Errorf("expected reading to stop with the context's cause, got %v", err)
+		}
+	}
+}
+
+func TestThrottle_IOPS(t *testing.T) {
+	t.Parallel()
+	th := newThrottle(100, 0, false, nil)
+
+	start := time.Now()
+	// the bucket starts full, so the first 100 are immediate and the next 10 take ~100ms
+	for range 110 {
+		if err := th.op(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("expected operations to be throttled, took %s", elapsed)
+	}
+}
+
+func TestThrottle_Bandwidth(t *testing.T) {
+	t.Parallel()
+	th := newThrottle(0, 1000, false, nil)
+
+	start := time.Now()
+	n, err := io.Copy(io.Discard, th.reader(context.Background(), bytes.NewReader(make([]byte, 1200))))
+	if err != nil || n != 1200 {
+		t.Fatalf("unexpected copy result: %d, %v", n, err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("expected reads to be throttled, took %s", elapsed)
+	}
+}
+
+func TestThrottle_Cancelled(t *testing.T) {
+	t.Parallel()
+	th := newThrottle(1, 0, false, nil)
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(errInterrupted)
+
+	_ = th.op(ctx) // consumes the initial token
+	if err := th.op(ctx); !errors.Is(err, errInterrupted) {
+		t.Errorf("expected the context's cause, got %v", err)
+	}
+}
+
+func TestThrottle_AdaptiveBackoff(t *testing.T) {
+	t.Parallel()
+	samples := []float64{0.9, 0.9, 0.1}
+	th := &throttle{adaptive: true, busy: func(ioCount) (float64, bool) {
+		v := samples[0]
+		if len(samples) > 1 {
+			samples = samples[1:]
+		}
+		return v, true
+	}}
+
+	start := time.Now()
+	// sampling is rate limited, so reset lastSample to force a fresh reading each time
+	go func() {
+		for range 3 {
+			time.Sleep(100 * time.Millisecond)
+			th.mu.Lock()
+			th.lastSample = time.Time{}
+			th.mu.Unlock()
+		}
+	}()
+	if err := th.op(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 250*time.Millisecond {
+		t.Errorf("expected to back off while disks were busy, took %s", elapsed)
+	}
+}
+
+func TestThrottle_CountsOwnIO(t *testing.T) {
+	t.Parallel()
+	var own ioCount
+	th := &throttle{adaptive: true, busy: func(o ioCount) (float64, bool) {
+		own = o
+		return 0, true
+	}}
+	ctx := context.Background()
+	for range 2 {
+		if err := th.op(ctx); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := io.Copy(io.Discard, th.reader(ctx, bytes.NewReader(make([]byte, 10)))); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	th.lastSample = time.Time{}
+	th.diskBusy()
+	if want := (ioCount{ops: 2, bytes: 10}); own != want {
+		t.Errorf("sampled with own I/O %+v, want %+v", own, want)
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// traceBatch is how many finished spans are held before they're exported, so long runs don't hold every span.
+	traceBatch = 512
+	// traceTimeout bounds each export to the collector.
+	traceTimeout = 10 * time.Second
+)
+
+// tracer records spans for the scan, compare, and action phases of a run, exporting them to an OpenTelemetry
+// collector with OTLP over HTTP, encoded as JSON.
+type tracer struct {
+	// endpoint is the collector's OTLP/HTTP base URL, to which /v1/traces is appended.
+	endpoint string
+	// headers are sent with every export, e.g. for authentication, from OTEL_EXPORTER_OTLP_HEADERS.
+	headers map[string]string
+	traceID string
+
+	mu      sync.Mutex
+	spans   []*span
+	exports sync.WaitGroup
+	err     error
+}
+
+// newTracer returns a tracer exporting to endpoint, or nil when there's no endpoint.
+func newTracer(endpoint string) (*tracer, error) {
+	if endpoint == "" {
+		return nil, nil
+	}
+	headers := make(map[string]string)
+	for _, h := range strings.Split(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), ",") {
+		if strings.TrimSpace(h) == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(h, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid OTEL_EXPORTER_OTLP_HEADERS entry %q; expected key=value", h)
+		}
+		headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return &tracer{endpoint: strings.TrimSuffix(endpoint, "/"), headers: headers, traceID: randomID(16)}, nil
+}
+
+// span is a timed operation within a run. A nil *span records nothing, so untraced runs needn't check for one.
+type span struct {
+	t      *tracer
+	id     string
+	parent string
+	name   string
+	start  time.Time
+	end    time.Time
+	attrs  map[string]any
+	err    error
+}
+
+type spanKey struct{}
+
+// root starts the span covering a whole run, which every other span descends from.
+func (t *tracer) root(ctx context.Context, name string, attrs ...any) (context.Context, *span) {
+	if t == nil {
+		return ctx, nil
+	}
+	s := &span{t: t, id: randomID(8), name: name, start: time.Now(), attrs: spanAttrs(attrs)}
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// startSpan starts a span named name within the span ctx carries, if any. attrs are alternating keys and values.
+func startSpan(ctx context.Context, name string, attrs ...any) (context.Context, *span) {
+	parent, _ := ctx.Value(spanKey{}).(*span)
+	if parent == nil {
+		return ctx, nil
+	}
+	s := &span{t: parent.t, id: randomID(8), parent: parent.id, name: name, start: time.Now(), attrs: spanAttrs(attrs)}
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+func spanAttrs(attrs []any) map[string]any {
+	m := make(map[string]any, len(attrs)/2)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		m[fmt.Sprint(attrs[i])] = attrs[i+1]
+	}
+	return m
+}
+
+// set adds an attribute to s.
+func (s *span) set(key string, value any) {
+	if s != nil {
+		s.attrs[key] = value
+	}
+}
+
+// finish ends s, marking it failed when err is set.
+func (s *span) finish(err error) {
+	if s == nil {
+		return
+	}
+	s.end, s.err = time.Now(), err
+	t := s.t
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.spans = append(t.spans, s)
+	if len(t.spans) >= traceBatch {
+		batch := t.spans
+		t.spans = nil
+		t.exports.Go(func() { t.export(batch) })
+	}
+}
+
+// flush exports every span not yet exported, returning the first error any export met.
+func (t *tracer) flush() error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	batch := t.spans
+	t.spans = nil
+	t.mu.Unlock()
+	if len(batch) > 0 {
+		t.export(batch)
+	}
+	t.exports.Wait()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+func (t *tracer) export(batch []*span) {
+	if err := t.post(batch); err != nil {
+		t.mu.Lock()
+		if t.err == nil {
+			t.err = err
+		}
+		t.mu.Unlock()
+	}
+}
+
+func (t *tracer) post(batch []*span) error {
+	body, err := json.Marshal(t.request(batch))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), traceTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint+"/v1/traces", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ohman/"+version)
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("the OTLP collector responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// The OTLP/JSON encoding of spans, trimmed to what ohman sends.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            otlpStatus      `json:"status"`
+	}
+	otlpAttribute struct {
+		Key   string         `json:"key"`
+		Value map[string]any `json:"value"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+const (
+	otlpKindInternal = 1
+	otlpStatusError  = 2
+)
+
+func (t *tracer) request(batch []*span) otlpRequest {
+	spans := make([]otlpSpan, len(batch))
+	for i, s := range batch {
+		spans[i] = otlpSpan{
+			TraceID:           t.traceID,
+			SpanID:            s.id,
+			ParentSpanID:      s.parent,
+			Name:              s.name,
+			Kind:              otlpKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		}
+		for k, v := range s.attrs {
+			spans[i].Attributes = append(spans[i].Attributes, otlpAttr(k, v))
+		}
+		if s.err != nil {
+			spans[i].Status = otlpStatus{Code: otlpStatusError, Message: s.err.Error()}
+		}
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: []otlpAttribute{otlpAttr("service.name", "ohman"), otlpAttr("service.version", version)}},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "ohman", Version: version}, Spans: spans}},
+	}}}
+}
+
+func otlpAttr(key string, v any) otlpAttribute {
+	switch v := v.(type) {
+	case int:
+		return otlpAttribute{Key: key, Value: map[string]any{"intValue": strconv.Itoa(v)}}
+	case int64:
+		return otlpAttribute{Key: key, Value: map[string]any{"intValue": strconv.FormatInt(v, 10)}}
+	case bool:
+		return otlpAttribute{Key: key, Value: map[string]any{"boolValue": v}}
+	case []string:
+		values := make([]map[string]any, len(v))
+		for i, s := range v {
+			values[i] = map[string]any{"stringValue": s}
+		}
+		return otlpAttribute{Key: key, Value: map[string]any{"arrayValue": map[string]any{"values": values}}}
+	}
+	return otlpAttribute{Key: key, Value: map[string]any{"stringValue": fmt.Sprint(v)}}
+}
+
+// randomID returns n random bytes, hex-encoded, as trace and span IDs are.
+func randomID(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
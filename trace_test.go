@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCLI_Run_Traces(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "Authorization=Bearer secret")
+	var (
+		mu    sync.Mutex
+		spans []otlpSpan
+	)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		var req otlpRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+	}))
+	defer collector.Close()
+
+	cli := &CLI{
+		Path:         []string{"/media"},
+		Delete:       true,
+		Out:          filepath.Join(t.TempDir(), "results.txt"),
+		Regex:        []string{defaultRegex},
+		OTLPEndpoint: collector.URL + "/",
+		memory: fstest.MapFS{
+			"media/book.pdf":     {Data: []byte("content")},
+			"media/book (1).pdf": {Data: []byte("content")},
+		},
+	}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	byName := make(map[string]otlpSpan)
+	ids := make(map[string]bool)
+	var names []string
+	for _, s := range spans {
+		byName[s.Name] = s
+		ids[s.SpanID] = true
+		names = append(names, s.Name)
+		if s.TraceID != spans[0].TraceID || len(s.TraceID) != 32 || len(s.SpanID) != 16 {
+			t.Errorf("span %s has trace ID %q and span ID %q", s.Name, s.TraceID, s.SpanID)
+		}
+	}
+	slices.Sort(names)
+	if want := []string{"act", "compare", "compare.group", "delete", "ohman.run", "scan"}; !slices.Equal(names, want) {
+		t.Fatalf("spans = %q, want %q", names, want)
+	}
+	for _, s := range spans {
+		if s.Name != "ohman.run" && !ids[s.ParentSpanID] {
+			t.Errorf("span %s has no parent among the exported spans", s.Name)
+		}
+	}
+	if byName["delete"].ParentSpanID != byName["act"].SpanID {
+		t.Error("deletions should be traced within the act phase")
+	}
+	if attrs := byName["scan"].Attributes; !slices.ContainsFunc(attrs, func(a otlpAttribute) bool {
+		return a.Key == "files" && a.Value["intValue"] == "2"
+	}) {
+		t.Errorf("scan span attributes = %v, want 2 files", attrs)
+	}
+}
+
+func TestTracer_ExportFails(t *testing.T) {
+	t.Parallel()
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer collector.Close()
+
+	tr, err := newTracer(collector.URL)
+	if err != nil {
+		t.Fatalf("newTracer() error = %v", err)
+	}
+	_, span := tr.root(t.Context(), "ohman.run")
+	span.finish(nil)
+	if err := tr.flush(); err == nil {
+		t.Error("expected the collector's failure to be returned")
+	}
+
+	var none *tracer
+	ctx, span := none.root(t.Context(), "ohman.run")
+	if _, child := startSpan(ctx, "scan"); child != nil || span != nil {
+		t.Error("expected no spans without a tracer")
+	}
+}
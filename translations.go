@@ -0,0 +1,55 @@
+package main
+
+import "golang.org/x/text/language"
+
+// translations maps messages passed to tr, by their English format, to their translation into each language. A
+// language is supported once it's added here; messages it lacks are left in English.
+var translations = map[language.Tag]map[string]string{
+	language.German: {
+		description: `⚠️  WARNUNG: Dieses Programm löscht Dateien endgültig. DIE NUTZUNG ERFOLGT AUF EIGENE GEFAHR.
+
+Diese Software wird ohne Mängelgewähr und ohne jegliche Garantie bereitgestellt.
+
+Sichere immer zuerst deine Dateien und probiere es vorher mit --dryrun aus.
+`,
+		"Interrupted: finishing the current group and writing results. Press Ctrl+C again to abort immediately.": "Unterbrochen: Die aktuelle Gruppe wird abgeschlossen und die Ergebnisse werden geschrieben. Drücke erneut Strg+C, um sofort abzubrechen.",
+
+		"refusing to delete within %s, which is %s; pass --force-root if this is intended": "Löschen in %s verweigert, da es sich um %s handelt; gib --force-root an, falls das beabsichtigt ist",
+		"the root of a filesystem":                  "das Wurzelverzeichnis eines Dateisystems",
+		"a home directory":                          "ein Home-Verzeichnis",
+		"the directory containing home directories": "das Verzeichnis, das die Home-Verzeichnisse enthält",
+		"a mount point":                             "einen Einhängepunkt",
+		"another ohman run is already processing %s; try again once it has finished": "ein anderer ohman-Lauf verarbeitet bereits %s; versuche es erneut, sobald er beendet ist",
+
+		"Warning: %d group(s) have copies whose content differs from the original. These groups are never changed unless --allow-different is given.": "Warnung: %d Gruppe(n) enthalten Kopien, deren Inhalt sich vom Original unterscheidet. Diese Gruppen werden nur mit --allow-different verändert.",
+		"Warning: skipped %d inaccessible entries":           "Warnung: %d unzugängliche Einträge übersprungen",
+		"Warning: skipping %s, which is %s, already scanned": "Warnung: %s wird übersprungen, da es %s ist, das bereits durchsucht wurde",
+		"Warning: skipping %s: %v":                           "Warnung: %s wird übersprungen: %v",
+
+		"Warning: %s is within a read-only snapshot, where nothing can be deleted or renamed": "Warnung: %s liegt in einem schreibgeschützten Snapshot, in dem nichts gelöscht oder umbenannt werden kann",
+
+		"Warning: couldn't lower ohman's priority: %v": "Warnung: Die Priorität von ohman konnte nicht gesenkt werden: %v",
+
+		deleteHelp: "⚠️  WARNUNG: Doppelte Dateien löschen (unter Windows in den Papierkorb, sofern --permanent nicht angegeben ist). NUTZUNG AUF EIGENE GEFAHR. Ohne jegliche Garantie.",
+
+		"Warning: %v": "Warnung: %v",
+		"Warning: unable to compare %s with %s: %v":                                                                      "Warnung: %s konnte nicht mit %s verglichen werden: %v",
+		"Warning: unable to compare the size of %s with %s: %v":                                                          "Warnung: Die Größe von %s konnte nicht mit %s verglichen werden: %v",
+		"Warning: %s (%s) differs in size from %s (%s) by %.0f%%, more than --max-size-diff; its group won't be changed": "Warnung: %s (%s) unterscheidet sich in der Größe von %s (%s) um %.0f%%, mehr als --max-size-diff; seine Gruppe wird nicht verändert",
+		"Warning: %v; it won't be carried over to %s":                                                                    "Warnung: %v; es wird nicht auf %s übertragen",
+		"Warning: the metadata of %s wasn't all kept: %v":                                                                "Warnung: Die Metadaten von %s wurden nicht vollständig übernommen: %v",
+		"Warning: failed to write the audit log %s: %v":                                                                  "Warnung: Das Audit-Log %s konnte nicht geschrieben werden: %v",
+		"Warning: webhook notification failed: %v":                                                                       "Warnung: Die Webhook-Benachrichtigung ist fehlgeschlagen: %v",
+		"Watching %d path(s) for duplicates. Press Ctrl+C to stop.":                                                      "%d Pfad(e) werden auf Duplikate überwacht. Drücke Strg+C zum Beenden.",
+		"Warning: unable to watch %s: %v":                                                                                "Warnung: %s kann nicht überwacht werden: %v",
+
+		"Found %d duplicate(s) of %d file(s)":    "%d Duplikat(e) von %d Datei(en) gefunden",
+		"Run %s.":                                "Lauf %s.",
+		"%s; nothing was changed.":               "%s; es wurde nichts verändert.",
+		"%s: deleted %d, renamed %d, failed %d.": "%s: %d gelöscht, %d umbenannt, %d fehlgeschlagen.",
+		"Results written to %s":                  "Ergebnisse nach %s geschrieben",
+		"Results appended to %s":                 "Ergebnisse an %s angehängt",
+		"ohman finished":                         "ohman ist fertig",
+		"ohman failed":                           "ohman ist fehlgeschlagen",
+	},
+}
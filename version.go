@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+
+	"github.com/alecthomas/kong"
+)
+
+// buildInfo describes the running binary, for --version --json.
+type buildInfo struct {
+	Version  string `json:"version"`
+	Commit   string `json:"commit"`
+	Date     string `json:"date"`
+	Go       string `json:"go"`
+	Platform string `json:"platform"`
+}
+
+// versionFlag is --version. Like kong.VersionFlag, it's handled before any command's required flags and arguments
+// are checked, so e.g. ohman daemon --version works without a --schedule.
+type versionFlag bool
+
+// BeforeReset prints version information and exits, as JSON if --json is given wherever it falls on the command
+// line, as flags haven't been applied yet.
+func (versionFlag) BeforeReset(k *kong.Kong, ctx *kong.Context) error {
+	asJSON := false
+	for _, p := range ctx.Path {
+		if p.Flag != nil && p.Flag.Name == "json" {
+			asJSON = true
+		}
+	}
+	if err := writeVersion(k.Stdout, asJSON); err != nil {
+		return err
+	}
+	k.Exit(0)
+	return nil
+}
+
+// writeVersion prints the version, or every detail of the build as a JSON object when asJSON is set.
+func writeVersion(w io.Writer, asJSON bool) error {
+	if !asJSON {
+		_, err := fmt.Fprintln(w, version)
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(buildInfo{
+		Version:  version,
+		Commit:   commit,
+		Date:     date,
+		Go:       runtime.Version(),
+		Platform: runtime.GOOS + "/" + runtime.GOARCH,
+	})
+}
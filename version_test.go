@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+func TestApp_Version(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		args []string
+		json bool
+	}{
+		{"plain", []string{"--version"}, false},
+		{"json", []string{"--version", "--json"}, true},
+		{"json first", []string{"--json", "--version"}, true},
+		{"command with required flags", []string{"daemon", "--version"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var out bytes.Buffer
+			exited := -1
+			parser, err := kong.New(&App{}, kong.Name("ohman"), kong.Vars{"default_pattern": defaultPattern, "delete_help": deleteHelp},
+				kong.Writers(&out, &out), kong.Exit(func(code int) { exited = code; panic(exited) }))
+			if err != nil {
+				t.Fatalf("failed to build the parser: %v", err)
+			}
+			func() {
+				defer func() { _ = recover() }()
+				_, _ = parser.Parse(tt.args)
+			}()
+			if exited != 0 {
+				t.Fatalf("expected --version to exit 0, got %d with output %q", exited, out.String())
+			}
+			if !tt.json {
+				if got := out.String(); got != version+"\n" {
+					t.Errorf("expected %q, got %q", version+"\n", got)
+				}
+				return
+			}
+			var info buildInfo
+			if err := json.Unmarshal(out.Bytes(), &info); err != nil {
+				t.Fatalf("expected JSON, got %q: %v", out.String(), err)
+			}
+			want := buildInfo{Version: version, Commit: commit, Date: date, Go: runtime.Version(), Platform: runtime.GOOS + "/" + runtime.GOARCH}
+			if info != want {
+				t.Errorf("expected %+v, got %+v", want, info)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// videoAspectSlack is how much two videos' aspect ratios may differ, as encoders round dimensions differently.
+const videoAspectSlack = 0.03
+
+// videoExtensions are the formats probed for --match video.
+var videoExtensions = map[string]bool{
+	".mp4": true, ".m4v": true, ".mkv": true, ".webm": true, ".avi": true, ".mov": true, ".wmv": true, ".flv": true,
+	".ts": true, ".m2ts": true, ".mpg": true, ".mpeg": true,
+}
+
+// episodePattern finds episode numbers such as S01E02 or 1x02 in a file name.
+var episodePattern = regexp.MustCompile(`(?i)(?:\bs(\d{1,2})[ ._-]?e(\d{1,3})|\b(\d{1,2})x(\d{2,3}))\b`)
+
+// videoInfo is what a prober reports about a video.
+type videoInfo struct {
+	Duration      time.Duration
+	Width, Height int
+	// BitRate is the overall bitrate in bits per second, or zero when unknown.
+	BitRate int64
+	// Title is the title in the container's metadata, if any.
+	Title string
+}
+
+// videoFile is a video found by the scan, along with what's needed to group it and choose which copy to keep.
+type videoFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+	info    videoInfo
+	episode string
+}
+
+// scanVideos groups videos which appear to be encodes of the same thing: their durations and aspect ratios are close,
+// and their container titles and episode numbers, where both have them, agree. The highest resolution copy in each
+// group is treated as the original, then the highest bitrate, then the largest file, then the oldest.
+func (c *CLI) scanVideos(ctx context.Context) (map[string][]string, error) {
+	probe := c.probe
+	if probe == nil {
+		if _, err := exec.LookPath(c.ffprobePath()); err != nil {
+			return nil, fmt.Errorf("--match video needs FFmpeg's ffprobe (see --ffprobe): %w", err)
+		}
+		probe = c.ffprobe
+	}
+
+	var videos []videoFile
+	err := c.walk(ctx, func(path string, info os.FileInfo) {
+		if videoExtensions[strings.ToLower(filepath.Ext(path))] {
+			videos = append(videos, videoFile{path: path, size: info.Size(), modTime: info.ModTime(), episode: episode(path)})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	probed := videos[:0]
+	for _, video := range videos {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("%w while probing videos; no files were changed", context.Cause(ctx))
+		}
+		if err := c.throttle.op(ctx); err != nil {
+			return nil, fmt.Errorf("%w while probing videos; no files were changed", context.Cause(ctx))
+		}
+		info, err := probe(ctx, video.path)
+		if err == nil && (info.Duration <= 0 || info.Width <= 0 || info.Height <= 0) {
+			err = errors.New("no video stream found")
+		}
+		if err != nil {
+			if !c.SkipErrors {
+				return nil, fmt.Errorf("unable to probe %s: %w", video.path, err)
+			}
+			c.skip(video.path, err)
+			continue
+		}
+		video.info = info
+		probed = append(probed, video)
+	}
+
+	files := make(map[string][]string)
+	for _, cluster := range groupSimilar(len(probed), func(i, j int) bool {
+		return sameVideo(probed[i], probed[j], c.VideoSlack)
+	}) {
+		members := make([]videoFile, len(cluster))
+		for i, idx := range cluster {
+			members[i] = probed[idx]
+		}
+		slices.SortFunc(members, func(a, b videoFile) int {
+			return cmp.Or(
+				cmp.Compare(b.info.Width*b.info.Height, a.info.Width*a.info.Height),
+				cmp.Compare(b.info.BitRate, a.info.BitRate),
+				cmp.Compare(b.size, a.size),
+				a.modTime.Compare(b.modTime),
+				cmp.Compare(a.path, b.path),
+			)
+		})
+		for _, m := range members[1:] {
+			files[members[0].path] = append(files[members[0].path], m.path)
+		}
+	}
+	return files, nil
+}
+
+// sameVideo reports whether a and b appear to be encodes of the same video.
+func sameVideo(a, b videoFile, slack time.Duration) bool {
+	if (a.info.Duration - b.info.Duration).Abs() > slack {
+		return false
+	}
+	aspectA := float64(a.info.Width) / float64(a.info.Height)
+	aspectB := float64(b.info.Width) / float64(b.info.Height)
+	if math.Abs(aspectA-aspectB)/max(aspectA, aspectB) > videoAspectSlack {
+		return false
+	}
+	if a.info.Title != "" && b.info.Title != "" && normalizeTag(a.info.Title) != normalizeTag(b.info.Title) {
+		return false
+	}
+	if a.episode != "" && b.episode != "" && a.episode != b.episode {
+		return false
+	}
+	return true
+}
+
+// episode returns the season and episode numbers in path's name, normalized as e.g. "s1e2", or "" if there are none.
+func episode(path string) string {
+	m := episodePattern.FindStringSubmatch(filepath.Base(path))
+	if m == nil {
+		return ""
+	}
+	season, number := m[1], m[2]
+	if season == "" {
+		season, number = m[3], m[4]
+	}
+	s, _ := strconv.Atoi(season)
+	e, _ := strconv.Atoi(number)
+	return fmt.Sprintf("s%de%d", s, e)
+}
+
+func (c *CLI) ffprobePath() string {
+	if c.Ffprobe == "" {
+		return "ffprobe"
+	}
+	return c.Ffprobe
+}
+
+// ffprobe probes the video at path by running FFmpeg's ffprobe.
+func (c *CLI) ffprobe(ctx context.Context, path string) (videoInfo, error) {
+	var stderr strings.Builder
+	cmd := exec.CommandContext(ctx, c.ffprobePath(), "-v", "error", "-print_format", "json", "-show_format", "-show_streams", path)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return videoInfo{}, fmt.Errorf("%w: %s", err, msg)
+		}
+		return videoInfo{}, err
+	}
+	return parseFfprobe(out)
+}
+
+// parseFfprobe reads the output of ffprobe -print_format json -show_format -show_streams, using the first video
+// stream's dimensions.
+func parseFfprobe(out []byte) (videoInfo, error) {
+	var result struct {
+		Streams []struct {
+			CodecType   string         `json:"codec_type"`
+			Width       int            `json:"width"`
+			Height      int            `json:"height"`
+			Disposition map[string]int `json:"disposition"`
+		} `json:"streams"`
+		Format struct {
+			Duration string            `json:"duration"`
+			BitRate  string            `json:"bit_rate"`
+			Tags     map[string]string `json:"tags"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(out, &result); err != nil {
+		return videoInfo{}, fmt.Errorf("unexpected output from ffprobe: %w", err)
+	}
+
+	var info videoInfo
+	if seconds, err := strconv.ParseFloat(result.Format.Duration, 64); err == nil {
+		info.Duration = time.Duration(seconds * float64(time.Second))
+	}
+	info.BitRate, _ = strconv.ParseInt(result.Format.BitRate, 10, 64)
+	for key, value := range result.Format.Tags {
+		// tag names are upper case in Matroska and lower case in MP4
+		if strings.EqualFold(key, "title") {
+			info.Title = strings.TrimSpace(value)
+		}
+	}
+	for _, s := range result.Streams {
+		// cover art is reported as a video stream too
+		if s.CodecType == "video" && s.Disposition["attached_pic"] == 0 {
+			info.Width, info.Height = s.Width, s.Height
+			break
+		}
+	}
+	return info, nil
+}
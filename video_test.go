@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseFfprobe(t *testing.T) {
+	out := `{
+		"streams": [
+			{"index": 0, "codec_type": "video", "codec_name": "mjpeg", "width": 600, "height": 600, "disposition": {"attached_pic": 1}},
+			{"index": 1, "codec_type": "audio", "codec_name": "aac"},
+			{"index": 2, "codec_type": "video", "codec_name": "h264", "width": 1920, "height": 1080, "disposition": {"default": 1, "attached_pic": 0}}
+		],
+		"format": {"format_name": "matroska,webm", "duration": "1325.441000", "bit_rate": "4512345", "tags": {"TITLE": " Pilot "}}
+	}`
+	info, err := parseFfprobe([]byte(out))
+	if err != nil {
+		t.Fatalf("parseFfprobe() error = %v", err)
+	}
+	want := videoInfo{Duration: 1325441 * time.Millisecond, Width: 1920, Height: 1080, BitRate: 4512345, Title: "Pilot"}
+	if info != want {
+		t.Errorf("parseFfprobe() = %+v, want %+v", info, want)
+	}
+
+	if _, err := parseFfprobe([]byte("Invalid data found when processing input")); err == nil {
+		t.Error("parseFfprobe() of invalid output should fail")
+	}
+}
+
+func TestEpisode(t *testing.T) {
+	tests := map[string]string{
+		"Show.S01E02.1080p.mkv":      "s1e2",
+		"show s01 e02.mp4":           "s1e2",
+		"Show - s1e02 - Pilot.mkv":   "s1e2",
+		"Show.S01.E02.720p.mkv":      "s1e2",
+		"Show 1x02.avi":              "s1e2",
+		"Movie (2019) 1920x1080.mp4": "",
+		"Movie.mp4":                  "",
+	}
+	for name, want := range tests {
+		if got := episode(filepath.Join("tv", name)); got != want {
+			t.Errorf("episode(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestSameVideo(t *testing.T) {
+	base := videoFile{info: videoInfo{Duration: 42 * time.Minute, Width: 1920, Height: 1080, Title: "Pilot"}, episode: "s1e1"}
+	tests := []struct {
+		name   string
+		modify func(v *videoFile)
+		want   bool
+	}{
+		{"lower resolution", func(v *videoFile) { v.info.Width, v.info.Height = 1280, 720 }, true},
+		{"odd dimensions", func(v *videoFile) { v.info.Width, v.info.Height = 720, 404 }, true},
+		{"a second longer", func(v *videoFile) { v.info.Duration += time.Second }, true},
+		{"no title", func(v *videoFile) { v.info.Title = "" }, true},
+		{"no episode", func(v *videoFile) { v.episode = "" }, true},
+		{"a minute longer", func(v *videoFile) { v.info.Duration += time.Minute }, false},
+		{"4:3", func(v *videoFile) { v.info.Width, v.info.Height = 1440, 1080 }, false},
+		{"other title", func(v *videoFile) { v.info.Title = "Second Episode" }, false},
+		{"other episode", func(v *videoFile) { v.episode = "s1e2" }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			other := base
+			tt.modify(&other)
+			if got := sameVideo(base, other, 2*time.Second); got != tt.want {
+				t.Errorf("sameVideo() = %v, want %v", got, tt.want)
+			}
+			if got := sameVideo(other, base, 2*time.Second); got != tt.want {
+				t.Errorf("sameVideo() reversed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCLI_ScanVideos(t *testing.T) {
+	dir := t.TempDir()
+	episode := 44 * time.Minute
+	infos := map[string]videoInfo{
+		"Show.S01E01.720p.mkv":       {Duration: episode, Width: 1280, Height: 720, BitRate: 2_000_000},
+		"Show.S01E01.1080p.mkv":      {Duration: episode + 500*time.Millisecond, Width: 1920, Height: 1080, BitRate: 5_000_000},
+		"Show - 1x01 (1).mp4":        {Duration: episode, Width: 1920, Height: 1080, BitRate: 3_000_000},
+		"Show.S01E02.1080p.mkv":      {Duration: episode, Width: 1920, Height: 1080, BitRate: 5_000_000},
+		"Home Movie.mov":             {Duration: 3 * time.Minute, Width: 1080, Height: 1920},
+		"Home Movie (export).mp4":    {Duration: 3*time.Minute + time.Second, Width: 720, Height: 1280},
+		"corrupt.avi":                {},
+		"Show.S01E01.1080p.mkv.part": {},
+	}
+	for name := range infos {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+
+	var probed []string
+	cli := &CLI{Path: []string{dir}, Match: "video", VideoSlack: 2 * time.Second, SkipErrors: true}
+	cli.probe = func(_ context.Context, path string) (videoInfo, error) {
+		probed = append(probed, filepath.Base(path))
+		if filepath.Base(path) == "corrupt.avi" {
+			return videoInfo{}, errors.New("moov atom not found")
+		}
+		return infos[filepath.Base(path)], nil
+	}
+	files, err := cli.scanVideos(context.Background())
+	if err != nil {
+		t.Fatalf("scanVideos() error = %v", err)
+	}
+	if len(probed) != 7 {
+		t.Errorf("probed %v, want only the 7 videos", probed)
+	}
+	if cli.skipped != 1 {
+		t.Errorf("skipped = %d, want 1 for corrupt.avi", cli.skipped)
+	}
+	want := map[string][]string{
+		filepath.Join(dir, "Show.S01E01.1080p.mkv"): {filepath.Join(dir, "Show - 1x01 (1).mp4"), filepath.Join(dir, "Show.S01E01.720p.mkv")},
+		filepath.Join(dir, "Home Movie.mov"):        {filepath.Join(dir, "Home Movie (export).mp4")},
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("scanVideos() = %v, want %v", files, want)
+	}
+}
+
+func TestCLI_ScanVideos_MissingFfprobe(t *testing.T) {
+	cli := &CLI{Path: []string{t.TempDir()}, Match: "video", Ffprobe: filepath.Join(t.TempDir(), "ffprobe")}
+	if _, err := cli.scanVideos(context.Background()); err == nil {
+		t.Error("scanVideos() without ffprobe should fail")
+	}
+}
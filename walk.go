@@ -0,0 +1,160 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// listAhead bounds how many directories are listed at once while walking, and how many of each directory's
+// subdirectories are listed ahead of the walk reaching them.
+const listAhead = 8
+
+// walkParallel walks the tree at root like filepath.Walk, calling visit for each file and directory in lexical
+// order, but lists directories ahead of the walk in parallel, as listing is the slowest part of walking a network
+// mount. Entries are only statted when more than their name and type is asked of them. Symlinks aren't followed.
+func walkParallel(root string, visit filepath.WalkFunc) error {
+	return walkAhead(root, listAhead, visit)
+}
+
+// walkAhead is walkParallel, listing no more than ahead directories at once.
+func walkAhead(root string, ahead int, visit filepath.WalkFunc) error {
+	info, err := os.Lstat(root)
+	if err != nil {
+		err = visit(root, nil, err)
+	} else {
+		w := &walker{slots: make(chan struct{}, ahead)}
+		err = w.walk(root, info, w.list(root), visit)
+	}
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+// walker lists directories for walkAhead, no more than it has slots for at once.
+type walker struct {
+	slots chan struct{}
+}
+
+// listing is a directory's entries, which are ready once done is closed.
+type listing struct {
+	done    chan struct{}
+	entries []os.DirEntry
+	err     error
+}
+
+// list starts listing the directory at path.
+func (w *walker) list(path string) *listing {
+	l := &listing{done: make(chan struct{})}
+	go func() {
+		w.slots <- struct{}{}
+		l.entries, l.err = os.ReadDir(path)
+		<-w.slots
+		close(l.done)
+	}()
+	return l
+}
+
+// walk visits path, and everything beneath it when it's a directory whose listing is pending.
+func (w *walker) walk(path string, info fs.FileInfo, pending *listing, visit filepath.WalkFunc) error {
+	if !info.IsDir() {
+		return visit(path, info, nil)
+	}
+
+	<-pending.done
+	entries, err := pending.entries, pending.err
+	if verr := visit(path, info, err); err != nil || verr != nil {
+		return verr
+	}
+
+	// the listings of the next few subdirectories are started before they're reached
+	var dirs []int
+	for i, e := range entries {
+		if e.IsDir() {
+			dirs = append(dirs, i)
+		}
+	}
+	ahead := cap(w.slots)
+	listings := make(map[int]*listing, ahead)
+	for _, i := range dirs[:min(ahead, len(dirs))] {
+		listings[i] = w.list(filepath.Join(path, entries[i].Name()))
+	}
+
+	reached := 0
+	for i, e := range entries {
+		name := filepath.Join(path, e.Name())
+		var l *listing
+		if e.IsDir() {
+			l = listings[i]
+			delete(listings, i)
+			if next := reached + ahead; next < len(dirs) {
+				listings[dirs[next]] = w.list(filepath.Join(path, entries[dirs[next]].Name()))
+			}
+			reached++
+		}
+		if err := w.walk(name, &entryInfo{DirEntry: e}, l, visit); err != nil {
+			if !e.IsDir() || err != filepath.SkipDir {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// entryInfo describes a directory entry, only statting it when more than its name and type are asked for. An entry
+// which can no longer be statted is described as empty, and statError says why.
+type entryInfo struct {
+	fs.DirEntry
+	once sync.Once
+	info fs.FileInfo
+	err  error
+}
+
+func (e *entryInfo) stat() fs.FileInfo {
+	e.once.Do(func() {
+		e.info, e.err = e.DirEntry.Info()
+	})
+	return e.info
+}
+
+// statError stats the entry described by info, if it hasn't been, returning the error that failed with. Only entries
+// described lazily, by the parallel walk, can fail once they've been found.
+func statError(info fs.FileInfo) error {
+	e, ok := info.(*entryInfo)
+	if !ok {
+		return nil
+	}
+	e.stat()
+	return e.err
+}
+
+func (e *entryInfo) Size() int64 {
+	if info := e.stat(); info != nil {
+		return info.Size()
+	}
+	return 0
+}
+
+func (e *entryInfo) Mode() fs.FileMode {
+	if info := e.stat(); info != nil {
+		return info.Mode()
+	}
+	return e.Type()
+}
+
+func (e *entryInfo) ModTime() time.Time {
+	if info := e.stat(); info != nil {
+		return info.ModTime()
+	}
+	return time.Time{}
+}
+
+func (e *entryInfo) Sys() any {
+	if info := e.stat(); info != nil {
+		return info.Sys()
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWalkParallel(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	for i := range 12 {
+		for _, name := range []string{"a.pdf", "b (1).pdf", fmt.Sprintf("sub/c%d.pdf", i)} {
+			path := filepath.Join(root, fmt.Sprintf("dir%02d", i), name)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatalf("failed to create %s: %v", filepath.Dir(path), err)
+			}
+			createTestFile(t, path, name)
+		}
+	}
+	createTestFile(t, filepath.Join(root, "top.pdf"), "top")
+
+	// skip a directory, and the rest of another after one of its files
+	skip := func(path string, info os.FileInfo) error {
+		switch filepath.Base(path) {
+		case "dir03":
+			return filepath.SkipDir
+		case "a.pdf":
+			if filepath.Base(filepath.Dir(path)) == "dir05" {
+				return filepath.SkipDir
+			}
+		}
+		return nil
+	}
+	walked := func(walk func(string, filepath.WalkFunc) error) []string {
+		var paths []string
+		err := walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			entry := path + "/"
+			if !info.IsDir() {
+				// a directory's size varies by filesystem
+				entry = fmt.Sprintf("%s %d", path, info.Size())
+			}
+			paths = append(paths, entry)
+			return skip(path, info)
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return paths
+	}
+
+	want, got := walked(filepath.Walk), walked(walkParallel)
+	if !slices.Equal(got, want) {
+		t.Errorf("walkParallel visited\n%q\nwant, as filepath.Walk does,\n%q", got, want)
+	}
+}
+
+func TestWalkParallel_MissingRoot(t *testing.T) {
+	t.Parallel()
+	missing := filepath.Join(t.TempDir(), "missing")
+	var visited []string
+	err := walkParallel(missing, func(path string, info os.FileInfo, err error) error {
+		visited = append(visited, path)
+		return err
+	})
+	if !os.IsNotExist(err) || !slices.Equal(visited, []string{missing}) {
+		t.Errorf("walkParallel() = %v visiting %q, want the root's error", err, visited)
+	}
+}
+
+func TestCLI_Walk_SkipsVanishedFiles(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	for _, name := range []string{"a.pdf", "b.pdf"} {
+		createTestFile(t, filepath.Join(dir, name), "content")
+	}
+
+	for _, skipErrors := range []bool{false, true} {
+		createTestFile(t, filepath.Join(dir, "b.pdf"), "content")
+		c := &CLI{Path: []string{dir}, SkipErrors: skipErrors, Quiet: true}
+		var visited []string
+		err := c.walk(t.Context(), func(path string, info os.FileInfo) {
+			visited = append(visited, fmt.Sprintf("%s %d", filepath.Base(path), info.Size()))
+			// b.pdf is listed along with a.pdf, but gone by the time it's statted
+			_ = os.Remove(filepath.Join(dir, "b.pdf"))
+		})
+		if !skipErrors {
+			if err == nil || !strings.Contains(err.Error(), "b.pdf") {
+				t.Errorf("walk() = %v, want b.pdf's error", err)
+			}
+			continue
+		}
+		if err != nil || c.skipped != 1 || !slices.Equal(visited, []string{"a.pdf 7"}) {
+			t.Errorf("walk() = %v visiting %q, skipping %d, want only a.pdf visited and b.pdf skipped", err, visited, c.skipped)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// WatchCmd monitors directories and applies the configured policy to duplicates as they appear.
+type WatchCmd struct {
+	CLI `embed:""`
+
+	Debounce time.Duration `name:"debounce" help:"Wait until no new files have appeared for this long before processing them." default:"5s"`
+}
+
+func (w *WatchCmd) Run(kctx *Context) error {
+	if err := w.resolvePaths(os.Stdin); err != nil {
+		return err
+	}
+	if len(w.Path) == 0 {
+		return fmt.Errorf("at least one path must be specified")
+	}
+	if w.Out != "" {
+		return fmt.Errorf("--out isn't supported when watching; results are written to stdout as they happen")
+	}
+	if w.Match != "" && w.Match != "name" {
+		return fmt.Errorf("--match %s isn't supported when watching", w.Match)
+	}
+	if w.PluginMatcher != "" {
+		return fmt.Errorf("--plugin-matcher isn't supported when watching, as new files are matched by name")
+	}
+	if conflict := w.watchConflict(); conflict != "" {
+		return fmt.Errorf("%s isn't supported when watching", conflict)
+	}
+	patterns, err := w.setupPolicy()
+	if err != nil {
+		return err
+	}
+
+	ctx := kctx.context()
+	if w.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, w.Timeout, fmt.Errorf("%w after %s", errTimedOut, w.Timeout))
+		defer cancel()
+	}
+	w.beNice()
+	w.throttle = newThrottle(w.MaxIOPS, w.Bandwidth, w.AdaptiveThrottle, w.Path)
+	if w.protected, err = newProtector(w.Protect); err != nil {
+		return err
+	}
+	if err := w.checkRoots(); err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to start watching: %w", err)
+	}
+	defer func() { _ = watcher.Close() }()
+
+	for _, p := range w.Path {
+		if err := w.watchTree(watcher, p); err != nil {
+			return fmt.Errorf("error watching path %s: %v", p, err)
+		}
+	}
+	fmt.Fprintln(os.Stderr, tr("Watching %d path(s) for duplicates. Press Ctrl+C to stop.", len(w.Path)))
+
+	// pending holds the directories in which matching files have appeared since the last batch
+	pending := make(map[string]struct{})
+	timer := time.NewTimer(w.Debounce)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if errors.Is(context.Cause(ctx), errInterrupted) {
+				return nil
+			}
+			return context.Cause(ctx)
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) && !event.Has(fsnotify.Rename) {
+				continue
+			}
+			if w.leftOut(event.Name, nil) {
+				continue
+			}
+			if info, err := os.Lstat(nativePath(event.Name)); err == nil && info.IsDir() {
+				if event.Has(fsnotify.Create) {
+					if err := w.watchTree(watcher, event.Name); err != nil {
+						fmt.Fprintln(os.Stderr, tr("Warning: unable to watch %s: %v", event.Name, err))
+					}
+				}
+				continue
+			}
+			if _, ok := originalFor(patterns, event.Name); ok {
+				pending[filepath.Dir(event.Name)] = struct{}{}
+				timer.Reset(w.Debounce)
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintln(os.Stderr, tr("Warning: %v", err))
+
+		case <-timer.C:
+			var lock *runLock
+			if w.Delete && !w.DryRun {
+				if lock, err = acquireRunLock(w.LockDir, w.Lock, w.Path); err != nil {
+					// keep the pending directories and try again later
+					fmt.Fprintln(os.Stderr, tr("Warning: %v", err))
+					timer.Reset(w.Debounce)
+					continue
+				}
+			}
+			// each batch is a run of its own in the audit log, the history, and to the webhook
+			started := time.Now()
+			w.runID = newRunID(started)
+			if w.audit, err = openAuditLog(w.AuditLog); err != nil {
+				if lock != nil {
+					lock.release()
+				}
+				fmt.Fprintln(os.Stderr, tr("Warning: %v", err))
+				timer.Reset(w.Debounce)
+				continue
+			}
+			files := w.collect(patterns, pending)
+			clear(pending)
+			groups, _ := w.apply(ctx, files)
+			if w.PruneEmptyDirs && w.Delete && !w.DryRun {
+				w.pruneEmptyDirs(context.WithoutCancel(ctx), groups, nil, nil)
+			}
+			if aerr := w.audit.close(); aerr != nil {
+				fmt.Fprintln(os.Stderr, tr("Warning: failed to write the audit log %s: %v", w.AuditLog, aerr))
+			}
+			w.audit = nil
+			if lock != nil {
+				lock.release()
+			}
+			if len(groups) > 0 {
+				fmt.Println(headResults(w.Format, w.runID, renderColored(w.Format, groups, newPalette(os.Stdout, w.NoColor))))
+			}
+			failures := collectFailures(groups)
+			if w.Webhook != "" {
+				if werr := notifyWebhook(context.WithoutCancel(ctx), w.Webhook, newWebhookPayload(&w.CLI, started, groups, failures)); werr != nil {
+					fmt.Fprintln(os.Stderr, tr("Warning: webhook notification failed: %v", werr))
+				}
+			}
+			if w.History {
+				w.recordHistory(started, groups, failures)
+			}
+			if failures != nil {
+				if w.FailFast {
+					return failures
+				}
+				fmt.Fprintln(os.Stderr, tr("Warning: %v", failures))
+			}
+		}
+	}
+}
+
+// watchConflict returns the first option given which needs every file beneath the paths, not only those in the
+// directories where copies have appeared, or "" when there's none.
+func (w *WatchCmd) watchConflict() string {
+	switch {
+	case w.Empty == "list" || w.Empty == "delete":
+		return "--empty " + w.Empty
+	case w.WriteChecksums != "":
+		return "--write-checksums"
+	case len(w.PreferPath) > 0:
+		return "--prefer-path"
+	case w.PartialDownloads:
+		return "--partial-downloads"
+	case w.cleansSync():
+		return "--preset " + syncPreset
+	}
+	return ""
+}
+
+// watchTree adds root and every directory beneath it to watcher, as fsnotify doesn't watch recursively.
+func (w *WatchCmd) watchTree(watcher *fsnotify.Watcher, root string) error {
+	seen := make(map[[2]uint64]string)
+	return walkAhead(root, w.lists(), func(path string, info os.FileInfo, err error) error {
+		if path != root && w.leftOut(path, info) {
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if err != nil {
+			if !w.SkipErrors || path == root {
+				return err
+			}
+			fmt.Fprintln(os.Stderr, tr("Warning: skipping %s: %v", path, err))
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if err := w.revisit(seen, path, info); err != nil {
+			return err
+		}
+		return watcher.Add(path)
+	})
+}
+
+// collect gathers every duplicate in the given directories, not just the newly appeared ones, so a group's policy
+// (e.g. keeping the newest file) considers all of its copies. Zero-byte files are left out with --empty skip.
+func (w *WatchCmd) collect(patterns copyPatterns, dirs map[string]struct{}) map[string][]string {
+	index := newCopyIndex(patterns)
+	for dir := range dirs {
+		entries, err := os.ReadDir(nativePath(dir))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, tr("Warning: skipping %s: %v", dir, err))
+			continue
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			if entry.IsDir() || isSpecial(entry.Type()) || w.leftOut(path, nil) {
+				continue
+			}
+			if !w.emptyMatched() {
+				// --empty skip
+				if info, err := entry.Info(); err != nil || info.Size() == 0 {
+					continue
+				}
+			}
+			index.add(path)
+		}
+	}
+	return index.files()
+}
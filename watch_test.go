@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchCmd_Run_DeletesNewDuplicates(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "original content")
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	w := &WatchCmd{
+		CLI: CLI{
+			Path:       []string{dir},
+			Delete:     true,
+			SkipErrors: true,
+			Regex:      []string{defaultRegex},
+		},
+		Debounce: 50 * time.Millisecond,
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- w.Run(&Context{Ctx: ctx}) }()
+
+	// give the watcher a moment to register before creating the duplicate
+	time.Sleep(100 * time.Millisecond)
+	duplicate := filepath.Join(dir, "book (1).pdf")
+	createTestFile(t, duplicate, "original content")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for fileExists(duplicate) && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	cancel(errInterrupted)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fileExists(duplicate) {
+		t.Error("duplicate should be deleted once it appears")
+	}
+	if !fileExists(filepath.Join(dir, "book.pdf")) {
+		t.Error("original should still exist")
+	}
+}
+
+func TestWatchCmd_Run_RejectsOut(t *testing.T) {
+	t.Parallel()
+	w := &WatchCmd{CLI: CLI{Path: []string{setupTestDir(t)}, Out: "results.txt", Regex: []string{defaultRegex}}}
+	if err := w.Run(nil); err == nil {
+		t.Fatal("expected an error when --out is given")
+	}
+}
+
+func TestWatchCmd_Run_RejectsWholeScanOptions(t *testing.T) {
+	t.Parallel()
+	for _, cli := range []CLI{
+		{Empty: "list"},
+		{Empty: "delete"},
+		{WriteChecksums: "sums.txt"},
+		{PreferPath: []string{"/mnt/library"}},
+		{PartialDownloads: true},
+		{Preset: []string{syncPreset}},
+	} {
+		cli.Path, cli.Regex = []string{setupTestDir(t)}, []string{defaultRegex}
+		w := &WatchCmd{CLI: cli}
+		if err := w.Run(nil); err == nil || !strings.Contains(err.Error(), "isn't supported when watching") {
+			t.Errorf("expected %+v to be rejected, got %v", cli, err)
+		}
+	}
+}
+
+func TestWatchCmd_Run_EmptySkip(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "original content")
+	createTestFile(t, filepath.Join(dir, "notes.mp3"), "")
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	w := &WatchCmd{
+		CLI:      CLI{Path: []string{dir}, Delete: true, Empty: "skip", Regex: []string{defaultRegex}},
+		Debounce: 50 * time.Millisecond,
+	}
+	done := make(chan error, 1)
+	go func() { done <- w.Run(&Context{Ctx: ctx}) }()
+
+	time.Sleep(100 * time.Millisecond)
+	duplicate, empty := filepath.Join(dir, "book (1).pdf"), filepath.Join(dir, "notes (1).mp3")
+	createTestFile(t, empty, "")
+	createTestFile(t, duplicate, "original content")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for fileExists(duplicate) && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	cancel(errInterrupted)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fileExists(duplicate) {
+		t.Error("duplicate should be deleted once it appears")
+	}
+	if !fileExists(empty) {
+		t.Error("an empty copy should be left alone with --empty skip")
+	}
+}
+
+func TestWatchCmd_Run_AuditLog(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "original content")
+	auditPath := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	w := &WatchCmd{
+		CLI: CLI{
+			Path:       []string{dir},
+			Delete:     true,
+			SkipErrors: true,
+			AuditLog:   auditPath,
+			Regex:      []string{defaultRegex},
+		},
+		Debounce: 50 * time.Millisecond,
+	}
+	done := make(chan error, 1)
+	go func() { done <- w.Run(&Context{Ctx: ctx}) }()
+
+	time.Sleep(100 * time.Millisecond)
+	duplicate := filepath.Join(dir, "book (1).pdf")
+	createTestFile(t, duplicate, "original content")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for fileExists(duplicate) && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	cancel(errInterrupted)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	f, err := os.Open(auditPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	if count, _, err := verifyAuditLog(f, ""); err != nil || count != 1 {
+		t.Errorf("the audit log holds %d records (%v), want the watched delete's", count, err)
+	}
+}
+
+func TestWatchCmd_Run_Filter(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "original content")
+	createTestFile(t, filepath.Join(dir, "song.mp3"), "original content")
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	w := &WatchCmd{
+		CLI: CLI{
+			Path:       []string{dir},
+			Delete:     true,
+			SkipErrors: true,
+			Filter:     `ext == "mp3"`,
+			Regex:      []string{defaultRegex},
+		},
+		Debounce: 50 * time.Millisecond,
+	}
+	done := make(chan error, 1)
+	go func() { done <- w.Run(&Context{Ctx: ctx}) }()
+
+	time.Sleep(100 * time.Millisecond)
+	book, song := filepath.Join(dir, "book (1).pdf"), filepath.Join(dir, "song (1).mp3")
+	createTestFile(t, book, "original content")
+	createTestFile(t, song, "original content")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for fileExists(song) && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	cancel(errInterrupted)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fileExists(song) {
+		t.Error("song (1).mp3 matches --filter, so should be deleted")
+	}
+	if !fileExists(book) {
+		t.Error("book (1).pdf doesn't match --filter, so should be left alone")
+	}
+}
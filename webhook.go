@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/alecthomas/kong"
+)
+
+// webhookTimeout bounds how long a run waits for the webhook endpoint to respond.
+const webhookTimeout = 10 * time.Second
+
+// webhookPayload is the JSON body POSTed to --webhook once a run completes or fails.
+type webhookPayload struct {
+	// Event is "completed" or "failed".
+	Event string `json:"event"`
+	// RunID identifies the run in its results, audit log records, and history.
+	RunID      string    `json:"run_id,omitempty"`
+	Paths      []string  `json:"paths"`
+	DryRun     bool      `json:"dry_run"`
+	Started    time.Time `json:"started"`
+	Finished   time.Time `json:"finished"`
+	ExitCode   int       `json:"exit_code"`
+	Error      string    `json:"error,omitempty"`
+	Groups     int       `json:"groups"`
+	Duplicates int       `json:"duplicates"`
+	Deleted    int       `json:"deleted"`
+	Renamed    int       `json:"renamed"`
+	Failures   int       `json:"failures"`
+	Results    []group   `json:"results,omitempty"`
+}
+
+// runCounts tallies what a run found and did.
+type runCounts struct {
+	Groups, Duplicates, Deleted, Renamed, Failures int
+	// Reclaimed is the bytes freed by deleting duplicates.
+	Reclaimed int64
+}
+
+func countGroups(groups []group) runCounts {
+	n := runCounts{Groups: len(groups)}
+	for _, g := range groups {
+		n.Duplicates += len(g.Duplicates)
+		for _, a := range g.Actions {
+			switch {
+			case a.Err != nil:
+				n.Failures++
+			case a.Op == opDelete:
+				n.Deleted++
+				n.Reclaimed += a.Size
+			case a.Op == opRename:
+				n.Renamed++
+			}
+		}
+	}
+	return n
+}
+
+// summary describes the counts in a sentence, as printed by --quiet.
+func (n runCounts) summary(dryRun bool) string {
+	found := tr("Found %d duplicate(s) of %d file(s)", n.Duplicates, n.Groups)
+	if dryRun {
+		return tr("%s; nothing was changed.", found)
+	}
+	return tr("%s: deleted %d, renamed %d, failed %d.", found, n.Deleted, n.Renamed, n.Failures)
+}
+
+// summary is the summary of n followed by the run's id, for people to find the run by.
+func (c *CLI) summary(n runCounts) string {
+	s := n.summary(c.DryRun || !c.Delete)
+	if c.runID != "" {
+		s += " " + tr("Run %s.", c.runID)
+	}
+	return s
+}
+
+func newWebhookPayload(c *CLI, started time.Time, groups []group, runErr error) webhookPayload {
+	p := webhookPayload{
+		Event:    "completed",
+		RunID:    c.runID,
+		Paths:    c.Path,
+		DryRun:   c.DryRun || !c.Delete,
+		Started:  started,
+		Finished: time.Now(),
+		ExitCode: c.status,
+	}
+	n := countGroups(groups)
+	if c.Stream {
+		// streamed groups aren't held, only counted
+		n = c.streamed
+	}
+	p.Groups, p.Duplicates, p.Deleted, p.Renamed, p.Failures = n.Groups, n.Duplicates, n.Deleted, n.Renamed, n.Failures
+	if runErr != nil {
+		p.Event, p.Error, p.ExitCode = "failed", runErr.Error(), exitFatal
+		var coder kong.ExitCoder
+		if errors.As(runErr, &coder) {
+			p.ExitCode = coder.ExitCode()
+		}
+	}
+	if c.WebhookResults {
+		p.Results = groups
+	}
+	return p
+}
+
+// notifyWebhook POSTs payload to url, treating any non-2xx response as an error.
+func notifyWebhook(ctx context.Context, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ohman/"+version)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
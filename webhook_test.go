@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+// newWebhookServer records the payloads POSTed to it.
+func newWebhookServer(t *testing.T, status int) (*httptest.Server, <-chan webhookPayload) {
+	t.Helper()
+	received := make(chan webhookPayload, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p webhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		received <- p
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(ts.Close)
+	return ts, received
+}
+
+func TestCLI_Run_Webhook_Completed(t *testing.T) {
+	t.Parallel()
+	dir := setupTestDir(t)
+	createTestFile(t, filepath.Join(dir, "book.pdf"), "original content")
+	createTestFile(t, filepath.Join(dir, "book (1).pdf"), "duplicate 1")
+	createTestFile(t, filepath.Join(dir, "book (2).pdf"), "duplicate 2")
+
+	ts, received := newWebhookServer(t, http.StatusNoContent)
+	cli := &CLI{
+		Path:           []string{dir},
+		Delete:         true,
+		AllowDifferent: true,
+		Out:            filepath.Join(dir, "results.txt"),
+		Regex:          []string{defaultRegex},
+		Webhook:        ts.URL,
+		WebhookResults: true,
+	}
+	if err := cli.Run(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	p := <-received
+	if p.Event != "completed" || p.DryRun || p.ExitCode != exitOK {
+		t.Errorf("unexpected payload: %+v", p)
+	}
+	if p.Groups != 1 || p.Duplicates != 2 || p.Deleted != 2 || p.Failures != 0 {
+		t.Errorf("unexpected counts: %+v", p)
+	}
+	if len(p.Results) != 1 || p.Results[0].Original != filepath.Join(dir, "book.pdf") {
+		t.Errorf("expected full results, got: %+v", p.Results)
+	}
+}
+
+func TestCLI_Run_Webhook_Failed(t *testing.T) {
+	t.Parallel()
+	ts, received := newWebhookServer(t, http.StatusOK)
+	cli := &CLI{
+		Path:    []string{filepath.Join(setupTestDir(t), "missing")},
+		Regex:   []string{defaultRegex},
+		Webhook: ts.URL,
+	}
+	if err := cli.Run(nil); err == nil {
+		t.Fatal("expected an error for a missing path")
+	}
+
+	p := <-received
+	if p.Event != "failed" || p.Error == "" || p.ExitCode != exitFatal {
+		t.Errorf("unexpected payload: %+v", p)
+	}
+	if p.Results != nil {
+		t.Errorf("results should only be sent with --webhook-results, got: %+v", p.Results)
+	}
+}
+
+func TestNotifyWebhook_ErrorStatus(t *testing.T) {
+	t.Parallel()
+	ts, _ := newWebhookServer(t, http.StatusInternalServerError)
+	if err := notifyWebhook(context.Background(), ts.URL, webhookPayload{Event: "completed"}); err == nil {
+		t.Error("expected an error for a non-2xx response")
+	}
+}
+
+func TestCountGroups_Summary(t *testing.T) {
+	t.Parallel()
+	groups := []group{
+		{Original: "a.pdf", Duplicates: []string{"a (1).pdf", "a (2).pdf"}, Actions: []action{
+			{Op: opDelete, Path: "a (1).pdf"},
+			{Op: opDelete, Path: "a (2).pdf", Err: errors.New("denied")},
+		}},
+		{Original: "b.pdf", Duplicates: []string{"b (1).pdf"}, Actions: []action{
+			{Op: opRename, Path: "b (1).pdf"},
+		}},
+	}
+	n := countGroups(groups)
+	if n != (runCounts{Groups: 2, Duplicates: 3, Deleted: 1, Renamed: 1, Failures: 1}) {
+		t.Errorf("countGroups() = %+v", n)
+	}
+	if got, want := n.summary(false), "Found 3 duplicate(s) of 2 file(s): deleted 1, renamed 1, failed 1."; got != want {
+		t.Errorf("summary() = %q, want %q", got, want)
+	}
+	if got, want := n.summary(true), "Found 3 duplicate(s) of 2 file(s); nothing was changed."; got != want {
+		t.Errorf("summary(dryRun) = %q, want %q", got, want)
+	}
+}